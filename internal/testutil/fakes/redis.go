@@ -0,0 +1,724 @@
+// Package fakes provides hand-written, in-memory fakes of SDK client
+// interfaces for unit tests that need realistic stateful behavior rather
+// than call-by-call expectations.
+//
+// Fakes complement the mockery-generated mocks in internal/testutil/mocks:
+// use a mock to assert on exact interactions, and a fake when the code
+// under test performs a sequence of reads and writes whose results depend
+// on earlier calls (e.g., a journal appended to and then read back).
+package fakes
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+)
+
+// Redis is an in-memory implementation of [redis.Cmdable] supporting
+// strings, hashes, lists, sets, key expiration, and publishing. It is safe for
+// concurrent use. Wrap it with [redis.NewFromClient] to obtain a
+// [*redis.Client] for the code under test:
+//
+//	fake := fakes.NewRedis()
+//	client := redis.NewFromClient(fake, nil)
+//
+// Set Err to make every subsequent command fail with that error, which is
+// useful for exercising error paths.
+//
+// Lua scripts cannot be executed; register a Go equivalent for each script
+// the code under test uses with [Redis.HandleScript].
+//
+// The fake does not implement [redis.Subscriber]; receive published
+// messages with [Redis.Listen] instead.
+type Redis struct {
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	lists    map[string][]string
+	sets     map[string]map[string]struct{}
+	expireAt map[string]time.Time
+	scripts  map[string]ScriptFunc
+	listens  map[string][]chan string
+
+	// evalMu serializes script handlers so that each runs atomically with
+	// respect to other scripts, as on a real server.
+	evalMu sync.Mutex
+
+	// Err, when non-nil, is returned by every command.
+	Err error
+}
+
+// Compile-time interface compliance check.
+var _ redis.Cmdable = (*Redis)(nil)
+
+// NewRedis returns an empty in-memory Redis fake.
+func NewRedis() *Redis {
+	return &Redis{
+		strings:  make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+		lists:    make(map[string][]string),
+		sets:     make(map[string]map[string]struct{}),
+		expireAt: make(map[string]time.Time),
+		scripts:  make(map[string]ScriptFunc),
+		listens:  make(map[string][]chan string),
+	}
+}
+
+// listenBuffer is the number of undelivered messages a [Redis.Listen]
+// channel holds before further messages to it are dropped.
+const listenBuffer = 64
+
+// Listen returns a channel receiving the payload of every message
+// subsequently published to channel, and a function that stops delivery
+// and closes it. Messages are dropped rather than blocking Publish when
+// the channel is full, as Redis does for slow subscribers.
+func (r *Redis) Listen(channel string) (<-chan string, func()) {
+	ch := make(chan string, listenBuffer)
+	r.mu.Lock()
+	r.listens[channel] = append(r.listens[channel], ch)
+	r.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			chans := r.listens[channel]
+			for i, c := range chans {
+				if c == ch {
+					r.listens[channel] = append(chans[:i:i], chans[i+1:]...)
+					break
+				}
+			}
+			close(ch)
+		})
+	}
+}
+
+// ScriptFunc emulates a Lua script for [Redis.Eval]. It receives the fake
+// itself (so it can issue ordinary commands) and the script's keys and
+// arguments, and returns the value the script would return.
+type ScriptFunc func(r *Redis, keys []string, args []interface{}) (interface{}, error)
+
+// HandleScript registers fn as the implementation of script. Eval calls
+// with an unregistered script fail.
+func (r *Redis) HandleScript(script string, fn ScriptFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts[script] = fn
+}
+
+// SetError sets the error returned by every subsequent command. Pass nil
+// to restore normal behavior.
+func (r *Redis) SetError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Err = err
+}
+
+// Keys returns the number of live keys across all data types.
+func (r *Redis) Keys() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expireAllLocked()
+	return len(r.strings) + len(r.hashes) + len(r.lists) + len(r.sets)
+}
+
+// expireLocked removes key if its expiration has passed.
+func (r *Redis) expireLocked(key string) {
+	if at, ok := r.expireAt[key]; ok && !time.Now().Before(at) {
+		r.deleteLocked(key)
+	}
+}
+
+// expireAllLocked removes every expired key.
+func (r *Redis) expireAllLocked() {
+	for key := range r.expireAt {
+		r.expireLocked(key)
+	}
+}
+
+// deleteLocked removes key from every keyspace and reports whether it
+// existed.
+func (r *Redis) deleteLocked(key string) bool {
+	_, s := r.strings[key]
+	_, h := r.hashes[key]
+	_, l := r.lists[key]
+	_, m := r.sets[key]
+	delete(r.strings, key)
+	delete(r.hashes, key)
+	delete(r.lists, key)
+	delete(r.sets, key)
+	delete(r.expireAt, key)
+	return s || h || l || m
+}
+
+// existsLocked reports whether key exists in any keyspace.
+func (r *Redis) existsLocked(key string) bool {
+	r.expireLocked(key)
+	_, s := r.strings[key]
+	_, h := r.hashes[key]
+	_, l := r.lists[key]
+	_, m := r.sets[key]
+	return s || h || l || m
+}
+
+// toString renders a command argument the way go-redis would.
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case bool:
+		if t {
+			return "1"
+		}
+		return "0"
+	default:
+		if s, ok := v.(interface{ String() string }); ok {
+			return s.String()
+		}
+		return ""
+	}
+}
+
+// Set implements [redis.Cmdable].
+func (r *Redis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *goredis.StatusCmd {
+	cmd := goredis.NewStatusCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.deleteLocked(key)
+	r.strings[key] = toString(value)
+	if expiration > 0 {
+		r.expireAt[key] = time.Now().Add(expiration)
+	}
+	cmd.SetVal("OK")
+	return cmd
+}
+
+// Get implements [redis.Cmdable]. Returns [goredis.Nil] for missing keys.
+func (r *Redis) Get(ctx context.Context, key string) *goredis.StringCmd {
+	cmd := goredis.NewStringCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	v, ok := r.strings[key]
+	if !ok {
+		cmd.SetErr(goredis.Nil)
+		return cmd
+	}
+	cmd.SetVal(v)
+	return cmd
+}
+
+// Del implements [redis.Cmdable].
+func (r *Redis) Del(ctx context.Context, keys ...string) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	var n int64
+	for _, k := range keys {
+		r.expireLocked(k)
+		if r.deleteLocked(k) {
+			n++
+		}
+	}
+	cmd.SetVal(n)
+	return cmd
+}
+
+// Exists implements [redis.Cmdable].
+func (r *Redis) Exists(ctx context.Context, keys ...string) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	var n int64
+	for _, k := range keys {
+		if r.existsLocked(k) {
+			n++
+		}
+	}
+	cmd.SetVal(n)
+	return cmd
+}
+
+// Expire implements [redis.Cmdable].
+func (r *Redis) Expire(ctx context.Context, key string, expiration time.Duration) *goredis.BoolCmd {
+	cmd := goredis.NewBoolCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	if !r.existsLocked(key) {
+		cmd.SetVal(false)
+		return cmd
+	}
+	r.expireAt[key] = time.Now().Add(expiration)
+	cmd.SetVal(true)
+	return cmd
+}
+
+// TTL implements [redis.Cmdable]. Returns -2 for missing keys and -1 for
+// keys without an expiration, matching Redis semantics.
+func (r *Redis) TTL(ctx context.Context, key string) *goredis.DurationCmd {
+	cmd := goredis.NewDurationCmd(ctx, time.Second)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	if !r.existsLocked(key) {
+		cmd.SetVal(-2)
+		return cmd
+	}
+	at, ok := r.expireAt[key]
+	if !ok {
+		cmd.SetVal(-1)
+		return cmd
+	}
+	cmd.SetVal(time.Until(at))
+	return cmd
+}
+
+// Incr implements [redis.Cmdable].
+func (r *Redis) Incr(ctx context.Context, key string) *goredis.IntCmd {
+	return r.incrBy(ctx, key, 1)
+}
+
+// Decr implements [redis.Cmdable].
+func (r *Redis) Decr(ctx context.Context, key string) *goredis.IntCmd {
+	return r.incrBy(ctx, key, -1)
+}
+
+// incrBy adds delta to the integer stored at key.
+func (r *Redis) incrBy(ctx context.Context, key string, delta int64) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	var cur int64
+	if v, ok := r.strings[key]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			cmd.SetErr(goredis.Nil)
+			return cmd
+		}
+		cur = n
+	}
+	cur += delta
+	r.strings[key] = strconv.FormatInt(cur, 10)
+	cmd.SetVal(cur)
+	return cmd
+}
+
+// HSet implements [redis.Cmdable]. Values must be alternating field/value
+// pairs or a single map[string]interface{} / map[string]string.
+func (r *Redis) HSet(ctx context.Context, key string, values ...interface{}) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	h, ok := r.hashes[key]
+	if !ok {
+		h = make(map[string]string)
+		r.hashes[key] = h
+	}
+	var added int64
+	set := func(f, v string) {
+		if _, exists := h[f]; !exists {
+			added++
+		}
+		h[f] = v
+	}
+	if len(values) == 1 {
+		switch m := values[0].(type) {
+		case map[string]interface{}:
+			for f, v := range m {
+				set(f, toString(v))
+			}
+		case map[string]string:
+			for f, v := range m {
+				set(f, v)
+			}
+		}
+	} else {
+		for i := 0; i+1 < len(values); i += 2 {
+			set(toString(values[i]), toString(values[i+1]))
+		}
+	}
+	cmd.SetVal(added)
+	return cmd
+}
+
+// HGet implements [redis.Cmdable]. Returns [goredis.Nil] for missing
+// fields.
+func (r *Redis) HGet(ctx context.Context, key, field string) *goredis.StringCmd {
+	cmd := goredis.NewStringCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	v, ok := r.hashes[key][field]
+	if !ok {
+		cmd.SetErr(goredis.Nil)
+		return cmd
+	}
+	cmd.SetVal(v)
+	return cmd
+}
+
+// HGetAll implements [redis.Cmdable].
+func (r *Redis) HGetAll(ctx context.Context, key string) *goredis.MapStringStringCmd {
+	cmd := goredis.NewMapStringStringCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	out := make(map[string]string, len(r.hashes[key]))
+	for f, v := range r.hashes[key] {
+		out[f] = v
+	}
+	cmd.SetVal(out)
+	return cmd
+}
+
+// HDel implements [redis.Cmdable].
+func (r *Redis) HDel(ctx context.Context, key string, fields ...string) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	var n int64
+	for _, f := range fields {
+		if _, ok := r.hashes[key][f]; ok {
+			delete(r.hashes[key], f)
+			n++
+		}
+	}
+	if len(r.hashes[key]) == 0 {
+		delete(r.hashes, key)
+	}
+	cmd.SetVal(n)
+	return cmd
+}
+
+// LPush implements [redis.Cmdable].
+func (r *Redis) LPush(ctx context.Context, key string, values ...interface{}) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	for _, v := range values {
+		r.lists[key] = append([]string{toString(v)}, r.lists[key]...)
+	}
+	cmd.SetVal(int64(len(r.lists[key])))
+	return cmd
+}
+
+// RPush implements [redis.Cmdable].
+func (r *Redis) RPush(ctx context.Context, key string, values ...interface{}) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	for _, v := range values {
+		r.lists[key] = append(r.lists[key], toString(v))
+	}
+	cmd.SetVal(int64(len(r.lists[key])))
+	return cmd
+}
+
+// listRange resolves Redis-style inclusive, possibly negative, offsets
+// against a list of length n. ok is false if the range is empty.
+func listRange(n int, start, stop int64) (lo, hi int, ok bool) {
+	s, e := int(start), int(stop)
+	if s < 0 {
+		s += n
+	}
+	if e < 0 {
+		e += n
+	}
+	if s < 0 {
+		s = 0
+	}
+	if e >= n {
+		e = n - 1
+	}
+	if s > e || s >= n {
+		return 0, 0, false
+	}
+	return s, e, true
+}
+
+// LRange implements [redis.Cmdable].
+func (r *Redis) LRange(ctx context.Context, key string, start, stop int64) *goredis.StringSliceCmd {
+	cmd := goredis.NewStringSliceCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	list := r.lists[key]
+	lo, hi, ok := listRange(len(list), start, stop)
+	if !ok {
+		cmd.SetVal([]string{})
+		return cmd
+	}
+	out := make([]string, hi-lo+1)
+	copy(out, list[lo:hi+1])
+	cmd.SetVal(out)
+	return cmd
+}
+
+// LLen implements [redis.Cmdable].
+func (r *Redis) LLen(ctx context.Context, key string) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	cmd.SetVal(int64(len(r.lists[key])))
+	return cmd
+}
+
+// LTrim implements [redis.Cmdable].
+func (r *Redis) LTrim(ctx context.Context, key string, start, stop int64) *goredis.StatusCmd {
+	cmd := goredis.NewStatusCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	list := r.lists[key]
+	lo, hi, ok := listRange(len(list), start, stop)
+	if !ok {
+		delete(r.lists, key)
+	} else {
+		trimmed := make([]string, hi-lo+1)
+		copy(trimmed, list[lo:hi+1])
+		r.lists[key] = trimmed
+	}
+	cmd.SetVal("OK")
+	return cmd
+}
+
+// SAdd implements [redis.Cmdable].
+func (r *Redis) SAdd(ctx context.Context, key string, members ...interface{}) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	set, ok := r.sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		r.sets[key] = set
+	}
+	var n int64
+	for _, m := range members {
+		s := toString(m)
+		if _, exists := set[s]; !exists {
+			set[s] = struct{}{}
+			n++
+		}
+	}
+	cmd.SetVal(n)
+	return cmd
+}
+
+// SMembers implements [redis.Cmdable].
+func (r *Redis) SMembers(ctx context.Context, key string) *goredis.StringSliceCmd {
+	cmd := goredis.NewStringSliceCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	out := make([]string, 0, len(r.sets[key]))
+	for m := range r.sets[key] {
+		out = append(out, m)
+	}
+	cmd.SetVal(out)
+	return cmd
+}
+
+// SIsMember implements [redis.Cmdable].
+func (r *Redis) SIsMember(ctx context.Context, key string, member interface{}) *goredis.BoolCmd {
+	cmd := goredis.NewBoolCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	_, ok := r.sets[key][toString(member)]
+	cmd.SetVal(ok)
+	return cmd
+}
+
+// SRem implements [redis.Cmdable].
+func (r *Redis) SRem(ctx context.Context, key string, members ...interface{}) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	r.expireLocked(key)
+	var n int64
+	for _, m := range members {
+		s := toString(m)
+		if _, ok := r.sets[key][s]; ok {
+			delete(r.sets[key], s)
+			n++
+		}
+	}
+	if len(r.sets[key]) == 0 {
+		delete(r.sets, key)
+	}
+	cmd.SetVal(n)
+	return cmd
+}
+
+// Eval implements [redis.Cmdable] by dispatching to the handler registered
+// for script with [Redis.HandleScript]. A nil handler result is reported
+// as [goredis.Nil], matching go-redis.
+func (r *Redis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *goredis.Cmd {
+	cmd := goredis.NewCmd(ctx)
+	r.mu.Lock()
+	err := r.Err
+	fn, ok := r.scripts[script]
+	r.mu.Unlock()
+	if err != nil {
+		cmd.SetErr(err)
+		return cmd
+	}
+	if !ok {
+		cmd.SetErr(errors.New("fakes: no handler registered for script"))
+		return cmd
+	}
+
+	r.evalMu.Lock()
+	defer r.evalMu.Unlock()
+	val, err := fn(r, keys, args)
+	switch {
+	case err != nil:
+		cmd.SetErr(err)
+	case val == nil:
+		cmd.SetErr(goredis.Nil)
+	default:
+		cmd.SetVal(val)
+	}
+	return cmd
+}
+
+// Publish implements [redis.Cmdable] by delivering message to the
+// channels returned by [Redis.Listen] for channel.
+func (r *Redis) Publish(ctx context.Context, channel string, message interface{}) *goredis.IntCmd {
+	cmd := goredis.NewIntCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	payload := toString(message)
+	var n int64
+	for _, ch := range r.listens[channel] {
+		select {
+		case ch <- payload:
+			n++
+		default:
+		}
+	}
+	cmd.SetVal(n)
+	return cmd
+}
+
+// Ping implements [redis.Cmdable].
+func (r *Redis) Ping(ctx context.Context) *goredis.StatusCmd {
+	cmd := goredis.NewStatusCmd(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		cmd.SetErr(r.Err)
+		return cmd
+	}
+	cmd.SetVal("PONG")
+	return cmd
+}
+
+// Close implements [redis.Cmdable]. It is a no-op.
+func (r *Redis) Close() error {
+	return nil
+}
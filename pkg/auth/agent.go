@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/lifecycle"
+)
+
+// AgentIdentity represents an AI agent operating within the platform,
+// either autonomously or on behalf of a user. It carries the agent's
+// metadata from [lifecycle.AgentInfo] (instance ID, name, version, and
+// capabilities), the permissions granted to the agent itself, and the
+// identity it acts for, if any.
+//
+// An agent acting on behalf of a user may do only what both of them may
+// do: its effective permissions, returned by [AgentIdentity.Permissions]
+// and checked by [AgentIdentity.HasPermission] and [Authorize], are the
+// intersection of the agent's permissions and the user's. See
+// [NewAgentIdentity] for how the intersection is computed.
+//
+// AgentIdentity is immutable after creation.
+type AgentIdentity struct {
+	id           string
+	name         string
+	version      string
+	capabilities []lifecycle.Capability
+	claims       map[string]any
+	agentPerms   []Permission
+	onBehalfOf   Identity
+	permissions  []Permission
+}
+
+// NewAgentIdentity creates a new AgentIdentity for the agent described by
+// info, whose ID, Name, Version, and Capabilities are recorded; the
+// remaining fields of info are runtime state and are ignored. Claims,
+// permissions, and capabilities are defensively copied to ensure
+// immutability.
+//
+// permissions are the permissions granted to the agent itself. If
+// onBehalfOf is nil, the agent acts autonomously and its effective
+// permissions are its own. Otherwise onBehalfOf is the delegating
+// identity, typically a [UserIdentity], and the effective permissions are
+// those granted by both: for each pair of an agent and a delegating
+// permission whose resources, actions, and scopes overlap, the narrower
+// of each is kept, so that {documents:*} and {documents:read:production}
+// yield {documents:read:production}. A delegating identity that does not
+// implement [PermissionLister] (e.g., [BasicIdentity]) grants nothing.
+//
+// Conditions of both permissions must hold. Conditions are evaluated
+// against the agent's claims, so a delegating permission whose condition
+// refers to claims (e.g., "owner == claims.sub") would be evaluated for
+// the wrong principal and is not delegated.
+//
+// Returns an error if info.ID is empty, as it is required for
+// authorization and audit purposes.
+func NewAgentIdentity(info lifecycle.AgentInfo, claims map[string]any, permissions []Permission, onBehalfOf Identity) (*AgentIdentity, error) {
+	if info.ID == "" {
+		return nil, errors.New("auth: agent identity id must not be empty")
+	}
+	copiedClaims := make(map[string]any, len(claims))
+	for k, v := range claims {
+		copiedClaims[k] = v
+	}
+	copiedCaps := make([]lifecycle.Capability, len(info.Capabilities))
+	for i, c := range info.Capabilities {
+		copiedCaps[i] = c.Clone()
+	}
+	copiedPerms := make([]Permission, len(permissions))
+	copy(copiedPerms, permissions)
+
+	effective := copiedPerms
+	if onBehalfOf != nil {
+		var delegated []Permission
+		if lister, ok := onBehalfOf.(PermissionLister); ok {
+			delegated = lister.Permissions()
+		}
+		effective = intersectPermissions(copiedPerms, delegated)
+	}
+	return &AgentIdentity{
+		id:           info.ID,
+		name:         info.Name,
+		version:      info.Version,
+		capabilities: copiedCaps,
+		claims:       copiedClaims,
+		agentPerms:   copiedPerms,
+		onBehalfOf:   onBehalfOf,
+		permissions:  effective,
+	}, nil
+}
+
+// ID returns the agent instance identifier.
+func (a *AgentIdentity) ID() string { return a.id }
+
+// Type returns IdentityTypeAgent.
+func (a *AgentIdentity) Type() IdentityType { return IdentityTypeAgent }
+
+// Claims returns a shallow copy of the agent identity's claims.
+func (a *AgentIdentity) Claims() map[string]any {
+	copied := make(map[string]any, len(a.claims))
+	for k, v := range a.claims {
+		copied[k] = v
+	}
+	return copied
+}
+
+// HasPermission checks whether the agent's effective permissions grant
+// the specified permission. Supports wildcard matching: a permission with
+// Resource="*" matches any resource, and Action="*" matches any action.
+func (a *AgentIdentity) HasPermission(resource, action string) bool {
+	return hasPermission(a.permissions, resource, action)
+}
+
+// Name returns the human-readable name of the agent type.
+func (a *AgentIdentity) Name() string { return a.name }
+
+// Version returns the version of the agent implementation.
+func (a *AgentIdentity) Version() string { return a.version }
+
+// Capabilities returns a deep copy of the agent's capabilities.
+func (a *AgentIdentity) Capabilities() []lifecycle.Capability {
+	copied := make([]lifecycle.Capability, len(a.capabilities))
+	for i, c := range a.capabilities {
+		copied[i] = c.Clone()
+	}
+	return copied
+}
+
+// HasCapability reports whether the agent declares a capability with the
+// given name.
+func (a *AgentIdentity) HasCapability(name string) bool {
+	for _, c := range a.capabilities {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// OnBehalfOf returns the identity the agent acts for, or nil if the agent
+// acts autonomously.
+func (a *AgentIdentity) OnBehalfOf() Identity { return a.onBehalfOf }
+
+// AgentPermissions returns a copy of the permissions granted to the agent
+// itself, before intersection with those of the delegating identity.
+func (a *AgentIdentity) AgentPermissions() []Permission {
+	copied := make([]Permission, len(a.agentPerms))
+	copy(copied, a.agentPerms)
+	return copied
+}
+
+// Permissions returns a copy of the agent's effective permissions: its
+// own permissions if it acts autonomously, or their intersection with
+// those of the delegating identity otherwise.
+func (a *AgentIdentity) Permissions() []Permission {
+	copied := make([]Permission, len(a.permissions))
+	copy(copied, a.permissions)
+	return copied
+}
+
+// intersectPermissions returns the permissions granted by both agent and
+// delegated: the narrowest permission of each overlapping pair, without
+// duplicates, in the order of agent.
+func intersectPermissions(agent, delegated []Permission) []Permission {
+	result := make([]Permission, 0)
+	seen := make(map[Permission]bool)
+	for _, a := range agent {
+		for _, d := range delegated {
+			p, ok := narrowPermission(a, d)
+			if !ok || seen[p] {
+				continue
+			}
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// narrowPermission returns the permission granting exactly what both a
+// and d grant. Reports false if they do not overlap, or if d has a
+// condition that refers to claims.
+func narrowPermission(a, d Permission) (Permission, bool) {
+	resource, ok := narrowValue(a.Resource, d.Resource, a.Resource == "*", d.Resource == "*")
+	if !ok {
+		return Permission{}, false
+	}
+	action, ok := narrowValue(a.Action, d.Action, a.Action == "*", d.Action == "*")
+	if !ok {
+		return Permission{}, false
+	}
+	scope, ok := narrowValue(a.Scope, d.Scope,
+		a.Scope == "" || a.Scope == "*", d.Scope == "" || d.Scope == "*")
+	if !ok {
+		return Permission{}, false
+	}
+
+	condition := a.Condition
+	if d.Condition != "" {
+		c, err := compiledCondition(d.Condition)
+		if err != nil || c.refersToClaims() {
+			return Permission{}, false
+		}
+		if condition == "" {
+			condition = d.Condition
+		} else {
+			condition = "(" + condition + ") && (" + d.Condition + ")"
+		}
+	}
+	return Permission{Resource: resource, Action: action, Scope: scope, Condition: condition}, true
+}
+
+// narrowValue returns the narrower of two permission values, where aAny
+// and dAny report whether each matches every value. Reports false if
+// neither matches every value and they differ.
+func narrowValue(a, d string, aAny, dAny bool) (string, bool) {
+	switch {
+	case aAny:
+		return d, true
+	case dAny, a == d:
+		return a, true
+	default:
+		return "", false
+	}
+}
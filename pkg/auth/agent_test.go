@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+	"github.com/StricklySoft/stricklysoft-core/pkg/lifecycle"
+)
+
+// newTestAgentInfo returns the info of a running agent with one
+// capability.
+func newTestAgentInfo() lifecycle.AgentInfo {
+	return lifecycle.AgentInfo{
+		ID:      "agent-1",
+		Name:    "research-agent",
+		Version: "1.4.0",
+		State:   lifecycle.StateRunning,
+		Capabilities: []lifecycle.Capability{
+			{Name: "web-search", Version: "1.0.0", Metadata: map[string]string{"provider": "internal"}},
+		},
+	}
+}
+
+// ---------------------------------------------------------------------------
+// NewAgentIdentity
+// ---------------------------------------------------------------------------
+
+func TestNewAgentIdentity(t *testing.T) {
+	t.Parallel()
+	info := newTestAgentInfo()
+	claims := map[string]any{"sub": "agent-1"}
+	perms := []Permission{{Resource: "documents", Action: "read"}}
+
+	agent, err := NewAgentIdentity(info, claims, perms, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "agent-1", agent.ID())
+	assert.Equal(t, IdentityTypeAgent, agent.Type())
+	assert.Equal(t, "research-agent", agent.Name())
+	assert.Equal(t, "1.4.0", agent.Version())
+	assert.Equal(t, info.Capabilities, agent.Capabilities())
+	assert.True(t, agent.HasCapability("web-search"))
+	assert.False(t, agent.HasCapability("code-execution"))
+	assert.Nil(t, agent.OnBehalfOf())
+	assert.Equal(t, perms, agent.Permissions(), "an autonomous agent should have its own permissions")
+	assert.Equal(t, perms, agent.AgentPermissions())
+	assert.True(t, agent.HasPermission("documents", "read"))
+
+	// Inputs and results are copies.
+	info.Capabilities[0].Metadata["provider"] = "mutated"
+	claims["sub"] = "mutated"
+	perms[0].Action = "delete"
+	caps := agent.Capabilities()
+	caps[0].Metadata["provider"] = "mutated"
+	assert.Equal(t, "internal", agent.Capabilities()[0].Metadata["provider"])
+	assert.Equal(t, "agent-1", agent.Claims()["sub"])
+	assert.True(t, agent.HasPermission("documents", "read"))
+	assert.False(t, agent.HasPermission("documents", "delete"))
+}
+
+func TestNewAgentIdentity_EmptyID(t *testing.T) {
+	t.Parallel()
+	_, err := NewAgentIdentity(lifecycle.AgentInfo{Name: "research-agent"}, nil, nil, nil)
+	assert.Error(t, err)
+}
+
+// ---------------------------------------------------------------------------
+// Delegation
+// ---------------------------------------------------------------------------
+
+func TestAgentIdentity_OnBehalfOf(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		agent []Permission
+		user  []Permission
+		want  []Permission
+	}{
+		{
+			name:  "common permission",
+			agent: []Permission{{Resource: "documents", Action: "read"}, {Resource: "agents", Action: "execute"}},
+			user:  []Permission{{Resource: "documents", Action: "read"}, {Resource: "users", Action: "delete"}},
+			want:  []Permission{{Resource: "documents", Action: "read"}},
+		},
+		{
+			name:  "agent wildcard narrowed by user",
+			agent: []Permission{{Resource: "*", Action: "*"}},
+			user:  []Permission{{Resource: "documents", Action: "write", Scope: "production"}},
+			want:  []Permission{{Resource: "documents", Action: "write", Scope: "production"}},
+		},
+		{
+			name:  "user wildcard narrowed by agent",
+			agent: []Permission{{Resource: "documents", Action: "read", Scope: "staging"}},
+			user:  []Permission{{Resource: "documents", Action: "*", Scope: "*"}},
+			want:  []Permission{{Resource: "documents", Action: "read", Scope: "staging"}},
+		},
+		{
+			name:  "resource and action from each side",
+			agent: []Permission{{Resource: "documents", Action: "*"}},
+			user:  []Permission{{Resource: "*", Action: "read"}},
+			want:  []Permission{{Resource: "documents", Action: "read"}},
+		},
+		{
+			name:  "disjoint scopes",
+			agent: []Permission{{Resource: "documents", Action: "read", Scope: "production"}},
+			user:  []Permission{{Resource: "documents", Action: "read", Scope: "staging"}},
+			want:  []Permission{},
+		},
+		{
+			name:  "duplicates removed",
+			agent: []Permission{{Resource: "documents", Action: "*"}, {Resource: "*", Action: "read"}},
+			user:  []Permission{{Resource: "documents", Action: "read"}},
+			want:  []Permission{{Resource: "documents", Action: "read"}},
+		},
+		{
+			name:  "user without permissions",
+			agent: []Permission{{Resource: "*", Action: "*"}},
+			want:  []Permission{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			user := mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", nil, tt.user)
+			agent, err := NewAgentIdentity(newTestAgentInfo(), nil, tt.agent, user)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, agent.Permissions())
+			assert.Equal(t, tt.agent, agent.AgentPermissions())
+			assert.Same(t, user, agent.OnBehalfOf())
+		})
+	}
+}
+
+func TestAgentIdentity_OnBehalfOfConditions(t *testing.T) {
+	t.Parallel()
+	agentPerms := []Permission{
+		{Resource: "documents", Action: "read", Condition: `namespace == "research"`},
+		{Resource: "reports", Action: "read"},
+		{Resource: "tickets", Action: "read"},
+	}
+	userPerms := []Permission{
+		{Resource: "documents", Action: "read", Condition: "time.hour >= 9"},
+		{Resource: "reports", Action: "read", Condition: `namespace != "finance"`},
+		{Resource: "tickets", Action: "read", Condition: "owner == claims.sub"},
+	}
+	user := mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", map[string]any{"sub": "usr-1"}, userPerms)
+	agent, err := NewAgentIdentity(newTestAgentInfo(), map[string]any{"sub": "agent-1"}, agentPerms, user)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Permission{
+		{Resource: "documents", Action: "read", Condition: `(namespace == "research") && (time.hour >= 9)`},
+		{Resource: "reports", Action: "read", Condition: `namespace != "finance"`},
+	}, agent.Permissions(), "user conditions on claims cannot be evaluated for the agent")
+
+	err = AuthorizeRequest(agent, AccessRequest{
+		Resource: "reports", Action: "read",
+		Attributes: map[string]any{"namespace": "finance"},
+	})
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationDenied), "error = %v", err)
+	assert.NoError(t, AuthorizeRequest(agent, AccessRequest{
+		Resource: "reports", Action: "read",
+		Attributes: map[string]any{"namespace": "research"},
+	}))
+}
+
+func TestAgentIdentity_Authorize(t *testing.T) {
+	t.Parallel()
+	user := mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", nil, []Permission{
+		{Resource: "documents", Action: "*", Scope: "production"},
+		{Resource: "users", Action: "delete"},
+	})
+	agent, err := NewAgentIdentity(newTestAgentInfo(), nil, []Permission{{Resource: "documents", Action: "read"}}, user)
+	require.NoError(t, err)
+
+	assert.NoError(t, Authorize(agent, "documents", "read", "production"))
+	err = Authorize(agent, "documents", "read", "staging")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationInsufficientScope), "error = %v", err)
+	err = Authorize(agent, "documents", "write", "production")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationDenied), "agent lacks write: %v", err)
+	err = Authorize(agent, "users", "delete", "")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationDenied), "agent lacks delete: %v", err)
+}
+
+func TestAgentIdentity_OnBehalfOfWithoutPermissions(t *testing.T) {
+	t.Parallel()
+	caller := NewBasicIdentity("usr-1", IdentityTypeUser, nil)
+	agent, err := NewAgentIdentity(newTestAgentInfo(), nil, []Permission{{Resource: "*", Action: "*"}}, caller)
+	require.NoError(t, err)
+	assert.Empty(t, agent.Permissions(), "an identity without a permission list should delegate nothing")
+	assert.False(t, agent.HasPermission("documents", "read"))
+}
@@ -0,0 +1,434 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/postgres"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+const (
+	// APIKeyPrefix starts every API key issued by [APIKeyValidator], so
+	// that keys can be routed with [MatchTokenPrefix] and found by secret
+	// scanners.
+	APIKeyPrefix = "sk_"
+
+	// DefaultAPIKeyTable is the table used by [APIKeyValidator] when no
+	// table name is supplied.
+	DefaultAPIKeyTable = "auth_api_keys"
+
+	// apiKeyIDBytes and apiKeySecretBytes are the random lengths of an
+	// API key's public identifier and its secret.
+	apiKeyIDBytes     = 8
+	apiKeySecretBytes = 32
+
+	// apiKeySaltBytes is the length of the random salt hashed with each
+	// key's secret.
+	apiKeySaltBytes = 16
+
+	// apiKeyLastUsedInterval is how stale a key's recorded last use may
+	// become before a validation updates it, which limits validations to
+	// one write per key per interval.
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// apiKeyIDLength is the length of the hex-encoded public key identifier.
+var apiKeyIDLength = hex.EncodedLen(apiKeyIDBytes)
+
+// APIKey describes an issued API key. It never holds the key itself,
+// which is only returned once by [APIKeyValidator.Issue].
+type APIKey struct {
+	// ID is the key's public identifier, embedded in the key after
+	// [APIKeyPrefix]. It is safe to log and display.
+	ID string `json:"id"`
+
+	// Name is a human-readable label for the key, used as the service
+	// name of the identities it authenticates.
+	Name string `json:"name"`
+
+	// OwnerID is the identity ID the key authenticates as.
+	OwnerID string `json:"owner_id"`
+
+	// Scopes are the permission strings granted to the key, in the
+	// format accepted by [ParsePermissionString].
+	Scopes []string `json:"scopes"`
+
+	// CreatedAt is when the key was issued.
+	CreatedAt time.Time `json:"created_at"`
+
+	// ExpiresAt is when the key stops being accepted. Zero means the key
+	// does not expire.
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// LastUsedAt is when the key was last accepted, to within a minute.
+	// Zero means the key has not been used.
+	LastUsedAt time.Time `json:"last_used_at"`
+
+	// RevokedAt is when the key was revoked. Zero means the key is not
+	// revoked.
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// APIKeyRequest describes an API key to issue with
+// [APIKeyValidator.Issue].
+type APIKeyRequest struct {
+	// Name is a human-readable label for the key. Required.
+	Name string
+
+	// OwnerID is the identity ID the key authenticates as. Required.
+	OwnerID string
+
+	// Scopes are the permission strings to grant, such as
+	// "agents:read" or "deployments:write:staging". Each must be accepted
+	// by [ParsePermissionString].
+	Scopes []string
+
+	// ExpiresAt is when the key stops being accepted. Zero means the key
+	// does not expire.
+	ExpiresAt time.Time
+}
+
+// ---------------------------------------------------------------------------
+// APIKeyValidator — long-lived API keys stored hashed in PostgreSQL
+// ---------------------------------------------------------------------------
+
+// APIKeyValidator authenticates machine clients with long-lived API keys
+// stored in a PostgreSQL table. It implements the [TokenValidator]
+// interface and issues, lists, and revokes keys. Call
+// [APIKeyValidator.EnsureSchema] once at startup (or manage the table
+// with your migration tool) before use.
+//
+// Keys have the form "sk_<id>_<secret>". Only the ID and a salted
+// SHA-256 hash of the secret are stored, so a leaked table does not
+// reveal usable keys. The secret holds 256 random bits, so a slow
+// password hash would add latency to every request without making it
+// harder to guess.
+//
+// Accepted keys produce a [ServiceIdentity] with the key's OwnerID as ID,
+// its Name as service name, and its scopes as permissions. Keys are
+// looked up on every request, so revocation takes effect immediately.
+//
+// APIKeyValidator is safe for concurrent use.
+type APIKeyValidator struct {
+	client *postgres.Client
+	table  string
+	tracer trace.Tracer
+	now    func() time.Time
+}
+
+// Compile-time assertion that APIKeyValidator implements TokenValidator.
+var _ TokenValidator = (*APIKeyValidator)(nil)
+
+// NewAPIKeyValidator returns an APIKeyValidator that stores keys in table
+// using client. If table is empty, [DefaultAPIKeyTable] is used.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if client is
+// nil or table is not a valid, optionally schema-qualified identifier.
+func NewAPIKeyValidator(client *postgres.Client, table string) (*APIKeyValidator, error) {
+	if client == nil {
+		return nil, sserr.New(sserr.CodeValidation, "auth: API key validator requires a client")
+	}
+	if table == "" {
+		table = DefaultAPIKeyTable
+	}
+	if !tableNamePattern.MatchString(table) {
+		return nil, sserr.Newf(sserr.CodeValidation, "auth: invalid API key table name %q", table)
+	}
+	return &APIKeyValidator{
+		client: client,
+		table:  table,
+		tracer: otel.Tracer(tracerName),
+		now:    time.Now,
+	}, nil
+}
+
+// EnsureSchema creates the API key table and its owner index if they do
+// not already exist.
+func (v *APIKeyValidator) EnsureSchema(ctx context.Context) error {
+	stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id           TEXT        PRIMARY KEY,
+	name         TEXT        NOT NULL,
+	owner_id     TEXT        NOT NULL,
+	scopes       TEXT[]      NOT NULL DEFAULT '{}',
+	salt         BYTEA       NOT NULL,
+	hash         BYTEA       NOT NULL,
+	created_at   TIMESTAMPTZ NOT NULL,
+	expires_at   TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	revoked_at   TIMESTAMPTZ
+)`, v.table)
+	if _, err := v.client.Exec(ctx, stmt); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to create API key table")
+	}
+	index := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_owner_idx ON %s (owner_id)`,
+		strings.ReplaceAll(v.table, ".", "_"), v.table)
+	if _, err := v.client.Exec(ctx, index); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to create API key table index")
+	}
+	return nil
+}
+
+// Issue creates an API key for req and returns the key, which is not
+// stored and cannot be retrieved again, with its description.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if req is
+// invalid, or [sserr.CodeInternalDatabase] if the key cannot be stored.
+func (v *APIKeyValidator) Issue(ctx context.Context, req APIKeyRequest) (string, APIKey, error) {
+	if req.Name == "" {
+		return "", APIKey{}, sserr.New(sserr.CodeValidation, "auth: API key name must not be empty")
+	}
+	if req.OwnerID == "" {
+		return "", APIKey{}, sserr.New(sserr.CodeValidation, "auth: API key owner ID must not be empty")
+	}
+	for _, s := range req.Scopes {
+		if _, err := ParsePermissionString(s); err != nil {
+			return "", APIKey{}, sserr.Wrapf(err, sserr.CodeValidation, "auth: invalid API key scope %q", s)
+		}
+	}
+	now := v.now().UTC()
+	if !req.ExpiresAt.IsZero() && !req.ExpiresAt.After(now) {
+		return "", APIKey{}, sserr.New(sserr.CodeValidation, "auth: API key expiry must be in the future")
+	}
+
+	random := make([]byte, apiKeyIDBytes+apiKeySecretBytes+apiKeySaltBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", APIKey{}, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to generate API key")
+	}
+	id := hex.EncodeToString(random[:apiKeyIDBytes])
+	secret := base64.RawURLEncoding.EncodeToString(random[apiKeyIDBytes : apiKeyIDBytes+apiKeySecretBytes])
+	salt := random[apiKeyIDBytes+apiKeySecretBytes:]
+
+	key := APIKey{
+		ID:        id,
+		Name:      req.Name,
+		OwnerID:   req.OwnerID,
+		Scopes:    append([]string{}, req.Scopes...),
+		CreatedAt: now,
+	}
+	if !req.ExpiresAt.IsZero() {
+		key.ExpiresAt = req.ExpiresAt.UTC()
+	}
+
+	stmt := fmt.Sprintf(`INSERT INTO %s (id, name, owner_id, scopes, salt, hash, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, v.table)
+	_, err := v.client.Exec(ctx, stmt, key.ID, key.Name, key.OwnerID, key.Scopes,
+		salt, hashAPIKeySecret(salt, secret), key.CreatedAt, nullTime(key.ExpiresAt))
+	if err != nil {
+		return "", APIKey{}, sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to store API key")
+	}
+	return APIKeyPrefix + id + "_" + secret, key, nil
+}
+
+// List returns the keys issued to ownerID, or every key if ownerID is
+// empty, oldest first. Revoked and expired keys are included.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeInternalDatabase] if the
+// table cannot be read.
+func (v *APIKeyValidator) List(ctx context.Context, ownerID string) ([]APIKey, error) {
+	stmt := fmt.Sprintf(`SELECT id, name, owner_id, scopes, created_at, expires_at, last_used_at, revoked_at
+FROM %s`, v.table)
+	var args []any
+	if ownerID != "" {
+		stmt += " WHERE owner_id = $1"
+		args = append(args, ownerID)
+	}
+	stmt += " ORDER BY created_at, id"
+
+	rows, err := v.client.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to list API keys")
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var key APIKey
+		var expiresAt, lastUsedAt, revokedAt *time.Time
+		if err := rows.Scan(&key.ID, &key.Name, &key.OwnerID, &key.Scopes, &key.CreatedAt,
+			&expiresAt, &lastUsedAt, &revokedAt); err != nil {
+			return nil, sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to scan API key")
+		}
+		key.CreatedAt = key.CreatedAt.UTC()
+		key.ExpiresAt, key.LastUsedAt, key.RevokedAt = timeOrZero(expiresAt), timeOrZero(lastUsedAt), timeOrZero(revokedAt)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to list API keys")
+	}
+	return keys, nil
+}
+
+// Revoke revokes the key with the public identifier id, which is
+// rejected from then on. Revoking a revoked key succeeds and keeps the
+// original revocation time.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeNotFound] if there is no
+// such key, or [sserr.CodeInternalDatabase] if the table cannot be
+// updated.
+func (v *APIKeyValidator) Revoke(ctx context.Context, id string) error {
+	stmt := fmt.Sprintf(`UPDATE %s SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1`, v.table)
+	tag, err := v.client.Exec(ctx, stmt, id, v.now().UTC())
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to revoke API key")
+	}
+	if tag.RowsAffected() == 0 {
+		return sserr.Newf(sserr.CodeNotFound, "auth: API key %q not found", id)
+	}
+	return nil
+}
+
+// Validate checks the API key tokenStr and returns the identity it
+// authenticates, recording the key's use.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeAuthenticationInvalid]
+// if the key is malformed, unknown, or revoked,
+// [sserr.CodeAuthenticationExpired] if it has expired, or
+// [sserr.CodeUnavailable] if the table cannot be read.
+func (v *APIKeyValidator) Validate(ctx context.Context, tokenStr string) (Identity, error) {
+	ctx, span := startSpan(ctx, v.tracer, "auth.ValidateAPIKey")
+	defer span.End()
+
+	identity, err := v.validate(ctx, tokenStr, span)
+	if err != nil {
+		finishSpan(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("auth.identity_id", identity.ID()))
+	return identity, nil
+}
+
+// validate implements Validate.
+func (v *APIKeyValidator) validate(ctx context.Context, tokenStr string, span trace.Span) (Identity, error) {
+	id, secret, ok := parseAPIKey(tokenStr)
+	if !ok {
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: API key is malformed")
+	}
+	span.SetAttributes(attribute.String("auth.api_key_id", id))
+
+	stmt := fmt.Sprintf(`SELECT name, owner_id, scopes, salt, hash, expires_at, last_used_at, revoked_at
+FROM %s WHERE id = $1`, v.table)
+	key := APIKey{ID: id}
+	var salt, hash []byte
+	var expiresAt, lastUsedAt, revokedAt *time.Time
+	err := v.client.QueryRow(ctx, stmt, id).Scan(&key.Name, &key.OwnerID, &key.Scopes, &salt, &hash,
+		&expiresAt, &lastUsedAt, &revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: API key is invalid")
+	}
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeUnavailable, "auth: failed to look up API key")
+	}
+	// Compare in constant time, and before reporting any other state of
+	// the key, so that only holders of the secret learn about it.
+	if subtle.ConstantTimeCompare(hashAPIKeySecret(salt, secret), hash) != 1 {
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: API key is invalid")
+	}
+	if revokedAt != nil {
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: API key has been revoked")
+	}
+	now := v.now()
+	if expiresAt != nil && !now.Before(*expiresAt) {
+		return nil, sserr.New(sserr.CodeAuthenticationExpired, "auth: API key has expired")
+	}
+
+	permissions := make([]Permission, 0, len(key.Scopes))
+	for _, s := range key.Scopes {
+		p, err := ParsePermissionString(s)
+		if err != nil {
+			// Scopes are checked when keys are issued, so this is a
+			// corrupt or hand-edited row; grant nothing from it.
+			slog.WarnContext(ctx, "auth: skipping invalid API key scope",
+				"api_key_id", id,
+				"scope", s,
+				"error", err,
+			)
+			continue
+		}
+		permissions = append(permissions, p)
+	}
+	claims := map[string]any{
+		"sub":        key.OwnerID,
+		"api_key_id": id,
+		"scope":      strings.Join(key.Scopes, " "),
+	}
+	identity, err := NewServiceIdentity(key.OwnerID, key.Name, "", claims, permissions)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeAuthenticationInvalid, "auth: failed to create identity from API key")
+	}
+
+	if lastUsedAt == nil || now.Sub(*lastUsedAt) >= apiKeyLastUsedInterval {
+		v.recordUse(ctx, id, now)
+	}
+	return identity, nil
+}
+
+// recordUse sets the last use of the key with id to now. Failures are
+// logged rather than returned, since they do not affect the key's
+// validity.
+func (v *APIKeyValidator) recordUse(ctx context.Context, id string, now time.Time) {
+	stmt := fmt.Sprintf(`UPDATE %s SET last_used_at = $2 WHERE id = $1`, v.table)
+	if _, err := v.client.Exec(ctx, stmt, id, now.UTC()); err != nil {
+		slog.WarnContext(ctx, "auth: failed to record API key use",
+			"api_key_id", id,
+			"error", err,
+		)
+	}
+}
+
+// parseAPIKey splits an API key into its public identifier and secret.
+// It reports false if the key does not have the form "sk_<id>_<secret>".
+func parseAPIKey(key string) (id, secret string, ok bool) {
+	rest, found := strings.CutPrefix(key, APIKeyPrefix)
+	if !found || len(rest) < apiKeyIDLength+2 || rest[apiKeyIDLength] != '_' {
+		return "", "", false
+	}
+	id, secret = rest[:apiKeyIDLength], rest[apiKeyIDLength+1:]
+	if _, err := hex.DecodeString(id); err != nil || strings.ToLower(id) != id {
+		return "", "", false
+	}
+	if len(secret) != base64.RawURLEncoding.EncodedLen(apiKeySecretBytes) {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+// hashAPIKeySecret returns the SHA-256 hash of salt followed by secret.
+func hashAPIKeySecret(salt []byte, secret string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return h.Sum(nil)
+}
+
+// nullTime returns t, or nil if t is zero, for nullable timestamp
+// columns.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// timeOrZero returns the UTC time t points to, or the zero time if t is
+// nil.
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/postgres"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// apiKeyTestNow is the fixed time seen by validators from
+// newAPIKeyTestValidator.
+var apiKeyTestNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// apiKeyTestID and apiKeyTestKey are a well-formed API key and its
+// public identifier.
+const (
+	apiKeyTestID  = "0123456789abcdef"
+	apiKeyTestKey = APIKeyPrefix + apiKeyTestID + "_AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+)
+
+// apiKeyTestColumns are the columns read by APIKeyValidator.Validate.
+var apiKeyTestColumns = []string{"name", "owner_id", "scopes", "salt", "hash", "expires_at", "last_used_at", "revoked_at"}
+
+// captureArg is a pgxmock argument matcher that records the value it is
+// matched against.
+type captureArg struct {
+	value any
+}
+
+func (c *captureArg) Match(v any) bool {
+	c.value = v
+	return true
+}
+
+// newAPIKeyTestValidator returns an APIKeyValidator backed by a pgxmock
+// pool, with its clock fixed at apiKeyTestNow.
+func newAPIKeyTestValidator(t *testing.T) (*APIKeyValidator, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	t.Cleanup(mock.Close)
+	v, err := NewAPIKeyValidator(postgres.NewFromPool(mock, nil), "")
+	require.NoError(t, err)
+	v.now = func() time.Time { return apiKeyTestNow }
+	return v, mock
+}
+
+// apiKeyTestRow returns the row stored for apiKeyTestKey with the given
+// expiry, last use, and revocation times, which may be nil.
+func apiKeyTestRow(expiresAt, lastUsedAt, revokedAt *time.Time) *pgxmock.Rows {
+	_, secret, _ := parseAPIKey(apiKeyTestKey)
+	salt := []byte("0123456789abcdef")
+	return pgxmock.NewRows(apiKeyTestColumns).AddRow("ci-deployer", "svc-ci", []string{"deployments:write:staging", "agents:read"},
+		salt, hashAPIKeySecret(salt, secret), expiresAt, lastUsedAt, revokedAt)
+}
+
+// ---------------------------------------------------------------------------
+// Construction and schema
+// ---------------------------------------------------------------------------
+
+func TestNewAPIKeyValidator_Invalid(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	_, err = NewAPIKeyValidator(nil, "")
+	assert.True(t, sserr.IsValidation(err))
+	_, err = NewAPIKeyValidator(postgres.NewFromPool(mock, nil), "keys; DROP TABLE x")
+	assert.True(t, sserr.IsValidation(err))
+}
+
+func TestAPIKeyValidator_EnsureSchema(t *testing.T) {
+	t.Parallel()
+	v, mock := newAPIKeyTestValidator(t)
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS auth_api_keys").WillReturnResult(pgxmock.NewResult("CREATE", 0))
+	mock.ExpectExec("CREATE INDEX IF NOT EXISTS auth_api_keys_owner_idx").WillReturnResult(pgxmock.NewResult("CREATE", 0))
+
+	require.NoError(t, v.EnsureSchema(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// ---------------------------------------------------------------------------
+// Issue, List, and Revoke
+// ---------------------------------------------------------------------------
+
+func TestAPIKeyValidator_IssueAndValidate(t *testing.T) {
+	t.Parallel()
+	v, mock := newAPIKeyTestValidator(t)
+	ctx := context.Background()
+
+	id, salt, hash := &captureArg{}, &captureArg{}, &captureArg{}
+	mock.ExpectExec("INSERT INTO auth_api_keys").
+		WithArgs(id, "ci-deployer", "svc-ci", []string{"agents:read"}, salt, hash, apiKeyTestNow, nil).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	key, info, err := v.Issue(ctx, APIKeyRequest{Name: "ci-deployer", OwnerID: "svc-ci", Scopes: []string{"agents:read"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix+info.ID+"_"), "key %q", key)
+	assert.Equal(t, info.ID, id.value)
+	assert.Equal(t, apiKeyTestNow, info.CreatedAt)
+	assert.True(t, info.ExpiresAt.IsZero())
+	assert.NotContains(t, string(hash.value.([]byte)), strings.TrimPrefix(key, APIKeyPrefix+info.ID+"_"))
+
+	mock.ExpectQuery("SELECT name, owner_id, scopes, salt, hash").WithArgs(info.ID).
+		WillReturnRows(pgxmock.NewRows(apiKeyTestColumns).AddRow("ci-deployer", "svc-ci", []string{"agents:read"},
+			salt.value, hash.value, nil, nil, nil))
+	mock.ExpectExec("UPDATE auth_api_keys SET last_used_at").WithArgs(info.ID, apiKeyTestNow).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	identity, err := v.Validate(ctx, key)
+	require.NoError(t, err)
+	svc, ok := identity.(*ServiceIdentity)
+	require.True(t, ok, "expected *ServiceIdentity, got %T", identity)
+	assert.Equal(t, "svc-ci", svc.ID())
+	assert.Equal(t, "ci-deployer", svc.ServiceName())
+	assert.True(t, svc.HasPermission("agents", "read"))
+	assert.Equal(t, info.ID, svc.Claims()["api_key_id"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyValidator_Issue_Invalid(t *testing.T) {
+	t.Parallel()
+	v, _ := newAPIKeyTestValidator(t)
+	tests := []struct {
+		name string
+		req  APIKeyRequest
+	}{
+		{"no name", APIKeyRequest{OwnerID: "svc-ci"}},
+		{"no owner", APIKeyRequest{Name: "ci"}},
+		{"bad scope", APIKeyRequest{Name: "ci", OwnerID: "svc-ci", Scopes: []string{"nocolon"}}},
+		{"past expiry", APIKeyRequest{Name: "ci", OwnerID: "svc-ci", ExpiresAt: apiKeyTestNow}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := v.Issue(context.Background(), tt.req)
+			assert.True(t, sserr.IsValidation(err), "error = %v", err)
+		})
+	}
+}
+
+func TestAPIKeyValidator_List(t *testing.T) {
+	t.Parallel()
+	v, mock := newAPIKeyTestValidator(t)
+	expires := apiKeyTestNow.Add(time.Hour)
+	mock.ExpectQuery("SELECT id, name, owner_id, scopes, created_at, expires_at, last_used_at, revoked_at FROM auth_api_keys WHERE owner_id = \\$1").
+		WithArgs("svc-ci").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "owner_id", "scopes", "created_at", "expires_at", "last_used_at", "revoked_at"}).
+			AddRow("a", "first", "svc-ci", []string{"agents:read"}, apiKeyTestNow, &expires, nil, nil).
+			AddRow("b", "second", "svc-ci", []string{}, apiKeyTestNow, nil, &apiKeyTestNow, &apiKeyTestNow))
+
+	keys, err := v.List(context.Background(), "svc-ci")
+	require.NoError(t, err)
+	assert.Equal(t, []APIKey{
+		{ID: "a", Name: "first", OwnerID: "svc-ci", Scopes: []string{"agents:read"}, CreatedAt: apiKeyTestNow, ExpiresAt: expires},
+		{ID: "b", Name: "second", OwnerID: "svc-ci", Scopes: []string{}, CreatedAt: apiKeyTestNow, LastUsedAt: apiKeyTestNow, RevokedAt: apiKeyTestNow},
+	}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyValidator_Revoke(t *testing.T) {
+	t.Parallel()
+	v, mock := newAPIKeyTestValidator(t)
+	mock.ExpectExec("UPDATE auth_api_keys SET revoked_at").WithArgs("a", apiKeyTestNow).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE auth_api_keys SET revoked_at").WithArgs("missing", apiKeyTestNow).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	require.NoError(t, v.Revoke(context.Background(), "a"))
+	err := v.Revoke(context.Background(), "missing")
+	assert.True(t, sserr.IsNotFound(err), "error = %v", err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// ---------------------------------------------------------------------------
+// Validate
+// ---------------------------------------------------------------------------
+
+func TestAPIKeyValidator_Validate_Malformed(t *testing.T) {
+	t.Parallel()
+	v, mock := newAPIKeyTestValidator(t)
+	for _, key := range []string{
+		"",
+		"token",
+		"sk_0123456789abcdef",
+		"sk_0123456789ABCDEF_AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+		"sk_0123456789abcdef-AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+		"sk_0123456789abcdef_short",
+		"pk_0123456789abcdef_AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+	} {
+		_, err := v.Validate(context.Background(), key)
+		assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "key %q: %v", key, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet(), "malformed keys should not be looked up")
+}
+
+func TestAPIKeyValidator_Validate_RecentUseNotRecorded(t *testing.T) {
+	t.Parallel()
+	v, mock := newAPIKeyTestValidator(t)
+	recent := apiKeyTestNow.Add(-10 * time.Second)
+	mock.ExpectQuery("SELECT name").WithArgs(apiKeyTestID).WillReturnRows(apiKeyTestRow(nil, &recent, nil))
+
+	identity, err := v.Validate(context.Background(), apiKeyTestKey)
+	require.NoError(t, err)
+	assert.Contains(t, identity.(*ServiceIdentity).Permissions(),
+		Permission{Resource: "deployments", Action: "write", Scope: "staging"})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyValidator_Validate_Rejects(t *testing.T) {
+	t.Parallel()
+	past := apiKeyTestNow.Add(-time.Minute)
+	wrongSecret := pgxmock.NewRows(apiKeyTestColumns).
+		AddRow("ci", "svc-ci", []string{}, []byte("salt"), []byte("not the hash"), nil, nil, nil)
+
+	tests := []struct {
+		name   string
+		expect func(mock pgxmock.PgxPoolIface)
+		code   sserr.Code
+	}{
+		{"unknown", func(m pgxmock.PgxPoolIface) {
+			m.ExpectQuery("SELECT name").WithArgs(apiKeyTestID).WillReturnError(pgx.ErrNoRows)
+		}, sserr.CodeAuthenticationInvalid},
+		{"wrong secret", func(m pgxmock.PgxPoolIface) {
+			m.ExpectQuery("SELECT name").WithArgs(apiKeyTestID).WillReturnRows(wrongSecret)
+		}, sserr.CodeAuthenticationInvalid},
+		{"revoked", func(m pgxmock.PgxPoolIface) {
+			m.ExpectQuery("SELECT name").WithArgs(apiKeyTestID).WillReturnRows(apiKeyTestRow(nil, nil, &past))
+		}, sserr.CodeAuthenticationInvalid},
+		{"expired", func(m pgxmock.PgxPoolIface) {
+			m.ExpectQuery("SELECT name").WithArgs(apiKeyTestID).WillReturnRows(apiKeyTestRow(&past, nil, nil))
+		}, sserr.CodeAuthenticationExpired},
+		{"database down", func(m pgxmock.PgxPoolIface) {
+			m.ExpectQuery("SELECT name").WithArgs(apiKeyTestID).WillReturnError(errors.New("connection refused"))
+		}, sserr.CodeUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			v, mock := newAPIKeyTestValidator(t)
+			tt.expect(mock)
+			_, err := v.Validate(context.Background(), apiKeyTestKey)
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, tt.code), "error = %v", err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
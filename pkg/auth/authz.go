@@ -0,0 +1,447 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// PermissionLister is implemented by identities that carry an explicit
+// permission list, such as [ServiceIdentity], [UserIdentity], and
+// [AgentIdentity].
+type PermissionLister interface {
+	Permissions() []Permission
+}
+
+// PermissionsOf returns the permissions of identity as a [PermissionSet].
+// Identities that do not implement [PermissionLister] (e.g.,
+// [BasicIdentity]) yield an empty set.
+func PermissionsOf(identity Identity) *PermissionSet {
+	if lister, ok := identity.(PermissionLister); ok {
+		return NewPermissionSet(lister.Permissions())
+	}
+	return NewPermissionSet(nil)
+}
+
+// Authorize checks whether identity is granted action on resource within
+// scope. An empty scope is a scope-agnostic check, as with
+// [PermissionSet.Match].
+//
+// Conditional permissions are evaluated against the identity's claims
+// only; a condition that refers to request attributes does not hold. Use
+// [AuthorizeRequest] to supply attributes.
+//
+// Returns nil if access is granted, or a [*sserr.Error] with code
+// [sserr.CodeAuthentication] if identity is nil,
+// [sserr.CodeAuthorizationInsufficientScope] if the identity holds the
+// permission but not for scope, or [sserr.CodeAuthorizationDenied]
+// otherwise.
+func Authorize(identity Identity, resource, action, scope string) error {
+	return AuthorizeRequest(identity, AccessRequest{Resource: resource, Action: action, Scope: scope})
+}
+
+// AuthorizeRequest is [Authorize] for req, whose Attributes and Time are
+// used to evaluate conditional permissions. req.Claims is replaced with
+// the identity's claims.
+func AuthorizeRequest(identity Identity, req AccessRequest) error {
+	if identity == nil {
+		return sserr.New(sserr.CodeAuthentication, "auth: no authenticated identity")
+	}
+	req.Claims = identity.Claims()
+	if err := authorize(PermissionsOf(identity), req, req.Scope != ""); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AuthorizeContext is [Authorize] for the identity stored in ctx by
+// [HTTPMiddleware] or the gRPC server interceptors.
+func AuthorizeContext(ctx context.Context, resource, action, scope string) error {
+	identity, _ := IdentityFromContext(ctx)
+	return Authorize(identity, resource, action, scope)
+}
+
+// authorize makes an authorization decision for req against ps,
+// evaluating conditional permissions against req. When scoped is true, the
+// request targets a specific scope: an empty scope then means the scope
+// could not be determined, and only global permissions (scope "" or "*")
+// are accepted.
+func authorize(ps *PermissionSet, req AccessRequest, scoped bool) *sserr.Error {
+	resource, action, scope := req.Resource, req.Action, req.Scope
+	if req.Time.IsZero() {
+		req.Time = time.Now().UTC()
+	}
+	unscoped := req
+	unscoped.Scope = ""
+
+	var granted bool
+	switch {
+	case !scoped:
+		granted = ps.MatchRequest(unscoped)
+	case scope == "":
+		granted = ps.matchGlobal(resource, action) || ps.matchConditional(req, true)
+	default:
+		granted = ps.MatchRequest(req)
+	}
+	if granted {
+		return nil
+	}
+
+	perm := Permission{Resource: resource, Action: action, Scope: scope}
+	details := map[string]any{"permission": perm.String()}
+	if scoped && ps.MatchRequest(unscoped) {
+		if scope == "" {
+			return sserr.Newf(sserr.CodeAuthorizationInsufficientScope,
+				"auth: permission %s requires a scope", perm).WithDetails(details)
+		}
+		details["scope"] = scope
+		return sserr.Newf(sserr.CodeAuthorizationInsufficientScope,
+			"auth: permission %s not granted for scope %q", Permission{Resource: resource, Action: action}, scope).
+			WithDetails(details)
+	}
+	return sserr.Newf(sserr.CodeAuthorizationDenied,
+		"auth: permission %s denied", perm).WithDetails(details)
+}
+
+// matchGlobal reports whether the set grants resource and action in every
+// scope, i.e., through a permission whose scope is "" or "*".
+func (ps *PermissionSet) matchGlobal(resource, action string) bool {
+	if ps.Has(resource, action, "") {
+		return true
+	}
+	for _, p := range ps.wildcards {
+		if (p.Scope == "" || p.Scope == "*") && p.Match(resource, action, "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// ===========================================================================
+// Scope and Attribute Extraction
+// ===========================================================================
+
+// ScopeExtractor returns the authorization scope targeted by an HTTP
+// request (e.g., a tenant or environment), or "" if the request does not
+// name one.
+type ScopeExtractor func(r *http.Request) string
+
+// GRPCScopeExtractor returns the authorization scope targeted by a gRPC
+// call, or "" if the call does not name one. req is nil for streams.
+type GRPCScopeExtractor func(ctx context.Context, fullMethod string, req any) string
+
+// AttributeExtractor returns the attributes of an HTTP request that
+// conditional permissions are evaluated against (see [Condition]), such
+// as the owner or namespace of the resource being accessed.
+type AttributeExtractor func(r *http.Request) map[string]any
+
+// GRPCAttributeExtractor returns the attributes of a gRPC call that
+// conditional permissions are evaluated against. req is nil for streams.
+type GRPCAttributeExtractor func(ctx context.Context, fullMethod string, req any) map[string]any
+
+// ScopeFromHeader extracts the scope from the named request header.
+func ScopeFromHeader(name string) ScopeExtractor {
+	return func(r *http.Request) string { return r.Header.Get(name) }
+}
+
+// ScopeFromQuery extracts the scope from the named query parameter.
+func ScopeFromQuery(param string) ScopeExtractor {
+	return func(r *http.Request) string { return r.URL.Query().Get(param) }
+}
+
+// ScopeFromPathValue extracts the scope from the named wildcard of the
+// [http.ServeMux] pattern that matched the request (e.g., "env" in
+// "/envs/{env}/deployments").
+func ScopeFromPathValue(name string) ScopeExtractor {
+	return func(r *http.Request) string { return r.PathValue(name) }
+}
+
+// ScopeFromMetadata extracts the scope from the named incoming gRPC
+// metadata key.
+func ScopeFromMetadata(key string) GRPCScopeExtractor {
+	return func(ctx context.Context, _ string, _ any) string {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return ""
+		}
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+}
+
+// AuthorizationOption configures [RequirePermission] and
+// [NewMethodAuthorizer].
+type AuthorizationOption func(*authorizationOptions)
+
+// authorizationOptions holds the settings applied by
+// [AuthorizationOption]s.
+type authorizationOptions struct {
+	httpScope      ScopeExtractor
+	grpcScope      GRPCScopeExtractor
+	httpAttributes AttributeExtractor
+	grpcAttributes GRPCAttributeExtractor
+}
+
+// WithScopeExtractor sets how [RequirePermission] determines the scope of
+// a request. Without it, checks are scope-agnostic. With it, a request
+// whose scope cannot be determined is only granted by a global
+// permission.
+func WithScopeExtractor(fn ScopeExtractor) AuthorizationOption {
+	return func(o *authorizationOptions) { o.httpScope = fn }
+}
+
+// WithGRPCScopeExtractor sets how a [MethodAuthorizer] determines the
+// scope of a call, with the same semantics as [WithScopeExtractor].
+// Methods whose permission names a fixed scope ignore the extractor.
+func WithGRPCScopeExtractor(fn GRPCScopeExtractor) AuthorizationOption {
+	return func(o *authorizationOptions) { o.grpcScope = fn }
+}
+
+// WithAttributeExtractor sets how [RequirePermission] determines the
+// attributes of a request for evaluating conditional permissions. Without
+// it, conditions are evaluated against the identity's claims only.
+func WithAttributeExtractor(fn AttributeExtractor) AuthorizationOption {
+	return func(o *authorizationOptions) { o.httpAttributes = fn }
+}
+
+// WithGRPCAttributeExtractor sets how a [MethodAuthorizer] determines the
+// attributes of a call, with the same semantics as
+// [WithAttributeExtractor].
+func WithGRPCAttributeExtractor(fn GRPCAttributeExtractor) AuthorizationOption {
+	return func(o *authorizationOptions) { o.grpcAttributes = fn }
+}
+
+// newAuthorizationOptions applies opts to a zero-value configuration.
+func newAuthorizationOptions(opts []AuthorizationOption) authorizationOptions {
+	var o authorizationOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// ===========================================================================
+// HTTP
+// ===========================================================================
+
+// RequirePermission returns an HTTP middleware that only passes requests
+// whose identity is granted action on resource. It must run after
+// [HTTPMiddleware], which stores the identity in the request context.
+//
+// Requests without an identity receive HTTP 401 Unauthorized; denied
+// requests receive HTTP 403 Forbidden, whether the permission is missing
+// ([sserr.CodeAuthorizationDenied]) or not granted for the request's
+// scope ([sserr.CodeAuthorizationInsufficientScope]). Both are written
+// with [WriteProblem], so clients can tell the two apart by code.
+//
+// Example:
+//
+//	mux.Handle("DELETE /envs/{env}/executions/{id}",
+//	    auth.RequirePermission("executions", "delete",
+//	        auth.WithScopeExtractor(auth.ScopeFromPathValue("env")),
+//	    )(deleteHandler))
+func RequirePermission(resource, action string, opts ...AuthorizationOption) func(http.Handler) http.Handler {
+	o := newAuthorizationOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			identity, ok := IdentityFromContext(ctx)
+			if !ok {
+				WriteProblem(w, r, sserr.New(sserr.CodeAuthentication, "auth: authentication required"))
+				return
+			}
+
+			req := AccessRequest{Resource: resource, Action: action, Claims: identity.Claims()}
+			if o.httpScope != nil {
+				req.Scope = o.httpScope(r)
+			}
+			if o.httpAttributes != nil {
+				req.Attributes = o.httpAttributes(r)
+			}
+			if err := authorize(PermissionsOf(identity), req, o.httpScope != nil); err != nil {
+				logDenied(ctx, identity, err)
+				WriteProblem(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ===========================================================================
+// gRPC
+// ===========================================================================
+
+// methodRule is the requirement for one gRPC method.
+type methodRule struct {
+	perm Permission
+
+	// public is true if the method requires authentication only.
+	public bool
+}
+
+// MethodAuthorizer authorizes gRPC calls against a declarative map of
+// method names to required permissions. It is safe for concurrent use.
+type MethodAuthorizer struct {
+	rules      map[string]methodRule
+	scope      GRPCScopeExtractor
+	attributes GRPCAttributeExtractor
+}
+
+// NewMethodAuthorizer creates a [MethodAuthorizer] from a map of full
+// gRPC method names to permission strings in [ParsePermissionString]
+// format:
+//
+//	authz, err := auth.NewMethodAuthorizer(map[string]string{
+//	    "/executions.v1.ExecutionService/Get":    "executions:read",
+//	    "/executions.v1.ExecutionService/Delete": "executions:delete",
+//	    "/admin.v1.AdminService/*":               "admin:*:production",
+//	    "/grpc.health.v1.Health/*":               "",
+//	})
+//
+// A key of the form "/package.Service/*" applies to every method of the
+// service that has no entry of its own. An empty permission requires
+// authentication only. Calls to methods without an entry are denied. A
+// permission with a scope (e.g., "admin:*:production") is always checked
+// against that scope; otherwise the scope comes from
+// [WithGRPCScopeExtractor], if set.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidation] if a method
+// name or permission string is malformed, or a permission has a condition.
+func NewMethodAuthorizer(permissions map[string]string, opts ...AuthorizationOption) (*MethodAuthorizer, error) {
+	o := newAuthorizationOptions(opts)
+	rules := make(map[string]methodRule, len(permissions))
+	for method, perm := range permissions {
+		service, name, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+		if !strings.HasPrefix(method, "/") || !ok || service == "" || name == "" || strings.Contains(name, "/") {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"auth: invalid gRPC method name %q, expected \"/package.Service/Method\"", method)
+		}
+		if perm == "" {
+			rules[method] = methodRule{public: true}
+			continue
+		}
+		p, err := ParsePermissionString(perm)
+		if err != nil {
+			return nil, sserr.Wrapf(err, sserr.CodeValidation,
+				"auth: invalid permission for gRPC method %q", method)
+		}
+		if p.Condition != "" {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"auth: permission for gRPC method %q must not have a condition", method)
+		}
+		rules[method] = methodRule{perm: p}
+	}
+	return &MethodAuthorizer{rules: rules, scope: o.grpcScope, attributes: o.grpcAttributes}, nil
+}
+
+// Authorize checks the identity in ctx against the rule for fullMethod.
+// req is passed to the scope and attribute extractors and may be nil.
+//
+// Returns nil if the call is allowed, or a [*sserr.Error] with code
+// [sserr.CodeAuthentication] if ctx has no identity,
+// [sserr.CodeAuthorizationInsufficientScope] if the identity holds the
+// permission but not for the call's scope, or
+// [sserr.CodeAuthorizationDenied] otherwise, including for methods
+// without a rule.
+func (a *MethodAuthorizer) Authorize(ctx context.Context, fullMethod string, req any) error {
+	identity, ok := IdentityFromContext(ctx)
+	if !ok {
+		return sserr.New(sserr.CodeAuthentication, "auth: no authenticated identity")
+	}
+
+	rule, ok := a.rules[fullMethod]
+	if !ok {
+		if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+			rule, ok = a.rules[fullMethod[:i]+"/*"]
+		}
+	}
+	if !ok {
+		err := sserr.Newf(sserr.CodeAuthorizationDenied,
+			"auth: no permission is configured for method %s", fullMethod)
+		logDenied(ctx, identity, err)
+		return err
+	}
+	if rule.public {
+		return nil
+	}
+
+	access := AccessRequest{Resource: rule.perm.Resource, Action: rule.perm.Action, Scope: rule.perm.Scope, Claims: identity.Claims()}
+	scoped := access.Scope != ""
+	if !scoped && a.scope != nil {
+		access.Scope, scoped = a.scope(ctx, fullMethod, req), true
+	}
+	if a.attributes != nil {
+		access.Attributes = a.attributes(ctx, fullMethod, req)
+	}
+	if err := authorize(PermissionsOf(identity), access, scoped); err != nil {
+		logDenied(ctx, identity, err)
+		return err
+	}
+	return nil
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor that
+// authorizes each call with [MethodAuthorizer.Authorize]. Chain it after
+// [UnaryServerInterceptor], which authenticates the caller:
+//
+//	grpc.NewServer(grpc.ChainUnaryInterceptor(
+//	    auth.UnaryServerInterceptor(validator, "executions"),
+//	    authz.UnaryServerInterceptor(),
+//	))
+//
+// Denied calls fail with PermissionDenied; calls without an identity fail
+// with Unauthenticated. Both carry the platform error code as described
+// in [GRPCStatus].
+func (a *MethodAuthorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if err := a.Authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, GRPCStatus(ctx, err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC stream server interceptor that
+// authorizes each stream with [MethodAuthorizer.Authorize]. The scope and
+// attribute extractors receive a nil request.
+func (a *MethodAuthorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := a.Authorize(ss.Context(), info.FullMethod, nil); err != nil {
+			return GRPCStatus(ss.Context(), err)
+		}
+		return handler(srv, ss)
+	}
+}
+
+// logDenied records an authorization denial for audit.
+func logDenied(ctx context.Context, identity Identity, err *sserr.Error) {
+	slog.WarnContext(ctx, "auth: authorization denied",
+		"identity_id", identity.ID(),
+		"identity_type", string(identity.Type()),
+		"code", string(err.Code),
+		"error", err.Message,
+	)
+}
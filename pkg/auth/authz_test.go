@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// newAuthzTestIdentity returns a service identity with a mix of global,
+// scoped, and wildcard permissions.
+func newAuthzTestIdentity(t *testing.T) Identity {
+	t.Helper()
+	identity, err := NewServiceIdentity("svc-1", "orchestrator", "default", nil, []Permission{
+		{Resource: "executions", Action: "read"},
+		{Resource: "executions", Action: "delete", Scope: "staging"},
+		{Resource: "agents", Action: "*"},
+	})
+	require.NoError(t, err)
+	return identity
+}
+
+// ---------------------------------------------------------------------------
+// Authorize
+// ---------------------------------------------------------------------------
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+	identity := newAuthzTestIdentity(t)
+	tests := []struct {
+		name                    string
+		resource, action, scope string
+		code                    sserr.Code
+	}{
+		{"global permission", "executions", "read", "", ""},
+		{"global permission in scope", "executions", "read", "production", ""},
+		{"scoped permission in scope", "executions", "delete", "staging", ""},
+		{"scoped permission scope-agnostic", "executions", "delete", "", ""},
+		{"wildcard action", "agents", "restart", "production", ""},
+		{"scoped permission wrong scope", "executions", "delete", "production", sserr.CodeAuthorizationInsufficientScope},
+		{"missing permission", "executions", "write", "", sserr.CodeAuthorizationDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := Authorize(identity, tt.resource, tt.action, tt.scope)
+			if tt.code == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, tt.code), "error code = %v, want %v", sserr.GetCode(err), tt.code)
+		})
+	}
+}
+
+func TestAuthorize_NoPermissions(t *testing.T) {
+	t.Parallel()
+	assert.True(t, sserr.HasCode(Authorize(nil, "executions", "read", ""), sserr.CodeAuthentication))
+
+	// BasicIdentity carries no permissions.
+	err := Authorize(newTestIdentity(), "executions", "read", "")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationDenied))
+
+	ctx := ContextWithIdentity(context.Background(), newAuthzTestIdentity(t))
+	assert.NoError(t, AuthorizeContext(ctx, "executions", "read", ""))
+}
+
+// ---------------------------------------------------------------------------
+// RequirePermission
+// ---------------------------------------------------------------------------
+
+func TestRequirePermission(t *testing.T) {
+	t.Parallel()
+	identity := newAuthzTestIdentity(t)
+	serve := func(ctx context.Context, mw func(http.Handler) http.Handler, target string) int {
+		handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		req := httptest.NewRequest(http.MethodDelete, target, nil).WithContext(ctx)
+		req.Header.Set("X-Env", "staging")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	ctx := ContextWithIdentity(context.Background(), identity)
+
+	assert.Equal(t, http.StatusNoContent, serve(ctx, RequirePermission("executions", "read"), "/"))
+	assert.Equal(t, http.StatusForbidden, serve(ctx, RequirePermission("executions", "write"), "/"))
+	assert.Equal(t, http.StatusUnauthorized, serve(context.Background(), RequirePermission("executions", "read"), "/"))
+
+	byHeader := RequirePermission("executions", "delete", WithScopeExtractor(ScopeFromHeader("X-Env")))
+	assert.Equal(t, http.StatusNoContent, serve(ctx, byHeader, "/"))
+
+	byQuery := RequirePermission("executions", "delete", WithScopeExtractor(ScopeFromQuery("env")))
+	assert.Equal(t, http.StatusNoContent, serve(ctx, byQuery, "/?env=staging"))
+	assert.Equal(t, http.StatusForbidden, serve(ctx, byQuery, "/?env=production"))
+	// A scoped permission does not satisfy a request with no scope.
+	assert.Equal(t, http.StatusForbidden, serve(ctx, byQuery, "/"))
+	// A global permission does.
+	assert.Equal(t, http.StatusNoContent, serve(ctx,
+		RequirePermission("executions", "read", WithScopeExtractor(ScopeFromQuery("env"))), "/"))
+}
+
+func TestRequirePermission_PathValue(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.Handle("DELETE /envs/{env}/executions/{id}",
+		RequirePermission("executions", "delete", WithScopeExtractor(ScopeFromPathValue("env")))(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})))
+	ctx := ContextWithIdentity(context.Background(), newAuthzTestIdentity(t))
+
+	for target, want := range map[string]int{
+		"/envs/staging/executions/1":    http.StatusNoContent,
+		"/envs/production/executions/1": http.StatusForbidden,
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, target, nil).WithContext(ctx))
+		assert.Equal(t, want, rr.Code, target)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// MethodAuthorizer
+// ---------------------------------------------------------------------------
+
+func TestNewMethodAuthorizer_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, perms := range []map[string]string{
+		{"pkg.Svc/Get": "executions:read"},
+		{"/pkg.Svc": "executions:read"},
+		{"/pkg.Svc/Get/Extra": "executions:read"},
+		{"/pkg.Svc/Get": "executions"},
+		{"/pkg.Svc/Get": "executions:read[owner == claims.sub]"},
+	} {
+		_, err := NewMethodAuthorizer(perms)
+		assert.True(t, sserr.IsValidation(err), "perms %v: error = %v", perms, err)
+	}
+}
+
+func TestMethodAuthorizer_UnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+	authz, err := NewMethodAuthorizer(map[string]string{
+		"/exec.v1.ExecutionService/Get":    "executions:read",
+		"/exec.v1.ExecutionService/Delete": "executions:delete",
+		"/exec.v1.ExecutionService/Purge":  "executions:delete:production",
+		"/agents.v1.AgentService/*":        "agents:manage",
+		"/grpc.health.v1.Health/*":         "",
+	}, WithGRPCScopeExtractor(ScopeFromMetadata("x-env")))
+	require.NoError(t, err)
+	interceptor := authz.UnaryServerInterceptor()
+
+	call := func(ctx context.Context, method string) codes.Code {
+		_, err := interceptor(ctx, "req", &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req any) (any, error) { return "ok", nil })
+		return status.Code(err)
+	}
+	ctx := ContextWithIdentity(context.Background(), newAuthzTestIdentity(t))
+	staging := metadata.NewIncomingContext(ctx, metadata.Pairs("x-env", "staging"))
+	production := metadata.NewIncomingContext(ctx, metadata.Pairs("x-env", "production"))
+
+	assert.Equal(t, codes.OK, call(ctx, "/exec.v1.ExecutionService/Get"))
+	assert.Equal(t, codes.OK, call(staging, "/exec.v1.ExecutionService/Delete"))
+	assert.Equal(t, codes.PermissionDenied, call(production, "/exec.v1.ExecutionService/Delete"))
+	assert.Equal(t, codes.PermissionDenied, call(ctx, "/exec.v1.ExecutionService/Delete"), "missing scope")
+	assert.Equal(t, codes.PermissionDenied, call(staging, "/exec.v1.ExecutionService/Purge"), "fixed scope overrides metadata")
+	assert.Equal(t, codes.OK, call(ctx, "/agents.v1.AgentService/Restart"))
+	assert.Equal(t, codes.OK, call(ctx, "/grpc.health.v1.Health/Check"))
+	assert.Equal(t, codes.PermissionDenied, call(ctx, "/exec.v1.ExecutionService/Unlisted"))
+	assert.Equal(t, codes.Unauthenticated, call(context.Background(), "/grpc.health.v1.Health/Check"))
+}
+
+func TestMethodAuthorizer_Codes(t *testing.T) {
+	t.Parallel()
+	authz, err := NewMethodAuthorizer(map[string]string{
+		"/exec.v1.ExecutionService/Delete": "executions:delete",
+		"/exec.v1.ExecutionService/Write":  "executions:write",
+	}, WithGRPCScopeExtractor(func(context.Context, string, any) string { return "production" }))
+	require.NoError(t, err)
+	ctx := ContextWithIdentity(context.Background(), newAuthzTestIdentity(t))
+
+	err = authz.Authorize(ctx, "/exec.v1.ExecutionService/Delete", nil)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationInsufficientScope))
+	err = authz.Authorize(ctx, "/exec.v1.ExecutionService/Write", nil)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationDenied))
+}
+
+func TestMethodAuthorizer_StreamServerInterceptor(t *testing.T) {
+	t.Parallel()
+	authz, err := NewMethodAuthorizer(map[string]string{"/exec.v1.ExecutionService/Watch": "executions:read"})
+	require.NoError(t, err)
+	interceptor := authz.StreamServerInterceptor()
+
+	var called bool
+	handler := func(srv any, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+	ctx := ContextWithIdentity(context.Background(), newAuthzTestIdentity(t))
+	err = interceptor(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/exec.v1.ExecutionService/Watch"}, handler)
+	require.NoError(t, err)
+	assert.True(t, called)
+
+	err = interceptor(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/exec.v1.ExecutionService/Other"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// CallChainPolicy restricts the call chains accepted by [HTTPMiddleware],
+// [UnaryServerInterceptor], and [StreamServerInterceptor]. Set it with
+// [WithCallChainPolicy].
+//
+// The call chain is read from propagated headers, so the policy is only
+// as trustworthy as they are: combine it with [WithPropagationVerifier]
+// so that callers cannot forge or strip chain entries.
+type CallChainPolicy struct {
+	// MaxDepth is the maximum number of callers, including truncated
+	// ones (see [CallChain.Hops]), a chain may have when it reaches the
+	// service. Zero means [MaxCallChainDepth], which also rejects every
+	// truncated chain.
+	MaxDepth int
+
+	// AllowLoops accepts chains that pass through the same service more
+	// than once, or that already contain the receiving service. By
+	// default such chains are rejected, which stops agent-to-agent call
+	// loops.
+	AllowLoops bool
+
+	// AuthorizeDeputy decides whether the authenticated identity may act
+	// on behalf of the chain's originator. It is called when a request
+	// carries a call chain whose OriginalID differs from the
+	// authenticated identity, i.e., when a service calls with its own
+	// credentials on someone else's behalf. It returns nil to allow the
+	// call. If it is nil, such calls are rejected.
+	//
+	// Requests authenticated as the originator itself, such as those
+	// carrying the originator's forwarded token, are not checked.
+	AuthorizeDeputy func(ctx context.Context, deputy Identity, chain *CallChain) error
+}
+
+// RequireDeputyPermission returns a [CallChainPolicy.AuthorizeDeputy]
+// function that allows a deputy holding action on resource, as decided by
+// [AuthorizeRequest]. Conditional permissions can refer to the attributes
+// "original_id" and "original_type" of the chain's originator and
+// "caller_service", the service that made the call:
+//
+//	auth.CallChainPolicy{
+//	    AuthorizeDeputy: auth.RequireDeputyPermission("identities", "act-as"),
+//	}
+//
+// with the deputy granted, e.g., "identities:act-as[original_type == 'user']".
+func RequireDeputyPermission(resource, action string) func(ctx context.Context, deputy Identity, chain *CallChain) error {
+	return func(_ context.Context, deputy Identity, chain *CallChain) error {
+		attributes := map[string]any{
+			"original_id":   chain.OriginalID,
+			"original_type": string(chain.OriginalType),
+		}
+		if n := len(chain.Callers); n > 0 {
+			attributes["caller_service"] = chain.Callers[n-1].ServiceName
+		}
+		return AuthorizeRequest(deputy, AccessRequest{Resource: resource, Action: action, Attributes: attributes})
+	}
+}
+
+// enforce checks chain, the call chain received by serviceName from
+// identity, against the policy. chain may be nil for direct calls.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeAuthorizationDenied] if
+// the chain is rejected.
+func (p *CallChainPolicy) enforce(ctx context.Context, identity Identity, chain *CallChain, serviceName string) *sserr.Error {
+	if chain == nil {
+		return nil
+	}
+
+	maxDepth := p.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = MaxCallChainDepth
+	}
+	if hops := chain.Hops(); hops > maxDepth {
+		return p.reject(ctx, identity, chain, serviceName,
+			sserr.Newf(sserr.CodeAuthorizationDenied, "auth: call chain depth %d exceeds maximum %d", hops, maxDepth).
+				WithDetails(map[string]any{"reason": "depth", "depth": hops, "max_depth": maxDepth}))
+	}
+
+	if !p.AllowLoops {
+		seen := make(map[string]struct{}, len(chain.Callers)+1)
+		if serviceName != "" {
+			seen[serviceName] = struct{}{}
+		}
+		for _, caller := range chain.Callers {
+			if caller.ServiceName == "" {
+				continue
+			}
+			if _, dup := seen[caller.ServiceName]; dup {
+				return p.reject(ctx, identity, chain, serviceName,
+					sserr.Newf(sserr.CodeAuthorizationDenied, "auth: call chain revisits service %q", caller.ServiceName).
+						WithDetails(map[string]any{"reason": "loop", "service": caller.ServiceName}))
+			}
+			seen[caller.ServiceName] = struct{}{}
+		}
+	}
+
+	if chain.OriginalID != identity.ID() {
+		var err error
+		if p.AuthorizeDeputy == nil {
+			err = sserr.New(sserr.CodeAuthorizationDenied, "auth: no deputy authorization is configured")
+		} else {
+			err = p.AuthorizeDeputy(ctx, identity, chain)
+		}
+		if err != nil {
+			return p.reject(ctx, identity, chain, serviceName,
+				sserr.Wrapf(err, sserr.CodeAuthorizationDenied, "auth: %s may not act on behalf of %s", identity.ID(), chain.OriginalID).
+					WithDetails(map[string]any{"reason": "deputy"}))
+		}
+	}
+	return nil
+}
+
+// reject logs a call chain rejection for audit and returns err.
+func (p *CallChainPolicy) reject(ctx context.Context, identity Identity, chain *CallChain, serviceName string, err *sserr.Error) *sserr.Error {
+	slog.WarnContext(ctx, "auth: call chain rejected",
+		"service", serviceName,
+		"identity_id", identity.ID(),
+		"original_id", chain.OriginalID,
+		"hops", chain.Hops(),
+		"error", err.Message,
+	)
+	return err
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newDeputyTestIdentity returns a service identity granted perms.
+func newDeputyTestIdentity(t *testing.T, perms ...string) Identity {
+	t.Helper()
+	var permissions []Permission
+	for _, s := range perms {
+		p, err := ParsePermissionString(s)
+		require.NoError(t, err)
+		permissions = append(permissions, p)
+	}
+	identity, err := NewServiceIdentity("svc-gateway", "gateway", "default", nil, permissions)
+	require.NoError(t, err)
+	return identity
+}
+
+// newTestCallChain returns a chain originating from user-42 through the
+// named services.
+func newTestCallChain(services ...string) *CallChain {
+	chain := &CallChain{OriginalID: "user-42", OriginalType: IdentityTypeUser}
+	for _, s := range services {
+		chain = chain.AppendCaller(CallerInfo{ServiceName: s})
+	}
+	return chain
+}
+
+// encodeTestCallChain serializes chain for use as a header value.
+func encodeTestCallChain(t *testing.T, chain *CallChain) string {
+	t.Helper()
+	encoded, err := SerializeCallChain(chain)
+	require.NoError(t, err)
+	return encoded
+}
+
+// assertChainRejected asserts that err denies the call chain for reason.
+func assertChainRejected(t *testing.T, err *sserr.Error, reason string) {
+	t.Helper()
+	require.NotNil(t, err)
+	assert.Equal(t, sserr.CodeAuthorizationDenied, err.Code)
+	assert.Equal(t, reason, err.Details["reason"])
+}
+
+// ---------------------------------------------------------------------------
+// CallChainPolicy
+// ---------------------------------------------------------------------------
+
+func TestCallChainPolicy_NilChain(t *testing.T) {
+	t.Parallel()
+	var p CallChainPolicy
+	assert.Nil(t, p.enforce(context.Background(), newTestIdentity(), nil, "svc-c"))
+}
+
+func TestCallChainPolicy_AcceptsChain(t *testing.T) {
+	t.Parallel()
+	var p CallChainPolicy
+	chain := newTestCallChain("svc-a", "svc-b")
+	assert.Nil(t, p.enforce(context.Background(), newTestIdentity(), chain, "svc-c"))
+}
+
+func TestCallChainPolicy_Depth(t *testing.T) {
+	t.Parallel()
+	p := CallChainPolicy{MaxDepth: 2}
+	ctx := context.Background()
+
+	assert.Nil(t, p.enforce(ctx, newTestIdentity(), newTestCallChain("svc-a", "svc-b"), "svc-z"))
+	err := p.enforce(ctx, newTestIdentity(), newTestCallChain("svc-a", "svc-b", "svc-c"), "svc-z")
+	assertChainRejected(t, err, "depth")
+	assert.Equal(t, 3, err.Details["depth"])
+}
+
+func TestCallChainPolicy_DefaultDepthRejectsTruncated(t *testing.T) {
+	t.Parallel()
+	var p CallChainPolicy
+	services := make([]string, MaxCallChainDepth+1)
+	for i := range services {
+		services[i] = fmt.Sprintf("svc-%d", i)
+	}
+	chain := newTestCallChain(services...)
+	require.Equal(t, 1, chain.Truncated)
+
+	err := p.enforce(context.Background(), newTestIdentity(), chain, "svc-z")
+	assertChainRejected(t, err, "depth")
+	assert.Equal(t, MaxCallChainDepth+1, err.Details["depth"])
+}
+
+func TestCallChainPolicy_Loop(t *testing.T) {
+	t.Parallel()
+	var p CallChainPolicy
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		services []string
+		receiver string
+	}{
+		{"revisits receiver", []string{"svc-a", "svc-b"}, "svc-a"},
+		{"revisits caller", []string{"svc-a", "svc-b", "svc-a"}, "svc-c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := p.enforce(ctx, newTestIdentity(), newTestCallChain(tt.services...), tt.receiver)
+			assertChainRejected(t, err, "loop")
+			assert.Equal(t, "svc-a", err.Details["service"])
+		})
+	}
+}
+
+func TestCallChainPolicy_AllowLoops(t *testing.T) {
+	t.Parallel()
+	p := CallChainPolicy{AllowLoops: true}
+	chain := newTestCallChain("svc-a", "svc-b", "svc-a")
+	assert.Nil(t, p.enforce(context.Background(), newTestIdentity(), chain, "svc-a"))
+}
+
+func TestCallChainPolicy_DeputyWithoutAuthorizer(t *testing.T) {
+	t.Parallel()
+	var p CallChainPolicy
+	err := p.enforce(context.Background(), newDeputyTestIdentity(t), newTestCallChain("svc-a"), "svc-b")
+	assertChainRejected(t, err, "deputy")
+}
+
+func TestCallChainPolicy_DeputyAuthorizer(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	chain := newTestCallChain("svc-a")
+	deputy := newDeputyTestIdentity(t)
+
+	var gotDeputy Identity
+	var gotChain *CallChain
+	p := CallChainPolicy{AuthorizeDeputy: func(_ context.Context, d Identity, c *CallChain) error {
+		gotDeputy, gotChain = d, c
+		return nil
+	}}
+	assert.Nil(t, p.enforce(ctx, deputy, chain, "svc-b"))
+	assert.Equal(t, deputy, gotDeputy)
+	assert.Equal(t, chain, gotChain)
+
+	denied := errors.New("not allowed")
+	p.AuthorizeDeputy = func(context.Context, Identity, *CallChain) error { return denied }
+	err := p.enforce(ctx, deputy, chain, "svc-b")
+	assertChainRejected(t, err, "deputy")
+	assert.ErrorIs(t, err, denied)
+}
+
+func TestRequireDeputyPermission(t *testing.T) {
+	t.Parallel()
+	p := CallChainPolicy{AuthorizeDeputy: RequireDeputyPermission("identities", "act-as")}
+	ctx := context.Background()
+	chain := newTestCallChain("svc-a")
+
+	tests := []struct {
+		name  string
+		perms []string
+		allow bool
+	}{
+		{"no permission", nil, false},
+		{"unconditional", []string{"identities:act-as"}, true},
+		{"matching condition", []string{"identities:act-as[original_type == 'user' && caller_service == 'svc-a']"}, true},
+		{"non-matching condition", []string{"identities:act-as[original_id == 'user-7']"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := p.enforce(ctx, newDeputyTestIdentity(t, tt.perms...), chain, "svc-b")
+			if tt.allow {
+				assert.Nil(t, err)
+			} else {
+				assertChainRejected(t, err, "deputy")
+			}
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Server integration
+// ---------------------------------------------------------------------------
+
+func TestHTTPMiddleware_CallChainPolicy(t *testing.T) {
+	t.Parallel()
+	validator := &mockValidator{identity: newTestIdentity()}
+	middleware := HTTPMiddleware(validator, "svc-a", WithCallChainPolicy(CallChainPolicy{}))
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		chain  string
+		status int
+	}{
+		{"no chain", "", http.StatusOK},
+		{"valid chain", encodeTestCallChain(t, newTestCallChain("svc-x")), http.StatusOK},
+		{"loop", encodeTestCallChain(t, newTestCallChain("svc-a", "svc-x")), http.StatusForbidden},
+		{"malformed", "!!! not valid base64 !!!", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+			req.Header.Set("Authorization", "Bearer valid-token")
+			if tt.chain != "" {
+				req.Header.Set(HeaderCallChain, tt.chain)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+}
+
+func TestUnaryServerInterceptor_CallChainPolicy(t *testing.T) {
+	t.Parallel()
+	validator := &mockValidator{identity: newTestIdentity()}
+	interceptor := UnaryServerInterceptor(validator, "svc-a", WithCallChainPolicy(CallChainPolicy{}))
+	handler := func(ctx context.Context, req any) (any, error) { return "response", nil }
+
+	tests := []struct {
+		name  string
+		chain string
+		code  codes.Code
+	}{
+		{"valid chain", encodeTestCallChain(t, newTestCallChain("svc-x")), codes.OK},
+		{"loop", encodeTestCallChain(t, newTestCallChain("svc-a", "svc-x")), codes.PermissionDenied},
+		{"malformed", "!!! not valid base64 !!!", codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			md := metadata.Pairs(HeaderAuthorization, "Bearer valid-token", HeaderCallChain, tt.chain)
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+			_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{}, handler)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestStreamServerInterceptor_CallChainPolicy(t *testing.T) {
+	t.Parallel()
+	validator := &mockValidator{identity: newTestIdentity()}
+	interceptor := StreamServerInterceptor(validator, "svc-a", WithCallChainPolicy(CallChainPolicy{MaxDepth: 1}))
+
+	chain := encodeTestCallChain(t, newTestCallChain("svc-x", "svc-y"))
+	md := metadata.Pairs(HeaderAuthorization, "Bearer valid-token", HeaderCallChain, chain)
+	stream := &mockServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+	handler := func(srv any, ss grpc.ServerStream) error {
+		t.Error("handler should not be called")
+		return nil
+	}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
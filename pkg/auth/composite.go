@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// errNoMatchingValidator returns the error combinators report when they
+// have no validator for a token.
+func errNoMatchingValidator() *sserr.Error {
+	return sserr.New(sserr.CodeAuthentication, "auth: no matching validator for token")
+}
+
+// ---------------------------------------------------------------------------
+// Error aggregation
+// ---------------------------------------------------------------------------
+
+// errorSpecificity ranks a validation error by how much it says about
+// the token, from least to most specific:
+//
+//  1. [sserr.CodeAuthentication] or a plain error: no validator took
+//     responsibility for the token.
+//  2. Other authentication codes, such as
+//     [sserr.CodeAuthenticationInvalid], which validators also return
+//     for tokens of a kind they do not handle.
+//  3. Non-authentication codes, such as [sserr.CodeUnavailable]: the
+//     token could not be checked, so it may be valid.
+//  4. [sserr.CodeAuthenticationExpired]: a validator recognized and
+//     verified the token, and it has expired.
+func errorSpecificity(err error) int {
+	switch code := sserr.GetCode(err); {
+	case code == "" || code == sserr.CodeAuthentication:
+		return 1
+	case code == sserr.CodeAuthenticationExpired:
+		return 4
+	case code.Category() == sserr.CodeAuthentication.Category():
+		return 2
+	default:
+		return 3
+	}
+}
+
+// mostSpecificError returns whichever of current and err ranks higher by
+// [errorSpecificity], preferring current on a tie so that the earliest
+// validator's error wins. current may be nil.
+func mostSpecificError(current, err error) error {
+	if current == nil || errorSpecificity(err) > errorSpecificity(current) {
+		return err
+	}
+	return current
+}
+
+// ---------------------------------------------------------------------------
+// FirstOf — try validators in order
+// ---------------------------------------------------------------------------
+
+// firstOf is the [TokenValidator] returned by [FirstOf].
+type firstOf struct {
+	validators []TokenValidator
+}
+
+// FirstOf returns a [TokenValidator] that tries validators in order and
+// returns the identity from the first that accepts the token. Nil
+// validators are skipped.
+//
+// If every validator rejects the token, the most specific of their
+// errors is returned: an expired token is reported as expired, and an
+// unreachable validator as [sserr.CodeUnavailable], rather than being
+// masked by another validator's [sserr.CodeAuthenticationInvalid] for a
+// token it does not handle. See [errorSpecificity].
+//
+// FirstOf lets a service accept tokens of several kinds, such as API
+// keys, JWTs, and opaque tokens checked with an [IntrospectionValidator].
+// Put the cheapest validators first: a token is only passed on after the
+// validators before it have rejected it. Use [Conditional] to send each
+// token only to the validator for its kind.
+//
+// Every validator may see a token rejected by those before it. Never
+// put a validator that sends tokens to a third party, such as an
+// [IntrospectionValidator] for a partner's endpoint, after validators
+// of internal tokens: an internal token they reject, for example
+// because it has expired, would be disclosed to that party. Use
+// [Conditional] to route tokens to such a validator instead.
+func FirstOf(validators ...TokenValidator) TokenValidator {
+	return &firstOf{validators: nonNilValidators(validators)}
+}
+
+// Validate implements [TokenValidator].
+func (f *firstOf) Validate(ctx context.Context, token string) (Identity, error) {
+	var validationErr error
+	for _, v := range f.validators {
+		identity, err := v.Validate(ctx, token)
+		if err == nil {
+			return identity, nil
+		}
+		validationErr = mostSpecificError(validationErr, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if validationErr == nil {
+		return nil, errNoMatchingValidator()
+	}
+	return nil, validationErr
+}
+
+// ---------------------------------------------------------------------------
+// All — require every validator to agree
+// ---------------------------------------------------------------------------
+
+// all is the [TokenValidator] returned by [All].
+type all struct {
+	validators []TokenValidator
+}
+
+// All returns a [TokenValidator] that accepts a token only if every
+// validator accepts it and they agree on the identity's ID and type. It
+// returns the identity from the first validator, including its
+// permissions. Nil validators are skipped.
+//
+// Validators run in order, and the first rejection is returned. If the
+// validators disagree, All returns a *[sserr.Error] with code
+// [sserr.CodeAuthenticationInvalid].
+//
+// All suits defense in depth, such as verifying a JWT locally and also
+// introspecting it to catch revocations:
+//
+//	validator := auth.All(jwtValidator, introspectionValidator)
+func All(validators ...TokenValidator) TokenValidator {
+	return &all{validators: nonNilValidators(validators)}
+}
+
+// Validate implements [TokenValidator].
+func (a *all) Validate(ctx context.Context, token string) (Identity, error) {
+	if len(a.validators) == 0 {
+		return nil, errNoMatchingValidator()
+	}
+	var first Identity
+	for _, v := range a.validators {
+		identity, err := v.Validate(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = identity
+			continue
+		}
+		if identity.ID() != first.ID() || identity.Type() != first.Type() {
+			return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: validators disagree on token identity").
+				WithDetails(map[string]any{
+					"identity_id":       first.ID(),
+					"other_identity_id": identity.ID(),
+				})
+		}
+	}
+	return first, nil
+}
+
+// ---------------------------------------------------------------------------
+// Conditional — route tokens by shape or header
+// ---------------------------------------------------------------------------
+
+// ValidatorMatcher reports whether a token, presented with the request
+// whose context is ctx, should be sent to a validator. See
+// [ValidatorRoute].
+type ValidatorMatcher func(ctx context.Context, token string) bool
+
+// ValidatorRoute pairs a [ValidatorMatcher] with the validator for the
+// tokens it matches.
+type ValidatorRoute struct {
+	// Match selects the tokens for Validator. A nil Match matches every
+	// token, which makes the route a default.
+	Match ValidatorMatcher
+
+	// Validator validates the tokens selected by Match.
+	Validator TokenValidator
+}
+
+// conditional is the [TokenValidator] returned by [Conditional].
+type conditional struct {
+	routes []ValidatorRoute
+}
+
+// Conditional returns a [TokenValidator] that sends each token to the
+// validator of the first route that matches it, and returns that
+// validator's result. Unlike [FirstOf], a token rejected by its validator
+// is not tried elsewhere. Routes with a nil Validator are skipped.
+//
+// If no route matches, Conditional returns a *[sserr.Error] with code
+// [sserr.CodeAuthentication].
+//
+//	validator := auth.Conditional(
+//	    auth.ValidatorRoute{Match: auth.MatchTokenPrefix("sk_"), Validator: apiKeys},
+//	    auth.ValidatorRoute{Match: auth.MatchJWT(), Validator: jwtValidator},
+//	    auth.ValidatorRoute{Validator: introspectionValidator},
+//	)
+func Conditional(routes ...ValidatorRoute) TokenValidator {
+	c := &conditional{routes: make([]ValidatorRoute, 0, len(routes))}
+	for _, r := range routes {
+		if r.Validator != nil {
+			c.routes = append(c.routes, r)
+		}
+	}
+	return c
+}
+
+// Validate implements [TokenValidator].
+func (c *conditional) Validate(ctx context.Context, token string) (Identity, error) {
+	for _, r := range c.routes {
+		if r.Match == nil || r.Match(ctx, token) {
+			return r.Validator.Validate(ctx, token)
+		}
+	}
+	return nil, errNoMatchingValidator()
+}
+
+// MatchJWT returns a [ValidatorMatcher] for tokens shaped like a JWS
+// compact serialization: three non-empty base64url segments separated
+// by dots. The token is not decoded.
+func MatchJWT() ValidatorMatcher {
+	return func(_ context.Context, token string) bool {
+		parts := strings.Split(token, ".")
+		if len(parts) != 3 {
+			return false
+		}
+		for _, part := range parts {
+			if part == "" || strings.IndexFunc(part, func(r rune) bool { return !isBase64URLRune(r) }) >= 0 {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchTokenPrefix returns a [ValidatorMatcher] for tokens starting with
+// prefix, such as the "sk_" of API keys.
+func MatchTokenPrefix(prefix string) ValidatorMatcher {
+	return func(_ context.Context, token string) bool {
+		return strings.HasPrefix(token, prefix)
+	}
+}
+
+// MatchHeader returns a [ValidatorMatcher] for tokens presented with
+// the request header (or gRPC metadata) name set to value, or, if value
+// is empty, present with any non-empty value. It only matches within
+// [HTTPMiddleware], [UnaryServerInterceptor], and
+// [StreamServerInterceptor], which make the request's headers available
+// to validators.
+//
+// Headers are chosen by the client, so use MatchHeader to pick among
+// validators that are each trusted for the tokens they accept, never
+// to skip validation.
+func MatchHeader(name, value string) ValidatorMatcher {
+	return func(ctx context.Context, _ string) bool {
+		got := requestHeader(ctx, name)
+		if value == "" {
+			return got != ""
+		}
+		return got == value
+	}
+}
+
+// isBase64URLRune reports whether r belongs to the unpadded base64url
+// alphabet.
+func isBase64URLRune(r rune) bool {
+	return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
+
+// nonNilValidators returns the non-nil entries of validators in a new
+// slice.
+func nonNilValidators(validators []TokenValidator) []TokenValidator {
+	result := make([]TokenValidator, 0, len(validators))
+	for _, v := range validators {
+		if v != nil {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// contextWithRequestHeader returns a context carrying the headers of an
+// inbound HTTP request, for [MatchHeader].
+func contextWithRequestHeader(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, requestHeaderKey, header)
+}
+
+// requestHeader returns the value of the inbound request header name:
+// from the HTTP request headers stored by [HTTPMiddleware], or else from
+// the incoming gRPC metadata. Returns an empty string if it is absent.
+func requestHeader(ctx context.Context, name string) string {
+	if header, ok := ctx.Value(requestHeaderKey).(http.Header); ok {
+		return header.Get(name)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
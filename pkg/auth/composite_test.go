@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// countingValidator is a TokenValidator that records how often it is
+// called and delegates to mockValidator.
+type countingValidator struct {
+	mockValidator
+	calls int
+}
+
+func (c *countingValidator) Validate(ctx context.Context, token string) (Identity, error) {
+	c.calls++
+	return c.mockValidator.Validate(ctx, token)
+}
+
+// rejecting returns a validator that rejects every token with code.
+func rejecting(code sserr.Code) *countingValidator {
+	return &countingValidator{mockValidator: mockValidator{err: sserr.New(code, "auth: rejected")}}
+}
+
+// accepting returns a validator that accepts every token as identity.
+func accepting(identity Identity) *countingValidator {
+	return &countingValidator{mockValidator: mockValidator{identity: identity}}
+}
+
+// ---------------------------------------------------------------------------
+// FirstOf
+// ---------------------------------------------------------------------------
+
+func TestFirstOf_ReturnsFirstSuccess(t *testing.T) {
+	t.Parallel()
+	first := rejecting(sserr.CodeAuthenticationInvalid)
+	second := accepting(newTestIdentity())
+	third := accepting(NewBasicIdentity("other", IdentityTypeUser, nil))
+
+	identity, err := FirstOf(first, nil, second, third).Validate(context.Background(), "token")
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", identity.ID())
+	assert.Equal(t, 1, first.calls)
+	assert.Equal(t, 0, third.calls)
+}
+
+func TestFirstOf_MostSpecificError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		codes []sserr.Code
+		want  sserr.Code
+	}{
+		{"invalid over no match", []sserr.Code{sserr.CodeAuthentication, sserr.CodeAuthenticationInvalid}, sserr.CodeAuthenticationInvalid},
+		{"unavailable over invalid", []sserr.Code{sserr.CodeAuthenticationInvalid, sserr.CodeUnavailable}, sserr.CodeUnavailable},
+		{"expired over unavailable", []sserr.Code{sserr.CodeUnavailable, sserr.CodeAuthenticationExpired, sserr.CodeAuthenticationInvalid}, sserr.CodeAuthenticationExpired},
+		{"earliest on tie", []sserr.Code{sserr.CodeInternal, sserr.CodeUnavailable}, sserr.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var validators []TokenValidator
+			for _, code := range tt.codes {
+				validators = append(validators, rejecting(code))
+			}
+			_, err := FirstOf(validators...).Validate(context.Background(), "token")
+			require.Error(t, err)
+			assert.Equal(t, tt.want, sserr.GetCode(err))
+		})
+	}
+}
+
+func TestFirstOf_PlainErrorRanksLowest(t *testing.T) {
+	t.Parallel()
+	plain := &mockValidator{err: errors.New("boom")}
+	_, err := FirstOf(plain, rejecting(sserr.CodeAuthenticationInvalid)).Validate(context.Background(), "token")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid))
+}
+
+func TestFirstOf_StopsWhenContextDone(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	second := accepting(newTestIdentity())
+
+	_, err := FirstOf(rejecting(sserr.CodeUnavailable), second).Validate(ctx, "token")
+	require.Error(t, err)
+	assert.Equal(t, 0, second.calls)
+}
+
+func TestFirstOf_NoValidators(t *testing.T) {
+	t.Parallel()
+	_, err := FirstOf().Validate(context.Background(), "token")
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthentication))
+}
+
+// ---------------------------------------------------------------------------
+// All
+// ---------------------------------------------------------------------------
+
+func TestAll_Agreement(t *testing.T) {
+	t.Parallel()
+	first := newTestIdentity()
+	identity, err := All(accepting(first), accepting(NewBasicIdentity("user-42", IdentityTypeUser, nil))).
+		Validate(context.Background(), "token")
+	require.NoError(t, err)
+	assert.Same(t, first, identity)
+}
+
+func TestAll_Disagreement(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		other Identity
+	}{
+		{"different ID", NewBasicIdentity("user-7", IdentityTypeUser, nil)},
+		{"different type", NewBasicIdentity("user-42", IdentityTypeService, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := All(accepting(newTestIdentity()), accepting(tt.other)).Validate(context.Background(), "token")
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid))
+		})
+	}
+}
+
+func TestAll_FirstRejection(t *testing.T) {
+	t.Parallel()
+	last := accepting(newTestIdentity())
+	_, err := All(accepting(newTestIdentity()), rejecting(sserr.CodeAuthenticationExpired), last).
+		Validate(context.Background(), "token")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationExpired))
+	assert.Equal(t, 0, last.calls)
+}
+
+func TestAll_NoValidators(t *testing.T) {
+	t.Parallel()
+	_, err := All(nil).Validate(context.Background(), "token")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthentication))
+}
+
+// ---------------------------------------------------------------------------
+// Conditional
+// ---------------------------------------------------------------------------
+
+func TestConditional_RoutesByShape(t *testing.T) {
+	t.Parallel()
+	apiKeys := accepting(NewBasicIdentity("key-1", IdentityTypeService, nil))
+	jwts := accepting(newTestIdentity())
+	opaque := rejecting(sserr.CodeAuthenticationInvalid)
+	validator := Conditional(
+		ValidatorRoute{Match: MatchTokenPrefix("sk_"), Validator: apiKeys},
+		ValidatorRoute{Match: MatchJWT(), Validator: jwts},
+		ValidatorRoute{Validator: opaque},
+	)
+
+	identity, err := validator.Validate(context.Background(), "sk_live_abc")
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", identity.ID())
+
+	identity, err = validator.Validate(context.Background(), "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJ1In0.c2ln")
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", identity.ID())
+
+	_, err = validator.Validate(context.Background(), "opaque-token")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid))
+	assert.Equal(t, 1, apiKeys.calls)
+	assert.Equal(t, 1, jwts.calls)
+	assert.Equal(t, 1, opaque.calls)
+}
+
+func TestConditional_NoMatch(t *testing.T) {
+	t.Parallel()
+	validator := Conditional(
+		ValidatorRoute{Match: MatchTokenPrefix("sk_"), Validator: accepting(newTestIdentity())},
+		ValidatorRoute{Validator: nil},
+	)
+	_, err := validator.Validate(context.Background(), "token")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthentication))
+}
+
+func TestMatchJWT(t *testing.T) {
+	t.Parallel()
+	match := MatchJWT()
+	for token, want := range map[string]bool{
+		"aGVhZGVy.cGF5bG9hZA.c2ln": true,
+		"a-b_c.d.e":                true,
+		"aGVhZGVy.cGF5bG9hZA":      false,
+		"aGVhZGVy..c2ln":           false,
+		"a.b.c.d":                  false,
+		"a+b.c.d":                  false,
+		"a=.b.c":                   false,
+		"sk_live_abc":              false,
+	} {
+		assert.Equal(t, want, match(context.Background(), token), token)
+	}
+}
+
+func TestMatchHeader(t *testing.T) {
+	t.Parallel()
+	grpcCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-token-type", "api-key"))
+	httpCtx := contextWithRequestHeader(context.Background(), http.Header{"X-Token-Type": {"api-key"}})
+
+	assert.True(t, MatchHeader("X-Token-Type", "api-key")(grpcCtx, "token"))
+	assert.True(t, MatchHeader("X-Token-Type", "api-key")(httpCtx, "token"))
+	assert.True(t, MatchHeader("X-Token-Type", "")(httpCtx, "token"))
+	assert.False(t, MatchHeader("X-Token-Type", "jwt")(httpCtx, "token"))
+	assert.False(t, MatchHeader("X-Token-Type", "")(context.Background(), "token"))
+}
+
+func TestHTTPMiddleware_ConditionalByHeader(t *testing.T) {
+	t.Parallel()
+	validator := Conditional(
+		ValidatorRoute{Match: MatchHeader("X-Token-Type", "api-key"), Validator: accepting(newTestIdentity())},
+	)
+	handler := HTTPMiddleware(validator, "test-service")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for header, status := range map[string]int{"api-key": http.StatusOK, "": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		if header != "" {
+			req.Header.Set("X-Token-Type", header)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, status, rr.Code, header)
+	}
+}
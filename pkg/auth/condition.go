@@ -0,0 +1,666 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ---------------------------------------------------------------------------
+// AccessRequest — input to conditional permissions
+// ---------------------------------------------------------------------------
+
+// AccessRequest describes an access to be checked against permissions,
+// including the attributes that permission conditions are evaluated over.
+type AccessRequest struct {
+	// Resource, Action, and Scope are the access being checked, with the
+	// semantics of [Permission.Match].
+	Resource string
+	Action   string
+	Scope    string
+
+	// Claims are the claims of the identity requesting access.
+	Claims map[string]any
+
+	// Attributes are attributes of the request, such as the namespace or
+	// owner of the resource being accessed.
+	Attributes map[string]any
+
+	// Time is the time of the request. If zero, the current UTC time is
+	// used.
+	Time time.Time
+}
+
+// ---------------------------------------------------------------------------
+// Condition — attribute-based access control expressions
+// ---------------------------------------------------------------------------
+
+// Condition is a compiled permission condition: a boolean expression over
+// the attributes of an [AccessRequest]. Conditions are written as:
+//
+//	namespace == claims.tenant
+//	"admin" in claims.roles || owner == claims.sub
+//	time.hour >= 9 && time.hour < 17 && !(time.weekday in ["saturday", "sunday"])
+//
+// Names are resolved as follows:
+//   - resource, action, scope: the access being checked.
+//   - claims.<name>: an identity claim. Dotted names select nested
+//     claims, unless a claim has the exact dotted name.
+//   - time.hour, time.minute (numbers), time.weekday (lowercase English
+//     day name), time.date ("2006-01-02"), time.unix (seconds): the
+//     request time, in the location of [AccessRequest.Time].
+//   - Any other name: a request attribute, with dotted names selecting
+//     nested attributes.
+//
+// Literals are strings in single or double quotes, numbers, true, false,
+// and lists of literals in square brackets. The operators are ==, !=, <,
+// <=, >, >= (numbers and strings), in (membership in a list), !, &&, and
+// ||, with parentheses for grouping.
+//
+// Evaluation fails closed: a condition that names a missing attribute, or
+// compares values of different types, is an error, and a permission whose
+// condition fails does not apply.
+//
+// Condition is safe for concurrent use by multiple goroutines.
+type Condition struct {
+	source string
+	root   condNode
+}
+
+// CompileCondition parses a condition expression. Returns an error if expr
+// is not a valid condition.
+func CompileCondition(expr string) (*Condition, error) {
+	tokens, err := lexCondition(expr)
+	if err != nil {
+		return nil, fmt.Errorf("auth: invalid condition %q: %w", expr, err)
+	}
+	p := &condParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != condEOF {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("auth: invalid condition %q: %w", expr, err)
+	}
+	return &Condition{source: expr, root: root}, nil
+}
+
+// String returns the condition's source expression.
+func (c *Condition) String() string {
+	return c.source
+}
+
+// Evaluate reports whether the condition holds for req. Returns an error
+// if the condition names a missing attribute or is not well-typed for
+// req; callers making access decisions must treat an error as a denial.
+func (c *Condition) Evaluate(req AccessRequest) (bool, error) {
+	if req.Time.IsZero() {
+		req.Time = time.Now().UTC()
+	}
+	v, err := c.root.eval(&req)
+	if err != nil {
+		return false, fmt.Errorf("auth: condition %q: %w", c.source, err)
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("auth: condition %q does not evaluate to a boolean", c.source)
+	}
+	return b, nil
+}
+
+// refersToClaims reports whether the condition names an identity claim.
+func (c *Condition) refersToClaims() bool {
+	return nodeRefersToClaims(c.root)
+}
+
+// nodeRefersToClaims reports whether n or any of its operands names an
+// identity claim.
+func nodeRefersToClaims(n condNode) bool {
+	switch n := n.(type) {
+	case condName:
+		return strings.HasPrefix(n.path, "claims.")
+	case condNot:
+		return nodeRefersToClaims(n.operand)
+	case condLogical:
+		return nodeRefersToClaims(n.left) || nodeRefersToClaims(n.right)
+	case condCompare:
+		return nodeRefersToClaims(n.left) || nodeRefersToClaims(n.right)
+	default:
+		return false
+	}
+}
+
+// maxCachedConditions bounds the number of compiled conditions kept by
+// compiledCondition.
+const maxCachedConditions = 1024
+
+// conditionCache holds compiled conditions keyed by source, so that
+// permissions carrying a condition string are not re-parsed on every
+// check.
+var conditionCache = struct {
+	sync.RWMutex
+	entries map[string]*Condition
+}{entries: make(map[string]*Condition)}
+
+// compiledCondition returns the compiled form of expr, from the cache if
+// possible.
+func compiledCondition(expr string) (*Condition, error) {
+	conditionCache.RLock()
+	c, ok := conditionCache.entries[expr]
+	conditionCache.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	c, err := CompileCondition(expr)
+	if err != nil {
+		return nil, err
+	}
+	conditionCache.Lock()
+	if len(conditionCache.entries) < maxCachedConditions {
+		conditionCache.entries[expr] = c
+	}
+	conditionCache.Unlock()
+	return c, nil
+}
+
+// Allows reports whether the permission grants req: it must match
+// req.Resource, req.Action, and req.Scope as in [Permission.Match], and
+// its Condition, if any, must hold for req. A condition that cannot be
+// compiled or evaluated does not hold.
+func (p Permission) Allows(req AccessRequest) bool {
+	if !p.matchAccess(req.Resource, req.Action, req.Scope) {
+		return false
+	}
+	if p.Condition == "" {
+		return true
+	}
+	c, err := compiledCondition(p.Condition)
+	if err != nil {
+		return false
+	}
+	ok, err := c.Evaluate(req)
+	return err == nil && ok
+}
+
+// ---------------------------------------------------------------------------
+// Evaluation
+// ---------------------------------------------------------------------------
+
+// condNode is a node of a compiled condition.
+type condNode interface {
+	eval(req *AccessRequest) (any, error)
+}
+
+// condLiteral is a literal value: string, float64, bool, or []any.
+type condLiteral struct{ value any }
+
+func (n condLiteral) eval(*AccessRequest) (any, error) { return n.value, nil }
+
+// condName is a reference to an attribute of the request.
+type condName struct{ path string }
+
+func (n condName) eval(req *AccessRequest) (any, error) {
+	switch n.path {
+	case "resource":
+		return req.Resource, nil
+	case "action":
+		return req.Action, nil
+	case "scope":
+		return req.Scope, nil
+	}
+
+	var v any
+	var ok bool
+	if rest, found := strings.CutPrefix(n.path, "claims."); found {
+		v, ok = lookupPath(req.Claims, rest)
+	} else if rest, found := strings.CutPrefix(n.path, "time."); found {
+		v, ok = timeAttribute(req.Time, rest)
+	} else {
+		v, ok = lookupPath(req.Attributes, n.path)
+	}
+	if !ok || v == nil {
+		return nil, fmt.Errorf("%s is not set", n.path)
+	}
+	return normalizeValue(v), nil
+}
+
+// condNot is logical negation.
+type condNot struct{ operand condNode }
+
+func (n condNot) eval(req *AccessRequest) (any, error) {
+	b, err := evalBool(n.operand, req)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+// condLogical is a short-circuiting && or || operation.
+type condLogical struct {
+	and         bool
+	left, right condNode
+}
+
+func (n condLogical) eval(req *AccessRequest) (any, error) {
+	left, err := evalBool(n.left, req)
+	if err != nil {
+		return nil, err
+	}
+	if left != n.and {
+		// false && x is false; true || x is true.
+		return left, nil
+	}
+	return evalBool(n.right, req)
+}
+
+// condCompare is a comparison or membership operation.
+type condCompare struct {
+	op          string
+	left, right condNode
+}
+
+func (n condCompare) eval(req *AccessRequest) (any, error) {
+	left, err := n.left.eval(req)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "in" {
+		list, ok := right.([]any)
+		if !ok {
+			return nil, fmt.Errorf("right operand of in is not a list")
+		}
+		for _, elem := range list {
+			if eq, err := compareValues("==", left, normalizeValue(elem)); err == nil && eq {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return compareValues(n.op, left, right)
+}
+
+// compareValues applies the comparison op to two normalized values of the
+// same type.
+func compareValues(op string, left, right any) (bool, error) {
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string with %T", right)
+		}
+		return compareOrdered(op, strings.Compare(l, r))
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare number with %T", right)
+		}
+		switch {
+		case l < r:
+			return compareOrdered(op, -1)
+		case l > r:
+			return compareOrdered(op, 1)
+		default:
+			return compareOrdered(op, 0)
+		}
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return false, fmt.Errorf("cannot compare bool with %T", right)
+		}
+		switch op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+		return false, fmt.Errorf("operator %s is not defined on bool", op)
+	default:
+		return false, fmt.Errorf("operator %s is not defined on %T", op, left)
+	}
+}
+
+// compareOrdered returns the result of op given the three-way comparison
+// result cmp.
+func compareOrdered(op string, cmp int) (bool, error) {
+	switch op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %s", op)
+}
+
+// evalBool evaluates n and requires a boolean result.
+func evalBool(n condNode, req *AccessRequest) (bool, error) {
+	v, err := n.eval(req)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a boolean, got %T", v)
+	}
+	return b, nil
+}
+
+// lookupPath returns the value of the dotted path in m. An exact key
+// match takes precedence over nested lookup, as with OIDC claim mapping.
+func lookupPath(m map[string]any, path string) (any, bool) {
+	if v, ok := m[path]; ok {
+		return v, true
+	}
+	var v any = m
+	for _, part := range strings.Split(path, ".") {
+		nested, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = nested[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// timeAttribute returns the named attribute of t.
+func timeAttribute(t time.Time, name string) (any, bool) {
+	switch name {
+	case "hour":
+		return float64(t.Hour()), true
+	case "minute":
+		return float64(t.Minute()), true
+	case "weekday":
+		return strings.ToLower(t.Weekday().String()), true
+	case "date":
+		return t.Format(time.DateOnly), true
+	case "unix":
+		return float64(t.Unix()), true
+	}
+	return nil, false
+}
+
+// normalizeValue converts Go numeric types to float64 and string slices
+// to []any, matching the types produced by JSON decoding of claims.
+func normalizeValue(v any) any {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case []string:
+		list := make([]any, len(n))
+		for i, s := range n {
+			list[i] = s
+		}
+		return list
+	}
+	return v
+}
+
+// ---------------------------------------------------------------------------
+// Parsing
+// ---------------------------------------------------------------------------
+
+// condTokenKind classifies condition tokens.
+type condTokenKind int
+
+const (
+	condEOF condTokenKind = iota
+	condIdent
+	condString
+	condNumber
+	condOp
+)
+
+// condToken is a lexical token of a condition.
+type condToken struct {
+	kind  condTokenKind
+	text  string
+	value any
+}
+
+// condOperators lists the operator and punctuation tokens, longest first.
+var condOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+// lexCondition splits expr into tokens.
+func lexCondition(expr string) ([]condToken, error) {
+	var tokens []condToken
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(expr[i+1:], byte(c))
+			if end < 0 {
+				return nil, errors.New("unterminated string")
+			}
+			s := expr[i+1 : i+1+end]
+			tokens = append(tokens, condToken{kind: condString, text: s, value: s})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			j := i + 1
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			f, err := strconv.ParseFloat(expr[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", expr[i:j])
+			}
+			tokens = append(tokens, condToken{kind: condNumber, text: expr[i:j], value: f})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(expr) && (expr[j] == '_' || expr[j] == '.' || expr[j] == '-' ||
+				unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				j++
+			}
+			tokens = append(tokens, condToken{kind: condIdent, text: expr[i:j]})
+			i = j
+		default:
+			matched := false
+			for _, op := range condOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, condToken{kind: condOp, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+	return append(tokens, condToken{kind: condEOF}), nil
+}
+
+// condParser is a recursive-descent parser over condition tokens:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) operand ]
+//	operand = literal | name | list | "(" or ")"
+type condParser struct {
+	tokens []condToken
+	pos    int
+}
+
+// peek returns the next token without consuming it.
+func (p *condParser) peek() condToken {
+	return p.tokens[p.pos]
+}
+
+// next consumes and returns the next token.
+func (p *condParser) next() condToken {
+	t := p.tokens[p.pos]
+	if t.kind != condEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *condParser) accept(op string) bool {
+	if t := p.peek(); t.kind == condOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *condParser) parseOr() (condNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = condLogical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseAnd() (condNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = condLogical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseUnary() (condNode, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return condNot{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *condParser) parseCompare() (condNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	var op string
+	switch {
+	case t.kind == condOp && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		op = t.text
+	case t.kind == condIdent && t.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return condCompare{op: op, left: left, right: right}, nil
+}
+
+func (p *condParser) parseOperand() (condNode, error) {
+	t := p.next()
+	switch t.kind {
+	case condString, condNumber:
+		return condLiteral{value: t.value}, nil
+	case condIdent:
+		switch t.text {
+		case "true":
+			return condLiteral{value: true}, nil
+		case "false":
+			return condLiteral{value: false}, nil
+		case "in":
+			return nil, errors.New("unexpected in")
+		}
+		if strings.HasPrefix(t.text, ".") || strings.HasSuffix(t.text, ".") || strings.Contains(t.text, "..") {
+			return nil, fmt.Errorf("invalid name %q", t.text)
+		}
+		return condName{path: t.text}, nil
+	case condOp:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, errors.New("missing )")
+			}
+			return inner, nil
+		case "[":
+			return p.parseList()
+		}
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return nil, errors.New("unexpected end of condition")
+}
+
+// parseList parses the elements of a list literal after its "[".
+func (p *condParser) parseList() (condNode, error) {
+	list := []any{}
+	if p.accept("]") {
+		return condLiteral{value: list}, nil
+	}
+	for {
+		t := p.next()
+		switch {
+		case t.kind == condString || t.kind == condNumber:
+			list = append(list, t.value)
+		case t.kind == condIdent && (t.text == "true" || t.text == "false"):
+			list = append(list, t.text == "true")
+		default:
+			return nil, errors.New("list elements must be literals")
+		}
+		if p.accept("]") {
+			return condLiteral{value: list}, nil
+		}
+		if !p.accept(",") {
+			return nil, errors.New("missing ] after list")
+		}
+	}
+}
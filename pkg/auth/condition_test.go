@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// newConditionTestIdentity returns a user identity of tenant "acme" with
+// conditional permissions on documents.
+func newConditionTestIdentity(t *testing.T) Identity {
+	t.Helper()
+	var perms []Permission
+	for _, s := range []string{
+		"documents:read[namespace == claims.tenant]",
+		"documents:write:production[owner == claims.sub && time.hour >= 9 && time.hour < 17]",
+		"reports:read",
+	} {
+		p, err := ParsePermissionString(s)
+		require.NoError(t, err)
+		perms = append(perms, p)
+	}
+	identity, err := NewUserIdentity("user-1", "alice@example.com", "Alice",
+		map[string]any{"sub": "user-1", "tenant": "acme", "org": map[string]any{"tier": "gold"}}, perms)
+	require.NoError(t, err)
+	return identity
+}
+
+// ---------------------------------------------------------------------------
+// Condition
+// ---------------------------------------------------------------------------
+
+func TestCompileCondition_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, expr := range []string{
+		"",
+		"owner ==",
+		"owner == 'alice",
+		"(owner == claims.sub",
+		"owner == claims.sub)",
+		"owner in [claims.sub]",
+		"owner # 1",
+		"claims..sub == 'a'",
+		"in == 'a'",
+	} {
+		_, err := CompileCondition(expr)
+		assert.Error(t, err, "expr %q", expr)
+	}
+}
+
+func TestCondition_Evaluate(t *testing.T) {
+	t.Parallel()
+	// Monday, 10:30 UTC.
+	now := time.Date(2026, time.March, 2, 10, 30, 0, 0, time.UTC)
+	req := AccessRequest{
+		Resource: "documents",
+		Action:   "read",
+		Scope:    "production",
+		Claims: map[string]any{
+			"sub":         "user-1",
+			"roles":       []any{"editor", "auditor"},
+			"level":       float64(3),
+			"org":         map[string]any{"tier": "gold"},
+			"example.com": "dotted",
+		},
+		Attributes: map[string]any{
+			"owner":     "user-1",
+			"size":      42,
+			"labels":    map[string]any{"team": "payments"},
+			"public":    false,
+			"reviewers": []string{"user-2"},
+		},
+		Time: now,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`owner == claims.sub`, true},
+		{`owner != claims.sub`, false},
+		{`"editor" in claims.roles`, true},
+		{`"admin" in claims.roles`, false},
+		{`claims.sub in reviewers`, false},
+		{`size > 10 && size <= 42`, true},
+		{`claims.level >= 5 || claims.org.tier == "gold"`, true},
+		{`claims.example.com == 'dotted'`, true},
+		{`labels.team in ["payments", "billing"]`, true},
+		{`!public`, true},
+		{`public == false`, true},
+		{`scope == "production" && resource == "documents" && action == "read"`, true},
+		{`time.hour >= 9 && time.hour < 17`, true},
+		{`time.weekday in ["saturday", "sunday"]`, false},
+		{`time.date == "2026-03-02"`, true},
+		{`!(owner == "user-2") && (size < 0 || true)`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			c, err := CompileCondition(tt.expr)
+			require.NoError(t, err)
+			got, err := c.Evaluate(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCondition_EvaluateFailsClosed(t *testing.T) {
+	t.Parallel()
+	req := AccessRequest{
+		Claims:     map[string]any{"sub": "user-1"},
+		Attributes: map[string]any{"owner": "user-1", "size": 42},
+	}
+	for _, expr := range []string{
+		`missing == "x"`,
+		`claims.missing != "x"`,
+		`size == "42"`,
+		`owner`,
+		`owner && true`,
+		`"a" in owner`,
+		`time.century == 21`,
+	} {
+		c, err := CompileCondition(expr)
+		require.NoError(t, err, "expr %q", expr)
+		_, err = c.Evaluate(req)
+		assert.Error(t, err, "expr %q", expr)
+		assert.False(t, Permission{Resource: "*", Action: "*", Condition: expr}.Allows(req), "expr %q", expr)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Conditional permissions
+// ---------------------------------------------------------------------------
+
+func TestParsePermissionString_Condition(t *testing.T) {
+	t.Parallel()
+	p, err := ParsePermissionString("documents:write:production[owner == claims.sub && labels.x in ['a:b', 'c]']]")
+	require.NoError(t, err)
+	assert.Equal(t, Permission{
+		Resource:  "documents",
+		Action:    "write",
+		Scope:     "production",
+		Condition: "owner == claims.sub && labels.x in ['a:b', 'c]']",
+	}, p)
+
+	roundTrip, err := ParsePermissionString(p.String())
+	require.NoError(t, err)
+	assert.Equal(t, p, roundTrip)
+
+	for _, s := range []string{
+		"documents:read[",
+		"documents:read[]",
+		"documents:read[owner ==]",
+		"documents:read[owner == 'a'] ",
+	} {
+		_, err := ParsePermissionString(s)
+		assert.Error(t, err, "permission %q", s)
+	}
+}
+
+func TestPermission_ConditionalNeverMatches(t *testing.T) {
+	t.Parallel()
+	identity := newConditionTestIdentity(t)
+	assert.False(t, identity.HasPermission("documents", "read"))
+	assert.True(t, identity.HasPermission("reports", "read"))
+
+	ps := PermissionsOf(identity)
+	assert.False(t, ps.Match("documents", "read", ""))
+	assert.True(t, ps.MatchRequest(AccessRequest{
+		Resource:   "documents",
+		Action:     "read",
+		Claims:     identity.Claims(),
+		Attributes: map[string]any{"namespace": "acme"},
+	}))
+}
+
+func TestAuthorizeRequest(t *testing.T) {
+	t.Parallel()
+	identity := newConditionTestIdentity(t)
+	workday := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	night := time.Date(2026, time.March, 2, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		req  AccessRequest
+		code sserr.Code
+	}{
+		{"condition holds", AccessRequest{Resource: "documents", Action: "read",
+			Attributes: map[string]any{"namespace": "acme"}}, ""},
+		{"condition fails", AccessRequest{Resource: "documents", Action: "read",
+			Attributes: map[string]any{"namespace": "globex"}}, sserr.CodeAuthorizationDenied},
+		{"attribute missing", AccessRequest{Resource: "documents", Action: "read"}, sserr.CodeAuthorizationDenied},
+		{"caller claims ignored", AccessRequest{Resource: "documents", Action: "read",
+			Claims:     map[string]any{"tenant": "globex"},
+			Attributes: map[string]any{"namespace": "globex"}}, sserr.CodeAuthorizationDenied},
+		{"scoped condition holds", AccessRequest{Resource: "documents", Action: "write", Scope: "production",
+			Attributes: map[string]any{"owner": "user-1"}, Time: workday}, ""},
+		{"scoped condition outside hours", AccessRequest{Resource: "documents", Action: "write", Scope: "production",
+			Attributes: map[string]any{"owner": "user-1"}, Time: night}, sserr.CodeAuthorizationDenied},
+		{"scoped condition wrong scope", AccessRequest{Resource: "documents", Action: "write", Scope: "staging",
+			Attributes: map[string]any{"owner": "user-1"}, Time: workday}, sserr.CodeAuthorizationInsufficientScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := AuthorizeRequest(identity, tt.req)
+			if tt.code == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, sserr.HasCode(err, tt.code), "error = %v, want code %v", err, tt.code)
+		})
+	}
+
+	// Authorize has no attributes, so attribute conditions do not hold.
+	assert.True(t, sserr.HasCode(Authorize(identity, "documents", "read", ""), sserr.CodeAuthorizationDenied))
+}
+
+func TestRequirePermission_AttributeExtractor(t *testing.T) {
+	t.Parallel()
+	handler := RequirePermission("documents", "read",
+		WithAttributeExtractor(func(r *http.Request) map[string]any {
+			return map[string]any{"namespace": r.PathValue("ns")}
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	mux := http.NewServeMux()
+	mux.Handle("GET /namespaces/{ns}/documents", handler)
+	ctx := ContextWithIdentity(context.Background(), newConditionTestIdentity(t))
+
+	for target, want := range map[string]int{
+		"/namespaces/acme/documents":   http.StatusNoContent,
+		"/namespaces/globex/documents": http.StatusForbidden,
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+		assert.Equal(t, want, rr.Code, target)
+	}
+}
+
+func TestMethodAuthorizer_AttributeExtractor(t *testing.T) {
+	t.Parallel()
+	authz, err := NewMethodAuthorizer(map[string]string{
+		"/docs.v1.DocumentService/Get": "documents:read",
+	}, WithGRPCAttributeExtractor(func(_ context.Context, _ string, req any) map[string]any {
+		ns, _ := req.(string)
+		return map[string]any{"namespace": ns}
+	}))
+	require.NoError(t, err)
+	ctx := ContextWithIdentity(context.Background(), newConditionTestIdentity(t))
+
+	assert.NoError(t, authz.Authorize(ctx, "/docs.v1.DocumentService/Get", "acme"))
+	err = authz.Authorize(ctx, "/docs.v1.DocumentService/Get", "globex")
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationDenied), "error = %v", err)
+}
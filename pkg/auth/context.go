@@ -19,6 +19,10 @@ const (
 
 	// callChainKey stores the CallChain tracking request provenance.
 	callChainKey
+
+	// requestHeaderKey stores the inbound HTTP request headers while the
+	// token is validated, for [MatchHeader].
+	requestHeaderKey
 )
 
 // ContextWithIdentity returns a new context with the given Identity attached.
@@ -6,9 +6,9 @@ import (
 	"strings"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
 )
 
 // UnaryServerInterceptor returns a gRPC unary server interceptor that extracts
@@ -16,24 +16,42 @@ import (
 //
 // The interceptor performs the following steps:
 //  1. Extracts the "authorization" metadata value (bearer token)
-//  2. Validates the token using the provided [TokenValidator]
+//  2. Validates the token using the provided [TokenValidator], or, with
+//     [WithCertificateValidator] and no authorization metadata, the
+//     verified TLS client certificate using the [CertificateValidator]
 //  3. Stores the resulting [Identity] in the request context
-//  4. Extracts propagated caller service and call chain metadata
+//  4. Extracts propagated caller service and call chain metadata, and
+//     with [WithCallChainPolicy], enforces the call chain policy
 //  5. Passes the enriched context to the handler
 //
 // If no authorization metadata is present or the token is invalid, the
-// interceptor returns a gRPC Unauthenticated error.
+// interceptor returns a gRPC Unauthenticated error carrying the platform
+// error code (see [GRPCStatus]), so clients can tell an expired token
+// ([sserr.CodeAuthenticationExpired]) from a malformed one. Validator
+// errors in other categories keep their status, e.g. Unavailable for
+// [sserr.CodeUnavailable]. With [WithCertificateValidator], validator may
+// be nil to accept only client certificates.
 //
 // The serviceName parameter identifies the current service for call chain
-// tracking. It is recorded as the receiving service in audit logs.
-func UnaryServerInterceptor(validator TokenValidator, serviceName string) grpc.UnaryServerInterceptor {
+// tracking. It is recorded as the receiving service in audit logs. With
+// [WithPropagationVerifier], the interceptor also returns Unauthenticated
+// if the propagated identity metadata is unsigned, tampered with,
+// addressed to another service than serviceName (or the audience set by
+// [WithPropagationAudience]), replayed, or too old, and takes the
+// concrete type and descriptive fields, but never the claims or
+// permissions, of the authenticated identity from a verified typed
+// identity envelope as [HTTPMiddleware] does. With [WithCallChainPolicy], it returns
+// PermissionDenied if the call chain is rejected, or InvalidArgument if it
+// is malformed.
+func UnaryServerInterceptor(validator TokenValidator, serviceName string, opts ...PropagationOption) grpc.UnaryServerInterceptor {
+	o := newPropagationOptions(opts)
 	return func(
 		ctx context.Context,
 		req any,
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (any, error) {
-		ctx, err := extractIdentityFromGRPC(ctx, validator, serviceName)
+		ctx, err := extractIdentityFromGRPC(ctx, validator, serviceName, o)
 		if err != nil {
 			return nil, err
 		}
@@ -46,14 +64,15 @@ func UnaryServerInterceptor(validator TokenValidator, serviceName string) grpc.U
 //
 // This interceptor performs the same authentication steps as
 // [UnaryServerInterceptor] but wraps the stream to carry the enriched context.
-func StreamServerInterceptor(validator TokenValidator, serviceName string) grpc.StreamServerInterceptor {
+func StreamServerInterceptor(validator TokenValidator, serviceName string, opts ...PropagationOption) grpc.StreamServerInterceptor {
+	o := newPropagationOptions(opts)
 	return func(
 		srv any,
 		ss grpc.ServerStream,
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		ctx, err := extractIdentityFromGRPC(ss.Context(), validator, serviceName)
+		ctx, err := extractIdentityFromGRPC(ss.Context(), validator, serviceName, o)
 		if err != nil {
 			return err
 		}
@@ -74,7 +93,14 @@ func StreamServerInterceptor(validator TokenValidator, serviceName string) grpc.
 // metadata (allowing unauthenticated service-to-service calls where appropriate).
 //
 // The serviceName parameter identifies the current service in the call chain.
-func UnaryClientInterceptor(serviceName string) grpc.UnaryClientInterceptor {
+// With [WithPropagationSigner], the identity metadata is signed and
+// addressed to the first DNS label of the connection's target, or to the
+// audience set by [WithPropagationAudience]. With
+// [WithTokenSource], calls without authorization metadata are given the
+// source's bearer token, and fail with the source's error if it cannot
+// supply one.
+func UnaryClientInterceptor(serviceName string, opts ...PropagationOption) grpc.UnaryClientInterceptor {
+	o := newPropagationOptions(opts)
 	return func(
 		ctx context.Context,
 		method string,
@@ -83,7 +109,11 @@ func UnaryClientInterceptor(serviceName string) grpc.UnaryClientInterceptor {
 		invoker grpc.UnaryInvoker,
 		opts ...grpc.CallOption,
 	) error {
-		ctx = propagateIdentityToGRPC(ctx, serviceName)
+		ctx, err := outgoingTokenToGRPC(ctx, o.tokenSource)
+		if err != nil {
+			return err
+		}
+		ctx = propagateIdentityToGRPC(ctx, serviceName, o.signer, grpcAudience(cc, o))
 		return invoker(ctx, method, req, reply, cc, opts...)
 	}
 }
@@ -93,7 +123,8 @@ func UnaryClientInterceptor(serviceName string) grpc.UnaryClientInterceptor {
 //
 // This interceptor performs the same propagation steps as
 // [UnaryClientInterceptor].
-func StreamClientInterceptor(serviceName string) grpc.StreamClientInterceptor {
+func StreamClientInterceptor(serviceName string, opts ...PropagationOption) grpc.StreamClientInterceptor {
+	o := newPropagationOptions(opts)
 	return func(
 		ctx context.Context,
 		desc *grpc.StreamDesc,
@@ -102,33 +133,64 @@ func StreamClientInterceptor(serviceName string) grpc.StreamClientInterceptor {
 		streamer grpc.Streamer,
 		opts ...grpc.CallOption,
 	) (grpc.ClientStream, error) {
-		ctx = propagateIdentityToGRPC(ctx, serviceName)
+		ctx, err := outgoingTokenToGRPC(ctx, o.tokenSource)
+		if err != nil {
+			return nil, err
+		}
+		ctx = propagateIdentityToGRPC(ctx, serviceName, o.signer, grpcAudience(cc, o))
 		return streamer(ctx, desc, cc, method, opts...)
 	}
 }
 
 // extractIdentityFromGRPC extracts identity from incoming gRPC metadata,
 // validates the bearer token, and enriches the context with identity
-// information and call chain data.
-func extractIdentityFromGRPC(ctx context.Context, validator TokenValidator, serviceName string) (context.Context, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return ctx, status.Error(codes.Unauthenticated, "missing metadata")
-	}
-
-	// Extract and validate the bearer token.
+// information and call chain data. Without authorization metadata, the
+// peer's verified client certificate is validated instead if o has a
+// certificate validator. If o has a verifier, the propagated metadata
+// must carry a valid signature.
+func extractIdentityFromGRPC(ctx context.Context, validator TokenValidator, serviceName string, o propagationOptions) (context.Context, error) {
+	md, hasMD := metadata.FromIncomingContext(ctx)
 	tokens := md.Get(HeaderAuthorization)
-	if len(tokens) == 0 {
-		return ctx, status.Error(codes.Unauthenticated, "missing authorization metadata")
+
+	var identity Identity
+	var err error
+	if cert := grpcPeerCertificate(ctx); len(tokens) == 0 && o.certValidator != nil && cert != nil {
+		identity, err = o.certValidator.ValidateCertificate(ctx, cert)
+	} else {
+		// Extract and validate the bearer token.
+		if !hasMD {
+			return ctx, GRPCStatus(ctx, sserr.New(sserr.CodeAuthentication, "auth: missing metadata"))
+		}
+		if len(tokens) == 0 {
+			return ctx, GRPCStatus(ctx, sserr.New(sserr.CodeAuthentication, "auth: missing authorization metadata"))
+		}
+		token := ExtractBearerToken(tokens[0])
+		if token == "" || validator == nil {
+			return ctx, GRPCStatus(ctx, sserr.New(sserr.CodeAuthentication, "auth: invalid authorization format"))
+		}
+		identity, err = validator.Validate(ctx, token)
 	}
-	token := ExtractBearerToken(tokens[0])
-	if token == "" {
-		return ctx, status.Error(codes.Unauthenticated, "invalid authorization format")
+	if err != nil {
+		return ctx, GRPCStatus(ctx, authenticationError(err))
 	}
 
-	identity, err := validator.Validate(ctx, token)
-	if err != nil {
-		return ctx, status.Error(codes.Unauthenticated, "token validation failed")
+	// Verify the signature over the propagated metadata before trusting
+	// any of it.
+	if o.verifier != nil {
+		getValue := func(key string) string {
+			if values := md.Get(key); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+		if err := o.verifier.Verify(ctx, o.serverAudience(serviceName), getValue); err != nil {
+			slog.WarnContext(ctx, "auth: rejected propagated identity metadata",
+				"error", err,
+				"service", serviceName,
+			)
+			return ctx, GRPCStatus(ctx, propagationError(err))
+		}
+		identity = verifiedIdentity(ctx, identity, getValue, serviceName)
 	}
 
 	// Store the validated identity in the context.
@@ -140,27 +202,38 @@ func extractIdentityFromGRPC(ctx context.Context, validator TokenValidator, serv
 	}
 
 	// Extract and reconstruct the call chain from metadata.
+	var chain *CallChain
 	if chains := md.Get(HeaderCallChain); len(chains) > 0 && chains[0] != "" {
-		chain, err := DeserializeCallChain(chains[0])
+		chain, err = DeserializeCallChain(chains[0])
 		if err != nil {
-			// Log the error but don't fail the request — a malformed call
-			// chain header should not prevent processing. The identity itself
-			// was already validated.
 			slog.WarnContext(ctx, "auth: failed to deserialize call chain from gRPC metadata",
 				"error", err,
 				"service", serviceName,
 			)
+			// Without a policy, log the error but don't fail the request —
+			// a malformed call chain header should not prevent processing.
+			// The identity itself was already validated. With a policy, an
+			// unreadable chain cannot be checked.
+			if o.chainPolicy != nil {
+				return ctx, GRPCStatus(ctx, sserr.Wrap(err, sserr.CodeValidationFormat, "auth: malformed call chain metadata"))
+			}
 		} else if chain != nil {
 			ctx = ContextWithCallChain(ctx, chain)
 		}
 	}
+	if o.chainPolicy != nil {
+		if err := o.chainPolicy.enforce(ctx, identity, chain, serviceName); err != nil {
+			return ctx, GRPCStatus(ctx, err)
+		}
+	}
 
 	return ctx, nil
 }
 
 // propagateIdentityToGRPC adds identity information from the context to
-// outgoing gRPC metadata for downstream services.
-func propagateIdentityToGRPC(ctx context.Context, serviceName string) context.Context {
+// outgoing gRPC metadata for downstream services, signing it for audience
+// if signer is non-nil.
+func propagateIdentityToGRPC(ctx context.Context, serviceName string, signer *PropagationSigner, audience string) context.Context {
 	identity, ok := IdentityFromContext(ctx)
 	if !ok {
 		return ctx
@@ -192,6 +265,15 @@ func propagateIdentityToGRPC(ctx context.Context, serviceName string) context.Co
 		)
 		return ctx
 	}
+	if signer != nil {
+		if err := signer.Sign(audience, headers); err != nil {
+			slog.WarnContext(ctx, "auth: failed to sign identity for gRPC propagation",
+				"error", err,
+				"service", serviceName,
+			)
+			return ctx
+		}
+	}
 
 	// Convert headers to metadata pairs.
 	pairs := make([]string, 0, len(headers)*2)
@@ -209,6 +291,33 @@ func propagateIdentityToGRPC(ctx context.Context, serviceName string) context.Co
 	return metadata.NewOutgoingContext(ctx, md)
 }
 
+// grpcAudience returns the audience of identity metadata sent over cc:
+// the audience set by [WithPropagationAudience], or the first DNS label
+// of cc's target.
+func grpcAudience(cc *grpc.ClientConn, o propagationOptions) string {
+	if o.audience != "" || cc == nil {
+		return o.audience
+	}
+	return targetAudience(cc.Target())
+}
+
+// outgoingTokenToGRPC adds the bearer token of source to the outgoing
+// metadata of ctx, unless source is nil or the metadata already has an
+// authorization entry.
+func outgoingTokenToGRPC(ctx context.Context, source TokenSource) (context.Context, error) {
+	if source == nil {
+		return ctx, nil
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(HeaderAuthorization)) > 0 {
+		return ctx, nil
+	}
+	token, err := source.Token(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, HeaderAuthorization, bearerPrefix+token), nil
+}
+
 // wrappedServerStream wraps a grpc.ServerStream to override its Context method.
 // This is necessary because ServerStream.Context() returns the original stream
 // context, which does not contain the identity added by the interceptor.
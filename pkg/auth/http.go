@@ -3,6 +3,8 @@ package auth
 import (
 	"log/slog"
 	"net/http"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
 )
 
 // HTTPMiddleware returns an HTTP middleware that extracts and validates
@@ -10,16 +12,36 @@ import (
 //
 // The middleware performs the following steps:
 //  1. Extracts the "Authorization" header (bearer token)
-//  2. Validates the token using the provided [TokenValidator]
+//  2. Validates the token using the provided [TokenValidator], or, with
+//     [WithCertificateValidator] and no bearer token, the verified TLS
+//     client certificate using the [CertificateValidator]
 //  3. Stores the resulting [Identity] in the request context
-//  4. Extracts propagated caller service and call chain headers
+//  4. Extracts propagated caller service and call chain headers, and
+//     with [WithCallChainPolicy], enforces the call chain policy
 //  5. Passes the enriched request to the next handler
 //
 // If no Authorization header is present or the token is invalid, the
-// middleware responds with HTTP 401 Unauthorized.
+// middleware responds with HTTP 401 Unauthorized and an RFC 7807 problem
+// details body (see [WriteProblem]) whose code tells clients why, e.g.
+// [sserr.CodeAuthenticationExpired] for an expired token. Validator
+// errors in other categories keep their status, e.g. 503 Service
+// Unavailable for [sserr.CodeUnavailable].
+//
+// With [WithCertificateValidator], validator may be nil to accept only
+// client certificates.
 //
 // The serviceName parameter identifies the current service for call chain
-// tracking.
+// tracking. With [WithPropagationVerifier], the middleware also responds
+// with HTTP 401 if the propagated identity headers are unsigned, tampered
+// with, addressed to another service than serviceName (or the audience
+// set by [WithPropagationAudience]), replayed, or too old. If the verified
+// headers carry a typed identity envelope ([HeaderIdentity]) for the
+// authenticated identity, the identity stored in the context takes its
+// concrete type and descriptive fields (e.g., a [UserIdentity] with its
+// email) from the envelope, but its claims and permissions always come
+// from the validated credentials. With [WithCallChainPolicy], it
+// responds with HTTP 403 Forbidden if the call chain is rejected, or 400
+// Bad Request if it is malformed.
 //
 // Example:
 //
@@ -27,25 +49,47 @@ import (
 //	mux.HandleFunc("/api/data", handleData)
 //	handler := auth.HTTPMiddleware(validator, "my-service")(mux)
 //	http.ListenAndServe(":8080", handler)
-func HTTPMiddleware(validator TokenValidator, serviceName string) func(http.Handler) http.Handler {
+func HTTPMiddleware(validator TokenValidator, serviceName string, opts ...PropagationOption) func(http.Handler) http.Handler {
+	o := newPropagationOptions(opts)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract the bearer token from the Authorization header.
 			authHeader := r.Header.Get(HeaderAuthorization)
 			token := ExtractBearerToken(authHeader)
-			if token == "" {
-				http.Error(w, "missing or invalid authorization header", http.StatusUnauthorized)
-				return
-			}
 
-			// Validate the token and extract the identity.
+			// Validate the token, or the client certificate if there is
+			// no token, and extract the identity.
 			ctx := r.Context()
-			identity, err := validator.Validate(ctx, token)
+			var identity Identity
+			var err error
+			switch cert := verifiedPeerCertificate(r.TLS); {
+			case token != "" && validator != nil:
+				identity, err = validator.Validate(contextWithRequestHeader(ctx, r.Header), token)
+			case authHeader == "" && o.certValidator != nil && cert != nil:
+				identity, err = o.certValidator.ValidateCertificate(ctx, cert)
+			default:
+				WriteProblem(w, r, sserr.New(sserr.CodeAuthentication, "auth: missing or invalid authorization header"))
+				return
+			}
 			if err != nil {
-				http.Error(w, "token validation failed", http.StatusUnauthorized)
+				WriteProblem(w, r, authenticationError(err))
 				return
 			}
 
+			// Verify the signature over the propagated headers before
+			// trusting any of them.
+			if o.verifier != nil {
+				if err := o.verifier.Verify(ctx, o.serverAudience(serviceName), r.Header.Get); err != nil {
+					slog.WarnContext(ctx, "auth: rejected propagated identity headers",
+						"error", err,
+						"service", serviceName,
+					)
+					WriteProblem(w, r, propagationError(err))
+					return
+				}
+				identity = verifiedIdentity(ctx, identity, r.Header.Get, serviceName)
+			}
+
 			// Store the validated identity in the request context.
 			ctx = ContextWithIdentity(ctx, identity)
 
@@ -55,18 +99,31 @@ func HTTPMiddleware(validator TokenValidator, serviceName string) func(http.Hand
 			}
 
 			// Extract and reconstruct the call chain.
+			var chain *CallChain
 			if chainHeader := r.Header.Get(HeaderCallChain); chainHeader != "" {
-				chain, err := DeserializeCallChain(chainHeader)
+				chain, err = DeserializeCallChain(chainHeader)
 				if err != nil {
-					// Log but don't fail — the identity was already validated.
 					slog.WarnContext(ctx, "auth: failed to deserialize call chain from HTTP header",
 						"error", err,
 						"service", serviceName,
 					)
+					// Without a policy, log but don't fail — the identity
+					// was already validated. With one, an unreadable chain
+					// cannot be checked.
+					if o.chainPolicy != nil {
+						WriteProblem(w, r, sserr.Wrap(err, sserr.CodeValidationFormat, "auth: malformed call chain header"))
+						return
+					}
 				} else if chain != nil {
 					ctx = ContextWithCallChain(ctx, chain)
 				}
 			}
+			if o.chainPolicy != nil {
+				if err := o.chainPolicy.enforce(ctx, identity, chain, serviceName); err != nil {
+					WriteProblem(w, r, err)
+					return
+				}
+			}
 
 			// Continue with the enriched context.
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -77,6 +134,8 @@ func HTTPMiddleware(validator TokenValidator, serviceName string) func(http.Hand
 // PropagatingRoundTripper wraps an [http.RoundTripper] to propagate identity
 // context to outgoing HTTP requests. It reads the identity, caller service,
 // and call chain from the request context and adds them as HTTP headers.
+// With [WithTokenSource], it also authenticates the service itself with a
+// bearer token.
 //
 // This is used when a service needs to make outgoing HTTP calls to downstream
 // services while preserving the identity context for authorization and audit.
@@ -94,31 +153,87 @@ type PropagatingRoundTripper struct {
 
 	// wrapped is the underlying RoundTripper that performs the actual HTTP call.
 	wrapped http.RoundTripper
+
+	// signer signs the propagated headers. Nil disables signing.
+	signer *PropagationSigner
+
+	// audience is the audience of signed headers. Empty derives it from
+	// the request host.
+	audience string
+
+	// tokenSource supplies the service's own bearer token. Nil disables
+	// token injection.
+	tokenSource TokenSource
 }
 
 // NewPropagatingRoundTripper creates a new PropagatingRoundTripper that wraps
 // the given transport. If transport is nil, [http.DefaultTransport] is used.
 //
 // The serviceName parameter identifies the current service in the call chain.
-func NewPropagatingRoundTripper(serviceName string, transport http.RoundTripper) *PropagatingRoundTripper {
+// With [WithPropagationSigner], the propagated headers are signed and
+// addressed to the first DNS label of the request host, or to the
+// audience set by [WithPropagationAudience]. With
+// [WithTokenSource], requests without an Authorization header are given
+// the source's bearer token.
+func NewPropagatingRoundTripper(serviceName string, transport http.RoundTripper, opts ...PropagationOption) *PropagatingRoundTripper {
 	if transport == nil {
 		transport = http.DefaultTransport
 	}
+	o := newPropagationOptions(opts)
 	return &PropagatingRoundTripper{
 		serviceName: serviceName,
 		wrapped:     transport,
+		signer:      o.signer,
+		audience:    o.audience,
+		tokenSource: o.tokenSource,
 	}
 }
 
 // RoundTrip executes the HTTP request with identity headers injected from
-// the request context. If no identity is present in the context, the request
-// proceeds without modification.
+// the request context, and the bearer token of the token source if the
+// request has no Authorization header. If there is nothing to add, the
+// request proceeds without modification.
+//
+// If the token source fails, the request is not sent and its error is
+// returned.
 //
 // RoundTrip implements the [http.RoundTripper] interface.
 func (t *PropagatingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	headers := t.identityHeaders(r)
+	if t.tokenSource != nil && r.Header.Get(HeaderAuthorization) == "" {
+		token, err := t.tokenSource.Token(r.Context())
+		if err != nil {
+			// RoundTrip must close the request body, even on errors.
+			if r.Body != nil {
+				_ = r.Body.Close()
+			}
+			return nil, err
+		}
+		if headers == nil {
+			headers = make(map[string]string, 1)
+		}
+		headers[HeaderAuthorization] = bearerPrefix + token
+	}
+	if len(headers) == 0 {
+		return t.wrapped.RoundTrip(r)
+	}
+
+	// Clone the request to avoid mutating the original.
+	clone := r.Clone(r.Context())
+	for k, v := range headers {
+		clone.Header.Set(k, v)
+	}
+
+	return t.wrapped.RoundTrip(clone)
+}
+
+// identityHeaders returns the signed identity headers to propagate for
+// the identity in the context of r, or nil if there is no identity or
+// the headers cannot be built.
+func (t *PropagatingRoundTripper) identityHeaders(r *http.Request) map[string]string {
 	identity, ok := IdentityFromContext(r.Context())
 	if !ok {
-		return t.wrapped.RoundTrip(r)
+		return nil
 	}
 
 	// Build the call chain, appending the current service.
@@ -143,14 +258,20 @@ func (t *PropagatingRoundTripper) RoundTrip(r *http.Request) (*http.Response, er
 			"error", err,
 			"service", t.serviceName,
 		)
-		return t.wrapped.RoundTrip(r)
+		return nil
 	}
-
-	// Clone the request to avoid mutating the original.
-	clone := r.Clone(r.Context())
-	for k, v := range headers {
-		clone.Header.Set(k, v)
+	if t.signer != nil {
+		audience := t.audience
+		if audience == "" {
+			audience = serviceAudience(r.URL.Host)
+		}
+		if err := t.signer.Sign(audience, headers); err != nil {
+			slog.WarnContext(r.Context(), "auth: failed to sign identity headers for HTTP propagation",
+				"error", err,
+				"service", t.serviceName,
+			)
+			return nil
+		}
 	}
-
-	return t.wrapped.RoundTrip(clone)
+	return headers
 }
@@ -27,6 +27,7 @@ package auth
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 )
 
@@ -125,6 +126,21 @@ type TokenValidator interface {
 	Validate(ctx context.Context, token string) (Identity, error)
 }
 
+// CertificateValidator extracts the identity of a peer authenticated with
+// a TLS client certificate, for service-to-service mutual TLS. It is the
+// certificate counterpart of [TokenValidator], and is used by the HTTP
+// middleware and gRPC interceptors configured with
+// [WithCertificateValidator].
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type CertificateValidator interface {
+	// ValidateCertificate returns the Identity of the peer that presented
+	// cert, the leaf of a chain already verified by the TLS handshake.
+	// Returns an error if the certificate does not identify an accepted
+	// peer.
+	ValidateCertificate(ctx context.Context, cert *x509.Certificate) (Identity, error)
+}
+
 // BasicIdentity is a simple, immutable implementation of the Identity interface.
 // It is used for carrying identity information across service boundaries after
 // deserialization from gRPC metadata or HTTP headers.
@@ -226,6 +242,15 @@ type Permission struct {
 	// check scope is "" which matches any permission scope, preserving
 	// backward compatibility with existing code.
 	Scope string
+
+	// Condition is an optional attribute-based condition (see [Condition])
+	// that must also hold for the permission to apply, such as
+	// "namespace == claims.tenant". Conditional permissions are granted
+	// only by checks that supply request attributes — [Permission.Allows],
+	// [PermissionSet.MatchRequest], and the Authorize functions — and
+	// never by [Permission.Match] or [Identity.HasPermission], which have
+	// no attributes to evaluate them against.
+	Condition string
 }
 
 // Match reports whether this permission grants access to the specified
@@ -248,7 +273,15 @@ type Permission struct {
 // This symmetric design ensures that [Identity.HasPermission] (which passes
 // scope="") remains fully backward compatible: it matches permissions
 // regardless of their Scope value.
+//
+// A permission with a Condition never matches; use [Permission.Allows].
 func (p Permission) Match(resource, action, scope string) bool {
+	return p.Condition == "" && p.matchAccess(resource, action, scope)
+}
+
+// matchAccess reports whether the permission's resource, action, and scope
+// match, ignoring its Condition.
+func (p Permission) matchAccess(resource, action, scope string) bool {
 	resourceMatch := p.Resource == "*" || p.Resource == resource
 	actionMatch := p.Action == "*" || p.Action == action
 	scopeMatch := p.Scope == "" || p.Scope == "*" ||
@@ -260,15 +293,20 @@ func (p Permission) Match(resource, action, scope string) bool {
 // String returns a human-readable representation of the permission in
 // colon-delimited format. If the Scope is empty or "*" (global/wildcard),
 // the format is "resource:action". If a specific scope is set, the format
-// is "resource:action:scope".
+// is "resource:action:scope". A Condition is appended in square brackets,
+// as in "documents:read[owner == claims.sub]".
 //
 // This format is consistent with [ParsePermissionString] and is suitable
 // for logging, debugging, and serialization.
 func (p Permission) String() string {
-	if p.Scope == "" || p.Scope == "*" {
-		return p.Resource + ":" + p.Action
+	s := p.Resource + ":" + p.Action
+	if p.Scope != "" && p.Scope != "*" {
+		s += ":" + p.Scope
+	}
+	if p.Condition != "" {
+		s += "[" + p.Condition + "]"
 	}
-	return p.Resource + ":" + p.Action + ":" + p.Scope
+	return s
 }
 
 // ServiceIdentity represents a platform service or agent authenticated via
@@ -491,12 +529,18 @@ type CallChain struct {
 	// the originator, and the last entry is the service that made the
 	// current call.
 	Callers []CallerInfo `json:"callers"`
+
+	// Truncated is the number of callers dropped from the chain to keep it
+	// within [MaxCallChainDepth]. The dropped callers were between
+	// Callers[0] and Callers[1].
+	Truncated int `json:"truncated,omitempty"`
 }
 
 // MaxCallChainDepth is the maximum number of callers tracked in a CallChain.
 // When a chain exceeds this depth, the oldest intermediate callers are
 // truncated to prevent unbounded growth that could exceed HTTP header size
-// limits or cause excessive memory usage.
+// limits or cause excessive memory usage. The first caller is kept, and
+// the number of dropped callers is recorded in [CallChain.Truncated].
 //
 // The value 32 supports realistic deep call chains while keeping the
 // serialized header well within HTTP/2's default SETTINGS_MAX_HEADER_LIST_SIZE
@@ -505,29 +549,43 @@ const MaxCallChainDepth = 32
 
 // Depth returns the number of services in the call chain, including the
 // current service. A direct call (no intermediaries) has depth 0.
+// Truncated callers are not counted; see [CallChain.Hops].
 func (c *CallChain) Depth() int {
 	return len(c.Callers)
 }
 
+// Hops returns the number of services the request has passed through,
+// including callers dropped by truncation.
+func (c *CallChain) Hops() int {
+	return len(c.Callers) + c.Truncated
+}
+
 // AppendCaller adds a new caller to the end of the call chain and returns
 // the updated chain. The original CallChain is not modified.
 //
 // If appending the caller would exceed [MaxCallChainDepth], the oldest
-// intermediate callers are dropped to make room while preserving the most
-// recent callers (which are most useful for debugging).
+// intermediate callers are dropped to make room while preserving the first
+// caller (the request's entry point, needed for audit) and the most recent
+// callers (which are most useful for debugging). The number of dropped
+// callers is added to Truncated.
 func (c *CallChain) AppendCaller(caller CallerInfo) *CallChain {
 	callers := make([]CallerInfo, len(c.Callers), len(c.Callers)+1)
 	copy(callers, c.Callers)
 	callers = append(callers, caller)
+	truncated := c.Truncated
 
-	// Truncate oldest callers if the chain exceeds the maximum depth.
+	// Truncate the oldest intermediate callers if the chain exceeds the
+	// maximum depth.
 	if len(callers) > MaxCallChainDepth {
-		callers = callers[len(callers)-MaxCallChainDepth:]
+		dropped := len(callers) - MaxCallChainDepth
+		callers = append(callers[:1], callers[1+dropped:]...)
+		truncated += dropped
 	}
 
 	return &CallChain{
 		OriginalID:   c.OriginalID,
 		OriginalType: c.OriginalType,
 		Callers:      callers,
+		Truncated:    truncated,
 	}
 }
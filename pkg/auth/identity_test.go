@@ -160,11 +160,17 @@ func TestCallChain_AppendCaller_TruncatesAtMaxDepth(t *testing.T) {
 	last := chain.Callers[MaxCallChainDepth-1]
 	assert.Equal(t, "svc-overflow", last.ServiceName)
 
-	// The oldest caller (svc-0) should have been dropped.
-	first := chain.Callers[0]
-	assert.NotEqual(t, "svc-0", first.ServiceName, "oldest caller svc-0 should have been truncated")
-	// The first entry should now be svc-1 (second-oldest from previous chain).
-	assert.Equal(t, "svc-1", first.ServiceName)
+	// The first caller (svc-0) is kept for audit; the oldest intermediate
+	// caller (svc-1) should have been dropped and counted.
+	assert.Equal(t, "svc-0", chain.Callers[0].ServiceName)
+	assert.Equal(t, "svc-2", chain.Callers[1].ServiceName)
+	assert.Equal(t, 1, chain.Truncated)
+	assert.Equal(t, MaxCallChainDepth+1, chain.Hops())
+
+	chain = chain.AppendCaller(CallerInfo{ServiceName: "svc-overflow-2"})
+	assert.Equal(t, "svc-0", chain.Callers[0].ServiceName)
+	assert.Equal(t, "svc-3", chain.Callers[1].ServiceName)
+	assert.Equal(t, 2, chain.Truncated)
 }
 
 func TestCallChain_MaxCallChainDepth_IsReasonable(t *testing.T) {
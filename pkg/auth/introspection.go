@@ -0,0 +1,341 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+const (
+	// DefaultIntrospectionCacheTTL is the default time an active
+	// introspection result is cached. It bounds how long a token revoked
+	// at the authorization server is still accepted.
+	DefaultIntrospectionCacheTTL = time.Minute
+
+	// DefaultIntrospectionCacheMaxSize is the default maximum number of
+	// cached introspection results.
+	DefaultIntrospectionCacheMaxSize = 10000
+)
+
+// ---------------------------------------------------------------------------
+// IntrospectionConfig — configuration for the introspection validator
+// ---------------------------------------------------------------------------
+
+// IntrospectionConfig configures an [IntrospectionValidator].
+type IntrospectionConfig struct {
+	// Endpoint is the authorization server's RFC 7662 token introspection
+	// endpoint (e.g., "https://idp.example.com/oauth2/introspect").
+	// Required.
+	Endpoint string `json:"endpoint" env:"AUTH_INTROSPECTION_ENDPOINT"`
+
+	// ClientID and ClientSecret are the credentials the validator
+	// authenticates to the endpoint with, using HTTP Basic authentication
+	// (client_secret_basic). ClientID is required.
+	ClientID     string `json:"client_id" env:"AUTH_INTROSPECTION_CLIENT_ID"`
+	ClientSecret Secret `json:"client_secret" env:"AUTH_INTROSPECTION_CLIENT_SECRET"`
+
+	// Issuer is the expected "iss" member of introspection responses. If
+	// empty, the issuer is not validated. This field is optional.
+	Issuer string `json:"issuer,omitempty" env:"AUTH_INTROSPECTION_ISSUER"`
+
+	// Audience must be among the "aud" members of introspection
+	// responses. If empty, the audience is not validated. This field is
+	// optional.
+	Audience string `json:"audience,omitempty" env:"AUTH_INTROSPECTION_AUDIENCE"`
+
+	// ClaimMapping names the response members identities are built from,
+	// as for OIDC tokens. Responses without an email but with a
+	// "client_id" member, such as those for client-credentials tokens,
+	// produce a [ServiceIdentity] named after the client unless
+	// ServiceName is set. If the subject member is empty, the client ID
+	// is used as the identity ID.
+	ClaimMapping OIDCClaimMapping `json:"claim_mapping,omitempty"`
+
+	// CacheTTL is the maximum time an active result is cached. The
+	// actual TTL of an entry is the minimum of this value and the token's
+	// remaining lifetime. Must be non-negative. Zero means
+	// [DefaultIntrospectionCacheTTL].
+	CacheTTL time.Duration `json:"cache_ttl" env:"AUTH_INTROSPECTION_CACHE_TTL"`
+
+	// CacheMaxSize is the maximum number of cached results. Must be
+	// non-negative. Zero means [DefaultIntrospectionCacheMaxSize].
+	CacheMaxSize int `json:"cache_max_size" env:"AUTH_INTROSPECTION_CACHE_MAX_SIZE"`
+
+	// PermissionMapper extracts permissions from the introspection
+	// response members. If nil, [DefaultClaimsToPermissions] is used,
+	// which reads the "scope" member.
+	PermissionMapper func(claims map[string]any) []Permission `json:"-"`
+
+	// HTTPClient is the HTTP client used to call the endpoint. If nil, a
+	// default [http.Client] with a 10-second timeout is used.
+	HTTPClient HTTPClient `json:"-"`
+}
+
+// Validate checks the configuration for logical correctness and returns
+// a *[sserr.Error] with code [sserr.CodeValidation] if any field is
+// invalid.
+func (c *IntrospectionConfig) Validate() *sserr.Error {
+	u, err := url.Parse(c.Endpoint)
+	if c.Endpoint == "" || err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return sserr.New(sserr.CodeValidation, "auth: introspection endpoint must be an absolute http(s) URL")
+	}
+	if c.ClientID == "" {
+		return sserr.New(sserr.CodeValidation, "auth: introspection client ID must not be empty")
+	}
+	if c.CacheTTL < 0 {
+		return sserr.New(sserr.CodeValidation, "auth: introspection cache TTL must be non-negative")
+	}
+	if c.CacheMaxSize < 0 {
+		return sserr.New(sserr.CodeValidation, "auth: introspection cache max size must be non-negative")
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// IntrospectionValidator — RFC 7662 opaque token validation
+// ---------------------------------------------------------------------------
+
+// IntrospectionValidator validates opaque access tokens by asking the
+// issuing authorization server's OAuth 2.0 token introspection endpoint
+// (RFC 7662). It implements the [TokenValidator] interface.
+//
+// Active results are cached, so a token revoked at the authorization
+// server is accepted until its cache entry expires. Use [Conditional]
+// to accept both JWTs, verified locally by [JWTValidator], and opaque
+// tokens:
+//
+//	validator := auth.Conditional(
+//	    auth.ValidatorRoute{Match: auth.MatchJWT(), Validator: jwtValidator},
+//	    auth.ValidatorRoute{Validator: introspectionValidator},
+//	)
+//
+// Every token sent to the validator is disclosed to the introspection
+// endpoint. Do not put it after validators of internal tokens in a
+// [FirstOf], which would pass it every internal token they reject, such
+// as an expired JWT; route those tokens away from it instead.
+//
+// IntrospectionValidator is safe for concurrent use by multiple
+// goroutines.
+type IntrospectionValidator struct {
+	endpoint     string
+	clientID     string
+	clientSecret Secret
+	issuer       string
+	audience     string
+	claims       OIDCClaimMapping
+	permMapper   func(claims map[string]any) []Permission
+	httpClient   HTTPClient
+	tracer       trace.Tracer
+	tokenCache   *tokenCache
+}
+
+// Compile-time assertion that IntrospectionValidator implements
+// TokenValidator.
+var _ TokenValidator = (*IntrospectionValidator)(nil)
+
+// NewIntrospectionValidator creates an IntrospectionValidator with the
+// given configuration. The configuration is validated before use; an
+// error is returned if the configuration is invalid.
+func NewIntrospectionValidator(cfg IntrospectionConfig) (*IntrospectionValidator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	permMapper := cfg.PermissionMapper
+	if permMapper == nil {
+		permMapper = DefaultClaimsToPermissions
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	ttl := cfg.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultIntrospectionCacheTTL
+	}
+	maxSize := cfg.CacheMaxSize
+	if maxSize == 0 {
+		maxSize = DefaultIntrospectionCacheMaxSize
+	}
+	claims := cfg.ClaimMapping.withDefaults()
+	if claims.ServiceName == "" {
+		claims.ServiceName = "client_id"
+	}
+
+	return &IntrospectionValidator{
+		endpoint:     cfg.Endpoint,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		issuer:       cfg.Issuer,
+		audience:     cfg.Audience,
+		claims:       claims,
+		permMapper:   permMapper,
+		httpClient:   httpClient,
+		tracer:       otel.Tracer(tracerName),
+		tokenCache:   newTokenCache(ttl, maxSize),
+	}, nil
+}
+
+// Validate introspects tokenStr and returns the Identity it represents.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeAuthenticationInvalid]
+// if the token is inactive or its issuer or audience do not match,
+// [sserr.CodeAuthenticationExpired] if it has expired, or
+// [sserr.CodeUnavailable] if the endpoint is unreachable.
+func (v *IntrospectionValidator) Validate(ctx context.Context, tokenStr string) (Identity, error) {
+	ctx, span := startSpan(ctx, v.tracer, "auth.Introspect")
+	defer span.End()
+
+	if tokenStr == "" {
+		err := sserr.New(sserr.CodeAuthenticationInvalid, "auth: token must not be empty")
+		finishSpan(span, err)
+		return nil, err
+	}
+	if len(tokenStr) > maxTokenSize {
+		err := sserr.New(sserr.CodeAuthenticationInvalid, "auth: token exceeds maximum size")
+		finishSpan(span, err)
+		return nil, err
+	}
+
+	hash := tokenHash(tokenStr)
+	if identity, ok := v.tokenCache.get(hash); ok {
+		span.SetAttributes(attribute.Bool("auth.cache_hit", true))
+		return identity, nil
+	}
+	span.SetAttributes(attribute.Bool("auth.cache_hit", false))
+
+	claims, err := v.introspect(ctx, tokenStr)
+	if err != nil {
+		finishSpan(span, err)
+		return nil, err
+	}
+	identity, exp, err := v.identity(claims)
+	if err != nil {
+		finishSpan(span, err)
+		return nil, err
+	}
+	v.tokenCache.put(hash, identity, exp)
+
+	span.SetAttributes(
+		attribute.String("auth.identity_id", identity.ID()),
+		attribute.String("auth.identity_type", string(identity.Type())),
+	)
+	return identity, nil
+}
+
+// introspect sends tokenStr to the introspection endpoint and returns
+// the members of the response.
+func (v *IntrospectionValidator) introspect(ctx context.Context, tokenStr string) (map[string]any, error) {
+	form := url.Values{
+		"token":           {tokenStr},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to create introspection request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// RFC 6749 section 2.3.1: client credentials are form-encoded before
+	// being used as the Basic authentication user and password.
+	req.SetBasicAuth(url.QueryEscape(v.clientID), url.QueryEscape(v.clientSecret.Value()))
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeUnavailable, "auth: introspection endpoint is unreachable")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return nil, sserr.Newf(sserr.CodeUnavailable, "auth: introspection endpoint returned status %d", resp.StatusCode)
+	default:
+		return nil, sserr.Newf(sserr.CodeInternal, "auth: introspection endpoint returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeUnavailable, "auth: failed to read introspection response")
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to parse introspection response")
+	}
+	return claims, nil
+}
+
+// identity checks the introspection response claims and builds the
+// identity it describes. It also returns the token's expiration time,
+// which is zero if the response has none.
+func (v *IntrospectionValidator) identity(claims map[string]any) (Identity, time.Time, error) {
+	// Anything but "active": true, including a missing member, means the
+	// token must be rejected.
+	if active, _ := claims["active"].(bool); !active {
+		return nil, time.Time{}, sserr.New(sserr.CodeAuthenticationInvalid, "auth: token is not active")
+	}
+	delete(claims, "active")
+
+	now := time.Now()
+	var exp time.Time
+	if n, ok := claims["exp"].(float64); ok {
+		exp = time.Unix(int64(n), 0)
+		if !now.Before(exp) {
+			return nil, time.Time{}, sserr.New(sserr.CodeAuthenticationExpired, "auth: token has expired")
+		}
+	}
+	if n, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(n), 0)) {
+		return nil, time.Time{}, sserr.New(sserr.CodeAuthenticationInvalid, "auth: token is not yet valid")
+	}
+	if v.issuer != "" && claimString(claims, "iss") != v.issuer {
+		return nil, time.Time{}, sserr.New(sserr.CodeAuthenticationInvalid, "auth: token issuer is invalid")
+	}
+	if v.audience != "" && !slices.Contains(claimStrings(claims, "aud"), v.audience) {
+		return nil, time.Time{}, sserr.New(sserr.CodeAuthenticationInvalid, "auth: token audience is invalid")
+	}
+
+	mapping := v.claims
+	if claimString(claims, mapping.Subject) == "" {
+		mapping.Subject = "client_id"
+	}
+	if claimString(claims, mapping.Subject) == "" {
+		return nil, time.Time{}, sserr.New(sserr.CodeAuthenticationInvalid, "auth: introspection response has no subject or client ID")
+	}
+
+	identity, err := claimsIdentity(claims, mapping, "", v.permMapper(claims), "introspected token")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return identity, exp, nil
+}
+
+// claimStrings returns the claim name of claims as a list of strings. A
+// single string is returned as a one-element list, as for the "aud"
+// claim of JWTs and introspection responses.
+func claimStrings(claims map[string]any, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// introspectionTestServer stands in for an RFC 7662 introspection
+// endpoint. It answers with the response registered for the token, or
+// with {"active": false}, and counts the requests it receives.
+type introspectionTestServer struct {
+	*httptest.Server
+	requests  atomic.Int32
+	status    atomic.Int32
+	responses map[string]map[string]any
+}
+
+// newIntrospectionTestServer starts an introspectionTestServer that
+// requires the client credentials "client-a" and "secret-a".
+func newIntrospectionTestServer(t *testing.T, responses map[string]map[string]any) *introspectionTestServer {
+	t.Helper()
+	s := &introspectionTestServer{responses: responses}
+	s.status.Store(http.StatusOK)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "client-a", id)
+		assert.Equal(t, "secret-a", secret)
+		assert.Equal(t, "access_token", r.PostFormValue("token_type_hint"))
+
+		w.WriteHeader(int(s.status.Load()))
+		resp, ok := s.responses[r.PostFormValue("token")]
+		if !ok {
+			resp = map[string]any{"active": false}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newIntrospectionTestValidator creates an IntrospectionValidator for
+// the endpoint of s, adjusted by configure if it is not nil.
+func newIntrospectionTestValidator(t *testing.T, s *introspectionTestServer, configure func(*IntrospectionConfig)) *IntrospectionValidator {
+	t.Helper()
+	cfg := IntrospectionConfig{
+		Endpoint:     s.URL + "/oauth2/introspect",
+		ClientID:     "client-a",
+		ClientSecret: Secret("secret-a"),
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+	v, err := NewIntrospectionValidator(cfg)
+	require.NoError(t, err)
+	return v
+}
+
+// ---------------------------------------------------------------------------
+// IntrospectionConfig
+// ---------------------------------------------------------------------------
+
+func TestIntrospectionConfig_Validate(t *testing.T) {
+	t.Parallel()
+	valid := IntrospectionConfig{Endpoint: "https://idp.example.com/introspect", ClientID: "client-a"}
+	assert.Nil(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(*IntrospectionConfig)
+	}{
+		{"empty endpoint", func(c *IntrospectionConfig) { c.Endpoint = "" }},
+		{"relative endpoint", func(c *IntrospectionConfig) { c.Endpoint = "/introspect" }},
+		{"non-http endpoint", func(c *IntrospectionConfig) { c.Endpoint = "ftp://idp.example.com" }},
+		{"empty client ID", func(c *IntrospectionConfig) { c.ClientID = "" }},
+		{"negative cache TTL", func(c *IntrospectionConfig) { c.CacheTTL = -time.Second }},
+		{"negative cache size", func(c *IntrospectionConfig) { c.CacheMaxSize = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := valid
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			require.NotNil(t, err)
+			assert.True(t, sserr.IsValidation(err))
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// IntrospectionValidator
+// ---------------------------------------------------------------------------
+
+func TestIntrospectionValidator_UserToken(t *testing.T) {
+	t.Parallel()
+	s := newIntrospectionTestServer(t, map[string]map[string]any{
+		"opaque-user": {
+			"active": true,
+			"sub":    "user-1",
+			"email":  "alice@example.com",
+			"name":   "Alice",
+			"scope":  "documents:read reports:write:production",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		},
+	})
+	v := newIntrospectionTestValidator(t, s, nil)
+
+	identity, err := v.Validate(context.Background(), "opaque-user")
+	require.NoError(t, err)
+	user, ok := identity.(*UserIdentity)
+	require.True(t, ok, "expected *UserIdentity, got %T", identity)
+	assert.Equal(t, "user-1", user.ID())
+	assert.Equal(t, "alice@example.com", user.Email())
+	assert.True(t, user.HasPermission("documents", "read"))
+	assert.Contains(t, user.Permissions(), Permission{Resource: "reports", Action: "write", Scope: "production"})
+	_, hasActive := user.Claims()["active"]
+	assert.False(t, hasActive, "active member should not be a claim")
+
+	// The second validation is served from the cache.
+	_, err = v.Validate(context.Background(), "opaque-user")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), s.requests.Load())
+}
+
+func TestIntrospectionValidator_ClientCredentialsToken(t *testing.T) {
+	t.Parallel()
+	s := newIntrospectionTestServer(t, map[string]map[string]any{
+		"opaque-client": {"active": true, "client_id": "billing", "scope": "invoices:read"},
+	})
+	v := newIntrospectionTestValidator(t, s, nil)
+
+	identity, err := v.Validate(context.Background(), "opaque-client")
+	require.NoError(t, err)
+	svc, ok := identity.(*ServiceIdentity)
+	require.True(t, ok, "expected *ServiceIdentity, got %T", identity)
+	assert.Equal(t, "billing", svc.ID())
+	assert.Equal(t, "billing", svc.ServiceName())
+	assert.True(t, svc.HasPermission("invoices", "read"))
+}
+
+func TestIntrospectionValidator_Rejects(t *testing.T) {
+	t.Parallel()
+	s := newIntrospectionTestServer(t, map[string]map[string]any{
+		"expired":      {"active": true, "sub": "user-1", "exp": time.Now().Add(-time.Minute).Unix()},
+		"not-yet":      {"active": true, "sub": "user-1", "nbf": time.Now().Add(time.Hour).Unix()},
+		"wrong-iss":    {"active": true, "sub": "user-1", "iss": "https://other.example.com", "aud": "api"},
+		"wrong-aud":    {"active": true, "sub": "user-1", "iss": "https://idp.example.com", "aud": []string{"other"}},
+		"no-subject":   {"active": true, "iss": "https://idp.example.com", "aud": "api"},
+		"active-false": {"active": false, "sub": "user-1"},
+		"active-str":   {"active": "true", "sub": "user-1"},
+	})
+	v := newIntrospectionTestValidator(t, s, func(c *IntrospectionConfig) {
+		c.Issuer = "https://idp.example.com"
+		c.Audience = "api"
+	})
+
+	tests := []struct {
+		token string
+		code  sserr.Code
+	}{
+		{"expired", sserr.CodeAuthenticationExpired},
+		{"not-yet", sserr.CodeAuthenticationInvalid},
+		{"wrong-iss", sserr.CodeAuthenticationInvalid},
+		{"wrong-aud", sserr.CodeAuthenticationInvalid},
+		{"no-subject", sserr.CodeAuthenticationInvalid},
+		{"active-false", sserr.CodeAuthenticationInvalid},
+		{"active-str", sserr.CodeAuthenticationInvalid},
+		{"unknown", sserr.CodeAuthenticationInvalid},
+		{"", sserr.CodeAuthenticationInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			t.Parallel()
+			_, err := v.Validate(context.Background(), tt.token)
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, tt.code), "got %v", err)
+		})
+	}
+}
+
+func TestIntrospectionValidator_InactiveNotCached(t *testing.T) {
+	t.Parallel()
+	s := newIntrospectionTestServer(t, nil)
+	v := newIntrospectionTestValidator(t, s, nil)
+
+	for range 2 {
+		_, err := v.Validate(context.Background(), "opaque")
+		require.Error(t, err)
+	}
+	assert.Equal(t, int32(2), s.requests.Load())
+}
+
+func TestIntrospectionValidator_EndpointErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		status int
+		code   sserr.Code
+	}{
+		{http.StatusServiceUnavailable, sserr.CodeUnavailable},
+		{http.StatusTooManyRequests, sserr.CodeUnavailable},
+		{http.StatusUnauthorized, sserr.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			t.Parallel()
+			s := newIntrospectionTestServer(t, nil)
+			s.status.Store(int32(tt.status))
+			v := newIntrospectionTestValidator(t, s, nil)
+
+			_, err := v.Validate(context.Background(), "opaque")
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, tt.code), "got %v", err)
+		})
+	}
+}
+
+func TestIntrospectionValidator_Unreachable(t *testing.T) {
+	t.Parallel()
+	s := newIntrospectionTestServer(t, nil)
+	v := newIntrospectionTestValidator(t, s, nil)
+	s.Close()
+
+	_, err := v.Validate(context.Background(), "opaque")
+	require.Error(t, err)
+	assert.True(t, sserr.IsUnavailable(err))
+}
+
+// ---------------------------------------------------------------------------
+// Combined with JWTValidator
+// ---------------------------------------------------------------------------
+
+func TestFirstOf_JWTAndIntrospection(t *testing.T) {
+	t.Parallel()
+	s := newIntrospectionTestServer(t, map[string]map[string]any{
+		"opaque-client": {"active": true, "client_id": "billing"},
+	})
+	jwtValidator, err := NewJWTValidator(newPlatformConfig())
+	require.NoError(t, err)
+	validator := FirstOf(jwtValidator, nil, newIntrospectionTestValidator(t, s, nil))
+
+	jwtToken := jwtTestGenerateHMACToken(t, []byte(testSigningKey), jwt.MapClaims{
+		"iss": "stricklysoft-platform",
+		"sub": "user-42",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	identity, err := validator.Validate(context.Background(), jwtToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", identity.ID())
+	assert.Equal(t, int32(0), s.requests.Load(), "JWT should not be introspected")
+
+	identity, err = validator.Validate(context.Background(), "opaque-client")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", identity.ID())
+
+	_, err = validator.Validate(context.Background(), "unknown")
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid))
+}
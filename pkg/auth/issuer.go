@@ -0,0 +1,433 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ---------------------------------------------------------------------------
+// IssuerConfig — configuration for the token issuer
+// ---------------------------------------------------------------------------
+
+// IssuerConfig holds the configuration for [TokenIssuer]. The signing key,
+// issuer, and audience must match the [ValidatorConfig] of the services
+// that validate the issued tokens.
+type IssuerConfig struct {
+	// SigningKey is the HMAC key used to sign tokens (HS256). Must be at
+	// least 32 bytes unless Keyring is set. This is the same key as
+	// [ValidatorConfig.PlatformSigningKey].
+	SigningKey Secret `json:"-" env:"AUTH_PLATFORM_SIGNING_KEY"`
+
+	// Keyring, if set, replaces SigningKey and KeyID: tokens are signed
+	// with [Keyring.CurrentKey] and carry its ID, and expire no later than
+	// the key does.
+	Keyring *Keyring `json:"-"`
+
+	// KeyID is written to the "kid" header of issued tokens so that
+	// validators can select the key during rotation. Optional.
+	KeyID string `json:"key_id,omitempty" env:"AUTH_PLATFORM_KEY_ID"`
+
+	// Issuer is the "iss" claim of issued tokens. Defaults to
+	// "stricklysoft-platform".
+	Issuer string `json:"issuer" env:"AUTH_PLATFORM_ISSUER" envDefault:"stricklysoft-platform"`
+
+	// Audience is the default "aud" claim of issued tokens. If empty,
+	// tokens carry no audience unless [WithTokenAudience] is used.
+	Audience string `json:"audience,omitempty" env:"AUTH_PLATFORM_AUDIENCE"`
+
+	// TTL is the default lifetime of issued tokens. Must be positive.
+	// Defaults to 15 minutes.
+	TTL time.Duration `json:"ttl" env:"AUTH_TOKEN_TTL" envDefault:"15m"`
+
+	// MaxTTL is the longest lifetime a caller may request with
+	// [WithTokenTTL]. Must be at least TTL. Defaults to 24 hours.
+	MaxTTL time.Duration `json:"max_ttl" env:"AUTH_TOKEN_MAX_TTL" envDefault:"24h"`
+}
+
+// Validate checks the configuration for logical correctness and returns
+// a *[sserr.Error] with code [sserr.CodeValidation] if any field is invalid.
+func (c *IssuerConfig) Validate() *sserr.Error {
+	if c.Keyring == nil && len(c.SigningKey.Value()) < 32 {
+		return sserr.New(sserr.CodeValidation, "auth: token signing key must be at least 32 bytes")
+	}
+	if c.Issuer == "" {
+		return sserr.New(sserr.CodeValidation, "auth: token issuer must not be empty")
+	}
+	if c.TTL <= 0 {
+		return sserr.New(sserr.CodeValidation, "auth: token TTL must be positive")
+	}
+	if c.MaxTTL < c.TTL {
+		return sserr.New(sserr.CodeValidation, "auth: token max TTL must be at least the TTL")
+	}
+	return nil
+}
+
+// DefaultIssuerConfig returns an IssuerConfig with the platform defaults.
+// SigningKey must still be set.
+func DefaultIssuerConfig() IssuerConfig {
+	return IssuerConfig{
+		Issuer: "stricklysoft-platform",
+		TTL:    15 * time.Minute,
+		MaxTTL: 24 * time.Hour,
+	}
+}
+
+// ---------------------------------------------------------------------------
+// TokenOption — per-token settings
+// ---------------------------------------------------------------------------
+
+// TokenOption configures a single token issued by [TokenIssuer.Issue] or
+// [TokenIssuer.Delegate].
+type TokenOption func(*tokenOptions)
+
+// tokenOptions holds the settings applied by [TokenOption] functions.
+type tokenOptions struct {
+	ttl      time.Duration
+	audience []string
+	claims   map[string]any
+}
+
+// WithTokenTTL sets the token lifetime, overriding [IssuerConfig.TTL]. It
+// must be positive and at most [IssuerConfig.MaxTTL].
+func WithTokenTTL(ttl time.Duration) TokenOption {
+	return func(o *tokenOptions) { o.ttl = ttl }
+}
+
+// WithTokenAudience sets the "aud" claim, overriding
+// [IssuerConfig.Audience].
+func WithTokenAudience(audience ...string) TokenOption {
+	return func(o *tokenOptions) { o.audience = append([]string(nil), audience...) }
+}
+
+// WithTokenClaims adds custom claims to the token. Claims reserved by the
+// issuer (see [TokenIssuer.Issue]) are rejected.
+func WithTokenClaims(claims map[string]any) TokenOption {
+	return func(o *tokenOptions) {
+		if o.claims == nil {
+			o.claims = make(map[string]any, len(claims))
+		}
+		for k, v := range claims {
+			o.claims[k] = v
+		}
+	}
+}
+
+// reservedClaims are set by the issuer and cannot be copied from the
+// identity or supplied with [WithTokenClaims]. The permission-bearing
+// claims are reserved so that the "permissions" claim is the only source
+// of grants in an issued token.
+var reservedClaims = map[string]struct{}{
+	"iss": {}, "sub": {}, "aud": {}, "exp": {}, "nbf": {}, "iat": {}, "jti": {},
+	"act": {}, "permissions": {}, "roles": {}, "scope": {},
+	"email": {}, "name": {}, "service_name": {}, "namespace": {},
+}
+
+// ---------------------------------------------------------------------------
+// TokenIssuer
+// ---------------------------------------------------------------------------
+
+// TokenIssuer mints platform tokens (HS256) that [JWTValidator] accepts
+// when platform validation is enabled with the same key and issuer.
+//
+// TokenIssuer is safe for concurrent use by multiple goroutines.
+type TokenIssuer struct {
+	config IssuerConfig
+	tracer trace.Tracer
+
+	// now returns the current time. Replaced in tests.
+	now func() time.Time
+}
+
+// NewTokenIssuer creates a new TokenIssuer with the given configuration.
+// The configuration is validated before use; an error is returned if the
+// configuration is invalid.
+func NewTokenIssuer(cfg IssuerConfig) (*TokenIssuer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &TokenIssuer{
+		config: cfg,
+		tracer: otel.Tracer(tracerName),
+		now:    time.Now,
+	}, nil
+}
+
+// Issue mints a token for identity carrying all of its permissions.
+//
+// The token sets "iss", "sub" (the identity ID), "aud", "iat", "nbf",
+// "exp", a random "jti", and "permissions" (see [PermissionsOf]). User
+// identities also carry "email" and "name", and service identities
+// "service_name" and "namespace", so that [JWTValidator] reconstructs an
+// identity of the same type. Other claims of the identity are copied
+// unless reserved. The "roles" and "scope" claims are always dropped so
+// the permissions cannot widen when the token is validated. An "act"
+// claim from [TokenIssuer.Delegate] is preserved.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if identity is
+// nil or an option is invalid, [sserr.CodeUnavailable] if the keyring has
+// no active key or its current key has expired, or [sserr.CodeInternal]
+// if signing fails.
+func (i *TokenIssuer) Issue(ctx context.Context, identity Identity, opts ...TokenOption) (string, error) {
+	_, span := startSpan(ctx, i.tracer, "auth.IssueToken")
+	defer span.End()
+
+	if identity == nil {
+		err := sserr.New(sserr.CodeValidation, "auth: identity must not be nil")
+		finishSpan(span, err)
+		return "", err
+	}
+
+	// Keep the delegation path of an identity that was itself delegated,
+	// so re-issuing cannot turn a delegated token into a direct one.
+	act, _ := identity.Claims()["act"].(map[string]any)
+	token, err := i.sign(identity, PermissionsOf(identity).Permissions(), act, time.Time{}, opts)
+	if err != nil {
+		finishSpan(span, err)
+		return "", err
+	}
+	span.SetAttributes(
+		attribute.String("auth.identity_id", identity.ID()),
+		attribute.String("auth.identity_type", string(identity.Type())),
+	)
+	return token, nil
+}
+
+// Delegate mints a downscoped token that lets actor act on behalf of
+// subject. The token is issued for subject, carries only permissions,
+// and records actor in an RFC 8693 "act" claim ({"sub": actor ID}). If
+// subject was itself obtained through delegation, its "act" claim is
+// nested inside the new one, so the full delegation path is preserved.
+//
+// Every permission must be held by subject: a global permission (scope ""
+// or "*") requires a global grant, and a scoped permission a grant for
+// that scope or a global one. The token never outlives subject's own
+// token when subject carries an "exp" claim.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if subject or
+// actor is nil, permissions is empty or invalid, an option is invalid,
+// or subject's token has expired; [sserr.CodeAuthorizationDenied] if
+// subject does not hold a permission; [sserr.CodeUnavailable] if the
+// keyring has no active key or its current key has expired; or
+// [sserr.CodeInternal] if signing fails.
+func (i *TokenIssuer) Delegate(ctx context.Context, subject, actor Identity, permissions []Permission, opts ...TokenOption) (string, error) {
+	_, span := startSpan(ctx, i.tracer, "auth.DelegateToken")
+	defer span.End()
+
+	token, err := i.delegate(subject, actor, permissions, opts)
+	if err != nil {
+		finishSpan(span, err)
+		return "", err
+	}
+	span.SetAttributes(
+		attribute.String("auth.identity_id", subject.ID()),
+		attribute.String("auth.actor_id", actor.ID()),
+		attribute.Int("auth.permission_count", len(permissions)),
+	)
+	return token, nil
+}
+
+// delegate implements [TokenIssuer.Delegate] without tracing.
+func (i *TokenIssuer) delegate(subject, actor Identity, permissions []Permission, opts []TokenOption) (string, error) {
+	if subject == nil || actor == nil {
+		return "", sserr.New(sserr.CodeValidation, "auth: delegation subject and actor must not be nil")
+	}
+	if len(permissions) == 0 {
+		return "", sserr.New(sserr.CodeValidation, "auth: delegation requires at least one permission")
+	}
+
+	held := PermissionsOf(subject)
+	for _, p := range permissions {
+		if _, err := ParsePermissionString(p.String()); err != nil {
+			return "", sserr.Wrapf(err, sserr.CodeValidation, "auth: invalid delegated permission %q", p.String())
+		}
+		var ok bool
+		if p.Scope == "" || p.Scope == "*" {
+			ok = held.matchGlobal(p.Resource, p.Action)
+		} else {
+			ok = held.Match(p.Resource, p.Action, p.Scope)
+		}
+		if !ok {
+			return "", sserr.Newf(sserr.CodeAuthorizationDenied,
+				"auth: cannot delegate permission %s not held by %s", p, subject.ID()).
+				WithDetails(map[string]any{"permission": p.String()})
+		}
+	}
+
+	claims := subject.Claims()
+	act := map[string]any{"sub": actor.ID()}
+	if prior, ok := claims["act"].(map[string]any); ok {
+		act["act"] = prior
+	}
+
+	var notAfter time.Time
+	if exp, ok := numericDate(claims["exp"]); ok {
+		notAfter = exp
+	}
+	return i.sign(subject, permissions, act, notAfter, opts)
+}
+
+// sign builds the claims for identity and signs them. If notAfter is
+// non-zero, the expiry is capped at it.
+func (i *TokenIssuer) sign(identity Identity, permissions []Permission, act map[string]any, notAfter time.Time, opts []TokenOption) (string, error) {
+	o := tokenOptions{ttl: i.config.TTL}
+	if i.config.Audience != "" {
+		o.audience = []string{i.config.Audience}
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ttl <= 0 || o.ttl > i.config.MaxTTL {
+		return "", sserr.Newf(sserr.CodeValidation, "auth: token TTL must be positive and at most %s", i.config.MaxTTL)
+	}
+	for k := range o.claims {
+		if _, reserved := reservedClaims[k]; reserved {
+			return "", sserr.Newf(sserr.CodeValidation, "auth: claim %q is reserved", k)
+		}
+	}
+
+	mc := jwt.MapClaims{}
+	for k, v := range identity.Claims() {
+		if _, reserved := reservedClaims[k]; !reserved {
+			mc[k] = v
+		}
+	}
+	for k, v := range o.claims {
+		mc[k] = v
+	}
+
+	switch id := identity.(type) {
+	case *UserIdentity:
+		mc["email"] = id.Email()
+		if id.DisplayName() != "" {
+			mc["name"] = id.DisplayName()
+		}
+	case *ServiceIdentity:
+		mc["service_name"] = id.ServiceName()
+		if id.Namespace() != "" {
+			mc["namespace"] = id.Namespace()
+		}
+	}
+
+	perms := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		perms = append(perms, p.String())
+	}
+	sort.Strings(perms)
+
+	key := SigningKey{ID: i.config.KeyID, Secret: i.config.SigningKey}
+	if i.config.Keyring != nil {
+		current, err := i.config.Keyring.CurrentKey()
+		if err != nil {
+			return "", err
+		}
+		key = current
+	}
+
+	now := i.now()
+	if !notAfter.IsZero() && !notAfter.After(now) {
+		return "", sserr.New(sserr.CodeValidation, "auth: delegation subject token has expired")
+	}
+	if !key.NotAfter.IsZero() && !key.NotAfter.After(now) {
+		return "", sserr.Newf(sserr.CodeUnavailable, "auth: platform signing key %q has expired", key.ID)
+	}
+	exp := now.Add(o.ttl)
+	for _, limit := range []time.Time{notAfter, key.NotAfter} {
+		if !limit.IsZero() && limit.Before(exp) {
+			exp = limit
+		}
+	}
+
+	jti, err := newTokenID()
+	if err != nil {
+		return "", sserr.Wrap(err, sserr.CodeInternal, "auth: failed to generate token ID")
+	}
+
+	mc["iss"] = i.config.Issuer
+	mc["sub"] = identity.ID()
+	mc["iat"] = jwt.NewNumericDate(now)
+	mc["nbf"] = jwt.NewNumericDate(now)
+	mc["exp"] = jwt.NewNumericDate(exp)
+	mc["jti"] = jti
+	mc["permissions"] = perms
+	switch len(o.audience) {
+	case 0:
+	case 1:
+		mc["aud"] = o.audience[0]
+	default:
+		mc["aud"] = o.audience
+	}
+	if act != nil {
+		mc["act"] = act
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mc)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	signed, err := token.SignedString([]byte(key.Secret.Value()))
+	if err != nil {
+		return "", sserr.Wrap(err, sserr.CodeInternal, "auth: failed to sign token")
+	}
+	return signed, nil
+}
+
+// DelegationActor returns the ID of the identity acting on behalf of
+// identity, read from the "act" claim of a token issued by
+// [TokenIssuer.Delegate]. Returns false if identity was not delegated.
+func DelegationActor(identity Identity) (string, bool) {
+	if identity == nil {
+		return "", false
+	}
+	act, ok := identity.Claims()["act"].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	sub, ok := act["sub"].(string)
+	return sub, ok && sub != ""
+}
+
+// newTokenID returns a random 128-bit token ID for the "jti" claim.
+func newTokenID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// numericDate converts a JWT NumericDate claim value as decoded from JSON
+// (or set directly by callers) into a time.
+func numericDate(v any) (time.Time, bool) {
+	switch n := v.(type) {
+	case float64:
+		return time.Unix(int64(n), 0), true
+	case int64:
+		return time.Unix(n, 0), true
+	case int:
+		return time.Unix(int64(n), 0), true
+	case json.Number:
+		f, err := n.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(int64(f), 0), true
+	case *jwt.NumericDate:
+		if n == nil {
+			return time.Time{}, false
+		}
+		return n.Time, true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// newTestIssuer returns a TokenIssuer using the platform test signing key.
+func newTestIssuer(t *testing.T) *TokenIssuer {
+	t.Helper()
+	cfg := DefaultIssuerConfig()
+	cfg.SigningKey = Secret(testSigningKey)
+	cfg.KeyID = "key-1"
+	issuer, err := NewTokenIssuer(cfg)
+	require.NoError(t, err)
+	return issuer
+}
+
+// newTestUser returns a user identity with global and scoped permissions.
+func newTestUser(t *testing.T) *UserIdentity {
+	t.Helper()
+	user, err := NewUserIdentity("usr-1", "alice@example.com", "Alice",
+		map[string]any{"tenant": "acme", "roles": []any{"admin"}},
+		[]Permission{
+			{Resource: "executions", Action: "read"},
+			{Resource: "executions", Action: "delete", Scope: "staging"},
+			{Resource: "agents", Action: "*"},
+		})
+	require.NoError(t, err)
+	return user
+}
+
+// parseIssued verifies the signature of a token issued with the test key
+// and returns its header and claims. Time-based claims are not checked,
+// since tests pin the issuer clock.
+func parseIssued(t *testing.T, token string) (map[string]any, jwt.MapClaims) {
+	t.Helper()
+	parsed, err := jwt.Parse(token, func(*jwt.Token) (any, error) { return []byte(testSigningKey), nil },
+		jwt.WithoutClaimsValidation())
+	require.NoError(t, err)
+	return parsed.Header, parsed.Claims.(jwt.MapClaims)
+}
+
+// ---------------------------------------------------------------------------
+// IssuerConfig
+// ---------------------------------------------------------------------------
+
+func TestIssuerConfig_Validate(t *testing.T) {
+	t.Parallel()
+	valid := DefaultIssuerConfig()
+	valid.SigningKey = Secret(testSigningKey)
+	require.Nil(t, valid.Validate())
+
+	tests := map[string]func(c *IssuerConfig){
+		"short key":     func(c *IssuerConfig) { c.SigningKey = "short" },
+		"empty issuer":  func(c *IssuerConfig) { c.Issuer = "" },
+		"zero TTL":      func(c *IssuerConfig) { c.TTL = 0 },
+		"max below TTL": func(c *IssuerConfig) { c.MaxTTL = time.Minute },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			cfg := valid
+			mutate(&cfg)
+			_, err := NewTokenIssuer(cfg)
+			assert.True(t, sserr.IsValidation(err), "error = %v", err)
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Issue
+// ---------------------------------------------------------------------------
+
+func TestTokenIssuer_Issue(t *testing.T) {
+	t.Parallel()
+	issuer := newTestIssuer(t)
+	now := time.Unix(1_700_000_000, 0)
+	issuer.now = func() time.Time { return now }
+
+	token, err := issuer.Issue(context.Background(), newTestUser(t),
+		WithTokenAudience("executions-api"),
+		WithTokenTTL(time.Hour),
+		WithTokenClaims(map[string]any{"session": "s-1"}),
+	)
+	require.NoError(t, err)
+
+	header, claims := parseIssued(t, token)
+	assert.Equal(t, "HS256", header["alg"])
+	assert.Equal(t, "key-1", header["kid"])
+	assert.Equal(t, "stricklysoft-platform", claims["iss"])
+	assert.Equal(t, "usr-1", claims["sub"])
+	assert.Equal(t, "executions-api", claims["aud"])
+	assert.Equal(t, float64(now.Unix()), claims["iat"])
+	assert.Equal(t, float64(now.Unix()), claims["nbf"])
+	assert.Equal(t, float64(now.Add(time.Hour).Unix()), claims["exp"])
+	assert.Len(t, claims["jti"], 32)
+	assert.Equal(t, "alice@example.com", claims["email"])
+	assert.Equal(t, "Alice", claims["name"])
+	assert.Equal(t, "acme", claims["tenant"])
+	assert.Equal(t, "s-1", claims["session"])
+	assert.NotContains(t, claims, "roles", "roles would widen the permissions on validation")
+	assert.Equal(t, []any{"agents:*", "executions:delete:staging", "executions:read"}, claims["permissions"])
+	assert.NotContains(t, claims, "act")
+}
+
+func TestTokenIssuer_UniqueTokenIDs(t *testing.T) {
+	t.Parallel()
+	issuer := newTestIssuer(t)
+	user := newTestUser(t)
+	a, err := issuer.Issue(context.Background(), user)
+	require.NoError(t, err)
+	b, err := issuer.Issue(context.Background(), user)
+	require.NoError(t, err)
+	_, ca := parseIssued(t, a)
+	_, cb := parseIssued(t, b)
+	assert.NotEqual(t, ca["jti"], cb["jti"])
+}
+
+func TestTokenIssuer_IssueInvalid(t *testing.T) {
+	t.Parallel()
+	issuer := newTestIssuer(t)
+	user := newTestUser(t)
+	ctx := context.Background()
+
+	_, err := issuer.Issue(ctx, nil)
+	assert.True(t, sserr.IsValidation(err))
+	_, err = issuer.Issue(ctx, user, WithTokenTTL(25*time.Hour))
+	assert.True(t, sserr.IsValidation(err))
+	_, err = issuer.Issue(ctx, user, WithTokenTTL(-time.Second))
+	assert.True(t, sserr.IsValidation(err))
+	_, err = issuer.Issue(ctx, user, WithTokenClaims(map[string]any{"permissions": []string{"*:*"}}))
+	assert.True(t, sserr.IsValidation(err))
+}
+
+func TestTokenIssuer_RoundTrip(t *testing.T) {
+	t.Parallel()
+	issuer := newTestIssuer(t)
+	validator, err := NewJWTValidator(newPlatformConfig())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	token, err := issuer.Issue(ctx, newTestUser(t))
+	require.NoError(t, err)
+	identity, err := validator.Validate(ctx, token)
+	require.NoError(t, err)
+	user, ok := identity.(*UserIdentity)
+	require.True(t, ok, "identity type = %T", identity)
+	assert.Equal(t, "usr-1", user.ID())
+	assert.Equal(t, "alice@example.com", user.Email())
+	assert.ElementsMatch(t, newTestUser(t).Permissions(), user.Permissions())
+
+	svc, err := NewServiceIdentity("svc-1", "orchestrator", "platform", nil,
+		[]Permission{{Resource: "executions", Action: "write"}})
+	require.NoError(t, err)
+	token, err = issuer.Issue(ctx, svc)
+	require.NoError(t, err)
+	identity, err = validator.Validate(ctx, token)
+	require.NoError(t, err)
+	gotSvc, ok := identity.(*ServiceIdentity)
+	require.True(t, ok, "identity type = %T", identity)
+	assert.Equal(t, "orchestrator", gotSvc.ServiceName())
+	assert.Equal(t, "platform", gotSvc.Namespace())
+	assert.True(t, gotSvc.HasPermission("executions", "write"))
+}
+
+// ---------------------------------------------------------------------------
+// Delegate
+// ---------------------------------------------------------------------------
+
+func TestTokenIssuer_Delegate(t *testing.T) {
+	t.Parallel()
+	issuer := newTestIssuer(t)
+	validator, err := NewJWTValidator(newPlatformConfig())
+	require.NoError(t, err)
+	agent, err := NewServiceIdentity("agent-7", "summarizer", "agents", nil, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	token, err := issuer.Delegate(ctx, newTestUser(t), agent, []Permission{
+		{Resource: "executions", Action: "read", Scope: "production"},
+		{Resource: "agents", Action: "run"},
+	})
+	require.NoError(t, err)
+
+	_, claims := parseIssued(t, token)
+	assert.Equal(t, "usr-1", claims["sub"])
+	assert.Equal(t, map[string]any{"sub": "agent-7"}, claims["act"])
+	assert.Equal(t, []any{"agents:run", "executions:read:production"}, claims["permissions"])
+
+	identity, err := validator.Validate(ctx, token)
+	require.NoError(t, err)
+	actor, ok := DelegationActor(identity)
+	require.True(t, ok)
+	assert.Equal(t, "agent-7", actor)
+	ps := PermissionsOf(identity)
+	assert.True(t, ps.Match("executions", "read", "production"))
+	assert.False(t, ps.Match("executions", "read", "staging"), "delegated token must be downscoped")
+	assert.False(t, ps.Match("agents", "delete", ""))
+
+	// Delegating a delegated identity nests the actors, and re-issuing
+	// keeps them.
+	other, err := NewServiceIdentity("svc-2", "runner", "agents", nil, nil)
+	require.NoError(t, err)
+	nested, err := issuer.Delegate(ctx, identity, other, []Permission{{Resource: "agents", Action: "run"}})
+	require.NoError(t, err)
+	_, claims = parseIssued(t, nested)
+	assert.Equal(t, map[string]any{"sub": "svc-2", "act": map[string]any{"sub": "agent-7"}}, claims["act"])
+
+	reissued, err := issuer.Issue(ctx, identity)
+	require.NoError(t, err)
+	_, claims = parseIssued(t, reissued)
+	assert.Equal(t, map[string]any{"sub": "agent-7"}, claims["act"])
+}
+
+func TestTokenIssuer_DelegateDenied(t *testing.T) {
+	t.Parallel()
+	issuer := newTestIssuer(t)
+	user := newTestUser(t)
+	agent := newTestIdentity()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		perm Permission
+	}{
+		{"not held", Permission{Resource: "executions", Action: "write"}},
+		{"scoped grant requested globally", Permission{Resource: "executions", Action: "delete"}},
+		{"wrong scope", Permission{Resource: "executions", Action: "delete", Scope: "production"}},
+		{"wider wildcard", Permission{Resource: "*", Action: "read"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := issuer.Delegate(ctx, user, agent, []Permission{tt.perm})
+			assert.True(t, sserr.HasCode(err, sserr.CodeAuthorizationDenied), "error = %v", err)
+		})
+	}
+
+	_, err := issuer.Delegate(ctx, user, agent, nil)
+	assert.True(t, sserr.IsValidation(err))
+	_, err = issuer.Delegate(ctx, user, nil, []Permission{{Resource: "agents", Action: "run"}})
+	assert.True(t, sserr.IsValidation(err))
+}
+
+func TestTokenIssuer_DelegateCappedBySubjectExpiry(t *testing.T) {
+	t.Parallel()
+	issuer := newTestIssuer(t)
+	now := time.Unix(1_700_000_000, 0)
+	issuer.now = func() time.Time { return now }
+	subjectExp := now.Add(5 * time.Minute)
+	subject, err := NewUserIdentity("usr-1", "alice@example.com", "", map[string]any{"exp": float64(subjectExp.Unix())},
+		[]Permission{{Resource: "agents", Action: "run"}})
+	require.NoError(t, err)
+	perms := []Permission{{Resource: "agents", Action: "run"}}
+
+	token, err := issuer.Delegate(context.Background(), subject, newTestIdentity(), perms)
+	require.NoError(t, err)
+	_, claims := parseIssued(t, token)
+	assert.Equal(t, float64(subjectExp.Unix()), claims["exp"])
+
+	issuer.now = func() time.Time { return subjectExp.Add(time.Second) }
+	_, err = issuer.Delegate(context.Background(), subject, newTestIdentity(), perms)
+	assert.True(t, sserr.IsValidation(err))
+}
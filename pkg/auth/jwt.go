@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math/big"
 	"net/http"
 	"strings"
@@ -23,6 +24,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 
 	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
 )
@@ -130,10 +132,16 @@ type ValidatorConfig struct {
 	EnableOIDC bool `json:"enable_oidc" env:"AUTH_ENABLE_OIDC" envDefault:"false"`
 
 	// PlatformSigningKey is the HMAC signing key used to verify platform
-	// tokens. Must be at least 32 bytes when EnablePlatform is true.
+	// tokens. Must be at least 32 bytes when EnablePlatform is true and
+	// PlatformKeyring is nil.
 	// The Secret type prevents accidental logging of the key value.
 	PlatformSigningKey Secret `json:"-" env:"AUTH_PLATFORM_SIGNING_KEY"`
 
+	// PlatformKeyring holds several platform signing keys selected by
+	// the token's "kid" header, for rotation without a hard cutover. If
+	// set, it replaces PlatformSigningKey. This field is optional.
+	PlatformKeyring *Keyring `json:"-"`
+
 	// PlatformIssuer is the expected "iss" claim in platform tokens.
 	// Tokens with a different issuer are rejected. Defaults to
 	// "stricklysoft-platform".
@@ -154,6 +162,18 @@ type ValidatorConfig struct {
 	// the audience claim is not validated. This field is optional.
 	OIDCAudience string `json:"oidc_audience,omitempty" env:"AUTH_OIDC_AUDIENCE"`
 
+	// OIDCIssuers configures additional OIDC providers, each with its own
+	// audience, algorithms, claim mapping, and permission mapper. Tokens
+	// are routed to the provider whose issuer URL matches their "iss"
+	// claim. OIDCIssuerURL, if set, is treated as one more issuer with
+	// the default settings. When EnableOIDC is true, at least one issuer
+	// must be configured.
+	//
+	// Unlike those of OIDCIssuerURL, identities from these issuers have
+	// IDs qualified by the issuer URL (see [OIDCIdentityID]), and hold
+	// permissions only from the issuer's own PermissionMapper.
+	OIDCIssuers []OIDCIssuerConfig `json:"oidc_issuers,omitempty"`
+
 	// KubernetesIssuer is the expected "iss" claim in Kubernetes
 	// ServiceAccount tokens. Defaults to
 	// "https://kubernetes.default.svc.cluster.local".
@@ -164,6 +184,27 @@ type ValidatorConfig struct {
 	// "https://kubernetes.default.svc.cluster.local".
 	KubernetesAudience string `json:"kubernetes_audience" env:"AUTH_KUBERNETES_AUDIENCE" envDefault:"https://kubernetes.default.svc.cluster.local"`
 
+	// KubernetesTokenReview controls whether Kubernetes tokens are
+	// verified with the API server's TokenReview API instead of locally
+	// via JWKS. TokenReview also rejects bound tokens whose pod has been
+	// deleted. If the API server is unreachable, tokens are verified via
+	// JWKS instead. The validator authenticates to the API server with
+	// the token at SATokenPath, whose ServiceAccount must be allowed to
+	// create tokenreviews. Defaults to false.
+	KubernetesTokenReview bool `json:"kubernetes_token_review" env:"AUTH_KUBERNETES_TOKEN_REVIEW" envDefault:"false"`
+
+	// KubernetesAPIServer is the base URL of the Kubernetes API server
+	// used for TokenReview requests. If empty, defaults to
+	// [DefaultKubernetesAPIServer] ("https://kubernetes.default.svc").
+	// HTTPClient must trust the API server's certificate.
+	KubernetesAPIServer string `json:"kubernetes_api_server,omitempty" env:"AUTH_KUBERNETES_API_SERVER" envDefault:"https://kubernetes.default.svc"`
+
+	// TokenReviewCacheTTL is the maximum time a TokenReview result is
+	// cached, and so how long a token may still be accepted after its pod
+	// is deleted. If zero, defaults to [DefaultTokenReviewCacheTTL]
+	// (1 minute). Must be non-negative.
+	TokenReviewCacheTTL time.Duration `json:"token_review_cache_ttl" env:"AUTH_TOKEN_REVIEW_CACHE_TTL" envDefault:"1m"`
+
 	// TokenCacheTTL is the maximum time a validated token identity is
 	// cached before re-validation is required. The actual cache TTL for
 	// a token is the minimum of this value and the token's remaining
@@ -181,6 +222,12 @@ type ValidatorConfig struct {
 	// Defaults to 1 hour.
 	JWKSCacheTTL time.Duration `json:"jwks_cache_ttl" env:"AUTH_JWKS_CACHE_TTL" envDefault:"1h"`
 
+	// JWKSMaxStale is how long past JWKSCacheTTL the last successfully
+	// fetched JWKS is still used while the provider cannot be reached,
+	// so that a provider outage does not reject valid tokens. If zero,
+	// defaults to [DefaultJWKSMaxStale] (24 hours). Must be non-negative.
+	JWKSMaxStale time.Duration `json:"jwks_max_stale" env:"AUTH_JWKS_MAX_STALE" envDefault:"24h"`
+
 	// ClockSkew is the maximum allowed clock difference between the
 	// validator and the token issuer. Tokens within this window of their
 	// expiration or not-before times are still considered valid. Must be
@@ -190,6 +237,7 @@ type ValidatorConfig struct {
 	// PermissionMapper is a function that extracts permissions from JWT
 	// claims. If nil, [DefaultClaimsToPermissions] is used. This allows
 	// custom permission extraction logic for non-standard claim formats.
+	// It is not used for the issuers of OIDCIssuers.
 	PermissionMapper func(claims map[string]any) []Permission `json:"-"`
 
 	// HTTPClient is the HTTP client used for fetching JWKS and OIDC
@@ -197,12 +245,31 @@ type ValidatorConfig struct {
 	// 10-second timeout is used.
 	HTTPClient HTTPClient `json:"-"`
 
+	// RevocationChecker, if set, is consulted for every token that is not
+	// already cached, after its signature and claims are verified. If it
+	// also implements [RevocationNotifier], revoked tokens are evicted
+	// from the token cache as revocations are reported. This field is
+	// optional.
+	RevocationChecker RevocationChecker `json:"-"`
+
+	// SharedTokenCache, if set, is a second-tier token cache shared with
+	// the other replicas of the service, such as [RedisTokenCache]. It is
+	// consulted when a token is not in the in-memory cache, and every
+	// validated identity is stored in it for the same TTL. Tokens found
+	// in it are still checked against RevocationChecker. If the shared
+	// cache fails, tokens are validated as if it were not set. This field
+	// is optional.
+	SharedTokenCache SharedTokenCache `json:"-"`
+
 	// SATokenPath is the filesystem path to the Kubernetes ServiceAccount
 	// token file. If empty, defaults to [DefaultSATokenPath]
 	// ("/var/run/secrets/kubernetes.io/serviceaccount/token").
 	SATokenPath string `json:"sa_token_path,omitempty" env:"AUTH_K8S_SA_TOKEN_PATH"`
 }
 
+// DefaultJWKSMaxStale is the default for [ValidatorConfig.JWKSMaxStale].
+const DefaultJWKSMaxStale = 24 * time.Hour
+
 // DefaultSATokenPath is the default filesystem path to the Kubernetes
 // ServiceAccount token, as mounted by the Kubernetes pod runtime.
 const DefaultSATokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
@@ -216,24 +283,27 @@ const maxTokenSize = 8192
 //
 // Validation rules:
 //   - At least one token type must be enabled
-//   - If EnablePlatform: PlatformSigningKey must be at least 32 bytes
-//   - If EnableOIDC: OIDCIssuerURL must not be empty
-//   - TokenCacheTTL, JWKSCacheTTL, and ClockSkew must be non-negative
+//   - If EnablePlatform without PlatformKeyring: PlatformSigningKey must
+//     be at least 32 bytes
+//   - If EnableOIDC: OIDCIssuerURL or OIDCIssuers must be set, issuer
+//     URLs must be unique, and only asymmetric algorithms are allowed
+//   - TokenCacheTTL, JWKSCacheTTL, JWKSMaxStale, TokenReviewCacheTTL, and
+//     ClockSkew must be non-negative
 //   - TokenCacheMaxSize must be greater than zero
 func (c *ValidatorConfig) Validate() *sserr.Error {
 	if !c.EnableKubernetes && !c.EnablePlatform && !c.EnableOIDC {
 		return sserr.New(sserr.CodeValidation, "auth: at least one token type must be enabled (kubernetes, platform, or oidc)")
 	}
 
-	if c.EnablePlatform {
+	if c.EnablePlatform && c.PlatformKeyring == nil {
 		if len(c.PlatformSigningKey.Value()) < 32 {
 			return sserr.New(sserr.CodeValidation, "auth: platform signing key must be at least 32 bytes")
 		}
 	}
 
 	if c.EnableOIDC {
-		if c.OIDCIssuerURL == "" {
-			return sserr.New(sserr.CodeValidation, "auth: OIDC issuer URL must not be empty when OIDC is enabled")
+		if err := c.validateOIDCIssuers(); err != nil {
+			return err
 		}
 	}
 
@@ -245,6 +315,14 @@ func (c *ValidatorConfig) Validate() *sserr.Error {
 		return sserr.New(sserr.CodeValidation, "auth: JWKS cache TTL must be non-negative")
 	}
 
+	if c.JWKSMaxStale < 0 {
+		return sserr.New(sserr.CodeValidation, "auth: JWKS max stale must be non-negative")
+	}
+
+	if c.TokenReviewCacheTTL < 0 {
+		return sserr.New(sserr.CodeValidation, "auth: token review cache TTL must be non-negative")
+	}
+
 	if c.ClockSkew < 0 {
 		return sserr.New(sserr.CodeValidation, "auth: clock skew must be non-negative")
 	}
@@ -262,17 +340,20 @@ func (c *ValidatorConfig) Validate() *sserr.Error {
 // enabled and configured.
 func DefaultValidatorConfig() ValidatorConfig {
 	return ValidatorConfig{
-		EnableKubernetes:   true,
-		EnablePlatform:     false,
-		EnableOIDC:         false,
-		PlatformIssuer:     "stricklysoft-platform",
-		KubernetesIssuer:   "https://kubernetes.default.svc.cluster.local",
-		KubernetesAudience: "https://kubernetes.default.svc.cluster.local",
-		TokenCacheTTL:      5 * time.Minute,
-		TokenCacheMaxSize:  10000,
-		JWKSCacheTTL:       1 * time.Hour,
-		ClockSkew:          30 * time.Second,
-		SATokenPath:        DefaultSATokenPath,
+		EnableKubernetes:    true,
+		EnablePlatform:      false,
+		EnableOIDC:          false,
+		PlatformIssuer:      "stricklysoft-platform",
+		KubernetesIssuer:    "https://kubernetes.default.svc.cluster.local",
+		KubernetesAudience:  "https://kubernetes.default.svc.cluster.local",
+		KubernetesAPIServer: DefaultKubernetesAPIServer,
+		TokenCacheTTL:       5 * time.Minute,
+		TokenCacheMaxSize:   10000,
+		JWKSCacheTTL:        1 * time.Hour,
+		JWKSMaxStale:        DefaultJWKSMaxStale,
+		TokenReviewCacheTTL: DefaultTokenReviewCacheTTL,
+		ClockSkew:           30 * time.Second,
+		SATokenPath:         DefaultSATokenPath,
 	}
 }
 
@@ -294,6 +375,10 @@ type tokenCache struct {
 	entries map[string]*tokenCacheEntry
 	maxSize int
 	ttl     time.Duration
+
+	// generation counts invalidate calls, so that an identity checked for
+	// revocation before a concurrent invalidation is not cached after it.
+	generation uint64
 }
 
 // newTokenCache creates a new token cache with the given TTL and maximum
@@ -330,6 +415,30 @@ func (c *tokenCache) get(tokenHash string) (Identity, bool) {
 func (c *tokenCache) put(tokenHash string, identity Identity, tokenExp time.Time) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.putLocked(tokenHash, identity, tokenExp)
+}
+
+// revocationGeneration returns the number of invalidate calls so far.
+func (c *tokenCache) revocationGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// putUnlessInvalidated is put, except that nothing is stored if
+// invalidate has been called since generation was read with
+// revocationGeneration, since the revocation may apply to identity.
+func (c *tokenCache) putUnlessInvalidated(tokenHash string, identity Identity, tokenExp time.Time, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	c.putLocked(tokenHash, identity, tokenExp)
+}
+
+// putLocked implements put. Caller must hold the write lock.
+func (c *tokenCache) putLocked(tokenHash string, identity Identity, tokenExp time.Time) {
 
 	// Calculate effective TTL: min(cache TTL, token remaining lifetime).
 	ttl := c.ttl
@@ -370,6 +479,19 @@ func (c *tokenCache) put(tokenHash string, identity Identity, tokenExp time.Time
 	}
 }
 
+// invalidate removes the entries of the tokens revoked by r, matching
+// against the claims of the cached identities.
+func (c *tokenCache) invalidate(r Revocation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for k, v := range c.entries {
+		if r.matches(revocationClaims(v.identity.Claims())) {
+			delete(c.entries, k)
+		}
+	}
+}
+
 // evictExpired removes all expired entries from the cache. This method
 // acquires the write lock and is safe for concurrent use.
 func (c *tokenCache) evictExpired() {
@@ -393,63 +515,103 @@ func (c *tokenCache) evictExpiredLocked() {
 // jwksCache — caches JWKS public keys for OIDC/Kubernetes validation
 // ---------------------------------------------------------------------------
 
-// jwksCacheEntry stores fetched JWKS keys and the time they were fetched.
+const (
+	// jwksRefreshAhead is the fraction of the JWKS cache TTL after which
+	// a cached JWKS in use is refreshed in the background, so that
+	// requests do not wait for the fetch when the TTL expires.
+	jwksRefreshAhead = 0.75
+
+	// jwksKidRefetchInterval is the minimum time between fetches of a
+	// fresh JWKS triggered by tokens with an unknown key ID, which
+	// attackers can mint at will.
+	jwksKidRefetchInterval = 30 * time.Second
+
+	// jwksRetryInterval is the minimum time between fetch attempts
+	// after a fetch fails, so that requests do not pile onto a provider
+	// that is down or wait for it to time out.
+	jwksRetryInterval = 5 * time.Second
+)
+
+// jwksCacheEntry stores the last successfully fetched JWKS keys for a URL
+// and the state of its fetches. Entries are replaced, never modified, so
+// a pointer read under the lock may be used after releasing it.
 type jwksCacheEntry struct {
 	keys      map[string]any // kid -> *rsa.PublicKey or *ecdsa.PublicKey
-	fetchedAt time.Time
+	fetchedAt time.Time      // zero if no fetch has succeeded
+
+	// retryAt is the earliest time a fetch is attempted after the last
+	// one failed. Zero if the last fetch succeeded.
+	retryAt time.Time
 }
 
 // jwksCache caches JSON Web Key Sets (JWKS) fetched from OIDC providers
-// and Kubernetes API servers. Keys are cached per JWKS URL and refreshed
-// after the configured TTL expires.
+// and Kubernetes API servers. Keys are cached per JWKS URL.
+//
+// A JWKS in use is refreshed in the background once it is older than
+// jwksRefreshAhead of the TTL, and synchronously once the TTL has
+// expired. Concurrent fetches of the same URL are merged into one. An
+// unknown key ID triggers a refetch, to pick up rotated keys, at most
+// every jwksKidRefetchInterval. While the provider cannot be reached,
+// the last fetched keys are used for up to maxStale past the TTL, and
+// fetches are retried at most every jwksRetryInterval.
 type jwksCache struct {
-	mu      sync.RWMutex
-	entries map[string]*jwksCacheEntry
-	ttl     time.Duration
-	client  HTTPClient
+	mu       sync.RWMutex
+	entries  map[string]*jwksCacheEntry
+	ttl      time.Duration
+	maxStale time.Duration
+	client   HTTPClient
+	fetches  singleflight.Group
+	now      func() time.Time
 }
 
-// newJWKSCache creates a new JWKS cache with the given TTL and HTTP client.
-func newJWKSCache(ttl time.Duration, client HTTPClient) *jwksCache {
+// newJWKSCache creates a new JWKS cache with the given TTL, maximum
+// staleness, and HTTP client.
+func newJWKSCache(ttl, maxStale time.Duration, client HTTPClient) *jwksCache {
 	return &jwksCache{
-		entries: make(map[string]*jwksCacheEntry),
-		ttl:     ttl,
-		client:  client,
+		entries:  make(map[string]*jwksCacheEntry),
+		ttl:      ttl,
+		maxStale: maxStale,
+		client:   client,
+		now:      time.Now,
 	}
 }
 
 // getKey retrieves a public key by key ID (kid) from the JWKS at the given
-// URL. If the JWKS is not cached or the cache has expired, the JWKS is
-// fetched from the URL. If the kid is not found in a cached JWKS, the
-// cache is refreshed (to handle key rotation). Returns the key on success,
-// or an error if the key cannot be found or the JWKS cannot be fetched.
+// URL, fetching the JWKS if it is not cached, has expired, or does not
+// contain kid (subject to the limits described on [jwksCache]). Returns
+// the key on success, or an error if the key cannot be found. If the
+// JWKS cannot be fetched and no usable cached key exists, the error is a
+// *[sserr.Error] with code [sserr.CodeUnavailable].
 func (c *jwksCache) getKey(ctx context.Context, jwksURL, kid string) (any, error) {
+	now := c.now()
 	c.mu.RLock()
-	entry, ok := c.entries[jwksURL]
-	if ok && time.Since(entry.fetchedAt) < c.ttl {
-		key, exists := entry.keys[kid]
-		c.mu.RUnlock()
-		if exists {
+	entry := c.entries[jwksURL]
+	c.mu.RUnlock()
+
+	if entry != nil {
+		age := now.Sub(entry.fetchedAt)
+		key, known := entry.keys[kid]
+		fresh := !entry.fetchedAt.IsZero() && age < c.ttl
+		if known && fresh {
+			if age >= time.Duration(float64(c.ttl)*jwksRefreshAhead) && !now.Before(entry.retryAt) {
+				c.refreshInBackground(ctx, jwksURL)
+			}
 			return key, nil
 		}
-		// Kid not found in cached JWKS — may be a key rotation; refetch.
-	} else {
-		c.mu.RUnlock()
-	}
 
-	// Fetch or re-fetch JWKS.
-	keys, err := c.fetchJWKS(ctx, jwksURL)
-	if err != nil {
-		return nil, fmt.Errorf("auth: failed to fetch JWKS from %s: %w", jwksURL, err)
+		// The JWKS must be fetched, unless a fetch failed recently or
+		// this is an unknown key ID in a JWKS fetched recently.
+		rateLimited := now.Before(entry.retryAt) ||
+			(!known && fresh && age < jwksKidRefetchInterval)
+		if rateLimited {
+			return c.cachedKey(entry, jwksURL, kid, now, nil)
+		}
 	}
 
-	c.mu.Lock()
-	c.entries[jwksURL] = &jwksCacheEntry{
-		keys:      keys,
-		fetchedAt: time.Now(),
+	keys, err := c.fetch(ctx, jwksURL)
+	if err != nil {
+		return c.cachedKey(entry, jwksURL, kid, now, err)
 	}
-	c.mu.Unlock()
-
 	key, exists := keys[kid]
 	if !exists {
 		return nil, fmt.Errorf("auth: key ID %q not found in JWKS from %s", kid, jwksURL)
@@ -457,6 +619,76 @@ func (c *jwksCache) getKey(ctx context.Context, jwksURL, kid string) (any, error
 	return key, nil
 }
 
+// cachedKey returns kid from the cached entry for jwksURL, which may be
+// nil, if the entry is no more than maxStale past its TTL. fetchErr is
+// the error of the fetch that was just attempted, or nil if none was.
+func (c *jwksCache) cachedKey(entry *jwksCacheEntry, jwksURL, kid string, now time.Time, fetchErr error) (any, error) {
+	usable := entry != nil && !entry.fetchedAt.IsZero() && now.Sub(entry.fetchedAt) < c.ttl+c.maxStale
+	if usable {
+		if key, ok := entry.keys[kid]; ok {
+			return key, nil
+		}
+		if fetchErr == nil {
+			return nil, fmt.Errorf("auth: key ID %q not found in JWKS from %s", kid, jwksURL)
+		}
+	}
+	if fetchErr == nil {
+		fetchErr = errors.New("auth: last JWKS fetch failed")
+	}
+	return nil, sserr.Wrapf(fetchErr, sserr.CodeUnavailable, "auth: failed to fetch JWKS from %s", jwksURL)
+}
+
+// refreshInBackground fetches the JWKS at jwksURL without waiting for
+// the result. The fetch outlives ctx, keeping only its values.
+func (c *jwksCache) refreshInBackground(ctx context.Context, jwksURL string) {
+	c.fetches.DoChan(jwksURL, func() (any, error) {
+		return c.fetchAndStore(context.WithoutCancel(ctx), jwksURL)
+	})
+}
+
+// fetch fetches the JWKS at jwksURL and stores it in the cache, sharing
+// the fetch with concurrent callers for the same URL. It returns early
+// with ctx's error if ctx is done first; the fetch itself continues.
+func (c *jwksCache) fetch(ctx context.Context, jwksURL string) (map[string]any, error) {
+	ch := c.fetches.DoChan(jwksURL, func() (any, error) {
+		return c.fetchAndStore(context.WithoutCancel(ctx), jwksURL)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(map[string]any), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchAndStore fetches the JWKS at jwksURL and records the result in
+// the cache. A failed fetch keeps the previously fetched keys.
+func (c *jwksCache) fetchAndStore(ctx context.Context, jwksURL string) (any, error) {
+	keys, err := c.fetchJWKS(ctx, jwksURL)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		entry := &jwksCacheEntry{retryAt: now.Add(jwksRetryInterval)}
+		if prev := c.entries[jwksURL]; prev != nil && !prev.fetchedAt.IsZero() {
+			entry.keys, entry.fetchedAt = prev.keys, prev.fetchedAt
+			slog.WarnContext(ctx, "auth: failed to refresh JWKS, using cached keys",
+				"error", err,
+				"jwks_url", jwksURL,
+				"age", now.Sub(prev.fetchedAt).String(),
+			)
+		}
+		c.entries[jwksURL] = entry
+		return nil, err
+	}
+	c.entries[jwksURL] = &jwksCacheEntry{keys: keys, fetchedAt: now}
+	return keys, nil
+}
+
 // jwksResponse represents the JSON structure of a JWKS endpoint response.
 type jwksResponse struct {
 	Keys []jwkKey `json:"keys"`
@@ -605,11 +837,14 @@ type JWTValidator struct {
 	permMapper func(claims map[string]any) []Permission
 	httpClient HTTPClient
 
-	// oidcJWKSURL caches the JWKS URL discovered from the OIDC provider's
+	// oidcProviders holds the configured OIDC issuers keyed by issuer
+	// URL, each caching the JWKS URL discovered from its
 	// .well-known/openid-configuration endpoint.
-	oidcJWKSURL    string
-	oidcJWKSMu     sync.Mutex
-	oidcDiscovered bool
+	oidcProviders map[string]*oidcProvider
+
+	// tokenReviewer verifies Kubernetes tokens with the TokenReview API.
+	// Nil unless KubernetesTokenReview is enabled.
+	tokenReviewer *tokenReviewer
 }
 
 // Compile-time assertion that JWTValidator implements TokenValidator.
@@ -623,6 +858,13 @@ var _ TokenValidator = (*JWTValidator)(nil)
 // If cfg.HTTPClient is nil, a default [http.Client] with a 10-second
 // timeout is used.
 // If cfg.SATokenPath is empty, [DefaultSATokenPath] is used.
+// If cfg.KubernetesAPIServer is empty, [DefaultKubernetesAPIServer] is
+// used; if cfg.TokenReviewCacheTTL is zero, [DefaultTokenReviewCacheTTL]
+// is used.
+// If cfg.JWKSMaxStale is zero, [DefaultJWKSMaxStale] is used.
+// If cfg.RevocationChecker implements [RevocationNotifier], the validator
+// registers with it to evict revoked tokens from its cache; the
+// registration lasts as long as the checker.
 func NewJWTValidator(cfg ValidatorConfig) (*JWTValidator, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -641,15 +883,32 @@ func NewJWTValidator(cfg ValidatorConfig) (*JWTValidator, error) {
 	if cfg.SATokenPath == "" {
 		cfg.SATokenPath = DefaultSATokenPath
 	}
+	if cfg.KubernetesAPIServer == "" {
+		cfg.KubernetesAPIServer = DefaultKubernetesAPIServer
+	}
+	if cfg.TokenReviewCacheTTL == 0 {
+		cfg.TokenReviewCacheTTL = DefaultTokenReviewCacheTTL
+	}
+	if cfg.JWKSMaxStale == 0 {
+		cfg.JWKSMaxStale = DefaultJWKSMaxStale
+	}
 
-	return &JWTValidator{
-		config:     cfg,
-		tracer:     otel.Tracer(tracerName),
-		tokenCache: newTokenCache(cfg.TokenCacheTTL, cfg.TokenCacheMaxSize),
-		jwksCache:  newJWKSCache(cfg.JWKSCacheTTL, httpClient),
-		permMapper: permMapper,
-		httpClient: httpClient,
-	}, nil
+	v := &JWTValidator{
+		config:        cfg,
+		tracer:        otel.Tracer(tracerName),
+		tokenCache:    newTokenCache(cfg.TokenCacheTTL, cfg.TokenCacheMaxSize),
+		jwksCache:     newJWKSCache(cfg.JWKSCacheTTL, cfg.JWKSMaxStale, httpClient),
+		permMapper:    permMapper,
+		httpClient:    httpClient,
+		oidcProviders: newOIDCProviders(&cfg, permMapper),
+	}
+	if cfg.EnableKubernetes && cfg.KubernetesTokenReview {
+		v.tokenReviewer = newTokenReviewer(&cfg, httpClient)
+	}
+	if notifier, ok := cfg.RevocationChecker.(RevocationNotifier); ok {
+		notifier.OnRevoke(v.tokenCache.invalidate)
+	}
+	return v, nil
 }
 
 // ---------------------------------------------------------------------------
@@ -662,12 +921,14 @@ func NewJWTValidator(cfg ValidatorConfig) (*JWTValidator, error) {
 //
 // The method performs the following steps:
 //  1. Rejects empty or oversized tokens
-//  2. Checks the in-memory token cache
+//  2. Checks the in-memory token cache, then the configured
+//     [SharedTokenCache], if any
 //  3. Parses the token without verification to inspect claims
 //  4. Detects the token type from the issuer claim
 //  5. Routes to the appropriate verification path
-//  6. Caches the validated identity
-//  7. Records OpenTelemetry span attributes and errors
+//  6. Checks the configured [RevocationChecker], if any
+//  7. Caches the validated identity in both caches
+//  8. Records OpenTelemetry span attributes and errors
 //
 // Returns a *[sserr.Error] with the appropriate error code on failure.
 func (v *JWTValidator) Validate(ctx context.Context, tokenStr string) (Identity, error) {
@@ -698,6 +959,21 @@ func (v *JWTValidator) Validate(ctx context.Context, tokenStr string) (Identity,
 	}
 	span.SetAttributes(attribute.Bool("auth.cache_hit", false))
 
+	// Check the shared token cache.
+	cached, err := v.sharedCacheGet(ctx, hash)
+	if err != nil {
+		finishSpan(span, err)
+		return nil, err
+	}
+	if cached != nil {
+		span.SetAttributes(
+			attribute.Bool("auth.shared_cache_hit", true),
+			attribute.String("auth.identity_id", cached.ID()),
+			attribute.String("auth.identity_type", string(cached.Type())),
+		)
+		return cached, nil
+	}
+
 	// Parse token without verification to inspect header and claims.
 	parser := jwt.NewParser()
 	unverified, parts, err := parser.ParseUnverified(tokenStr, jwt.MapClaims{})
@@ -737,7 +1013,7 @@ func (v *JWTValidator) Validate(ctx context.Context, tokenStr string) (Identity,
 	case TokenTypePlatform:
 		identity, validationErr = v.validatePlatformToken(ctx, tokenStr)
 	case TokenTypeOIDC:
-		identity, validationErr = v.validateOIDCToken(ctx, tokenStr)
+		identity, validationErr = v.validateOIDCToken(ctx, tokenStr, issuer)
 	case TokenTypeKubernetes:
 		identity, validationErr = v.validateKubernetesToken(ctx, tokenStr)
 	default:
@@ -750,9 +1026,21 @@ func (v *JWTValidator) Validate(ctx context.Context, tokenStr string) (Identity,
 		return nil, classifiedErr
 	}
 
-	// Cache the validated identity using the token's exp claim.
+	generation := v.tokenCache.revocationGeneration()
+	if err := v.checkRevocation(ctx, mc); err != nil {
+		finishSpan(span, err)
+		return nil, err
+	}
+
+	// Cache the validated identity using the token's exp claim. Reviewed
+	// Kubernetes tokens are cached no longer than the review cache TTL.
 	if exp, expErr := mc.GetExpirationTime(); expErr == nil && exp != nil {
-		v.tokenCache.put(hash, identity, exp.Time)
+		cacheUntil := exp.Time
+		if tokenType == TokenTypeKubernetes && v.tokenReviewer != nil {
+			cacheUntil = v.tokenReviewer.cacheUntil(cacheUntil)
+		}
+		v.tokenCache.putUnlessInvalidated(hash, identity, cacheUntil, generation)
+		v.sharedCachePut(ctx, hash, identity, cacheUntil)
 	}
 
 	// Set span attributes for successful validation.
@@ -764,6 +1052,70 @@ func (v *JWTValidator) Validate(ctx context.Context, tokenStr string) (Identity,
 	return identity, nil
 }
 
+// sharedCacheGet returns the identity cached for the token with the
+// given hash in the configured [SharedTokenCache], or nil if there is
+// none. A hit is checked for revocation, like a validated token, and
+// copied to the in-memory cache. A failing shared cache is logged and
+// treated as a miss.
+func (v *JWTValidator) sharedCacheGet(ctx context.Context, hash string) (Identity, error) {
+	if v.config.SharedTokenCache == nil {
+		return nil, nil
+	}
+	identity, expiresAt, err := v.config.SharedTokenCache.Get(ctx, hash)
+	if err != nil {
+		slog.WarnContext(ctx, "auth: shared token cache lookup failed, validating token",
+			"error", err,
+		)
+		return nil, nil
+	}
+	if identity == nil {
+		return nil, nil
+	}
+
+	generation := v.tokenCache.revocationGeneration()
+	if err := v.checkRevocation(ctx, jwt.MapClaims(identity.Claims())); err != nil {
+		return nil, err
+	}
+	v.tokenCache.putUnlessInvalidated(hash, identity, expiresAt, generation)
+	return identity, nil
+}
+
+// sharedCachePut stores identity in the configured [SharedTokenCache]
+// until the earlier of cacheUntil and the end of TokenCacheTTL. Failures
+// are logged, since the token is valid regardless.
+func (v *JWTValidator) sharedCachePut(ctx context.Context, hash string, identity Identity, cacheUntil time.Time) {
+	if v.config.SharedTokenCache == nil || v.config.TokenCacheTTL <= 0 {
+		return
+	}
+	if limit := time.Now().Add(v.config.TokenCacheTTL); limit.Before(cacheUntil) {
+		cacheUntil = limit
+	}
+	if err := v.config.SharedTokenCache.Put(ctx, hash, identity, cacheUntil); err != nil {
+		slog.WarnContext(ctx, "auth: failed to store token in shared cache",
+			"error", err,
+		)
+	}
+}
+
+// checkRevocation returns an error if the configured [RevocationChecker]
+// reports the token with claims as revoked. The check fails closed: if
+// the checker is unreachable the token is rejected with
+// [sserr.CodeUnavailable].
+func (v *JWTValidator) checkRevocation(ctx context.Context, claims jwt.MapClaims) *sserr.Error {
+	if v.config.RevocationChecker == nil {
+		return nil
+	}
+	tokenID, subject, issuedAt := revocationClaims(claims)
+	revoked, err := v.config.RevocationChecker.IsRevoked(ctx, tokenID, subject, issuedAt)
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeUnavailable, "auth: token revocation check failed")
+	}
+	if revoked {
+		return sserr.New(sserr.CodeAuthenticationInvalid, "auth: token has been revoked")
+	}
+	return nil
+}
+
 // detectTokenType determines which validation path to use based on the
 // token's issuer claim and signing algorithm.
 func (v *JWTValidator) detectTokenType(issuer, alg string) TokenType {
@@ -774,7 +1126,7 @@ func (v *JWTValidator) detectTokenType(issuer, alg string) TokenType {
 	if v.config.EnablePlatform && issuer == v.config.PlatformIssuer {
 		return TokenTypePlatform
 	}
-	if v.config.EnableOIDC && issuer == v.config.OIDCIssuerURL {
+	if v.config.EnableOIDC && v.config.hasOIDCIssuer(issuer) {
 		return TokenTypeOIDC
 	}
 
@@ -799,14 +1151,15 @@ func (v *JWTValidator) detectTokenType(issuer, alg string) TokenType {
 // ---------------------------------------------------------------------------
 
 // validatePlatformToken verifies a platform-issued JWT signed with HS256.
-// The token's signature is verified using the configured PlatformSigningKey.
+// The token's signature is verified using the configured PlatformKeyring,
+// or PlatformSigningKey if no keyring is set.
 //
 // CRITICAL: jwt.WithValidMethods restricts accepted algorithms to HS256 only,
 // preventing algorithm confusion attacks where an attacker could present an
 // RSA-signed token and trick the validator into using the public key as an
 // HMAC secret.
 func (v *JWTValidator) validatePlatformToken(ctx context.Context, tokenStr string) (Identity, error) {
-	_, span := startSpan(ctx, v.tracer, "auth.ValidatePlatformToken")
+	ctx, span := startSpan(ctx, v.tracer, "auth.ValidatePlatformToken")
 	defer span.End()
 
 	parserOpts := []jwt.ParserOption{
@@ -818,9 +1171,28 @@ func (v *JWTValidator) validatePlatformToken(ctx context.Context, tokenStr strin
 		parserOpts = append(parserOpts, jwt.WithAudience(v.config.PlatformAudience))
 	}
 
+	keyring := v.config.PlatformKeyring
+	var kid, result string
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return []byte(v.config.PlatformSigningKey.Value()), nil
+		if keyring == nil {
+			return []byte(v.config.PlatformSigningKey.Value()), nil
+		}
+		var key []byte
+		var err error
+		key, kid, result, err = keyring.verificationKey(token)
+		return key, err
 	}, parserOpts...)
+	if keyring != nil {
+		switch {
+		case result != "":
+		case err != nil:
+			result = "rejected"
+		default:
+			result = "verified"
+		}
+		keyring.recordVerification(ctx, kid, result)
+		span.SetAttributes(attribute.String("auth.kid", kid))
+	}
 	if err != nil {
 		finishSpan(span, err)
 		return nil, err
@@ -868,15 +1240,25 @@ func (v *JWTValidator) validatePlatformToken(ctx context.Context, tokenStr strin
 // validateOIDCToken — RSA/ECDSA token validation via OIDC discovery
 // ---------------------------------------------------------------------------
 
-// validateOIDCToken verifies a JWT issued by an external OIDC provider.
-// The token's signature is verified using public keys from the provider's
-// JWKS endpoint, discovered via the .well-known/openid-configuration document.
-func (v *JWTValidator) validateOIDCToken(ctx context.Context, tokenStr string) (Identity, error) {
+// validateOIDCToken verifies a JWT issued by the external OIDC provider
+// configured for issuer. The token's signature is verified using public
+// keys from the provider's JWKS endpoint, discovered via the
+// .well-known/openid-configuration document, and the identity is built
+// with the provider's claim mapping and permission mapper.
+func (v *JWTValidator) validateOIDCToken(ctx context.Context, tokenStr, issuer string) (Identity, error) {
 	_, span := startSpan(ctx, v.tracer, "auth.ValidateOIDCToken")
 	defer span.End()
+	span.SetAttributes(attribute.String("auth.issuer", issuer))
+
+	provider, ok := v.oidcProviders[issuer]
+	if !ok {
+		err := sserr.New(sserr.CodeAuthenticationInvalid, "auth: token issuer is not a configured OIDC issuer")
+		finishSpan(span, err)
+		return nil, err
+	}
 
 	// Discover JWKS URL from OIDC provider.
-	jwksURL, err := v.getOIDCJWKSURL(ctx)
+	jwksURL, err := provider.discoverJWKSURL(ctx, v.httpClient)
 	if err != nil {
 		wrappedErr := sserr.Wrap(err, sserr.CodeAuthentication, "auth: OIDC discovery failed")
 		finishSpan(span, wrappedErr)
@@ -884,12 +1266,12 @@ func (v *JWTValidator) validateOIDCToken(ctx context.Context, tokenStr string) (
 	}
 
 	parserOpts := []jwt.ParserOption{
-		jwt.WithValidMethods([]string{"RS256", "ES256"}),
-		jwt.WithIssuer(v.config.OIDCIssuerURL),
+		jwt.WithValidMethods(provider.algorithms),
+		jwt.WithIssuer(provider.issuerURL),
 		jwt.WithLeeway(v.config.ClockSkew),
 	}
-	if v.config.OIDCAudience != "" {
-		parserOpts = append(parserOpts, jwt.WithAudience(v.config.OIDCAudience))
+	if provider.audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(provider.audience))
 	}
 
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
@@ -911,44 +1293,12 @@ func (v *JWTValidator) validateOIDCToken(ctx context.Context, tokenStr string) (
 		return nil, err
 	}
 
-	claims := mapClaimsToMap(mc)
-	permissions := v.permMapper(claims)
-	sub, _ := claims["sub"].(string)
-	email, _ := claims["email"].(string)
-	name, _ := claims["name"].(string)
-
-	if email != "" {
-		identity, err := NewUserIdentity(sub, email, name, claims, permissions)
-		if err != nil {
-			wrappedErr := sserr.Wrap(err, sserr.CodeAuthenticationInvalid, "auth: failed to create user identity from OIDC token")
-			finishSpan(span, wrappedErr)
-			return nil, wrappedErr
-		}
-		return identity, nil
-	}
-
-	// Fallback for OIDC tokens without email.
-	return NewBasicIdentity(sub, IdentityTypeUser, claims), nil
-}
-
-// getOIDCJWKSURL returns the cached OIDC JWKS URL or fetches it from the
-// provider's .well-known/openid-configuration endpoint.
-func (v *JWTValidator) getOIDCJWKSURL(ctx context.Context) (string, error) {
-	v.oidcJWKSMu.Lock()
-	defer v.oidcJWKSMu.Unlock()
-
-	if v.oidcDiscovered && v.oidcJWKSURL != "" {
-		return v.oidcJWKSURL, nil
-	}
-
-	discovery, err := fetchOIDCDiscovery(ctx, v.config.OIDCIssuerURL, v.httpClient)
+	identity, err := provider.identity(mapClaimsToMap(mc))
 	if err != nil {
-		return "", err
+		finishSpan(span, err)
+		return nil, err
 	}
-
-	v.oidcJWKSURL = discovery.JWKSURI
-	v.oidcDiscovered = true
-	return v.oidcJWKSURL, nil
+	return identity, nil
 }
 
 // ---------------------------------------------------------------------------
@@ -960,14 +1310,29 @@ func (v *JWTValidator) getOIDCJWKSURL(ctx context.Context) (string, error) {
 // Kubernetes OIDC discovery endpoint to fetch JWKS. Kubernetes-specific
 // claims (namespace, service account name) are extracted using
 // parseK8sServiceAccountClaims from k8s.go.
+//
+// If KubernetesTokenReview is enabled, the token is verified with the
+// TokenReview API instead, falling back to JWKS only if the API server
+// is unreachable.
 func (v *JWTValidator) validateKubernetesToken(ctx context.Context, tokenStr string) (Identity, error) {
-	_, span := startSpan(ctx, v.tracer, "auth.ValidateKubernetesToken")
+	ctx, span := startSpan(ctx, v.tracer, "auth.ValidateKubernetesToken")
 	var retErr error
 	defer func() {
 		finishSpan(span, retErr)
 		span.End()
 	}()
 
+	if v.tokenReviewer != nil {
+		identity, err := v.tokenReviewer.validate(ctx, tokenStr, v.permMapper)
+		if !sserr.IsUnavailable(err) {
+			retErr = err
+			return identity, err
+		}
+		span.AddEvent("auth.token_review_fallback", trace.WithAttributes(
+			attribute.String("auth.token_review_error", err.Error()),
+		))
+	}
+
 	// Kubernetes exposes OIDC discovery at the issuer URL.
 	jwksURL := strings.TrimRight(v.config.KubernetesIssuer, "/") + "/openid/v1/jwks"
 
@@ -15,6 +15,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -292,6 +294,9 @@ func TestDefaultValidatorConfig(t *testing.T) {
 	assert.Equal(t, "stricklysoft-platform", cfg.PlatformIssuer)
 	assert.Equal(t, "https://kubernetes.default.svc.cluster.local", cfg.KubernetesIssuer)
 	assert.Equal(t, "https://kubernetes.default.svc.cluster.local", cfg.KubernetesAudience)
+	assert.False(t, cfg.KubernetesTokenReview)
+	assert.Equal(t, "https://kubernetes.default.svc", cfg.KubernetesAPIServer)
+	assert.Equal(t, time.Minute, cfg.TokenReviewCacheTTL)
 	assert.Equal(t, 5*time.Minute, cfg.TokenCacheTTL)
 	assert.Equal(t, 10000, cfg.TokenCacheMaxSize)
 	assert.Equal(t, 1*time.Hour, cfg.JWKSCacheTTL)
@@ -710,6 +715,39 @@ func TestValidate_OIDCToken_UnknownKid(t *testing.T) {
 	assert.Equal(t, sserr.CodeAuthenticationInvalid, ssErr.Code, "unknown kid should produce AUTH_003")
 }
 
+func TestValidate_OIDCToken_JWKSUnavailable(t *testing.T) {
+	t.Parallel()
+	rsaPriv, _ := jwtTestGenerateRSAKeyPair(t)
+	jwksSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(jwksSrv.Close)
+
+	discoveryURL := ""
+	discoverySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": discoveryURL, "jwks_uri": jwksSrv.URL})
+	}))
+	discoveryURL = discoverySrv.URL
+	t.Cleanup(discoverySrv.Close)
+
+	v, err := NewJWTValidator(ValidatorConfig{
+		EnableOIDC:        true,
+		OIDCIssuerURL:     discoverySrv.URL,
+		TokenCacheMaxSize: 100,
+		HTTPClient:        discoverySrv.Client(),
+	})
+	require.NoError(t, err)
+
+	tokenStr := jwtTestGenerateRSAToken(t, rsaPriv, "rsa-key-1", jwt.MapClaims{
+		"iss": discoverySrv.URL,
+		"sub": "user-1",
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	_, err = v.Validate(context.Background(), tokenStr)
+	require.Error(t, err)
+	assert.True(t, sserr.IsUnavailable(err), "JWKS outage should produce UNAVAIL_001, got %v", err)
+}
+
 // ---------------------------------------------------------------------------
 // Token cache tests
 // ---------------------------------------------------------------------------
@@ -890,7 +928,7 @@ func TestJWKSCache_FetchAndCache(t *testing.T) {
 	}))
 	t.Cleanup(srv.Close)
 
-	cache := newJWKSCache(1*time.Hour, srv.Client())
+	cache := newJWKSCache(1*time.Hour, DefaultJWKSMaxStale, srv.Client())
 
 	// First fetch.
 	key1, err := cache.getKey(context.Background(), srv.URL, "test-kid")
@@ -932,7 +970,7 @@ func TestJWKSCache_TTLExpiry(t *testing.T) {
 	t.Cleanup(srv.Close)
 
 	// Very short TTL.
-	cache := newJWKSCache(1*time.Millisecond, srv.Client())
+	cache := newJWKSCache(1*time.Millisecond, DefaultJWKSMaxStale, srv.Client())
 
 	// First fetch.
 	_, err := cache.getKey(context.Background(), srv.URL, "test-kid")
@@ -948,6 +986,188 @@ func TestJWKSCache_TTLExpiry(t *testing.T) {
 	assert.Equal(t, 2, fetchCount, "JWKS should have been re-fetched after TTL expiry")
 }
 
+// jwksTestServer serves a JWKS document with a single RSA key, "test-kid".
+// It counts requests, answers with status, and holds each request until
+// gate is closed if gate is not nil.
+type jwksTestServer struct {
+	*httptest.Server
+	fetches atomic.Int32
+	status  atomic.Int32
+	gate    chan struct{}
+}
+
+// newJWKSTestServer starts a jwksTestServer.
+func newJWKSTestServer(t *testing.T, gate chan struct{}) *jwksTestServer {
+	t.Helper()
+	_, rsaPub := jwtTestGenerateRSAKeyPair(t)
+	jwksDoc, err := json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-kid",
+			"n":   base64.RawURLEncoding.EncodeToString(rsaPub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaPub.E)).Bytes()),
+		}},
+	})
+	require.NoError(t, err)
+
+	s := &jwksTestServer{gate: gate}
+	s.status.Store(http.StatusOK)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		if s.gate != nil {
+			<-s.gate
+		}
+		w.WriteHeader(int(s.status.Load()))
+		_, _ = w.Write(jwksDoc)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newJWKSTestCache creates a jwksCache for s with a one-hour TTL and
+// maximum staleness, whose clock is read from now.
+func newJWKSTestCache(s *jwksTestServer, now *time.Time) *jwksCache {
+	cache := newJWKSCache(time.Hour, time.Hour, s.Client())
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func TestJWKSCache_ServesStaleKeysWhileProviderDown(t *testing.T) {
+	t.Parallel()
+	s := newJWKSTestServer(t, nil)
+	now := time.Now()
+	cache := newJWKSTestCache(s, &now)
+	ctx := context.Background()
+
+	_, err := cache.getKey(ctx, s.URL, "test-kid")
+	require.NoError(t, err)
+
+	s.status.Store(http.StatusServiceUnavailable)
+	now = now.Add(90 * time.Minute)
+	key, err := cache.getKey(ctx, s.URL, "test-kid")
+	require.NoError(t, err)
+	assert.NotNil(t, key)
+	assert.Equal(t, int32(2), s.fetches.Load())
+
+	// Failed fetches are not retried on every request.
+	_, err = cache.getKey(ctx, s.URL, "test-kid")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), s.fetches.Load())
+
+	// Past TTL plus the maximum staleness, the keys are no longer used.
+	now = now.Add(31 * time.Minute)
+	_, err = cache.getKey(ctx, s.URL, "test-kid")
+	require.Error(t, err)
+	assert.True(t, sserr.IsUnavailable(err), "got %v", err)
+
+	// Once the provider recovers, keys are fetched again.
+	s.status.Store(http.StatusOK)
+	now = now.Add(jwksRetryInterval)
+	_, err = cache.getKey(ctx, s.URL, "test-kid")
+	require.NoError(t, err)
+}
+
+func TestJWKSCache_ProviderDownWithoutCachedKeys(t *testing.T) {
+	t.Parallel()
+	s := newJWKSTestServer(t, nil)
+	s.status.Store(http.StatusBadGateway)
+	now := time.Now()
+	cache := newJWKSTestCache(s, &now)
+
+	for range 3 {
+		_, err := cache.getKey(context.Background(), s.URL, "test-kid")
+		require.Error(t, err)
+		assert.True(t, sserr.IsUnavailable(err), "got %v", err)
+	}
+	assert.Equal(t, int32(1), s.fetches.Load())
+}
+
+func TestJWKSCache_UnknownKidRefetchIsRateLimited(t *testing.T) {
+	t.Parallel()
+	s := newJWKSTestServer(t, nil)
+	now := time.Now()
+	cache := newJWKSTestCache(s, &now)
+	ctx := context.Background()
+
+	_, err := cache.getKey(ctx, s.URL, "test-kid")
+	require.NoError(t, err)
+	for range 5 {
+		_, err = cache.getKey(ctx, s.URL, "rotated-kid")
+		require.Error(t, err)
+		assert.False(t, sserr.IsUnavailable(err))
+	}
+	assert.Equal(t, int32(1), s.fetches.Load())
+
+	now = now.Add(jwksKidRefetchInterval)
+	_, err = cache.getKey(ctx, s.URL, "rotated-kid")
+	require.Error(t, err)
+	assert.Equal(t, int32(2), s.fetches.Load())
+}
+
+func TestJWKSCache_RefreshesInBackground(t *testing.T) {
+	t.Parallel()
+	s := newJWKSTestServer(t, nil)
+	var mu sync.Mutex
+	now := time.Now()
+	cache := newJWKSCache(time.Hour, time.Hour, s.Client())
+	cache.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	_, err := cache.getKey(context.Background(), s.URL, "test-kid")
+	require.NoError(t, err)
+
+	mu.Lock()
+	now = now.Add(50 * time.Minute)
+	mu.Unlock()
+	_, err = cache.getKey(context.Background(), s.URL, "test-kid")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		cache.mu.RLock()
+		defer cache.mu.RUnlock()
+		return cache.entries[s.URL].fetchedAt.Equal(cache.now())
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), s.fetches.Load())
+}
+
+func TestJWKSCache_ConcurrentFetchesShared(t *testing.T) {
+	t.Parallel()
+	gate := make(chan struct{})
+	s := newJWKSTestServer(t, gate)
+	cache := newJWKSCache(time.Hour, time.Hour, s.Client())
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.getKey(context.Background(), s.URL, "test-kid")
+			assert.NoError(t, err)
+		}()
+	}
+	require.Eventually(t, func() bool { return s.fetches.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(gate)
+	wg.Wait()
+	assert.Equal(t, int32(1), s.fetches.Load())
+}
+
+func TestJWKSCache_CanceledContextDoesNotWait(t *testing.T) {
+	t.Parallel()
+	gate := make(chan struct{})
+	s := newJWKSTestServer(t, gate)
+	defer close(gate)
+	cache := newJWKSCache(time.Hour, time.Hour, s.Client())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := cache.getKey(ctx, s.URL, "test-kid")
+	require.Error(t, err)
+	assert.True(t, sserr.IsUnavailable(err), "got %v", err)
+}
+
 // ---------------------------------------------------------------------------
 // tokenHash tests
 // ---------------------------------------------------------------------------
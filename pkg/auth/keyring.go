@@ -0,0 +1,412 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ---------------------------------------------------------------------------
+// SigningKey
+// ---------------------------------------------------------------------------
+
+// SigningKey is one HMAC key of a [Keyring], identified by the "kid"
+// header of the tokens it signs.
+type SigningKey struct {
+	// ID is the key ID written to and matched against the "kid" header.
+	ID string
+
+	// Secret is the HMAC key. Must be at least 32 bytes.
+	Secret Secret
+
+	// NotBefore is when the key becomes active. Zero means immediately.
+	NotBefore time.Time
+
+	// NotAfter is when the key stops verifying tokens. Zero means never.
+	NotAfter time.Time
+}
+
+// activeAt reports whether the key verifies tokens at t.
+func (k SigningKey) activeAt(t time.Time) bool {
+	return !t.Before(k.NotBefore) && (k.NotAfter.IsZero() || t.Before(k.NotAfter))
+}
+
+// ---------------------------------------------------------------------------
+// KeyringOption
+// ---------------------------------------------------------------------------
+
+// KeyringOption configures a [Keyring].
+type KeyringOption func(*keyringOptions)
+
+// keyringOptions holds the settings applied by [KeyringOption] functions.
+type keyringOptions struct {
+	meter metric.Meter
+}
+
+// WithKeyringMeter sets the meter used for keyring metrics. Defaults to
+// the global meter provider.
+func WithKeyringMeter(meter metric.Meter) KeyringOption {
+	return func(o *keyringOptions) { o.meter = meter }
+}
+
+// ---------------------------------------------------------------------------
+// Keyring
+// ---------------------------------------------------------------------------
+
+// Keyring holds the HMAC keys used to sign and verify platform tokens,
+// selected by key ID, so that keys can be rotated without a hard cutover:
+//
+//  1. Add the new key with NotBefore in the future and roll it out to
+//     every validator.
+//  2. Once NotBefore passes, issuers sign with the new key while
+//     validators still accept tokens signed with the old one.
+//  3. Set the old key's NotAfter past the longest token lifetime, then
+//     remove it.
+//
+// Set it as [ValidatorConfig.PlatformKeyring] and
+// [IssuerConfig.Keyring]. Keys can be replaced at runtime with
+// [Keyring.Replace], [Keyring.Reload], or [Keyring.Watch].
+//
+// The keyring records two counters: "auth.platform_key.verifications"
+// with attributes "auth.kid" (empty for unknown keys) and "auth.result"
+// ("verified", "unknown_key", "inactive_key", or "rejected"), and
+// "auth.platform_key.reloads" with attribute "auth.result" ("ok",
+// "unchanged", or "error").
+//
+// Keyring is safe for concurrent use by multiple goroutines.
+type Keyring struct {
+	mu          sync.RWMutex
+	keys        []SigningKey
+	fingerprint [sha256.Size]byte
+
+	verifications metric.Int64Counter
+	reloads       metric.Int64Counter
+
+	// now returns the current time. Replaced in tests.
+	now func() time.Time
+}
+
+// NewKeyring creates a Keyring holding keys.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if keys is
+// empty, a key ID is empty or duplicated, a secret is shorter than 32
+// bytes, or a key's NotAfter is not after its NotBefore.
+func NewKeyring(keys []SigningKey, opts ...KeyringOption) (*Keyring, error) {
+	o := keyringOptions{meter: otel.Meter(tracerName)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	k := &Keyring{now: time.Now}
+	if err := k.Replace(keys); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if k.verifications, err = o.meter.Int64Counter("auth.platform_key.verifications",
+		metric.WithDescription("Platform tokens checked against the keyring, by key ID and result."),
+		metric.WithUnit("{token}")); err != nil {
+		k.verifications = noop.Int64Counter{}
+	}
+	if k.reloads, err = o.meter.Int64Counter("auth.platform_key.reloads",
+		metric.WithDescription("Keyring reloads, by result."),
+		metric.WithUnit("{reload}")); err != nil {
+		k.reloads = noop.Int64Counter{}
+	}
+	return k, nil
+}
+
+// Replace atomically replaces all keys. On error the keyring is left
+// unchanged. The validation rules are those of [NewKeyring].
+func (k *Keyring) Replace(keys []SigningKey) error {
+	sorted, fingerprint, err := prepareKeys(keys)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = sorted
+	k.fingerprint = fingerprint
+	return nil
+}
+
+// KeyIDs returns the IDs of all keys, active or not, newest first: by
+// descending NotBefore, then by descending ID.
+func (k *Keyring) KeyIDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return keyIDs(k.keys)
+}
+
+// CurrentKey returns the key to sign new tokens with: the active key with
+// the latest NotBefore, or among keys activated at the same time, such as
+// keys without windows, the one with the greatest ID. Keys without
+// windows should therefore have IDs that sort in the order they are
+// created, such as "2026-10" or "20261018".
+//
+// Returns a *[sserr.Error] with code [sserr.CodeUnavailable] if no key is
+// active.
+func (k *Keyring) CurrentKey() (SigningKey, error) {
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, key := range k.keys {
+		if key.activeAt(now) {
+			return key, nil
+		}
+	}
+	return SigningKey{}, sserr.New(sserr.CodeUnavailable, "auth: no active platform signing key")
+}
+
+// verificationKey returns the key that verifies token, selected by its
+// "kid" header. A token without a "kid" is checked against every active
+// key, so tokens issued before key IDs were introduced keep working. It
+// also returns the key ID and the metric result for a failed lookup.
+func (k *Keyring) verificationKey(token *jwt.Token) (key []byte, kid, result string, err error) {
+	now := k.now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	kid, _ = token.Header["kid"].(string)
+	if kid == "" {
+		signed := token.Raw[:strings.LastIndexByte(token.Raw, '.')]
+		for _, key := range k.keys {
+			secret := []byte(key.Secret.Value())
+			if key.activeAt(now) && token.Method.Verify(signed, token.Signature, secret) == nil {
+				return secret, key.ID, "", nil
+			}
+		}
+		return nil, "", "rejected", sserr.New(sserr.CodeAuthenticationInvalid, "auth: token signature is invalid")
+	}
+
+	for _, key := range k.keys {
+		if key.ID != kid {
+			continue
+		}
+		if !key.activeAt(now) {
+			return nil, kid, "inactive_key", sserr.Newf(sserr.CodeAuthenticationInvalid,
+				"auth: signing key %q is not active", kid)
+		}
+		return []byte(key.Secret.Value()), kid, "", nil
+	}
+	// Report an unknown kid as empty: it is attacker-controlled and would
+	// otherwise inflate the metric's cardinality.
+	return nil, "", "unknown_key", sserr.Newf(sserr.CodeAuthenticationInvalid, "auth: unknown signing key %q", kid)
+}
+
+// recordVerification counts a verification attempt.
+func (k *Keyring) recordVerification(ctx context.Context, kid, result string) {
+	k.verifications.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("auth.kid", kid),
+		attribute.String("auth.result", result),
+	))
+}
+
+// Reload replaces the keys with those loaded from path by
+// [LoadSigningKeys]. It does nothing if the keys are unchanged. On error
+// the current keys are kept.
+func (k *Keyring) Reload(ctx context.Context, path string) error {
+	result := "ok"
+	defer func() {
+		k.reloads.Add(ctx, 1, metric.WithAttributes(attribute.String("auth.result", result)))
+	}()
+
+	keys, err := LoadSigningKeys(path)
+	if err != nil {
+		result = "error"
+		return err
+	}
+	sorted, fingerprint, err := prepareKeys(keys)
+	if err != nil {
+		result = "error"
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if fingerprint == k.fingerprint {
+		result = "unchanged"
+		return nil
+	}
+	k.keys = sorted
+	k.fingerprint = fingerprint
+	slog.InfoContext(ctx, "auth: platform keyring reloaded",
+		"path", path,
+		"key_ids", keyIDs(sorted),
+	)
+	return nil
+}
+
+// Watch calls [Keyring.Reload] every interval until ctx is cancelled.
+// Reload errors are logged and the current keys are kept. It is meant to
+// run in its own goroutine:
+//
+//	go keyring.Watch(ctx, "/etc/platform-keys", 30*time.Second)
+func (k *Keyring) Watch(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Reload(ctx, path); err != nil {
+				slog.WarnContext(ctx, "auth: platform keyring reload failed",
+					"path", path,
+					"error", err,
+				)
+			}
+		}
+	}
+}
+
+// prepareKeys validates keys and returns a copy sorted newest first (by
+// descending NotBefore, then by descending ID), along with a fingerprint
+// used to detect changes.
+func prepareKeys(keys []SigningKey) ([]SigningKey, [sha256.Size]byte, error) {
+	var fingerprint [sha256.Size]byte
+	if len(keys) == 0 {
+		return nil, fingerprint, sserr.New(sserr.CodeValidation, "auth: keyring must contain at least one key")
+	}
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, fingerprint, sserr.New(sserr.CodeValidation, "auth: signing key ID must not be empty")
+		}
+		if _, dup := seen[key.ID]; dup {
+			return nil, fingerprint, sserr.Newf(sserr.CodeValidation, "auth: duplicate signing key ID %q", key.ID)
+		}
+		seen[key.ID] = struct{}{}
+		if len(key.Secret.Value()) < 32 {
+			return nil, fingerprint, sserr.Newf(sserr.CodeValidation, "auth: signing key %q must be at least 32 bytes", key.ID)
+		}
+		if !key.NotAfter.IsZero() && !key.NotAfter.After(key.NotBefore) {
+			return nil, fingerprint, sserr.Newf(sserr.CodeValidation, "auth: signing key %q must expire after it activates", key.ID)
+		}
+	}
+
+	sorted := append([]SigningKey(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].NotBefore.Equal(sorted[j].NotBefore) {
+			return sorted[i].NotBefore.After(sorted[j].NotBefore)
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+
+	h := sha256.New()
+	for _, key := range sorted {
+		// Length-prefix each field so that values cannot run together.
+		for _, field := range []string{
+			key.ID, key.Secret.Value(),
+			key.NotBefore.UTC().Format(time.RFC3339Nano), key.NotAfter.UTC().Format(time.RFC3339Nano),
+		} {
+			_, _ = h.Write([]byte{byte(len(field) >> 8), byte(len(field))})
+			_, _ = h.Write([]byte(field))
+		}
+	}
+	copy(fingerprint[:], h.Sum(nil))
+	return sorted, fingerprint, nil
+}
+
+// keyIDs returns the IDs of keys.
+func keyIDs(keys []SigningKey) []string {
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = key.ID
+	}
+	return ids
+}
+
+// ---------------------------------------------------------------------------
+// Loading
+// ---------------------------------------------------------------------------
+
+// signingKeyFile is the JSON format read by [LoadSigningKeys].
+type signingKeyFile struct {
+	Keys []struct {
+		ID        string    `json:"kid"`
+		Secret    string    `json:"secret"`
+		NotBefore time.Time `json:"not_before"`
+		NotAfter  time.Time `json:"not_after"`
+	} `json:"keys"`
+}
+
+// LoadSigningKeys reads signing keys from path, which is either:
+//
+//   - a JSON file of the form
+//     {"keys": [{"kid": "...", "secret": "...", "not_before": "RFC 3339", "not_after": "RFC 3339"}]},
+//     where the times are optional; or
+//   - a directory with one file per key, named after the key ID and
+//     containing the secret, as produced by mounting a Kubernetes
+//     Secret. Entries starting with "." (such as the "..data" link
+//     Kubernetes uses for atomic updates) are skipped, and surrounding
+//     whitespace is trimmed from secrets. Such keys have no windows, so
+//     the key with the greatest ID signs (see [Keyring.CurrentKey]).
+//
+// The keys are not validated; see [NewKeyring]. Returns a *[sserr.Error]
+// with code [sserr.CodeInternal] if path cannot be read, or
+// [sserr.CodeValidation] if the JSON is malformed.
+func LoadSigningKeys(path string) ([]SigningKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to read signing keys")
+	}
+	if info.IsDir() {
+		return loadSigningKeyDir(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to read signing keys")
+	}
+	var file signingKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeValidation, "auth: malformed signing key file")
+	}
+	keys := make([]SigningKey, len(file.Keys))
+	for i, k := range file.Keys {
+		keys[i] = SigningKey{ID: k.ID, Secret: Secret(k.Secret), NotBefore: k.NotBefore, NotAfter: k.NotAfter}
+	}
+	return keys, nil
+}
+
+// loadSigningKeyDir reads one key per file from dir.
+func loadSigningKeyDir(dir string) ([]SigningKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to read signing keys")
+	}
+	var keys []SigningKey
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// Kubernetes mounts each key as a symlink, so stat the target.
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, sserr.Wrapf(err, sserr.CodeInternal, "auth: failed to read signing key %q", entry.Name())
+		}
+		keys = append(keys, SigningKey{ID: entry.Name(), Secret: Secret(strings.TrimSpace(string(data)))})
+	}
+	return keys, nil
+}
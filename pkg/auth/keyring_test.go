@@ -0,0 +1,420 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// countingMeter is a metric.Meter whose Int64Counters record their sums
+// by name and attribute set.
+type countingMeter struct {
+	noop.Meter
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (m *countingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &countingCounter{name: name, meter: m}, nil
+}
+
+// get returns the sum recorded for name with attrs, e.g.
+// get("auth.platform_key.reloads", "auth.result=ok").
+func (m *countingMeter) get(name, attrs string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[name+"{"+attrs+"}"]
+}
+
+type countingCounter struct {
+	noop.Int64Counter
+	name  string
+	meter *countingMeter
+}
+
+func (c *countingCounter) Add(_ context.Context, incr int64, opts ...metric.AddOption) {
+	attrs := metric.NewAddConfig(opts).Attributes()
+	key := c.name + "{" + attrs.Encoded(attribute.DefaultEncoder()) + "}"
+	c.meter.mu.Lock()
+	defer c.meter.mu.Unlock()
+	if c.meter.counts == nil {
+		c.meter.counts = make(map[string]int64)
+	}
+	c.meter.counts[key] += incr
+}
+
+// newTestKeyringSetup returns a keyring with its meter and a validator and
+// issuer that use it. The keyring clock is controlled with the returned
+// pointer, as Unix seconds.
+func newTestKeyringSetup(t *testing.T, keys []SigningKey) (*Keyring, *countingMeter, *JWTValidator, *TokenIssuer, *atomic.Int64) {
+	t.Helper()
+	meter := &countingMeter{}
+	keyring, err := NewKeyring(keys, WithKeyringMeter(meter))
+	require.NoError(t, err)
+	var clock atomic.Int64
+	clock.Store(time.Now().Unix())
+	keyring.now = func() time.Time { return time.Unix(clock.Load(), 0) }
+
+	vcfg := newPlatformConfig()
+	vcfg.PlatformSigningKey = ""
+	vcfg.PlatformKeyring = keyring
+	validator, err := NewJWTValidator(vcfg)
+	require.NoError(t, err)
+
+	icfg := DefaultIssuerConfig()
+	icfg.Keyring = keyring
+	issuer, err := NewTokenIssuer(icfg)
+	require.NoError(t, err)
+	return keyring, meter, validator, issuer, &clock
+}
+
+// ---------------------------------------------------------------------------
+// Keyring
+// ---------------------------------------------------------------------------
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	t.Parallel()
+	secret := Secret(testSigningKey)
+	now := time.Now()
+	tests := map[string][]SigningKey{
+		"empty":        nil,
+		"empty ID":     {{Secret: secret}},
+		"duplicate ID": {{ID: "a", Secret: secret}, {ID: "a", Secret: secret}},
+		"short secret": {{ID: "a", Secret: "short"}},
+		"bad window":   {{ID: "a", Secret: secret, NotBefore: now, NotAfter: now}},
+	}
+	for name, keys := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewKeyring(keys)
+			assert.True(t, sserr.IsValidation(err), "error = %v", err)
+		})
+	}
+}
+
+func TestKeyring_CurrentKey(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1_700_000_000, 0)
+	keyring, err := NewKeyring([]SigningKey{
+		{ID: "old", Secret: Secret(testSigningKey)},
+		{ID: "mid", Secret: Secret(testSigningKey), NotBefore: now.Add(-time.Hour)},
+		{ID: "next", Secret: Secret(testSigningKey), NotBefore: now.Add(time.Hour)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"next", "mid", "old"}, keyring.KeyIDs())
+
+	keyring.now = func() time.Time { return now }
+	key, err := keyring.CurrentKey()
+	require.NoError(t, err)
+	assert.Equal(t, "mid", key.ID, "a key is not used before it activates")
+
+	keyring.now = func() time.Time { return now.Add(2 * time.Hour) }
+	key, err = keyring.CurrentKey()
+	require.NoError(t, err)
+	assert.Equal(t, "next", key.ID)
+
+	require.NoError(t, keyring.Replace([]SigningKey{
+		{ID: "expired", Secret: Secret(testSigningKey), NotAfter: now},
+	}))
+	_, err = keyring.CurrentKey()
+	assert.True(t, sserr.IsUnavailable(err))
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	keyring, meter, validator, issuer, clock := newTestKeyringSetup(t, []SigningKey{
+		{ID: "k1", Secret: Secret(testSigningKey)},
+		{ID: "k2", Secret: Secret("another-32-byte-test-signing-key"), NotBefore: start.Add(time.Hour)},
+	})
+	ctx := context.Background()
+	user := newTestUser(t)
+
+	oldToken, err := issuer.Issue(ctx, user)
+	require.NoError(t, err)
+	header, _ := parseIssued(t, oldToken)
+	assert.Equal(t, "k1", header["kid"])
+
+	// After k2 activates, new tokens use it and tokens signed with k1
+	// still verify.
+	clock.Store(start.Add(2 * time.Hour).Unix())
+	newToken, err := issuer.Issue(ctx, user)
+	require.NoError(t, err)
+	_, err = validator.Validate(ctx, newToken)
+	require.NoError(t, err)
+	_, err = validator.Validate(ctx, oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.verifications", "auth.kid=k1,auth.result=verified"))
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.verifications", "auth.kid=k2,auth.result=verified"))
+
+	// Retiring k1 rejects its tokens.
+	require.NoError(t, keyring.Replace([]SigningKey{
+		{ID: "k1", Secret: Secret(testSigningKey), NotAfter: start.Add(90 * time.Minute)},
+		{ID: "k2", Secret: Secret("another-32-byte-test-signing-key"), NotBefore: start.Add(time.Hour)},
+	}))
+	retired, err := NewTokenIssuer(IssuerConfig{
+		SigningKey: Secret(testSigningKey), KeyID: "k1", Issuer: "stricklysoft-platform",
+		TTL: time.Minute, MaxTTL: time.Minute,
+	})
+	require.NoError(t, err)
+	token, err := retired.Issue(ctx, user)
+	require.NoError(t, err)
+	_, err = validator.Validate(ctx, token)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.verifications", "auth.kid=k1,auth.result=inactive_key"))
+}
+
+func TestKeyring_RejectedTokens(t *testing.T) {
+	t.Parallel()
+	_, meter, validator, _, _ := newTestKeyringSetup(t, []SigningKey{
+		{ID: "k1", Secret: Secret(testSigningKey)},
+	})
+	ctx := context.Background()
+	claims := func() map[string]any {
+		return map[string]any{
+			"iss": "stricklysoft-platform", "sub": "usr-1",
+			"exp": float64(time.Now().Add(time.Hour).Unix()),
+		}
+	}
+
+	forged := jwtTestGenerateHMACToken(t, []byte("attacker-controlled-32-byte-key!"), claims())
+	_, err := validator.Validate(ctx, forged)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.verifications", "auth.kid=,auth.result=rejected"))
+
+	issuer, err := NewTokenIssuer(IssuerConfig{
+		SigningKey: Secret(testSigningKey), KeyID: "attacker-chosen", Issuer: "stricklysoft-platform",
+		TTL: time.Minute, MaxTTL: time.Minute,
+	})
+	require.NoError(t, err)
+	token, err := issuer.Issue(ctx, newTestUser(t))
+	require.NoError(t, err)
+	_, err = validator.Validate(ctx, token)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.verifications", "auth.kid=,auth.result=unknown_key"))
+
+	// A token without a kid is matched against every active key.
+	legacy := jwtTestGenerateHMACToken(t, []byte(testSigningKey), claims())
+	_, err = validator.Validate(ctx, legacy)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.verifications", "auth.kid=k1,auth.result=verified"))
+}
+
+func TestTokenIssuer_KeyringCapsExpiry(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1_700_000_000, 0)
+	keyring, err := NewKeyring([]SigningKey{
+		{ID: "k1", Secret: Secret(testSigningKey), NotAfter: now.Add(time.Minute)},
+	})
+	require.NoError(t, err)
+	keyring.now = func() time.Time { return now }
+	issuer, err := NewTokenIssuer(IssuerConfig{Keyring: keyring, Issuer: "iss", TTL: time.Hour, MaxTTL: time.Hour})
+	require.NoError(t, err)
+	issuer.now = func() time.Time { return now }
+
+	token, err := issuer.Issue(context.Background(), newTestUser(t))
+	require.NoError(t, err)
+	_, claims := parseIssued(t, token)
+	assert.Equal(t, float64(now.Add(time.Minute).Unix()), claims["exp"])
+}
+
+func TestTokenIssuer_KeyringKeyExpired(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1_700_000_000, 0)
+	keyring, err := NewKeyring([]SigningKey{
+		{ID: "k1", Secret: Secret(testSigningKey), NotAfter: now.Add(time.Minute)},
+	})
+	require.NoError(t, err)
+	keyring.now = func() time.Time { return now }
+	issuer, err := NewTokenIssuer(IssuerConfig{Keyring: keyring, Issuer: "iss", TTL: time.Hour, MaxTTL: time.Hour})
+	require.NoError(t, err)
+	// The issuer's clock runs ahead of the keyring's, past the key's
+	// window.
+	issuer.now = func() time.Time { return now.Add(time.Minute) }
+
+	_, err = issuer.Issue(context.Background(), newTestUser(t))
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeUnavailable), "error = %v", err)
+	assert.NotContains(t, err.Error(), "delegation")
+}
+
+// ---------------------------------------------------------------------------
+// Loading and reloading
+// ---------------------------------------------------------------------------
+
+func TestLoadSigningKeys_File(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [
+		{"kid": "k1", "secret": "`+testSigningKey+`", "not_after": "2030-01-01T00:00:00Z"},
+		{"kid": "k2", "secret": "`+testSigningKey+`", "not_before": "2029-12-01T00:00:00Z"}
+	]}`), 0o600))
+
+	keys, err := LoadSigningKeys(path)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k1", keys[0].ID)
+	assert.Equal(t, testSigningKey, keys[0].Secret.Value())
+	assert.True(t, keys[0].NotBefore.IsZero())
+	assert.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), keys[0].NotAfter)
+	assert.Equal(t, time.Date(2029, 12, 1, 0, 0, 0, 0, time.UTC), keys[1].NotBefore)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [`), 0o600))
+	_, err = LoadSigningKeys(path)
+	assert.True(t, sserr.IsValidation(err))
+
+	_, err = LoadSigningKeys(filepath.Join(t.TempDir(), "missing"))
+	assert.True(t, sserr.IsInternal(err))
+}
+
+// writeSecretMount lays out dir like a Kubernetes Secret volume: the data
+// lives in a timestamped directory, "..data" links to it, and each key is
+// a symlink through "..data".
+func writeSecretMount(t *testing.T, dir, version string, keys map[string]string) {
+	t.Helper()
+	dataDir := filepath.Join(dir, "..data_"+version)
+	require.NoError(t, os.Mkdir(dataDir, 0o700))
+	for name, secret := range keys {
+		require.NoError(t, os.WriteFile(filepath.Join(dataDir, name), []byte(secret+"\n"), 0o600))
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	require.NoError(t, os.Symlink(filepath.Base(dataDir), tmp))
+	require.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+	for name := range keys {
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); err == nil {
+			continue
+		}
+		require.NoError(t, os.Symlink(filepath.Join("..data", name), link))
+	}
+}
+
+func TestLoadSigningKeys_SecretMount(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeSecretMount(t, dir, "1", map[string]string{"k1": testSigningKey, "k2": "another-32-byte-test-signing-key"})
+
+	keys, err := LoadSigningKeys(dir)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k1", keys[0].ID)
+	assert.Equal(t, testSigningKey, keys[0].Secret.Value(), "trailing newline is trimmed")
+	assert.Equal(t, "k2", keys[1].ID)
+}
+
+func TestKeyring_Reload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeSecretMount(t, dir, "1", map[string]string{"k1": testSigningKey})
+	meter := &countingMeter{}
+	keyring, err := NewKeyring([]SigningKey{{ID: "k1", Secret: Secret(testSigningKey)}}, WithKeyringMeter(meter))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, keyring.Reload(ctx, dir))
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.reloads", "auth.result=unchanged"))
+
+	writeSecretMount(t, dir, "2", map[string]string{"k1": testSigningKey, "k2": "another-32-byte-test-signing-key"})
+	require.NoError(t, keyring.Reload(ctx, dir))
+	assert.Equal(t, []string{"k2", "k1"}, keyring.KeyIDs())
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.reloads", "auth.result=ok"))
+
+	// An invalid mount keeps the current keys.
+	writeSecretMount(t, dir, "3", map[string]string{"k1": "short", "k2": "another-32-byte-test-signing-key"})
+	assert.True(t, sserr.IsValidation(keyring.Reload(ctx, dir)))
+	assert.Equal(t, []string{"k2", "k1"}, keyring.KeyIDs())
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.reloads", "auth.result=error"))
+}
+
+func TestKeyring_ReloadRotatesDirectoryKeys(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeSecretMount(t, dir, "1", map[string]string{"2026-09": testSigningKey})
+	keys, err := LoadSigningKeys(dir)
+	require.NoError(t, err)
+	keyring, _, validator, issuer, _ := newTestKeyringSetup(t, keys)
+	ctx := context.Background()
+	user := newTestUser(t)
+
+	oldToken, err := issuer.Issue(ctx, user)
+	require.NoError(t, err)
+	header, _ := parseIssued(t, oldToken)
+	assert.Equal(t, "2026-09", header["kid"])
+
+	// Adding a key to the mount makes it sign once reloaded, while
+	// tokens signed with the old key still verify.
+	writeSecretMount(t, dir, "2", map[string]string{"2026-09": testSigningKey, "2026-10": "another-32-byte-test-signing-key"})
+	require.NoError(t, keyring.Reload(ctx, dir))
+	current, err := keyring.CurrentKey()
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10", current.ID)
+
+	newToken, err := issuer.Issue(ctx, user)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10", parsed.Header["kid"])
+	_, err = validator.Validate(ctx, newToken)
+	require.NoError(t, err)
+	_, err = validator.Validate(ctx, oldToken)
+	require.NoError(t, err)
+}
+
+func TestKeyring_Watch(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	write := func(ids ...string) {
+		data := `{"keys": [`
+		for i, id := range ids {
+			if i > 0 {
+				data += ","
+			}
+			data += `{"kid": "` + id + `", "secret": "` + testSigningKey + `"}`
+		}
+		require.NoError(t, os.WriteFile(path, []byte(data+`]}`), 0o600))
+	}
+	write("k1")
+	keyring, err := NewKeyring([]SigningKey{{ID: "k1", Secret: Secret(testSigningKey)}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		keyring.Watch(ctx, path, 5*time.Millisecond)
+	}()
+
+	write("k1", "k2")
+	assert.Eventually(t, func() bool { return len(keyring.KeyIDs()) == 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Watch did not return after cancellation")
+	}
+}
+
+func TestValidatorConfig_PlatformKeyring(t *testing.T) {
+	t.Parallel()
+	keyring, err := NewKeyring([]SigningKey{{ID: "k1", Secret: Secret(testSigningKey)}})
+	require.NoError(t, err)
+	cfg := newPlatformConfig()
+	cfg.PlatformSigningKey = ""
+	require.NotNil(t, cfg.Validate())
+	cfg.PlatformKeyring = keyring
+	assert.Nil(t, cfg.Validate())
+}
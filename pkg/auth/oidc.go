@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ---------------------------------------------------------------------------
+// OIDCIssuerConfig — per-provider OIDC settings
+// ---------------------------------------------------------------------------
+
+// defaultOIDCAlgorithms are the signing algorithms accepted from an OIDC
+// issuer that does not set [OIDCIssuerConfig.AllowedAlgorithms].
+var defaultOIDCAlgorithms = []string{"RS256", "ES256"}
+
+// supportedOIDCAlgorithms are the asymmetric algorithms that can be
+// verified with keys from a JWKS endpoint. HMAC algorithms are excluded:
+// accepting them would let a token be verified with a public key used as
+// a shared secret.
+var supportedOIDCAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+}
+
+// OIDCIssuerConfig configures one external OpenID Connect provider
+// accepted by [JWTValidator]. Tokens are routed to the provider whose
+// IssuerURL equals their "iss" claim.
+type OIDCIssuerConfig struct {
+	// IssuerURL is the provider's issuer identifier (e.g.,
+	// "https://token.actions.githubusercontent.com"). The validator
+	// appends "/.well-known/openid-configuration" to discover the JWKS
+	// endpoint, and requires it to match the "iss" claim exactly.
+	IssuerURL string `json:"issuer_url"`
+
+	// Audience is the expected "aud" claim. If empty, the audience claim
+	// is not validated. This field is optional.
+	Audience string `json:"audience,omitempty"`
+
+	// AllowedAlgorithms lists the accepted signing algorithms. Defaults
+	// to RS256 and ES256. Only RS*, PS*, and ES* algorithms are allowed.
+	AllowedAlgorithms []string `json:"allowed_algorithms,omitempty"`
+
+	// ClaimMapping names the claims identities are built from.
+	ClaimMapping OIDCClaimMapping `json:"claim_mapping,omitempty"`
+
+	// PermissionMapper extracts permissions from the provider's claims.
+	// If nil, the provider's identities hold no permissions:
+	// [ValidatorConfig.PermissionMapper] is only used for the issuer set
+	// by [ValidatorConfig.OIDCIssuerURL].
+	PermissionMapper func(claims map[string]any) []Permission `json:"-"`
+}
+
+// OIDCClaimMapping names the claims of an OIDC provider's tokens that an
+// identity is built from. Empty fields use the standard claim names.
+type OIDCClaimMapping struct {
+	// Subject is the claim holding the identity ID. Defaults to "sub".
+	// For issuers configured in [ValidatorConfig.OIDCIssuers], the ID is
+	// qualified by the issuer URL; see [OIDCIdentityID].
+	Subject string `json:"subject,omitempty"`
+
+	// Email is the claim holding the user's email address. Tokens with a
+	// non-empty email produce a [UserIdentity]. Defaults to "email".
+	Email string `json:"email,omitempty"`
+
+	// Name is the claim holding the user's display name. Defaults to
+	// "name".
+	Name string `json:"name,omitempty"`
+
+	// ServiceName, if set, is the claim naming the workload of tokens
+	// without an email, such as the "repository" claim of CI tokens.
+	// Tokens with a non-empty service name produce a [ServiceIdentity].
+	// Tokens with neither an email nor a service name produce a
+	// [BasicIdentity], which holds no permissions.
+	ServiceName string `json:"service_name,omitempty"`
+
+	// Namespace, if set, is the claim holding the [ServiceIdentity]
+	// namespace.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// withDefaults returns m with empty fields set to their defaults.
+func (m OIDCClaimMapping) withDefaults() OIDCClaimMapping {
+	if m.Subject == "" {
+		m.Subject = "sub"
+	}
+	if m.Email == "" {
+		m.Email = "email"
+	}
+	if m.Name == "" {
+		m.Name = "name"
+	}
+	return m
+}
+
+// validate checks c and returns a *[sserr.Error] with code
+// [sserr.CodeValidation] if it is invalid.
+func (c *OIDCIssuerConfig) validate() *sserr.Error {
+	if c.IssuerURL == "" {
+		return sserr.New(sserr.CodeValidation, "auth: OIDC issuer URL must not be empty")
+	}
+	for _, alg := range c.AllowedAlgorithms {
+		if !slices.Contains(supportedOIDCAlgorithms, alg) {
+			return sserr.Newf(sserr.CodeValidation,
+				"auth: OIDC issuer %q allows unsupported algorithm %q", c.IssuerURL, alg)
+		}
+	}
+	return nil
+}
+
+// oidcIssuers returns the configured OIDC issuers: the legacy
+// OIDCIssuerURL and OIDCAudience, if set, followed by OIDCIssuers.
+func (c *ValidatorConfig) oidcIssuers() []OIDCIssuerConfig {
+	issuers := make([]OIDCIssuerConfig, 0, len(c.OIDCIssuers)+1)
+	if c.OIDCIssuerURL != "" {
+		issuers = append(issuers, OIDCIssuerConfig{IssuerURL: c.OIDCIssuerURL, Audience: c.OIDCAudience})
+	}
+	return append(issuers, c.OIDCIssuers...)
+}
+
+// hasOIDCIssuer reports whether issuer is a configured OIDC issuer.
+func (c *ValidatorConfig) hasOIDCIssuer(issuer string) bool {
+	if issuer == "" {
+		return false
+	}
+	if issuer == c.OIDCIssuerURL {
+		return true
+	}
+	return slices.ContainsFunc(c.OIDCIssuers, func(ic OIDCIssuerConfig) bool {
+		return ic.IssuerURL == issuer
+	})
+}
+
+// validateOIDCIssuers checks the OIDC issuer configuration. Returns a
+// *[sserr.Error] with code [sserr.CodeValidation] if it is invalid.
+func (c *ValidatorConfig) validateOIDCIssuers() *sserr.Error {
+	issuers := c.oidcIssuers()
+	if len(issuers) == 0 {
+		return sserr.New(sserr.CodeValidation, "auth: OIDC issuer URL must not be empty when OIDC is enabled")
+	}
+	seen := make(map[string]bool, len(issuers))
+	for i := range issuers {
+		if err := issuers[i].validate(); err != nil {
+			return err
+		}
+		if seen[issuers[i].IssuerURL] {
+			return sserr.Newf(sserr.CodeValidation,
+				"auth: OIDC issuer %q is configured more than once", issuers[i].IssuerURL)
+		}
+		seen[issuers[i].IssuerURL] = true
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// oidcProvider — runtime state of one OIDC issuer
+// ---------------------------------------------------------------------------
+
+// OIDCIdentityID returns the ID of the identity with subject issued by
+// the OIDC provider issuerURL: the issuer URL and the subject separated
+// by "#". Issuer URLs have no fragment, so the result is unique across
+// issuers and a token from one provider cannot take on the ID of
+// another's subject.
+//
+// The IDs of identities from [ValidatorConfig.OIDCIssuers] are built
+// this way; those from [ValidatorConfig.OIDCIssuerURL] keep the bare
+// subject for compatibility.
+func OIDCIdentityID(issuerURL, subject string) string {
+	return issuerURL + "#" + subject
+}
+
+// oidcProvider holds a validated OIDC issuer configuration with its
+// defaults applied, and caches the JWKS URL discovered for it.
+type oidcProvider struct {
+	issuerURL  string
+	audience   string
+	algorithms []string
+	claims     OIDCClaimMapping
+	permMapper func(claims map[string]any) []Permission
+
+	// qualifyID is set for issuers from OIDCIssuers, whose identity IDs
+	// are qualified by the issuer URL.
+	qualifyID bool
+
+	mu      sync.Mutex
+	jwksURL string
+}
+
+// newOIDCProviders builds the providers of cfg's OIDC issuers, keyed by
+// issuer URL. permMapper is used for the legacy OIDCIssuerURL issuer;
+// issuers from OIDCIssuers use only their own mapper.
+func newOIDCProviders(cfg *ValidatorConfig, permMapper func(map[string]any) []Permission) map[string]*oidcProvider {
+	if !cfg.EnableOIDC {
+		return nil
+	}
+	providers := make(map[string]*oidcProvider, len(cfg.OIDCIssuers)+1)
+	if cfg.OIDCIssuerURL != "" {
+		providers[cfg.OIDCIssuerURL] = newOIDCProvider(
+			OIDCIssuerConfig{IssuerURL: cfg.OIDCIssuerURL, Audience: cfg.OIDCAudience, PermissionMapper: permMapper}, false)
+	}
+	for _, ic := range cfg.OIDCIssuers {
+		providers[ic.IssuerURL] = newOIDCProvider(ic, true)
+	}
+	return providers
+}
+
+// newOIDCProvider builds the provider of ic. qualifyID qualifies its
+// identity IDs by the issuer URL.
+func newOIDCProvider(ic OIDCIssuerConfig, qualifyID bool) *oidcProvider {
+	p := &oidcProvider{
+		issuerURL:  ic.IssuerURL,
+		audience:   ic.Audience,
+		algorithms: slices.Clone(ic.AllowedAlgorithms),
+		claims:     ic.ClaimMapping.withDefaults(),
+		permMapper: ic.PermissionMapper,
+		qualifyID:  qualifyID,
+	}
+	if len(p.algorithms) == 0 {
+		p.algorithms = defaultOIDCAlgorithms
+	}
+	return p
+}
+
+// discoverJWKSURL returns the provider's cached JWKS URL, or fetches it
+// from the provider's .well-known/openid-configuration endpoint.
+func (p *oidcProvider) discoverJWKSURL(ctx context.Context, client HTTPClient) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.jwksURL != "" {
+		return p.jwksURL, nil
+	}
+
+	discovery, err := fetchOIDCDiscovery(ctx, p.issuerURL, client)
+	if err != nil {
+		return "", err
+	}
+	p.jwksURL = discovery.JWKSURI
+	return p.jwksURL, nil
+}
+
+// identity builds the identity for a verified token with claims.
+func (p *oidcProvider) identity(claims map[string]any) (Identity, error) {
+	var permissions []Permission
+	if p.permMapper != nil {
+		permissions = p.permMapper(claims)
+	}
+	issuer := ""
+	if p.qualifyID {
+		issuer = p.issuerURL
+	}
+	return claimsIdentity(claims, p.claims, issuer, permissions, "OIDC token")
+}
+
+// claimsIdentity builds the identity described by the claims of a
+// verified token, named by mapping and granted permissions. If issuer
+// is set, the identity ID is qualified by it with [OIDCIdentityID].
+// source names the kind of token in error messages.
+func claimsIdentity(claims map[string]any, mapping OIDCClaimMapping, issuer string, permissions []Permission, source string) (Identity, error) {
+	sub := claimString(claims, mapping.Subject)
+	if sub == "" {
+		return nil, sserr.Newf(sserr.CodeAuthenticationInvalid, "auth: %s has no subject", source)
+	}
+	if issuer != "" {
+		sub = OIDCIdentityID(issuer, sub)
+	}
+	email := claimString(claims, mapping.Email)
+	name := claimString(claims, mapping.Name)
+
+	if email != "" {
+		identity, err := NewUserIdentity(sub, email, name, claims, permissions)
+		if err != nil {
+			return nil, sserr.Wrapf(err, sserr.CodeAuthenticationInvalid, "auth: failed to create user identity from %s", source)
+		}
+		return identity, nil
+	}
+
+	if mapping.ServiceName != "" {
+		if serviceName := claimString(claims, mapping.ServiceName); serviceName != "" {
+			namespace := ""
+			if mapping.Namespace != "" {
+				namespace = claimString(claims, mapping.Namespace)
+			}
+			identity, err := NewServiceIdentity(sub, serviceName, namespace, claims, permissions)
+			if err != nil {
+				return nil, sserr.Wrapf(err, sserr.CodeAuthenticationInvalid, "auth: failed to create service identity from %s", source)
+			}
+			return identity, nil
+		}
+	}
+
+	// Fallback for tokens without email.
+	return NewBasicIdentity(sub, IdentityTypeUser, claims), nil
+}
+
+// claimString returns the string claim name of claims. If there is no
+// claim with that exact name, a dotted name such as "realm.user_id"
+// selects a nested claim.
+func claimString(claims map[string]any, name string) string {
+	if v, ok := claims[name]; ok {
+		s, _ := v.(string)
+		return s
+	}
+	var v any = claims
+	for _, part := range strings.Split(name, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = m[part]
+	}
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// oidcTestIssuer starts an OIDC provider whose discovery document points
+// at a JWKS server with the given keys, and returns its issuer URL.
+func oidcTestIssuer(t *testing.T, rsaKeys map[string]*rsa.PublicKey, ecKeys map[string]*ecdsa.PublicKey) string {
+	t.Helper()
+	jwksSrv := jwtTestServeJWKS(t, rsaKeys, ecKeys)
+	var issuerURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": issuerURL, "jwks_uri": jwksSrv.URL})
+	}))
+	t.Cleanup(srv.Close)
+	issuerURL = srv.URL
+	return issuerURL
+}
+
+// newOIDCConfig returns an OIDC-only validator config with issuers.
+func newOIDCConfig(issuers ...OIDCIssuerConfig) ValidatorConfig {
+	return ValidatorConfig{
+		EnableOIDC:        true,
+		OIDCIssuers:       issuers,
+		TokenCacheTTL:     5 * time.Minute,
+		TokenCacheMaxSize: 100,
+		JWKSCacheTTL:      time.Hour,
+		ClockSkew:         30 * time.Second,
+	}
+}
+
+// oidcTestClaims returns valid claims for a token from issuer.
+func oidcTestClaims(issuer string, extra jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss": issuer,
+		"exp": jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		"iat": jwt.NewNumericDate(time.Now()),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	return claims
+}
+
+// ---------------------------------------------------------------------------
+// Configuration
+// ---------------------------------------------------------------------------
+
+func TestValidatorConfig_Validate_OIDCIssuers(t *testing.T) {
+	t.Parallel()
+	valid := newOIDCConfig(OIDCIssuerConfig{IssuerURL: "https://sso.example.com"})
+	require.Nil(t, valid.Validate())
+
+	tests := map[string]ValidatorConfig{
+		"empty issuer URL": newOIDCConfig(OIDCIssuerConfig{}),
+		"duplicate issuer": newOIDCConfig(
+			OIDCIssuerConfig{IssuerURL: "https://sso.example.com"},
+			OIDCIssuerConfig{IssuerURL: "https://sso.example.com"},
+		),
+		"duplicate of OIDCIssuerURL": func() ValidatorConfig {
+			cfg := newOIDCConfig(OIDCIssuerConfig{IssuerURL: "https://sso.example.com"})
+			cfg.OIDCIssuerURL = "https://sso.example.com"
+			return cfg
+		}(),
+		"HMAC algorithm": newOIDCConfig(OIDCIssuerConfig{
+			IssuerURL: "https://sso.example.com", AllowedAlgorithms: []string{"RS256", "HS256"},
+		}),
+		"none algorithm": newOIDCConfig(OIDCIssuerConfig{
+			IssuerURL: "https://sso.example.com", AllowedAlgorithms: []string{"none"},
+		}),
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := cfg.Validate()
+			require.NotNil(t, err)
+			assert.Equal(t, sserr.CodeValidation, err.Code)
+		})
+	}
+}
+
+func TestDetectTokenType_OIDCIssuers(t *testing.T) {
+	t.Parallel()
+	v := &JWTValidator{config: ValidatorConfig{
+		EnablePlatform: true,
+		EnableOIDC:     true,
+		PlatformIssuer: "stricklysoft-platform",
+		OIDCIssuerURL:  "https://sso.example.com",
+		OIDCIssuers: []OIDCIssuerConfig{
+			{IssuerURL: "https://token.actions.githubusercontent.com"},
+		},
+	}}
+
+	assert.Equal(t, TokenTypeOIDC, v.detectTokenType("https://sso.example.com", "RS256"))
+	assert.Equal(t, TokenTypeOIDC, v.detectTokenType("https://token.actions.githubusercontent.com", "ES256"))
+	// An HMAC token claiming an OIDC issuer is still routed by issuer, and
+	// then rejected by the issuer's algorithm list.
+	assert.Equal(t, TokenTypeOIDC, v.detectTokenType("https://token.actions.githubusercontent.com", "HS256"))
+	assert.Equal(t, TokenTypePlatform, v.detectTokenType("stricklysoft-platform", "HS256"))
+}
+
+// ---------------------------------------------------------------------------
+// Routing and per-issuer settings
+// ---------------------------------------------------------------------------
+
+func TestValidate_MultipleOIDCIssuers(t *testing.T) {
+	t.Parallel()
+	ssoKey, ssoPub := jwtTestGenerateRSAKeyPair(t)
+	ciKey, ciPub := jwtTestGenerateECDSAKeyPair(t)
+	ssoURL := oidcTestIssuer(t, map[string]*rsa.PublicKey{"sso-1": ssoPub}, nil)
+	ciURL := oidcTestIssuer(t, nil, map[string]*ecdsa.PublicKey{"ci-1": ciPub})
+
+	cfg := newOIDCConfig(
+		OIDCIssuerConfig{
+			IssuerURL: ciURL,
+			Audience:  "stricklysoft-deploy",
+			// CI tokens identify a workflow and carry no email.
+			AllowedAlgorithms: []string{"ES256"},
+			ClaimMapping: OIDCClaimMapping{
+				Subject:     "job_workflow_ref",
+				ServiceName: "repository",
+				Namespace:   "repository_owner",
+			},
+			PermissionMapper: func(claims map[string]any) []Permission {
+				if claims["repository"] == "acme/platform" {
+					return []Permission{{Resource: "deployments", Action: "create"}}
+				}
+				return nil
+			},
+		},
+	)
+	cfg.OIDCIssuerURL = ssoURL
+	cfg.OIDCAudience = "stricklysoft"
+	v, err := NewJWTValidator(cfg)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	ssoToken := jwtTestGenerateRSAToken(t, ssoKey, "sso-1", oidcTestClaims(ssoURL, jwt.MapClaims{
+		"aud": "stricklysoft", "sub": "emp-7", "email": "emp@example.com", "roles": []any{"viewer"},
+	}))
+	identity, err := v.Validate(ctx, ssoToken)
+	require.NoError(t, err)
+	user, ok := identity.(*UserIdentity)
+	require.True(t, ok, "identity type = %T", identity)
+	assert.Equal(t, "emp-7", user.ID())
+	assert.Equal(t, "emp@example.com", user.Email())
+
+	ciToken := jwtTestGenerateECDSAToken(t, ciKey, "ci-1", oidcTestClaims(ciURL, jwt.MapClaims{
+		"aud":              "stricklysoft-deploy",
+		"sub":              "repo:acme/platform:ref:refs/heads/main",
+		"repository":       "acme/platform",
+		"repository_owner": "acme",
+		"job_workflow_ref": "acme/platform/.github/workflows/deploy.yml@refs/heads/main",
+	}))
+	identity, err = v.Validate(ctx, ciToken)
+	require.NoError(t, err)
+	svc, ok := identity.(*ServiceIdentity)
+	require.True(t, ok, "identity type = %T", identity)
+	assert.Equal(t, OIDCIdentityID(ciURL, "acme/platform/.github/workflows/deploy.yml@refs/heads/main"), svc.ID())
+	assert.Equal(t, "acme/platform", svc.ServiceName())
+	assert.Equal(t, "acme", svc.Namespace())
+	assert.True(t, PermissionsOf(identity).Match("deployments", "create", ""))
+
+	// Each issuer enforces its own audience.
+	wrongAud := jwtTestGenerateECDSAToken(t, ciKey, "ci-1", oidcTestClaims(ciURL, jwt.MapClaims{
+		"aud": "stricklysoft", "sub": "repo:acme/platform:ref:refs/heads/main",
+	}))
+	_, err = v.Validate(ctx, wrongAud)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+}
+
+func TestValidate_OIDCIssuerIsolation(t *testing.T) {
+	t.Parallel()
+	ssoKey, ssoPub := jwtTestGenerateRSAKeyPair(t)
+	partnerKey, partnerPub := jwtTestGenerateRSAKeyPair(t)
+	ssoURL := oidcTestIssuer(t, map[string]*rsa.PublicKey{"sso-1": ssoPub}, nil)
+	partnerURL := oidcTestIssuer(t, map[string]*rsa.PublicKey{"partner-1": partnerPub}, nil)
+
+	cfg := newOIDCConfig(OIDCIssuerConfig{IssuerURL: partnerURL})
+	cfg.OIDCIssuerURL = ssoURL
+	cfg.PermissionMapper = func(map[string]any) []Permission {
+		return []Permission{{Resource: "*", Action: "*"}}
+	}
+	v, err := NewJWTValidator(cfg)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	claims := jwt.MapClaims{"sub": "admin", "email": "admin@example.com"}
+	identity, err := v.Validate(ctx, jwtTestGenerateRSAToken(t, ssoKey, "sso-1", oidcTestClaims(ssoURL, claims)))
+	require.NoError(t, err)
+	assert.Equal(t, "admin", identity.ID())
+	assert.True(t, PermissionsOf(identity).Match("deployments", "create", ""))
+
+	// The same subject from an added issuer is a different identity, and
+	// the config-wide mapper does not grant it permissions.
+	identity, err = v.Validate(ctx, jwtTestGenerateRSAToken(t, partnerKey, "partner-1", oidcTestClaims(partnerURL, claims)))
+	require.NoError(t, err)
+	assert.Equal(t, OIDCIdentityID(partnerURL, "admin"), identity.ID())
+	assert.Empty(t, identity.(*UserIdentity).Permissions())
+	assert.False(t, PermissionsOf(identity).Match("deployments", "create", ""))
+}
+
+func TestValidate_OIDCMissingSubject(t *testing.T) {
+	t.Parallel()
+	rsaKey, rsaPub := jwtTestGenerateRSAKeyPair(t)
+	issuerURL := oidcTestIssuer(t, map[string]*rsa.PublicKey{"k1": rsaPub}, nil)
+	v, err := NewJWTValidator(newOIDCConfig(OIDCIssuerConfig{IssuerURL: issuerURL}))
+	require.NoError(t, err)
+
+	token := jwtTestGenerateRSAToken(t, rsaKey, "k1", oidcTestClaims(issuerURL, jwt.MapClaims{"name": "Ada"}))
+	_, err = v.Validate(context.Background(), token)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+}
+
+func TestValidate_OIDCIssuerAlgorithms(t *testing.T) {
+	t.Parallel()
+	rsaKey, rsaPub := jwtTestGenerateRSAKeyPair(t)
+	issuerURL := oidcTestIssuer(t, map[string]*rsa.PublicKey{"k1": rsaPub}, nil)
+	v, err := NewJWTValidator(newOIDCConfig(OIDCIssuerConfig{
+		IssuerURL: issuerURL, AllowedAlgorithms: []string{"ES256"},
+	}))
+	require.NoError(t, err)
+
+	token := jwtTestGenerateRSAToken(t, rsaKey, "k1", oidcTestClaims(issuerURL, jwt.MapClaims{"sub": "u-1"}))
+	_, err = v.Validate(context.Background(), token)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+}
+
+func TestValidate_OIDCUnknownIssuer(t *testing.T) {
+	t.Parallel()
+	rsaKey, rsaPub := jwtTestGenerateRSAKeyPair(t)
+	issuerURL := oidcTestIssuer(t, map[string]*rsa.PublicKey{"k1": rsaPub}, nil)
+	v, err := NewJWTValidator(newOIDCConfig(OIDCIssuerConfig{IssuerURL: issuerURL}))
+	require.NoError(t, err)
+
+	token := jwtTestGenerateRSAToken(t, rsaKey, "k1", oidcTestClaims("https://partner.example.com", jwt.MapClaims{"sub": "u-1"}))
+	_, err = v.Validate(context.Background(), token)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+}
+
+func TestClaimString(t *testing.T) {
+	t.Parallel()
+	claims := map[string]any{
+		"sub":                            "u-1",
+		"https://example.com/claims.uid": "namespaced",
+		"realm":                          map[string]any{"user_id": "nested"},
+		"count":                          float64(3),
+	}
+	assert.Equal(t, "u-1", claimString(claims, "sub"))
+	assert.Equal(t, "namespaced", claimString(claims, "https://example.com/claims.uid"))
+	assert.Equal(t, "nested", claimString(claims, "realm.user_id"))
+	assert.Empty(t, claimString(claims, "count"))
+	assert.Empty(t, claimString(claims, "realm.missing"))
+	assert.Empty(t, claimString(claims, "sub.child"))
+}
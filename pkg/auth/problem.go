@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+const (
+	// HeaderRequestID carries a caller-supplied correlation ID. When
+	// present on a rejected request, it is echoed in the error response;
+	// otherwise the trace ID or a generated ID is used.
+	HeaderRequestID = "x-request-id"
+
+	// ContentTypeProblemJSON is the media type of RFC 7807 problem
+	// details responses.
+	ContentTypeProblemJSON = "application/problem+json"
+
+	// ErrorDomain is the domain of the [errdetails.ErrorInfo] attached to
+	// gRPC errors returned by this package.
+	ErrorDomain = "auth.stricklysoft"
+
+	// problemTypePrefix prefixes the sserr code to form the problem type
+	// URI, e.g. "urn:stricklysoft:error:AUTH_002".
+	problemTypePrefix = "urn:stricklysoft:error:"
+
+	// maxRequestIDLength bounds the caller-supplied correlation ID that
+	// is echoed back, so a client cannot inflate error responses.
+	maxRequestIDLength = 128
+)
+
+// Problem is an RFC 7807 problem details object carrying a platform error
+// code. HTTP middleware in this package writes it as the body of every
+// error response with content type [ContentTypeProblemJSON].
+//
+// Clients should branch on Code rather than Status or Detail: for
+// example, [sserr.CodeAuthenticationExpired] means the token should be
+// refreshed and the request retried, while
+// [sserr.CodeAuthenticationInvalid] means retrying with the same
+// credentials will not help.
+type Problem struct {
+	// Type is a URI identifying the problem type, derived from Code.
+	Type string `json:"type"`
+
+	// Title is the HTTP status text.
+	Title string `json:"title"`
+
+	// Status is the HTTP status code.
+	Status int `json:"status"`
+
+	// Detail is the human-readable error message.
+	Detail string `json:"detail,omitempty"`
+
+	// Code is the machine-readable platform error code.
+	Code sserr.Code `json:"code"`
+
+	// CorrelationID identifies the request in server logs and traces.
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// NewProblem builds the [Problem] for err. Errors that are not a
+// [*sserr.Error] are reported as [sserr.CodeInternal] without their
+// message, since it may contain internal details.
+func NewProblem(err error, correlationID string) Problem {
+	e := publicError(err)
+	httpStatus := e.HTTPStatus()
+	return Problem{
+		Type:          problemTypePrefix + string(e.Code),
+		Title:         http.StatusText(httpStatus),
+		Status:        httpStatus,
+		Detail:        e.Message,
+		Code:          e.Code,
+		CorrelationID: correlationID,
+	}
+}
+
+// WriteProblem writes err to w as an RFC 7807 problem details response.
+// The correlation ID is taken from the request's [HeaderRequestID]
+// header, the active trace, or generated, and is also set as the
+// response's [HeaderRequestID] header. Authentication failures include a
+// WWW-Authenticate challenge as described in RFC 6750.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := NewProblem(err, correlationID(r.Context(), r.Header.Get(HeaderRequestID)))
+
+	h := w.Header()
+	h.Set("Content-Type", ContentTypeProblemJSON)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set(HeaderRequestID, problem.CorrelationID)
+	if problem.Status == http.StatusUnauthorized {
+		h.Set("WWW-Authenticate", bearerChallenge(problem))
+	}
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// bearerChallenge returns the RFC 6750 WWW-Authenticate value for an
+// authentication problem.
+func bearerChallenge(p Problem) string {
+	if p.Code == sserr.CodeAuthentication {
+		// No usable credentials were presented; RFC 6750 section 3.1
+		// says the challenge should then carry no error code.
+		return "Bearer"
+	}
+	desc := strings.NewReplacer(`\`, ``, `"`, `'`).Replace(p.Detail)
+	return fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, desc)
+}
+
+// GRPCStatus converts err into a gRPC status error. The status code is
+// derived from the error's category, and the status carries an
+// [errdetails.ErrorInfo] whose Reason is the platform error code, plus an
+// [errdetails.RequestInfo] with the correlation ID. The correlation ID is
+// taken from the incoming [HeaderRequestID] metadata, the active trace,
+// or generated. Errors that are not a [*sserr.Error] are reported as
+// [codes.Internal] without their message.
+func GRPCStatus(ctx context.Context, err error) error {
+	e := publicError(err)
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(HeaderRequestID); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	id := correlationID(ctx, requestID)
+
+	st := status.New(grpcCode(e.Code), e.Message)
+	withDetails, detailErr := st.WithDetails(
+		&errdetails.ErrorInfo{
+			Reason:   string(e.Code),
+			Domain:   ErrorDomain,
+			Metadata: map[string]string{"correlation_id": id},
+		},
+		&errdetails.RequestInfo{RequestId: id},
+	)
+	if detailErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// ErrorCodeFromStatus returns the platform error code carried by a gRPC
+// status error created by [GRPCStatus]. It lets gRPC clients distinguish,
+// for example, an expired token from a malformed one. Returns false if
+// err carries no [errdetails.ErrorInfo] in [ErrorDomain].
+func ErrorCodeFromStatus(err error) (sserr.Code, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return "", false
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == ErrorDomain {
+			return sserr.Code(info.GetReason()), true
+		}
+	}
+	return "", false
+}
+
+// publicError returns err as a [*sserr.Error] safe to expose to clients.
+func publicError(err error) *sserr.Error {
+	if e, ok := sserr.AsError(err); ok {
+		return e
+	}
+	return sserr.New(sserr.CodeInternal, "internal error")
+}
+
+// grpcCode maps a platform error code onto a gRPC status code.
+func grpcCode(code sserr.Code) codes.Code {
+	switch code.Category() {
+	case "VAL":
+		return codes.InvalidArgument
+	case "AUTH":
+		return codes.Unauthenticated
+	case "AUTHZ":
+		return codes.PermissionDenied
+	case "NF":
+		return codes.NotFound
+	case "CONF":
+		return codes.Aborted
+	case "UNAVAIL":
+		return codes.Unavailable
+	case "TIMEOUT":
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// correlationID returns requestID if it is usable, otherwise the trace ID
+// of the active span, otherwise a random ID.
+func correlationID(ctx context.Context, requestID string) string {
+	if validRequestID(requestID) {
+		return requestID
+	}
+	if traceID, ok := TraceIDFromContext(ctx); ok {
+		return traceID
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID reports whether a caller-supplied request ID is safe to
+// echo in headers, bodies, and logs: non-empty, bounded, and limited to
+// printable ASCII without quotes or backslashes.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= ' ' || c > '~' || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// authenticationError returns the error to report for a failed token
+// validation. Platform errors from the validator pass through so that
+// clients see the precise code; anything else becomes a generic
+// [sserr.CodeAuthentication] error.
+func authenticationError(err error) *sserr.Error {
+	if e, ok := sserr.AsError(err); ok {
+		return e
+	}
+	return sserr.Wrap(err, sserr.CodeAuthentication, "auth: token validation failed")
+}
+
+// propagationError returns the error to report for propagated identity
+// headers that failed verification. A failing nonce store stays
+// [sserr.CodeUnavailable] so the caller retries; every other failure is
+// reported as [sserr.CodeAuthenticationInvalid], since the caller's own
+// token is not at fault.
+func propagationError(err error) *sserr.Error {
+	if sserr.IsUnavailable(err) {
+		return sserr.Wrap(err, sserr.CodeUnavailable, "auth: unable to verify propagated identity headers")
+	}
+	return sserr.Wrap(err, sserr.CodeAuthenticationInvalid, "auth: invalid propagated identity headers")
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// serveProblem runs HTTPMiddleware with validator and returns the response
+// and decoded problem body.
+func serveProblem(t *testing.T, validator TokenValidator, header http.Header) (*httptest.ResponseRecorder, Problem) {
+	t.Helper()
+	handler := HTTPMiddleware(validator, "test-service")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("inner handler should not be called")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, ContentTypeProblemJSON, rr.Header().Get("Content-Type"))
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, rr.Code, problem.Status)
+	assert.Equal(t, http.StatusText(rr.Code), problem.Title)
+	assert.Equal(t, "urn:stricklysoft:error:"+string(problem.Code), problem.Type)
+	assert.Equal(t, rr.Header().Get(HeaderRequestID), problem.CorrelationID)
+	return rr, problem
+}
+
+// ---------------------------------------------------------------------------
+// HTTP problem details
+// ---------------------------------------------------------------------------
+
+func TestHTTPMiddleware_ProblemCodes(t *testing.T) {
+	t.Parallel()
+	bearer := http.Header{"Authorization": {"Bearer token"}}
+	tests := []struct {
+		name      string
+		validator *mockValidator
+		header    http.Header
+		status    int
+		code      sserr.Code
+		challenge string
+	}{
+		{
+			name:      "missing header",
+			validator: &mockValidator{identity: newTestIdentity()},
+			status:    http.StatusUnauthorized,
+			code:      sserr.CodeAuthentication,
+			challenge: "Bearer",
+		},
+		{
+			name:      "expired token",
+			validator: &mockValidator{err: sserr.New(sserr.CodeAuthenticationExpired, "auth: token has expired")},
+			header:    bearer,
+			status:    http.StatusUnauthorized,
+			code:      sserr.CodeAuthenticationExpired,
+			challenge: `Bearer error="invalid_token", error_description="auth: token has expired"`,
+		},
+		{
+			name:      "malformed token",
+			validator: &mockValidator{err: sserr.New(sserr.CodeAuthenticationInvalid, `auth: token is "malformed"`)},
+			header:    bearer,
+			status:    http.StatusUnauthorized,
+			code:      sserr.CodeAuthenticationInvalid,
+			challenge: `Bearer error="invalid_token", error_description="auth: token is 'malformed'"`,
+		},
+		{
+			name:      "plain validator error",
+			validator: &mockValidator{err: errors.New("secret internal detail")},
+			header:    bearer,
+			status:    http.StatusUnauthorized,
+			code:      sserr.CodeAuthentication,
+			challenge: "Bearer",
+		},
+		{
+			name:      "validator unavailable",
+			validator: &mockValidator{err: sserr.New(sserr.CodeUnavailable, "auth: JWKS endpoint unavailable")},
+			header:    bearer,
+			status:    http.StatusServiceUnavailable,
+			code:      sserr.CodeUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			rr, problem := serveProblem(t, tt.validator, tt.header)
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.challenge, rr.Header().Get("WWW-Authenticate"))
+			assert.NotContains(t, rr.Body.String(), "secret internal detail")
+		})
+	}
+}
+
+func TestWriteProblem_CorrelationID(t *testing.T) {
+	t.Parallel()
+	validator := &mockValidator{err: sserr.New(sserr.CodeAuthenticationExpired, "auth: token has expired")}
+
+	_, problem := serveProblem(t, validator, http.Header{
+		"Authorization": {"Bearer token"},
+		"X-Request-Id":  {"req-123"},
+	})
+	assert.Equal(t, "req-123", problem.CorrelationID)
+
+	// Unsafe request IDs are replaced with a generated one.
+	_, problem = serveProblem(t, validator, http.Header{
+		"Authorization": {"Bearer token"},
+		"X-Request-Id":  {`bad"id`},
+	})
+	assert.Len(t, problem.CorrelationID, 32)
+}
+
+func TestNewProblem_HidesNonPlatformErrors(t *testing.T) {
+	t.Parallel()
+	problem := NewProblem(errors.New("dial tcp 10.0.0.1:5432: refused"), "id")
+	assert.Equal(t, sserr.CodeInternal, problem.Code)
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+	assert.Equal(t, "internal error", problem.Detail)
+}
+
+func TestRequirePermission_Problem(t *testing.T) {
+	t.Parallel()
+	handler := RequirePermission("executions", "delete", WithScopeExtractor(ScopeFromQuery("env")))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodDelete, "/?env=production", nil)
+	req = req.WithContext(ContextWithIdentity(req.Context(), newAuthzTestIdentity(t)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Empty(t, rr.Header().Get("WWW-Authenticate"))
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, sserr.CodeAuthorizationInsufficientScope, problem.Code)
+}
+
+// ---------------------------------------------------------------------------
+// gRPC status details
+// ---------------------------------------------------------------------------
+
+func TestUnaryServerInterceptor_StatusDetails(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+		code sserr.Code
+	}{
+		{"expired", sserr.New(sserr.CodeAuthenticationExpired, "auth: token has expired"), codes.Unauthenticated, sserr.CodeAuthenticationExpired},
+		{"malformed", sserr.New(sserr.CodeAuthenticationInvalid, "auth: token is malformed"), codes.Unauthenticated, sserr.CodeAuthenticationInvalid},
+		{"plain error", errors.New("boom"), codes.Unauthenticated, sserr.CodeAuthentication},
+		{"unavailable", sserr.New(sserr.CodeUnavailable, "auth: JWKS endpoint unavailable"), codes.Unavailable, sserr.CodeUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			interceptor := UnaryServerInterceptor(&mockValidator{err: tt.err}, "test-service")
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				"authorization", "Bearer token",
+				HeaderRequestID, "req-123",
+			))
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+				func(ctx context.Context, req any) (any, error) {
+					t.Error("handler should not be called")
+					return nil, nil
+				})
+
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, st.Code())
+			code, ok := ErrorCodeFromStatus(err)
+			require.True(t, ok)
+			assert.Equal(t, tt.code, code)
+			assert.NotContains(t, st.Message(), "boom")
+
+			var requestID string
+			for _, d := range st.Details() {
+				if info, ok := d.(*errdetails.RequestInfo); ok {
+					requestID = info.GetRequestId()
+				}
+			}
+			assert.Equal(t, "req-123", requestID)
+		})
+	}
+}
+
+func TestGRPCStatus_Codes(t *testing.T) {
+	t.Parallel()
+	tests := map[sserr.Code]codes.Code{
+		sserr.CodeValidation:          codes.InvalidArgument,
+		sserr.CodeAuthentication:      codes.Unauthenticated,
+		sserr.CodeAuthorizationDenied: codes.PermissionDenied,
+		sserr.CodeNotFound:            codes.NotFound,
+		sserr.CodeConflict:            codes.Aborted,
+		sserr.CodeInternal:            codes.Internal,
+		sserr.CodeUnavailable:         codes.Unavailable,
+		sserr.CodeTimeout:             codes.DeadlineExceeded,
+	}
+	for code, want := range tests {
+		err := GRPCStatus(context.Background(), sserr.New(code, "msg"))
+		assert.Equal(t, want, status.Code(err), "code %s", code)
+	}
+
+	_, ok := ErrorCodeFromStatus(status.Error(codes.Internal, "plain"))
+	assert.False(t, ok)
+	_, ok = ErrorCodeFromStatus(errors.New("not a status"))
+	assert.False(t, ok)
+}
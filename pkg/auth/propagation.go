@@ -1,11 +1,19 @@
 package auth
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"sort"
 	"strings"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/lifecycle"
 )
 
 // Header and metadata key constants for identity propagation.
@@ -38,6 +46,16 @@ const (
 	// Do not include sensitive data (passwords, secrets) in claims.
 	HeaderIdentityClaims = "x-identity-claims"
 
+	// HeaderIdentity carries the identity as a typed envelope produced by
+	// [SerializeIdentity], from which the receiving service rebuilds the
+	// concrete identity type, including its permissions and type-specific
+	// fields such as a user's email. It is sent alongside the headers
+	// above, which older services keep reading.
+	//
+	// Security: The envelope carries permissions. Trust it only when the
+	// propagated headers are signed (see [WithPropagationVerifier]).
+	HeaderIdentity = "x-identity"
+
 	// HeaderCallerService carries the name of the service that forwarded the
 	// request. This allows the receiving service to identify its immediate
 	// upstream caller for audit and authorization purposes.
@@ -143,7 +161,8 @@ func SerializeCallChain(chain *CallChain) (string, error) {
 
 // DeserializeCallChain decodes a base64url-encoded JSON string into a CallChain.
 // Returns nil if the encoded string is empty.
-// Returns an error if the string cannot be decoded or parsed.
+// Returns an error if the string cannot be decoded or parsed, or if the
+// chain's truncated count is negative.
 func DeserializeCallChain(encoded string) (*CallChain, error) {
 	if encoded == "" {
 		return nil, nil
@@ -156,12 +175,274 @@ func DeserializeCallChain(encoded string) (*CallChain, error) {
 	if err := json.Unmarshal(data, &chain); err != nil {
 		return nil, fmt.Errorf("auth: failed to unmarshal call chain: %w", err)
 	}
+	if chain.Truncated < 0 {
+		return nil, fmt.Errorf("auth: call chain truncated count %d is negative", chain.Truncated)
+	}
 	return &chain, nil
 }
 
+// Identity record kinds, naming the concrete [Identity] type a record
+// rebuilds.
+const (
+	identityKindBasic   = "basic"
+	identityKindService = "service"
+	identityKindUser    = "user"
+	identityKindAgent   = "agent"
+)
+
+// errUnsupportedIdentity is returned when serializing an [Identity]
+// implemented outside this package, whose concrete type cannot be
+// rebuilt.
+var errUnsupportedIdentity = errors.New("auth: unsupported identity type")
+
+// errIdentityTooLarge is returned when a serialized [Identity] does not
+// fit [MaxHeaderValueSize] even without its prunable claims, typically
+// because of a long permission list.
+var errIdentityTooLarge = errors.New("auth: serialized identity is too large")
+
+// identityRecord is the serialized form of an [Identity] that preserves
+// its concrete type, so that the identity rebuilt from it grants the same
+// permissions as the original.
+type identityRecord struct {
+	Kind         string                 `json:"kind"`
+	ID           string                 `json:"id"`
+	Type         IdentityType           `json:"type,omitempty"`
+	Claims       map[string]any         `json:"claims,omitempty"`
+	Permissions  []Permission           `json:"permissions,omitempty"`
+	ServiceName  string                 `json:"service_name,omitempty"`
+	Namespace    string                 `json:"namespace,omitempty"`
+	Email        string                 `json:"email,omitempty"`
+	DisplayName  string                 `json:"display_name,omitempty"`
+	Name         string                 `json:"name,omitempty"`
+	Version      string                 `json:"version,omitempty"`
+	Capabilities []lifecycle.Capability `json:"capabilities,omitempty"`
+	OnBehalfOf   *identityRecord        `json:"on_behalf_of,omitempty"`
+}
+
+// newIdentityRecord returns the record of identity. Returns an error
+// wrapping errUnsupportedIdentity if identity is not one of the identity
+// types of this package.
+func newIdentityRecord(identity Identity) (identityRecord, error) {
+	switch id := identity.(type) {
+	case *BasicIdentity:
+		return identityRecord{
+			Kind:   identityKindBasic,
+			ID:     id.ID(),
+			Type:   id.Type(),
+			Claims: id.Claims(),
+		}, nil
+	case *ServiceIdentity:
+		return identityRecord{
+			Kind:        identityKindService,
+			ID:          id.ID(),
+			Claims:      id.Claims(),
+			Permissions: id.Permissions(),
+			ServiceName: id.ServiceName(),
+			Namespace:   id.Namespace(),
+		}, nil
+	case *UserIdentity:
+		return identityRecord{
+			Kind:        identityKindUser,
+			ID:          id.ID(),
+			Claims:      id.Claims(),
+			Permissions: id.Permissions(),
+			Email:       id.Email(),
+			DisplayName: id.DisplayName(),
+		}, nil
+	case *AgentIdentity:
+		record := identityRecord{
+			Kind:         identityKindAgent,
+			ID:           id.ID(),
+			Claims:       id.Claims(),
+			Permissions:  id.AgentPermissions(),
+			Name:         id.Name(),
+			Version:      id.Version(),
+			Capabilities: id.Capabilities(),
+		}
+		if id.OnBehalfOf() != nil {
+			onBehalfOf, err := newIdentityRecord(id.OnBehalfOf())
+			if err != nil {
+				return identityRecord{}, err
+			}
+			record.OnBehalfOf = &onBehalfOf
+		}
+		return record, nil
+	default:
+		return identityRecord{}, fmt.Errorf("%w %T", errUnsupportedIdentity, identity)
+	}
+}
+
+// identity rebuilds the identity described by r.
+func (r identityRecord) identity() (Identity, error) {
+	switch r.Kind {
+	case identityKindBasic:
+		if !r.Type.Valid() {
+			return nil, fmt.Errorf("auth: invalid identity type %q", r.Type)
+		}
+		return NewBasicIdentity(r.ID, r.Type, r.Claims), nil
+	case identityKindService:
+		return NewServiceIdentity(r.ID, r.ServiceName, r.Namespace, r.Claims, r.Permissions)
+	case identityKindUser:
+		return NewUserIdentity(r.ID, r.Email, r.DisplayName, r.Claims, r.Permissions)
+	case identityKindAgent:
+		var onBehalfOf Identity
+		if r.OnBehalfOf != nil {
+			var err error
+			if onBehalfOf, err = r.OnBehalfOf.identity(); err != nil {
+				return nil, err
+			}
+		}
+		info := lifecycle.AgentInfo{ID: r.ID, Name: r.Name, Version: r.Version, Capabilities: r.Capabilities}
+		return NewAgentIdentity(info, r.Claims, r.Permissions, onBehalfOf)
+	default:
+		return nil, fmt.Errorf("auth: unknown identity kind %q", r.Kind)
+	}
+}
+
+// compressedIdentityPrefix marks a [SerializeIdentity] value whose JSON
+// is gzip-compressed. The "." cannot occur in base64url, so the prefix
+// never begins an uncompressed value.
+const compressedIdentityPrefix = "gz."
+
+// maxIdentityJSONSize bounds the decompressed size of a serialized
+// identity, so that a small compressed header cannot expand without
+// limit.
+const maxIdentityJSONSize = 8 * MaxHeaderValueSize
+
+// retainedClaims lists the claims [SerializeIdentity] never prunes: the
+// registered JWT claims that identify the token and its subject, and the
+// delegation actor.
+var retainedClaims = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true,
+	"nbf": true, "iat": true, "jti": true, "act": true,
+}
+
+// SerializeIdentity encodes identity as a typed envelope for the
+// [HeaderIdentity] header, preserving its concrete type ([BasicIdentity],
+// [ServiceIdentity], [UserIdentity], or [AgentIdentity]), permissions,
+// and type-specific fields; an agent's delegating identity is encoded
+// with it. The envelope is base64url-encoded JSON, gzip-compressed if
+// that is needed to fit [MaxHeaderValueSize]. If it still does not fit,
+// claims are dropped, largest first, until it does; only the agent's own
+// claims are dropped, never those of its delegating identity. The
+// registered JWT claims (iss, sub, aud, exp, nbf, iat, jti) and the
+// delegation actor (act) are always kept, as are the permissions.
+//
+// Returns an empty string if identity is nil.
+// Returns an error if identity is implemented outside this package, if it
+// cannot be marshaled to JSON, or if it does not fit even without its
+// prunable claims.
+func SerializeIdentity(identity Identity) (string, error) {
+	if identity == nil {
+		return "", nil
+	}
+	record, err := newIdentityRecord(identity)
+	if err != nil {
+		return "", err
+	}
+
+	// Claims that may be pruned, largest first.
+	var prunable []string
+	sizes := make(map[string]int, len(record.Claims))
+	for k, v := range record.Claims {
+		if retainedClaims[k] {
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("auth: failed to marshal identity: %w", err)
+		}
+		prunable = append(prunable, k)
+		sizes[k] = len(k) + len(data)
+	}
+	sort.Slice(prunable, func(i, j int) bool {
+		if sizes[prunable[i]] != sizes[prunable[j]] {
+			return sizes[prunable[i]] > sizes[prunable[j]]
+		}
+		return prunable[i] < prunable[j]
+	})
+
+	for {
+		encoded, err := encodeIdentityRecord(record)
+		if err != nil {
+			return "", err
+		}
+		if len(encoded) <= MaxHeaderValueSize {
+			return encoded, nil
+		}
+		if len(prunable) == 0 {
+			return "", fmt.Errorf("%w: size %d exceeds maximum %d bytes", errIdentityTooLarge, len(encoded), MaxHeaderValueSize)
+		}
+		delete(record.Claims, prunable[0])
+		prunable = prunable[1:]
+	}
+}
+
+// encodeIdentityRecord encodes record as base64url JSON, compressing it if
+// the uncompressed form exceeds [MaxHeaderValueSize].
+func encodeIdentityRecord(record identityRecord) (string, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("auth: failed to marshal identity: %w", err)
+	}
+	if base64.RawURLEncoding.EncodedLen(len(data)) <= MaxHeaderValueSize {
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", fmt.Errorf("auth: failed to compress identity: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("auth: failed to compress identity: %w", err)
+	}
+	return compressedIdentityPrefix + base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DeserializeIdentity decodes an identity encoded by [SerializeIdentity],
+// rebuilding its concrete type.
+// Returns nil if the encoded string is empty.
+// Returns an error if the string cannot be decoded or parsed, or does
+// not describe a valid identity.
+func DeserializeIdentity(encoded string) (Identity, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	compressed := strings.HasPrefix(encoded, compressedIdentityPrefix)
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(encoded, compressedIdentityPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("auth: failed to decode identity: %w", err)
+	}
+	if compressed {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("auth: failed to decompress identity: %w", err)
+		}
+		data, err = io.ReadAll(io.LimitReader(zr, maxIdentityJSONSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("auth: failed to decompress identity: %w", err)
+		}
+		if len(data) > maxIdentityJSONSize {
+			return nil, fmt.Errorf("auth: decompressed identity exceeds maximum %d bytes", maxIdentityJSONSize)
+		}
+	}
+	var record identityRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("auth: failed to unmarshal identity: %w", err)
+	}
+	return record.identity()
+}
+
 // identityToHeaders extracts identity information into a set of key-value
 // pairs suitable for use as HTTP headers or gRPC metadata.
 // Returns nil if identity is nil.
+//
+// Identities of the types of this package are also sent as a typed
+// envelope in [HeaderIdentity]. Claims too large for [HeaderIdentityClaims]
+// are then sent only in the envelope. An envelope too large to send, such
+// as for an identity with many permissions, is omitted and logged; the
+// remaining headers are still sent, and the receiver rebuilds a
+// [BasicIdentity] from them.
 func identityToHeaders(identity Identity, callerService string, chain *CallChain) (map[string]string, error) {
 	if identity == nil {
 		return nil, nil
@@ -172,13 +453,30 @@ func identityToHeaders(identity Identity, callerService string, chain *CallChain
 		HeaderIdentityType: string(identity.Type()),
 	}
 
+	// Serialize the typed envelope unless the identity type is foreign.
+	encoded, err := SerializeIdentity(identity)
+	switch {
+	case err == nil:
+		headers[HeaderIdentity] = encoded
+	case errors.Is(err, errIdentityTooLarge):
+		slog.Warn("auth: identity envelope too large to propagate, sending identity headers only",
+			"error", err,
+			"identity_id", identity.ID(),
+			"identity_type", string(identity.Type()),
+		)
+	case !errors.Is(err, errUnsupportedIdentity):
+		return nil, err
+	}
+
 	// Serialize claims if present.
 	if claims := identity.Claims(); len(claims) > 0 {
 		encoded, err := SerializeClaims(claims)
-		if err != nil {
+		switch {
+		case err == nil:
+			headers[HeaderIdentityClaims] = encoded
+		case headers[HeaderIdentity] == "":
 			return nil, err
 		}
-		headers[HeaderIdentityClaims] = encoded
 	}
 
 	// Include caller service if set.
@@ -203,6 +501,9 @@ func identityToHeaders(identity Identity, callerService string, chain *CallChain
 //
 // The getValue function retrieves a single value for a given key.
 // Returns nil identity if no identity ID is found in the headers.
+// If [HeaderIdentity] is present, the identity is rebuilt from it with its
+// concrete type, and must match the identity ID and type headers;
+// otherwise a [BasicIdentity] is built from the individual headers.
 type headerGetter func(key string) string
 
 func identityFromHeaders(getValue headerGetter) (Identity, string, *CallChain, error) {
@@ -212,6 +513,106 @@ func identityFromHeaders(getValue headerGetter) (Identity, string, *CallChain, e
 	}
 
 	idType := IdentityType(getValue(HeaderIdentityType))
+
+	var identity Identity
+	if encoded := getValue(HeaderIdentity); encoded != "" {
+		var err error
+		identity, err = DeserializeIdentity(encoded)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("auth: invalid propagated identity: %w", err)
+		}
+		if identity.ID() != id || identity.Type() != idType {
+			return nil, "", nil, errors.New("auth: propagated identity does not match identity headers")
+		}
+	} else {
+		var err error
+		identity, err = basicIdentityFromHeaders(id, idType, getValue)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	// Extract caller service.
+	callerService := getValue(HeaderCallerService)
+
+	// Deserialize call chain.
+	var chain *CallChain
+	if encoded := getValue(HeaderCallChain); encoded != "" {
+		var err error
+		chain, err = DeserializeCallChain(encoded)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("auth: invalid propagated call chain: %w", err)
+		}
+	}
+
+	return identity, callerService, chain, nil
+}
+
+// verifiedIdentity returns validated, the identity authenticated by the
+// request's own credentials, with the descriptive fields of the
+// [HeaderIdentity] envelope read through getValue if the envelope names
+// the same identity: the concrete type and the email and display name of
+// a [UserIdentity], the service name and namespace of a
+// [ServiceIdentity], or the name, version, and capabilities of an
+// [AgentIdentity]. Claims and permissions always come from validated,
+// since the envelope is built by the immediate caller, which could
+// otherwise grant itself, or the user whose token it forwards, any
+// permission; for the same reason, an agent's delegating identity is
+// never taken from the envelope (see [TokenIssuer.Delegate]). It must
+// only be called once the headers have been verified by a
+// [PropagationVerifier].
+func verifiedIdentity(ctx context.Context, validated Identity, getValue headerGetter, serviceName string) Identity {
+	if getValue(HeaderIdentity) == "" {
+		return validated
+	}
+	envelope, _, _, err := identityFromHeaders(getValue)
+	if err == nil && (envelope.ID() != validated.ID() || envelope.Type() != validated.Type()) {
+		return validated
+	}
+	var described Identity
+	if err == nil {
+		described, err = describeIdentity(envelope, validated)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "auth: ignoring invalid propagated identity envelope",
+			"error", err,
+			"service", serviceName,
+		)
+		return validated
+	}
+	return described
+}
+
+// describeIdentity rebuilds validated as the concrete type of envelope,
+// which names the same identity, taking only descriptive fields from
+// envelope. Returns validated unchanged if envelope is a [BasicIdentity].
+func describeIdentity(envelope, validated Identity) (Identity, error) {
+	claims := validated.Claims()
+	var permissions []Permission
+	if lister, ok := validated.(PermissionLister); ok {
+		permissions = lister.Permissions()
+	}
+	switch e := envelope.(type) {
+	case *ServiceIdentity:
+		return NewServiceIdentity(validated.ID(), e.ServiceName(), e.Namespace(), claims, permissions)
+	case *UserIdentity:
+		return NewUserIdentity(validated.ID(), e.Email(), e.DisplayName(), claims, permissions)
+	case *AgentIdentity:
+		info := lifecycle.AgentInfo{ID: validated.ID(), Name: e.Name(), Version: e.Version(), Capabilities: e.Capabilities()}
+		if agent, ok := validated.(*AgentIdentity); ok {
+			// Keep the delegation established by the credentials.
+			return NewAgentIdentity(info, claims, agent.AgentPermissions(), agent.OnBehalfOf())
+		}
+		return NewAgentIdentity(info, claims, permissions, nil)
+	default:
+		return validated, nil
+	}
+}
+
+// basicIdentityFromHeaders builds a [BasicIdentity] from the identity ID,
+// type, and claims headers, as sent by services that predate
+// [HeaderIdentity].
+func basicIdentityFromHeaders(id string, idType IdentityType, getValue headerGetter) (Identity, error) {
 	if !idType.Valid() {
 		// Default to service identity if type is missing or invalid,
 		// since service-to-service propagation is the most common case.
@@ -232,24 +633,9 @@ func identityFromHeaders(getValue headerGetter) (Identity, string, *CallChain, e
 		var err error
 		claims, err = DeserializeClaims(encoded)
 		if err != nil {
-			return nil, "", nil, fmt.Errorf("auth: invalid propagated claims: %w", err)
+			return nil, fmt.Errorf("auth: invalid propagated claims: %w", err)
 		}
 	}
 
-	identity := NewBasicIdentity(id, idType, claims)
-
-	// Extract caller service.
-	callerService := getValue(HeaderCallerService)
-
-	// Deserialize call chain.
-	var chain *CallChain
-	if encoded := getValue(HeaderCallChain); encoded != "" {
-		var err error
-		chain, err = DeserializeCallChain(encoded)
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("auth: invalid propagated call chain: %w", err)
-		}
-	}
-
-	return identity, callerService, chain, nil
+	return NewBasicIdentity(id, idType, claims), nil
 }
@@ -1,9 +1,15 @@
 package auth
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"testing"
 
@@ -205,6 +211,13 @@ func TestDeserializeCallChain_InvalidJSON(t *testing.T) {
 	require.Error(t, err, "DeserializeCallChain did not return error for invalid JSON")
 }
 
+func TestDeserializeCallChain_NegativeTruncated(t *testing.T) {
+	t.Parallel()
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(`{"original_id":"user-42","truncated":-1}`))
+	_, err := DeserializeCallChain(encoded)
+	require.Error(t, err)
+}
+
 func TestSerializeCallChain_ExceedsMaxHeaderSize(t *testing.T) {
 	t.Parallel()
 	// Create a call chain with very long service names to exceed the limit.
@@ -237,6 +250,145 @@ func TestSerializeCallChain_Base64URLSafe(t *testing.T) {
 	assert.False(t, strings.ContainsAny(encoded, "+/="), "encoded call chain contains non-URL-safe characters: %q", encoded)
 }
 
+// randomClaim returns a string of n random base64url characters, which
+// compresses poorly.
+func randomClaim(t *testing.T, n int) string {
+	t.Helper()
+	raw := make([]byte, n*3/4)
+	_, err := rand.Read(raw)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ---------------------------------------------------------------------------
+// SerializeIdentity / DeserializeIdentity
+// ---------------------------------------------------------------------------
+
+func TestSerializeIdentity_RoundTrip(t *testing.T) {
+	t.Parallel()
+	perms := []Permission{
+		{Resource: "documents", Action: "read", Scope: "production"},
+		{Resource: "documents", Action: "write", Condition: "owner == claims.sub"},
+	}
+	claims := map[string]any{"sub": "id-1", "roles": []any{"admin"}, "exp": float64(1700000000)}
+	service, err := NewServiceIdentity("svc-1", "nexus-gateway", "platform", claims, perms)
+	require.NoError(t, err)
+	user, err := NewUserIdentity("usr-1", "ada@example.com", "Ada", claims, perms)
+	require.NoError(t, err)
+	agent, err := NewAgentIdentity(newTestAgentInfo(), claims, []Permission{{Resource: "*", Action: "read"}}, user)
+	require.NoError(t, err)
+
+	for _, identity := range []Identity{NewBasicIdentity("sys-1", IdentityTypeSystem, claims), service, user, agent} {
+		encoded, err := SerializeIdentity(identity)
+		require.NoError(t, err)
+		assert.False(t, strings.ContainsAny(encoded, "+/="), "encoded identity contains non-URL-safe characters: %q", encoded)
+		got, err := DeserializeIdentity(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, identity, got)
+	}
+
+	encoded, err := SerializeIdentity(nil)
+	require.NoError(t, err)
+	assert.Empty(t, encoded)
+	got, err := DeserializeIdentity("")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestSerializeIdentity_Compresses(t *testing.T) {
+	t.Parallel()
+	groups := make([]any, 500)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("engineering-platform-team-%03d", i)
+	}
+	user, err := NewUserIdentity("usr-1", "ada@example.com", "Ada", map[string]any{"groups": groups}, nil)
+	require.NoError(t, err)
+
+	encoded, err := SerializeIdentity(user)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, compressedIdentityPrefix), "expected a compressed envelope")
+	assert.LessOrEqual(t, len(encoded), MaxHeaderValueSize)
+	got, err := DeserializeIdentity(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, user, got)
+}
+
+func TestSerializeIdentity_PrunesClaims(t *testing.T) {
+	t.Parallel()
+	perms := []Permission{{Resource: "documents", Action: "read"}}
+	user, err := NewUserIdentity("usr-1", "ada@example.com", "Ada", map[string]any{
+		"sub":     "usr-1",
+		"jti":     "t-1",
+		"tenant":  "acme",
+		"profile": randomClaim(t, 6000),
+		"avatar":  randomClaim(t, 4000),
+		"notes":   randomClaim(t, 1000),
+	}, perms)
+	require.NoError(t, err)
+
+	encoded, err := SerializeIdentity(user)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(encoded), MaxHeaderValueSize)
+	got, err := DeserializeIdentity(encoded)
+	require.NoError(t, err)
+	gotUser, ok := got.(*UserIdentity)
+	require.True(t, ok, "type = %T", got)
+	assert.Equal(t, perms, gotUser.Permissions())
+	assert.Equal(t, "ada@example.com", gotUser.Email())
+
+	claims := gotUser.Claims()
+	assert.NotContains(t, claims, "profile", "the largest claim should be pruned")
+	assert.Contains(t, claims, "avatar", "pruning should stop once the envelope fits")
+	assert.Contains(t, claims, "notes")
+	assert.Equal(t, "acme", claims["tenant"])
+	assert.Equal(t, "usr-1", claims["sub"])
+	assert.Equal(t, "t-1", claims["jti"])
+}
+
+func TestSerializeIdentity_TooLarge(t *testing.T) {
+	t.Parallel()
+	identity := NewBasicIdentity("usr-1", IdentityTypeUser, map[string]any{"sub": randomClaim(t, 20000)})
+	_, err := SerializeIdentity(identity)
+	require.Error(t, err, "registered claims should not be pruned")
+	assert.Contains(t, err.Error(), "exceeds maximum")
+	assert.True(t, errors.Is(err, errIdentityTooLarge), "error = %v", err)
+}
+
+func TestSerializeIdentity_Unsupported(t *testing.T) {
+	t.Parallel()
+	_, err := SerializeIdentity(foreignIdentity{NewBasicIdentity("usr-1", IdentityTypeUser, nil)})
+	assert.True(t, errors.Is(err, errUnsupportedIdentity), "error = %v", err)
+}
+
+func TestDeserializeIdentity_Invalid(t *testing.T) {
+	t.Parallel()
+	encode := func(v any) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	_, err := zw.Write(make([]byte, 2*maxIdentityJSONSize))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	tests := map[string]string{
+		"invalid base64":       "!!! not valid base64 !!!",
+		"invalid json":         base64.RawURLEncoding.EncodeToString([]byte("{not json")),
+		"invalid gzip":         compressedIdentityPrefix + base64.RawURLEncoding.EncodeToString([]byte("not gzip")),
+		"decompression bomb":   compressedIdentityPrefix + base64.RawURLEncoding.EncodeToString(bomb.Bytes()),
+		"unknown kind":         encode(map[string]any{"kind": "robot", "id": "r-1"}),
+		"invalid basic type":   encode(map[string]any{"kind": "basic", "id": "x-1", "type": "robot"}),
+		"user without email":   encode(map[string]any{"kind": "user", "id": "usr-1"}),
+		"service without name": encode(map[string]any{"kind": "service", "id": "svc-1"}),
+	}
+	for name, encoded := range tests {
+		_, err := DeserializeIdentity(encoded)
+		assert.Error(t, err, name)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // identityToHeaders
 // ---------------------------------------------------------------------------
@@ -282,6 +434,61 @@ func TestIdentityToHeaders_NoClaims(t *testing.T) {
 	assert.False(t, exists, "HeaderCallChain should not be set for nil chain")
 }
 
+func TestIdentityToHeaders_Envelope(t *testing.T) {
+	t.Parallel()
+	user, err := NewUserIdentity("usr-1", "ada@example.com", "Ada",
+		map[string]any{"profile": randomClaim(t, 8000)}, nil)
+	require.NoError(t, err)
+
+	// Claims too large for their own header travel in the envelope only.
+	headers, err := identityToHeaders(user, "", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, headers[HeaderIdentity])
+	assert.NotContains(t, headers, HeaderIdentityClaims)
+
+	// Identities implemented elsewhere are sent without an envelope.
+	headers, err = identityToHeaders(foreignIdentity{NewBasicIdentity("usr-1", IdentityTypeUser, map[string]any{"role": "admin"})}, "", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, headers, HeaderIdentity)
+	assert.NotEmpty(t, headers[HeaderIdentityClaims])
+}
+
+func TestIdentityToHeaders_EnvelopeTooLarge(t *testing.T) {
+	t.Parallel()
+	// Add permissions until the envelope no longer fits in a header.
+	var perms []Permission
+	for {
+		perms = append(perms, Permission{Resource: randomClaim(t, 48), Action: "read"})
+		user := mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", map[string]any{"sub": "usr-1"}, perms)
+		if _, err := SerializeIdentity(user); err != nil {
+			require.True(t, errors.Is(err, errIdentityTooLarge), "error = %v", err)
+			break
+		}
+	}
+	user := mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", map[string]any{"sub": "usr-1"}, perms)
+	chain := &CallChain{OriginalID: "usr-1", OriginalType: IdentityTypeUser}
+
+	headers, err := identityToHeaders(user, "gateway", chain)
+	require.NoError(t, err, "an oversized envelope must not prevent propagation")
+	assert.NotContains(t, headers, HeaderIdentity)
+	assert.Equal(t, "usr-1", headers[HeaderIdentityID])
+	assert.Equal(t, string(IdentityTypeUser), headers[HeaderIdentityType])
+	assert.NotEmpty(t, headers[HeaderIdentityClaims])
+	assert.Equal(t, "gateway", headers[HeaderCallerService])
+	assert.NotEmpty(t, headers[HeaderCallChain])
+
+	// The identity still reaches a downstream service through the
+	// transport, as a BasicIdentity built from the legacy headers.
+	mock := &mockRoundTripper{response: &http.Response{StatusCode: http.StatusOK}}
+	rt := NewPropagatingRoundTripper("gateway", mock)
+	req, _ := http.NewRequestWithContext(ContextWithIdentity(context.Background(), user), http.MethodGet, "http://downstream/api", nil)
+	_, err = rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "usr-1", mock.capturedReq.Header.Get(HeaderIdentityID))
+	assert.Equal(t, string(IdentityTypeUser), mock.capturedReq.Header.Get(HeaderIdentityType))
+	assert.Empty(t, mock.capturedReq.Header.Get(HeaderIdentity))
+}
+
 // ---------------------------------------------------------------------------
 // identityFromHeaders
 // ---------------------------------------------------------------------------
@@ -316,6 +523,33 @@ func TestIdentityFromHeaders_RoundTrip(t *testing.T) {
 	require.Len(t, gotChain.Callers, 1)
 }
 
+func TestIdentityFromHeaders_ConcreteType(t *testing.T) {
+	t.Parallel()
+	perms := []Permission{{Resource: "documents", Action: "read"}}
+	original, err := NewServiceIdentity("svc-1", "nexus-gateway", "platform", map[string]any{"sub": "svc-1"}, perms)
+	require.NoError(t, err)
+	headers, err := identityToHeaders(original, "gateway", nil)
+	require.NoError(t, err)
+
+	identity, _, _, err := identityFromHeaders(mapGetter(headers))
+	require.NoError(t, err)
+	assert.Equal(t, original, identity)
+	assert.True(t, identity.HasPermission("documents", "read"))
+
+	// The envelope must describe the identity named by the other headers.
+	headers[HeaderIdentityID] = "svc-2"
+	_, _, _, err = identityFromHeaders(mapGetter(headers))
+	assert.Error(t, err)
+
+	// Without the envelope, a BasicIdentity is built as before.
+	headers[HeaderIdentityID] = "svc-1"
+	delete(headers, HeaderIdentity)
+	identity, _, _, err = identityFromHeaders(mapGetter(headers))
+	require.NoError(t, err)
+	assert.IsType(t, &BasicIdentity{}, identity)
+	assert.Equal(t, "svc-1", identity.Claims()["sub"])
+}
+
 func TestIdentityFromHeaders_NoHeaders(t *testing.T) {
 	t.Parallel()
 	getter := func(key string) string { return "" }
@@ -423,6 +657,7 @@ func TestHeaderConstants_Values(t *testing.T) {
 		{name: "identity-id", constant: HeaderIdentityID, expected: "x-identity-id"},
 		{name: "identity-type", constant: HeaderIdentityType, expected: "x-identity-type"},
 		{name: "identity-claims", constant: HeaderIdentityClaims, expected: "x-identity-claims"},
+		{name: "identity", constant: HeaderIdentity, expected: "x-identity"},
 		{name: "caller-service", constant: HeaderCallerService, expected: "x-caller-service"},
 		{name: "call-chain", constant: HeaderCallChain, expected: "x-call-chain"},
 	}
@@ -212,10 +212,32 @@ func ParseScopePermissions(scope string) []Permission {
 //	"deployments:write:prod"    -> Permission{Resource: "deployments", Action: "write", Scope: "prod"}
 //	"*:*:*"                     -> Permission{Resource: "*", Action: "*", Scope: "*"}
 //
+// Either format may be followed by a [Condition] in square brackets, which
+// sets the permission's Condition:
+//
+//	"documents:write[owner == claims.sub]"
+//	    -> Permission{Resource: "documents", Action: "write", Condition: "owner == claims.sub"}
+//
 // Returns an error if the string does not contain a colon separator, or
 // if either the resource or action part is empty after splitting. An empty
-// scope in three-part format (e.g., "docs:read:") is treated as an error.
+// scope in three-part format (e.g., "docs:read:") is treated as an error,
+// as is a condition that is empty or does not compile.
 func ParsePermissionString(s string) (Permission, error) {
+	var condition string
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		if !strings.HasSuffix(s, "]") {
+			return Permission{}, fmt.Errorf("auth: invalid permission string %q: unterminated condition", s)
+		}
+		condition = strings.TrimSpace(s[i+1 : len(s)-1])
+		if condition == "" {
+			return Permission{}, fmt.Errorf("auth: invalid permission string %q: empty condition", s)
+		}
+		if _, err := compiledCondition(condition); err != nil {
+			return Permission{}, err
+		}
+		s = s[:i]
+	}
+
 	parts := strings.SplitN(s, ":", 3)
 	if len(parts) < 2 {
 		return Permission{}, fmt.Errorf("auth: invalid permission string %q: missing colon separator", s)
@@ -239,7 +261,7 @@ func ParsePermissionString(s string) (Permission, error) {
 		}
 	}
 
-	return Permission{Resource: resource, Action: action, Scope: scope}, nil
+	return Permission{Resource: resource, Action: action, Scope: scope, Condition: condition}, nil
 }
 
 // FormatPermission returns the string representation of a [Permission] in
@@ -248,7 +270,8 @@ func ParsePermissionString(s string) (Permission, error) {
 // function value is needed (e.g., mapping, serialization pipelines).
 //
 // If the permission's Scope is empty or "*", the format is "resource:action".
-// Otherwise, the format is "resource:action:scope".
+// Otherwise, the format is "resource:action:scope". A Condition is appended
+// in square brackets.
 func FormatPermission(p Permission) string {
 	return p.String()
 }
@@ -281,6 +304,12 @@ type Role struct {
 	// Permissions is the set of resource/action/scope grants that this
 	// role provides. A role with no permissions grants no access.
 	Permissions []Permission
+
+	// Inherits names the roles whose permissions this role also grants
+	// (e.g., an operator role inheriting "viewer"). Inheritance is
+	// resolved by [RoleRegistry]; the methods of Role consider only
+	// Permissions.
+	Inherits []string
 }
 
 // HasPermission checks whether this role grants access to the specified
@@ -330,6 +359,9 @@ type resourceActionKey struct {
 //     stored in a map for O(1) lookup via [PermissionSet.Has].
 //   - Wildcard permissions (any field is "*") are stored in a slice for
 //     linear scanning when exact lookup misses.
+//   - Conditional permissions (with a Condition) are stored in a separate
+//     slice and considered only by [PermissionSet.MatchRequest], which
+//     has the request attributes needed to evaluate them.
 //
 // An additional scope-agnostic index (anyScope) enables O(1) lookups for
 // the common case where the check scope is "" or "*", which per the
@@ -357,6 +389,11 @@ type PermissionSet struct {
 	// These require linear scanning via Permission.Match().
 	wildcards []Permission
 
+	// conditional holds permissions with a Condition. They are excluded
+	// from exact, anyScope, and wildcards, so that only MatchRequest
+	// grants them.
+	conditional []Permission
+
 	// all holds the complete, ordered list of permissions for
 	// introspection via Permissions(). This preserves insertion order.
 	all []Permission
@@ -383,9 +420,12 @@ func NewPermissionSet(perms []Permission) *PermissionSet {
 
 		ps.all = append(ps.all, p)
 
-		if p.Resource == "*" || p.Action == "*" || p.Scope == "*" {
+		switch {
+		case p.Condition != "":
+			ps.conditional = append(ps.conditional, p)
+		case p.Resource == "*" || p.Action == "*" || p.Scope == "*":
 			ps.wildcards = append(ps.wildcards, p)
-		} else {
+		default:
 			ps.exact[p] = struct{}{}
 			ps.anyScope[resourceActionKey{Resource: p.Resource, Action: p.Action}] = struct{}{}
 		}
@@ -423,7 +463,8 @@ func (ps *PermissionSet) Has(resource, action, scope string) bool {
 //  4. Linear wildcard scan: falls back to scanning wildcard permissions.
 //
 // This is the recommended method for authorization decisions, as it
-// correctly handles both exact and wildcard permissions.
+// correctly handles both exact and wildcard permissions. Conditional
+// permissions are not considered; use [PermissionSet.MatchRequest].
 func (ps *PermissionSet) Match(resource, action, scope string) bool {
 	// Fast path 1: O(1) exact match for the full {resource, action, scope} tuple.
 	if ps.Has(resource, action, scope) {
@@ -456,6 +497,29 @@ func (ps *PermissionSet) Match(resource, action, scope string) bool {
 	return false
 }
 
+// MatchRequest checks whether the permission set grants req. It is
+// [PermissionSet.Match] for req's resource, action, and scope, extended to
+// conditional permissions, which are granted if their condition holds for
+// req (see [Permission.Allows]).
+func (ps *PermissionSet) MatchRequest(req AccessRequest) bool {
+	return ps.Match(req.Resource, req.Action, req.Scope) || ps.matchConditional(req, false)
+}
+
+// matchConditional reports whether a conditional permission in the set
+// allows req. If globalOnly is true, only permissions whose scope is "" or
+// "*" are considered.
+func (ps *PermissionSet) matchConditional(req AccessRequest, globalOnly bool) bool {
+	for _, p := range ps.conditional {
+		if globalOnly && p.Scope != "" && p.Scope != "*" {
+			continue
+		}
+		if p.Allows(req) {
+			return true
+		}
+	}
+	return false
+}
+
 // Permissions returns a defensive copy of all permissions in the set,
 // preserving the original insertion order (after deduplication). Callers
 // may safely modify the returned slice without affecting the PermissionSet.
@@ -0,0 +1,523 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ===========================================================================
+// Revocations
+// ===========================================================================
+
+// DefaultRevocationTTL is how long a revocation is kept when
+// [Revocation.ExpiresAt] is not set. It matches the default
+// [IssuerConfig.MaxTTL], so it outlives every token the platform issues
+// by default; set ExpiresAt explicitly for longer-lived tokens.
+const DefaultRevocationTTL = 24 * time.Hour
+
+// Revocation revokes either a single token, by its "jti" claim, or every
+// token of a subject issued at or before RevokedAt. Exactly one of
+// TokenID and Subject must be set.
+type Revocation struct {
+	// TokenID is the "jti" claim of the revoked token.
+	TokenID string `json:"jti,omitempty"`
+
+	// Subject is the "sub" claim of the revoked tokens. Tokens of the
+	// subject issued after RevokedAt, such as those obtained by logging
+	// in again after rotating a leaked credential, are not affected.
+	// Tokens without an "iat" claim are always affected.
+	Subject string `json:"sub,omitempty"`
+
+	// RevokedAt is the time of revocation. Defaults to the current time.
+	RevokedAt time.Time `json:"revoked_at"`
+
+	// ExpiresAt is when the revocation can be forgotten: the latest
+	// expiry of the tokens it revokes. Defaults to RevokedAt plus
+	// [DefaultRevocationTTL].
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// normalize applies the defaults and validates r. Returns a
+// *[sserr.Error] with code [sserr.CodeValidation] if r is invalid.
+func (r Revocation) normalize(now time.Time) (Revocation, error) {
+	if (r.TokenID == "") == (r.Subject == "") {
+		return r, sserr.New(sserr.CodeValidation,
+			"auth: revocation must set exactly one of token ID and subject")
+	}
+	if r.RevokedAt.IsZero() {
+		r.RevokedAt = now
+	}
+	if r.ExpiresAt.IsZero() {
+		r.ExpiresAt = r.RevokedAt.Add(DefaultRevocationTTL)
+	}
+	if !r.ExpiresAt.After(now) {
+		return r, sserr.New(sserr.CodeValidation,
+			"auth: revocation expiry must be in the future")
+	}
+	return r, nil
+}
+
+// matches reports whether r revokes the token with the given claims.
+func (r Revocation) matches(tokenID, subject string, issuedAt time.Time) bool {
+	if r.TokenID != "" {
+		return tokenID == r.TokenID
+	}
+	return subject == r.Subject && (issuedAt.IsZero() || !issuedAt.After(r.RevokedAt))
+}
+
+// revocationClaims returns the claims of a token that revocations match
+// against.
+func revocationClaims(claims map[string]any) (tokenID, subject string, issuedAt time.Time) {
+	tokenID, _ = claims["jti"].(string)
+	subject, _ = claims["sub"].(string)
+	issuedAt, _ = numericDate(claims["iat"])
+	return tokenID, subject, issuedAt
+}
+
+// RevocationChecker reports whether a token has been revoked. Set it as
+// [ValidatorConfig.RevocationChecker]; the validator consults it for
+// every token that is not already cached, after verifying the signature.
+// Implementations must be safe for concurrent use.
+type RevocationChecker interface {
+	// IsRevoked reports whether the token with the given "jti", "sub",
+	// and "iat" claims has been revoked. tokenID is empty and issuedAt is
+	// zero if the token lacks the claim.
+	IsRevoked(ctx context.Context, tokenID, subject string, issuedAt time.Time) (bool, error)
+}
+
+// RevocationNotifier is implemented by a [RevocationChecker] that can
+// report new revocations. [NewJWTValidator] subscribes to it and evicts
+// the revoked tokens from its cache, so that a revocation takes effect
+// before the cached identities expire.
+type RevocationNotifier interface {
+	// OnRevoke registers fn to be called with every new revocation.
+	OnRevoke(fn func(Revocation))
+}
+
+// revocationListeners holds the functions registered with OnRevoke.
+type revocationListeners struct {
+	mu  sync.RWMutex
+	fns []func(Revocation)
+}
+
+// add registers fn.
+func (l *revocationListeners) add(fn func(Revocation)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fns = append(l.fns, fn)
+}
+
+// notify calls every registered function with r.
+func (l *revocationListeners) notify(r Revocation) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, fn := range l.fns {
+		fn(r)
+	}
+}
+
+// ===========================================================================
+// MemoryRevocationStore
+// ===========================================================================
+
+// MemoryRevocationStore is an in-process [RevocationChecker]. Revocations
+// apply only to the validators of the same process; services with several
+// replicas should use [RedisRevocationStore].
+type MemoryRevocationStore struct {
+	mu        sync.RWMutex
+	tokens    map[string]Revocation
+	subjects  map[string]Revocation
+	nextSweep int
+	listeners revocationListeners
+	now       func() time.Time
+}
+
+// Compile-time interface compliance checks.
+var (
+	_ RevocationChecker  = (*MemoryRevocationStore)(nil)
+	_ RevocationNotifier = (*MemoryRevocationStore)(nil)
+)
+
+// minRevocationSweep is the number of stored revocations below which
+// [MemoryRevocationStore] does not bother evicting expired entries.
+const minRevocationSweep = 1024
+
+// NewMemoryRevocationStore creates an empty [MemoryRevocationStore].
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:    make(map[string]Revocation),
+		subjects:  make(map[string]Revocation),
+		nextSweep: minRevocationSweep,
+		now:       time.Now,
+	}
+}
+
+// Revoke records r and notifies the registered listeners. A subject
+// revocation is merged with any earlier one for the same subject: tokens
+// issued up to the later RevokedAt stay revoked until the later
+// ExpiresAt. Returns a *[sserr.Error] with code [sserr.CodeValidation]
+// if r is invalid.
+func (s *MemoryRevocationStore) Revoke(_ context.Context, r Revocation) error {
+	now := s.now()
+	r, err := r.normalize(now)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if r.TokenID != "" {
+		s.tokens[r.TokenID] = r
+	} else {
+		s.subjects[r.Subject] = mergeSubjectRevocation(s.subjects[r.Subject], r, now)
+	}
+	// Evict expired revocations once the maps have doubled since the last
+	// sweep, keeping the amortized cost per call constant.
+	if len(s.tokens)+len(s.subjects) >= s.nextSweep {
+		for _, m := range []map[string]Revocation{s.tokens, s.subjects} {
+			for k, v := range m {
+				if !now.Before(v.ExpiresAt) {
+					delete(m, k)
+				}
+			}
+		}
+		s.nextSweep = max(minRevocationSweep, 2*(len(s.tokens)+len(s.subjects)))
+	}
+	s.mu.Unlock()
+
+	s.listeners.notify(r)
+	return nil
+}
+
+// mergeSubjectRevocation combines an existing, possibly expired or zero,
+// subject revocation with a new one so that neither is weakened.
+func mergeSubjectRevocation(existing, r Revocation, now time.Time) Revocation {
+	if existing.Subject == "" || !now.Before(existing.ExpiresAt) {
+		return r
+	}
+	if existing.RevokedAt.After(r.RevokedAt) {
+		r.RevokedAt = existing.RevokedAt
+	}
+	if existing.ExpiresAt.After(r.ExpiresAt) {
+		r.ExpiresAt = existing.ExpiresAt
+	}
+	return r
+}
+
+// IsRevoked implements [RevocationChecker].
+func (s *MemoryRevocationStore) IsRevoked(_ context.Context, tokenID, subject string, issuedAt time.Time) (bool, error) {
+	now := s.now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r, ok := s.tokens[tokenID]; ok && tokenID != "" && now.Before(r.ExpiresAt) {
+		return true, nil
+	}
+	if r, ok := s.subjects[subject]; ok && subject != "" && now.Before(r.ExpiresAt) {
+		return r.matches(tokenID, subject, issuedAt), nil
+	}
+	return false, nil
+}
+
+// OnRevoke implements [RevocationNotifier].
+func (s *MemoryRevocationStore) OnRevoke(fn func(Revocation)) {
+	s.listeners.add(fn)
+}
+
+// ===========================================================================
+// RedisRevocationStore
+// ===========================================================================
+
+const (
+	// DefaultRevocationKeyPrefix is the key prefix used by
+	// [RedisRevocationStore] when none is supplied. Token revocations are
+	// stored at "<prefix>jti:<jti>" and subject revocations at
+	// "<prefix>sub:<sub>".
+	DefaultRevocationKeyPrefix = "auth:revoked:"
+
+	// DefaultRevocationChannel is the pub/sub channel used by
+	// [RedisRevocationStore] when none is supplied.
+	DefaultRevocationChannel = "auth:revocations"
+)
+
+// RedisRevocationOption configures a [RedisRevocationStore].
+type RedisRevocationOption func(*RedisRevocationStore)
+
+// WithRevocationKeyPrefix sets the key prefix. Defaults to
+// [DefaultRevocationKeyPrefix].
+func WithRevocationKeyPrefix(prefix string) RedisRevocationOption {
+	return func(s *RedisRevocationStore) {
+		if prefix != "" {
+			s.prefix = prefix
+		}
+	}
+}
+
+// WithRevocationChannel sets the pub/sub channel revocations are
+// broadcast on. Defaults to [DefaultRevocationChannel].
+func WithRevocationChannel(channel string) RedisRevocationOption {
+	return func(s *RedisRevocationStore) {
+		if channel != "" {
+			s.channel = channel
+		}
+	}
+}
+
+// RedisRevocationStore is a [RevocationChecker] shared by all replicas
+// through Redis. Each revocation is stored under a key that expires with
+// it and is broadcast on a pub/sub channel; run [RedisRevocationStore.Listen]
+// in every replica so that validators evict revoked tokens from their
+// caches as soon as any replica revokes them.
+//
+// RedisRevocationStore is safe for concurrent use.
+type RedisRevocationStore struct {
+	client    *redis.Client
+	prefix    string
+	channel   string
+	listeners revocationListeners
+	now       func() time.Time
+
+	// subscribe opens the subscription used by Listen. It is replaced in
+	// tests, since the Redis fake cannot open real subscriptions.
+	subscribe func(ctx context.Context) (<-chan string, func(), error)
+}
+
+// Compile-time interface compliance checks.
+var (
+	_ RevocationChecker  = (*RedisRevocationStore)(nil)
+	_ RevocationNotifier = (*RedisRevocationStore)(nil)
+)
+
+// NewRedisRevocationStore returns a revocation store backed by client.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if client is
+// nil.
+func NewRedisRevocationStore(client *redis.Client, opts ...RedisRevocationOption) (*RedisRevocationStore, error) {
+	if client == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"auth: redis revocation store requires a client")
+	}
+	s := &RedisRevocationStore{
+		client:  client,
+		prefix:  DefaultRevocationKeyPrefix,
+		channel: DefaultRevocationChannel,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.subscribe = s.subscribeRedis
+	return s, nil
+}
+
+// tokenKey returns the Redis key of a token revocation.
+func (s *RedisRevocationStore) tokenKey(tokenID string) string {
+	return s.prefix + "jti:" + tokenID
+}
+
+// subjectKey returns the Redis key of a subject revocation.
+func (s *RedisRevocationStore) subjectKey(subject string) string {
+	return s.prefix + "sub:" + subject
+}
+
+// Revoke stores r, broadcasts it to every replica, and notifies the local
+// listeners. A subject revocation is merged with any earlier one for the
+// same subject, as by [MemoryRevocationStore.Revoke], in a single script,
+// so concurrent revocations of a subject cannot weaken each other.
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if r is
+// invalid, or [sserr.CodeInternalDatabase] if Redis fails.
+//
+// The revocation is stored before it is broadcast, so it is enforced even
+// if the broadcast fails; in that case other replicas may keep serving
+// cached identities until their cache entries expire, and the error is
+// returned so the caller can retry.
+func (s *RedisRevocationStore) Revoke(ctx context.Context, r Revocation) error {
+	now := s.now()
+	r, err := r.normalize(now)
+	if err != nil {
+		return err
+	}
+
+	if r.Subject != "" {
+		r, err = s.revokeSubject(ctx, r, now)
+		if err != nil {
+			return err
+		}
+	} else {
+		value := strconv.FormatInt(r.RevokedAt.UnixNano(), 10)
+		if err := s.client.Set(ctx, s.tokenKey(r.TokenID), value, r.ExpiresAt.Sub(now)); err != nil {
+			return sserr.Wrap(err, sserr.CodeInternalDatabase,
+				"auth: failed to store revocation")
+		}
+	}
+	s.listeners.notify(r)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeInternal,
+			"auth: failed to encode revocation")
+	}
+	if _, err := s.client.Publish(ctx, s.channel, data); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"auth: failed to broadcast revocation")
+	}
+	return nil
+}
+
+// IsRevoked implements [RevocationChecker].
+func (s *RedisRevocationStore) IsRevoked(ctx context.Context, tokenID, subject string, issuedAt time.Time) (bool, error) {
+	if tokenID != "" {
+		n, err := s.client.Exists(ctx, s.tokenKey(tokenID))
+		if err != nil {
+			return false, sserr.Wrap(err, sserr.CodeInternalDatabase,
+				"auth: failed to check token revocation")
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+	if subject == "" {
+		return false, nil
+	}
+	value, err := s.client.Get(ctx, s.subjectKey(subject))
+	if errors.Is(err, goredis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"auth: failed to check subject revocation")
+	}
+	nanos, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		// Fail closed on a corrupt entry rather than ignore a revocation.
+		return true, nil
+	}
+	r := Revocation{Subject: subject, RevokedAt: time.Unix(0, nanos)}
+	return r.matches(tokenID, subject, issuedAt), nil
+}
+
+// redisRevokeSubjectScript stores the subject revocation at KEYS[1],
+// revoking at ARGV[1] (Unix nanoseconds) for ARGV[2] milliseconds, merged
+// with any stored revocation as by [mergeSubjectRevocation]: the later
+// revocation time and the longer expiry win. Revocation times are
+// compared as decimal strings, since they exceed the precision of Lua
+// numbers. Returns the stored revocation time and expiry in milliseconds.
+const redisRevokeSubjectScript = `
+local revoked = ARGV[1]
+local ttl = tonumber(ARGV[2])
+local cur = redis.call('GET', KEYS[1])
+if cur and string.match(cur, '^%d+$') then
+  if #cur > #revoked or (#cur == #revoked and cur > revoked) then
+    revoked = cur
+  end
+  local pttl = redis.call('PTTL', KEYS[1])
+  if pttl > ttl then
+    ttl = pttl
+  end
+end
+redis.call('SET', KEYS[1], revoked, 'PX', ttl)
+return {revoked, ttl}
+`
+
+// revokeSubject stores the subject revocation r, merged with any stored
+// revocation of the subject, and returns the merged revocation.
+func (s *RedisRevocationStore) revokeSubject(ctx context.Context, r Revocation, now time.Time) (Revocation, error) {
+	ttl := r.ExpiresAt.Sub(now)
+	result, err := s.client.Eval(ctx, redisRevokeSubjectScript, []string{s.subjectKey(r.Subject)},
+		strconv.FormatInt(r.RevokedAt.UnixNano(), 10), max(ttl.Milliseconds(), 1))
+	if err != nil {
+		return r, sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"auth: failed to store revocation")
+	}
+	stored, _ := result.([]interface{})
+	if len(stored) == 2 {
+		if value, ok := stored[0].(string); ok {
+			if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+				r.RevokedAt = time.Unix(0, nanos)
+			}
+		}
+		if ms, ok := stored[1].(int64); ok && time.Duration(ms)*time.Millisecond > ttl {
+			r.ExpiresAt = now.Add(time.Duration(ms) * time.Millisecond)
+		}
+	}
+	return r, nil
+}
+
+// OnRevoke implements [RevocationNotifier]. fn is called for revocations
+// made through this store and, while [RedisRevocationStore.Listen] runs,
+// for those broadcast by other replicas.
+func (s *RedisRevocationStore) OnRevoke(fn func(Revocation)) {
+	s.listeners.add(fn)
+}
+
+// Listen subscribes to the revocation channel and notifies the listeners
+// of every revocation broadcast by any replica until ctx is cancelled.
+// It returns nil when ctx is cancelled, or a *[sserr.Error] if the
+// subscription fails or is closed by the server; callers typically run it
+// in a goroutine and restart it on error.
+//
+// Revocations broadcast while no Listen call is subscribed are not
+// replayed, but they are still enforced for tokens that are not cached.
+func (s *RedisRevocationStore) Listen(ctx context.Context) error {
+	messages, closeSub, err := s.subscribe(ctx)
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeUnavailable,
+			"auth: failed to subscribe to revocations")
+	}
+	defer closeSub()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case payload, ok := <-messages:
+			if !ok {
+				return sserr.New(sserr.CodeUnavailable,
+					"auth: revocation subscription closed")
+			}
+			var r Revocation
+			if err := json.Unmarshal([]byte(payload), &r); err != nil {
+				slog.WarnContext(ctx, "auth: ignoring malformed revocation message",
+					"error", err)
+				continue
+			}
+			s.listeners.notify(r)
+		}
+	}
+}
+
+// subscribeRedis subscribes to the revocation channel with the Redis
+// client and returns the message payloads.
+func (s *RedisRevocationStore) subscribeRedis(ctx context.Context) (<-chan string, func(), error) {
+	sub, err := s.client.Subscribe(ctx, s.channel)
+	if err != nil {
+		return nil, nil, err
+	}
+	payloads := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(payloads)
+		for msg := range sub.Channel() {
+			select {
+			case payloads <- msg.Payload:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return payloads, func() {
+		once.Do(func() {
+			close(done)
+			_ = sub.Close()
+		})
+	}, nil
+}
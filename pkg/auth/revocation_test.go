@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/StricklySoft/stricklysoft-core/internal/testutil/fakes"
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// failingRevocationChecker is a RevocationChecker whose backend is down.
+type failingRevocationChecker struct{}
+
+func (failingRevocationChecker) IsRevoked(context.Context, string, string, time.Time) (bool, error) {
+	return false, errors.New("redis down")
+}
+
+// revocationTestToken returns a platform token for subject with the given
+// token ID, issued at iat.
+func revocationTestToken(t *testing.T, tokenID, subject string, iat time.Time) string {
+	t.Helper()
+	return jwtTestGenerateHMACToken(t, []byte(testSigningKey), jwt.MapClaims{
+		"iss": "stricklysoft-platform",
+		"sub": subject,
+		"jti": tokenID,
+		"iat": iat.Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+}
+
+// newRevocationValidator returns a platform validator using checker.
+func newRevocationValidator(t *testing.T, checker RevocationChecker) *JWTValidator {
+	t.Helper()
+	cfg := newPlatformConfig()
+	cfg.RevocationChecker = checker
+	v, err := NewJWTValidator(cfg)
+	require.NoError(t, err)
+	return v
+}
+
+// ---------------------------------------------------------------------------
+// Revocation
+// ---------------------------------------------------------------------------
+
+func TestRevocation_Invalid(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+	tests := map[string]Revocation{
+		"neither":         {},
+		"both":            {TokenID: "t-1", Subject: "usr-1"},
+		"already expired": {TokenID: "t-1", ExpiresAt: time.Now().Add(-time.Second)},
+	}
+	for name, r := range tests {
+		assert.True(t, sserr.IsValidation(store.Revoke(ctx, r)), name)
+	}
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+	revokedAt := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return revokedAt }
+
+	var notified []Revocation
+	store.OnRevoke(func(r Revocation) { notified = append(notified, r) })
+
+	require.NoError(t, store.Revoke(ctx, Revocation{TokenID: "t-1"}))
+	require.NoError(t, store.Revoke(ctx, Revocation{Subject: "usr-2"}))
+	require.Len(t, notified, 2)
+	assert.Equal(t, revokedAt.Add(DefaultRevocationTTL), notified[0].ExpiresAt)
+
+	tests := []struct {
+		name    string
+		tokenID string
+		subject string
+		iat     time.Time
+		want    bool
+	}{
+		{"revoked token", "t-1", "usr-1", revokedAt.Add(-time.Minute), true},
+		{"other token", "t-2", "usr-1", revokedAt.Add(-time.Minute), false},
+		{"subject token issued before", "t-3", "usr-2", revokedAt.Add(-time.Minute), true},
+		{"subject token issued at", "t-3", "usr-2", revokedAt, true},
+		{"subject token issued after", "t-4", "usr-2", revokedAt.Add(time.Second), false},
+		{"subject token without iat", "t-5", "usr-2", time.Time{}, true},
+		{"token without jti", "", "usr-1", revokedAt, false},
+	}
+	for _, tt := range tests {
+		got, err := store.IsRevoked(ctx, tt.tokenID, tt.subject, tt.iat)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+
+	// Revocations expire.
+	store.now = func() time.Time { return revokedAt.Add(DefaultRevocationTTL) }
+	revoked, err := store.IsRevoked(ctx, "t-1", "usr-2", revokedAt)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestMemoryRevocationStore_SubjectMerge(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Revoke(ctx, Revocation{Subject: "usr-1", ExpiresAt: now.Add(48 * time.Hour)}))
+	// A later, shorter revocation does not shorten the earlier one.
+	require.NoError(t, store.Revoke(ctx, Revocation{Subject: "usr-1", RevokedAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)}))
+
+	store.now = func() time.Time { return now.Add(2 * time.Hour) }
+	revoked, err := store.IsRevoked(ctx, "", "usr-1", now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+// ---------------------------------------------------------------------------
+// JWTValidator integration
+// ---------------------------------------------------------------------------
+
+func TestJWTValidator_Revocation(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryRevocationStore()
+	validator := newRevocationValidator(t, store)
+	ctx := context.Background()
+	issued := time.Now().Add(-time.Minute)
+
+	token := revocationTestToken(t, "t-1", "usr-1", issued)
+	_, err := validator.Validate(ctx, token)
+	require.NoError(t, err)
+
+	// The cached identity is evicted when the token is revoked.
+	require.NoError(t, store.Revoke(ctx, Revocation{TokenID: "t-1"}))
+	_, err = validator.Validate(ctx, token)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+
+	other := revocationTestToken(t, "t-2", "usr-2", issued)
+	_, err = validator.Validate(ctx, other)
+	require.NoError(t, err)
+	require.NoError(t, store.Revoke(ctx, Revocation{Subject: "usr-2"}))
+	_, err = validator.Validate(ctx, other)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+
+	// A token issued after the subject revocation is accepted.
+	fresh := revocationTestToken(t, "t-3", "usr-2", time.Now().Add(time.Second))
+	_, err = validator.Validate(ctx, fresh)
+	assert.NoError(t, err)
+}
+
+func TestJWTValidator_RevocationCheckFails(t *testing.T) {
+	t.Parallel()
+	validator := newRevocationValidator(t, failingRevocationChecker{})
+	_, err := validator.Validate(context.Background(), revocationTestToken(t, "t-1", "usr-1", time.Now()))
+	assert.True(t, sserr.IsUnavailable(err), "error = %v", err)
+}
+
+func TestTokenCache_InvalidateDuringCheck(t *testing.T) {
+	t.Parallel()
+	cache := newTokenCache(time.Minute, 10)
+	identity := NewBasicIdentity("usr-1", IdentityTypeUser, map[string]any{"sub": "usr-1", "jti": "t-1"})
+
+	generation := cache.revocationGeneration()
+	cache.invalidate(Revocation{TokenID: "t-1"})
+	cache.putUnlessInvalidated("hash", identity, time.Now().Add(time.Hour), generation)
+	_, ok := cache.get("hash")
+	assert.False(t, ok, "an identity checked before a revocation must not be cached after it")
+}
+
+// ---------------------------------------------------------------------------
+// RedisRevocationStore
+// ---------------------------------------------------------------------------
+
+// newRevocationFake returns a Redis fake that emulates the subject
+// revocation script.
+func newRevocationFake() *fakes.Redis {
+	fake := fakes.NewRedis()
+	fake.HandleScript(redisRevokeSubjectScript, func(r *fakes.Redis, keys []string, args []interface{}) (interface{}, error) {
+		ctx := context.Background()
+		revoked := args[0].(string)
+		ttl := time.Duration(args[1].(int64)) * time.Millisecond
+		if cur, err := r.Get(ctx, keys[0]).Result(); err == nil {
+			curNanos, _ := strconv.ParseInt(cur, 10, 64)
+			newNanos, _ := strconv.ParseInt(revoked, 10, 64)
+			if curNanos > newNanos {
+				revoked = cur
+			}
+			if remaining := r.TTL(ctx, keys[0]).Val(); remaining > ttl {
+				ttl = remaining
+			}
+		}
+		r.Set(ctx, keys[0], revoked, ttl)
+		return []interface{}{revoked, ttl.Milliseconds()}, nil
+	})
+	return fake
+}
+
+func TestNewRedisRevocationStore_NilClient(t *testing.T) {
+	t.Parallel()
+	_, err := NewRedisRevocationStore(nil)
+	assert.True(t, sserr.IsValidation(err))
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	t.Parallel()
+	fake := newRevocationFake()
+	store, err := NewRedisRevocationStore(redis.NewFromClient(fake, nil), WithRevocationKeyPrefix("test:revoked:"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, store.Revoke(ctx, Revocation{TokenID: "t-1", ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, store.Revoke(ctx, Revocation{Subject: "usr-2", RevokedAt: now}))
+	assert.Equal(t, 2, fake.Keys())
+
+	tests := []struct {
+		name    string
+		tokenID string
+		subject string
+		iat     time.Time
+		want    bool
+	}{
+		{"revoked token", "t-1", "usr-1", now, true},
+		{"other token", "t-2", "usr-1", now, false},
+		{"subject token issued before", "t-3", "usr-2", now.Add(-time.Minute), true},
+		{"subject token issued after", "t-4", "usr-2", now.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		got, err := store.IsRevoked(ctx, tt.tokenID, tt.subject, tt.iat)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+
+	// A later subject revocation keeps the earlier expiry, and an earlier
+	// one keeps the later revocation time; listeners see the merged
+	// revocation.
+	var notified []Revocation
+	store.OnRevoke(func(r Revocation) { notified = append(notified, r) })
+	require.NoError(t, store.Revoke(ctx, Revocation{Subject: "usr-2", ExpiresAt: time.Now().Add(time.Minute)}))
+	ttl, err := redis.NewFromClient(fake, nil).TTL(ctx, "test:revoked:sub:usr-2")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Hour)
+	require.Len(t, notified, 1)
+	assert.Greater(t, time.Until(notified[0].ExpiresAt), time.Hour)
+
+	merged := notified[0].RevokedAt
+	require.NoError(t, store.Revoke(ctx, Revocation{Subject: "usr-2", RevokedAt: now.Add(-time.Hour)}))
+	require.Len(t, notified, 2)
+	assert.True(t, notified[1].RevokedAt.Equal(merged), "revoked at %v, want %v", notified[1].RevokedAt, merged)
+	revoked, err := store.IsRevoked(ctx, "t-6", "usr-2", now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, revoked, "an earlier revocation must not lift a later one")
+
+	fake.SetError(errors.New("connection refused"))
+	_, err = store.IsRevoked(ctx, "t-1", "usr-1", now)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase), "error = %v", err)
+	err = store.Revoke(ctx, Revocation{TokenID: "t-5"})
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase), "error = %v", err)
+	err = store.Revoke(ctx, Revocation{Subject: "usr-3"})
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase), "error = %v", err)
+}
+
+func TestRedisRevocationStore_CrossReplicaInvalidation(t *testing.T) {
+	t.Parallel()
+	fake := fakes.NewRedis()
+	client := redis.NewFromClient(fake, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Replica B listens for revocations made by replica A.
+	storeA, err := NewRedisRevocationStore(client)
+	require.NoError(t, err)
+	storeB, err := NewRedisRevocationStore(client)
+	require.NoError(t, err)
+	messages, stop := fake.Listen(DefaultRevocationChannel)
+	storeB.subscribe = func(context.Context) (<-chan string, func(), error) { return messages, stop, nil }
+	listenErr := make(chan error, 1)
+	go func() { listenErr <- storeB.Listen(ctx) }()
+
+	validatorB := newRevocationValidator(t, storeB)
+	token := revocationTestToken(t, "t-1", "usr-1", time.Now())
+	_, err = validatorB.Validate(ctx, token)
+	require.NoError(t, err)
+	_, cached := validatorB.tokenCache.get(tokenHash(token))
+	require.True(t, cached)
+
+	require.NoError(t, storeA.Revoke(ctx, Revocation{TokenID: "t-1"}))
+	assert.Eventually(t, func() bool {
+		_, cached := validatorB.tokenCache.get(tokenHash(token))
+		return !cached
+	}, time.Second, 5*time.Millisecond)
+	_, err = validatorB.Validate(ctx, token)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+
+	// Malformed messages are ignored, and Listen returns on cancellation.
+	_, err = client.Publish(ctx, DefaultRevocationChannel, "not json")
+	require.NoError(t, err)
+	cancel()
+	select {
+	case err := <-listenErr:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Listen did not return after cancellation")
+	}
+}
@@ -0,0 +1,473 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/postgres"
+	"github.com/StricklySoft/stricklysoft-core/pkg/config"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ---------------------------------------------------------------------------
+// RoleRegistry
+// ---------------------------------------------------------------------------
+
+// RoleRegistry holds a set of [Role] definitions with inheritance resolved:
+// each role grants its own permissions plus those of every role it
+// inherits from, directly or transitively. Roles can be replaced at
+// runtime with [RoleRegistry.Replace], [RoleRegistry.Reload], or
+// [RoleRegistry.Watch], for example to pick up edits to a role file or
+// table without a restart.
+//
+// Use [RoleRegistry.PermissionMapper] as the PermissionMapper of a
+// validator so that role claims map to the registry's current roles.
+// Identities already in a validator's token cache keep the permissions
+// they were created with until their entry expires.
+//
+// RoleRegistry is safe for concurrent use by multiple goroutines.
+type RoleRegistry struct {
+	mu          sync.RWMutex
+	roles       map[string]Role
+	permissions RolePermissionMap
+	fingerprint [sha256.Size]byte
+}
+
+// NewRoleRegistry creates a RoleRegistry holding roles.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if roles is
+// empty, a role name is empty or duplicated, a role inherits from an
+// unknown role, or the inheritance graph has a cycle.
+func NewRoleRegistry(roles []Role) (*RoleRegistry, error) {
+	r := &RoleRegistry{}
+	if err := r.Replace(roles); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Replace atomically replaces all roles. On error the registry is left
+// unchanged. The validation rules are those of [NewRoleRegistry].
+func (r *RoleRegistry) Replace(roles []Role) error {
+	resolved, fingerprint, err := resolveRoles(roles)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set(resolved, fingerprint)
+	return nil
+}
+
+// set installs resolved roles. The caller must hold r.mu.
+func (r *RoleRegistry) set(resolved map[string]Role, fingerprint [sha256.Size]byte) {
+	permissions := make(RolePermissionMap, len(resolved))
+	for name, role := range resolved {
+		permissions[name] = role.Permissions
+	}
+	r.roles = resolved
+	r.permissions = permissions
+	r.fingerprint = fingerprint
+}
+
+// Reload replaces the roles with those loaded from source. It does
+// nothing if the roles are unchanged. On error the current roles are
+// kept.
+func (r *RoleRegistry) Reload(ctx context.Context, source RoleSource) error {
+	roles, err := source.LoadRoles(ctx)
+	if err != nil {
+		return err
+	}
+	resolved, fingerprint, err := resolveRoles(roles)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fingerprint == r.fingerprint {
+		return nil
+	}
+	r.set(resolved, fingerprint)
+	slog.InfoContext(ctx, "auth: roles reloaded", "roles", sortedRoleNames(resolved))
+	return nil
+}
+
+// Watch calls [RoleRegistry.Reload] every interval until ctx is
+// cancelled. Reload errors are logged and the current roles are kept. It
+// is meant to run in its own goroutine:
+//
+//	go registry.Watch(ctx, source, 30*time.Second)
+func (r *RoleRegistry) Watch(ctx context.Context, source RoleSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(ctx, source); err != nil {
+				slog.WarnContext(ctx, "auth: role reload failed", "error", err)
+			}
+		}
+	}
+}
+
+// Role returns the named role, with Permissions including inherited
+// permissions. The returned role is a copy.
+func (r *RoleRegistry) Role(name string) (Role, bool) {
+	r.mu.RLock()
+	role, ok := r.roles[name]
+	r.mu.RUnlock()
+	if !ok {
+		return Role{}, false
+	}
+	return copyRole(role), true
+}
+
+// Roles returns all roles sorted by name, with Permissions including
+// inherited permissions. The returned roles are copies.
+func (r *RoleRegistry) Roles() []Role {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	roles := make([]Role, 0, len(r.roles))
+	for _, name := range sortedRoleNames(r.roles) {
+		roles = append(roles, copyRole(r.roles[name]))
+	}
+	return roles
+}
+
+// RolePermissions returns a copy of the current roles as a
+// [RolePermissionMap], with inherited permissions included, for use with
+// [ClaimsToPermissions].
+func (r *RoleRegistry) RolePermissions() RolePermissionMap {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m := make(RolePermissionMap, len(r.permissions))
+	for name, perms := range r.permissions {
+		m[name] = append([]Permission(nil), perms...)
+	}
+	return m
+}
+
+// PermissionMapper returns a permission mapper, for use as
+// [ValidatorConfig.PermissionMapper] and similar fields, that applies
+// [ClaimsToPermissions] with the registry's roles at the time of each
+// call.
+func (r *RoleRegistry) PermissionMapper() func(claims map[string]any) []Permission {
+	return func(claims map[string]any) []Permission {
+		r.mu.RLock()
+		permissions := r.permissions
+		r.mu.RUnlock()
+		// The map is replaced, never modified, on reload, so it can be
+		// read without holding the lock.
+		return ClaimsToPermissions(claims, permissions)
+	}
+}
+
+// resolveRoles validates roles and returns them by name with inherited
+// permissions added, along with a fingerprint used to detect changes.
+func resolveRoles(roles []Role) (map[string]Role, [sha256.Size]byte, error) {
+	var fingerprint [sha256.Size]byte
+	if len(roles) == 0 {
+		return nil, fingerprint, sserr.New(sserr.CodeValidation, "auth: at least one role is required")
+	}
+	defined := make(map[string]Role, len(roles))
+	for _, role := range roles {
+		if strings.TrimSpace(role.Name) == "" {
+			return nil, fingerprint, sserr.New(sserr.CodeValidation, "auth: role name must not be empty")
+		}
+		if _, dup := defined[role.Name]; dup {
+			return nil, fingerprint, sserr.Newf(sserr.CodeValidation, "auth: duplicate role %q", role.Name)
+		}
+		defined[role.Name] = role
+	}
+	for _, role := range roles {
+		for _, parent := range role.Inherits {
+			if _, ok := defined[parent]; !ok {
+				return nil, fingerprint, sserr.Newf(sserr.CodeValidation,
+					"auth: role %q inherits from unknown role %q", role.Name, parent)
+			}
+		}
+	}
+
+	resolved := make(map[string]Role, len(defined))
+	// visiting holds the roles on the current inheritance path, in order,
+	// to detect and report cycles.
+	var visiting []string
+	var resolve func(name string) ([]Permission, error)
+	resolve = func(name string) ([]Permission, error) {
+		if role, ok := resolved[name]; ok {
+			return role.Permissions, nil
+		}
+		for i, v := range visiting {
+			if v == name {
+				cycle := append(append([]string(nil), visiting[i:]...), name)
+				return nil, sserr.Newf(sserr.CodeValidation,
+					"auth: role inheritance cycle: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		visiting = append(visiting, name)
+		defer func() { visiting = visiting[:len(visiting)-1] }()
+
+		role := defined[name]
+		seen := make(map[Permission]struct{})
+		var permissions []Permission
+		add := func(perms []Permission) {
+			for _, p := range perms {
+				if _, ok := seen[p]; !ok {
+					seen[p] = struct{}{}
+					permissions = append(permissions, p)
+				}
+			}
+		}
+		add(role.Permissions)
+		for _, parent := range role.Inherits {
+			inherited, err := resolve(parent)
+			if err != nil {
+				return nil, err
+			}
+			add(inherited)
+		}
+
+		role = copyRole(role)
+		role.Permissions = permissions
+		resolved[name] = role
+		return permissions, nil
+	}
+	for _, name := range sortedRoleNames(defined) {
+		if _, err := resolve(name); err != nil {
+			return nil, fingerprint, err
+		}
+	}
+
+	// Fingerprint the definitions rather than the resolved roles, so that
+	// a change to inheritance alone is also detected.
+	h := sha256.New()
+	for _, name := range sortedRoleNames(defined) {
+		data, _ := json.Marshal(roleDefinitionOf(defined[name]))
+		_, _ = h.Write(data)
+		_, _ = h.Write([]byte{'\n'})
+	}
+	copy(fingerprint[:], h.Sum(nil))
+	return resolved, fingerprint, nil
+}
+
+// copyRole returns a copy of role that shares no slices with it.
+func copyRole(role Role) Role {
+	role.Inherits = append([]string(nil), role.Inherits...)
+	role.Permissions = append([]Permission(nil), role.Permissions...)
+	return role
+}
+
+// sortedRoleNames returns the keys of roles in sorted order.
+func sortedRoleNames(roles map[string]Role) []string {
+	names := make([]string, 0, len(roles))
+	for name := range roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ---------------------------------------------------------------------------
+// RoleDefinition
+// ---------------------------------------------------------------------------
+
+// RoleDefinition is the serialized form of a [Role], as read from role
+// files and tables. Permissions are strings in [ParsePermissionString]
+// format.
+type RoleDefinition struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Inherits    []string `json:"inherits" yaml:"inherits"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+}
+
+// Role parses the definition's permissions and returns it as a [Role].
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if a
+// permission string is malformed.
+func (d RoleDefinition) Role() (Role, error) {
+	role := Role{
+		Name:        d.Name,
+		Description: d.Description,
+		Inherits:    append([]string(nil), d.Inherits...),
+	}
+	for _, s := range d.Permissions {
+		p, err := ParsePermissionString(s)
+		if err != nil {
+			return Role{}, sserr.Wrapf(err, sserr.CodeValidation, "auth: invalid permission in role %q", d.Name)
+		}
+		role.Permissions = append(role.Permissions, p)
+	}
+	return role, nil
+}
+
+// roleDefinitionOf returns the serialized form of role.
+func roleDefinitionOf(role Role) RoleDefinition {
+	d := RoleDefinition{Name: role.Name, Description: role.Description, Inherits: role.Inherits}
+	for _, p := range role.Permissions {
+		d.Permissions = append(d.Permissions, p.String())
+	}
+	return d
+}
+
+// rolesFromDefinitions converts defs to roles.
+func rolesFromDefinitions(defs []RoleDefinition) ([]Role, error) {
+	roles := make([]Role, 0, len(defs))
+	for _, d := range defs {
+		role, err := d.Role()
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// ---------------------------------------------------------------------------
+// Role sources
+// ---------------------------------------------------------------------------
+
+// RoleSource loads role definitions for a [RoleRegistry].
+type RoleSource interface {
+	// LoadRoles returns the current role definitions. Inheritance is not
+	// resolved.
+	LoadRoles(ctx context.Context) ([]Role, error)
+}
+
+// roleFile is the document read by [FileRoleSource].
+type roleFile struct {
+	Roles []RoleDefinition `json:"roles" yaml:"roles"`
+}
+
+// FileRoleSource loads roles from a YAML or JSON file with [config.Loader],
+// in the form:
+//
+//	roles:
+//	  - name: viewer
+//	    permissions: ["*:read"]
+//	  - name: operator
+//	    inherits: [viewer]
+//	    permissions: ["agents:*", "deployments:*"]
+//
+// The format is chosen by file extension, as with [config.Loader.WithFile].
+type FileRoleSource struct {
+	path string
+}
+
+// Compile-time assertion that FileRoleSource implements RoleSource.
+var _ RoleSource = (*FileRoleSource)(nil)
+
+// NewFileRoleSource returns a RoleSource that reads the file at path.
+func NewFileRoleSource(path string) *FileRoleSource {
+	return &FileRoleSource{path: path}
+}
+
+// LoadRoles implements [RoleSource]. Unlike configuration files, a role
+// file must exist: a missing file is an error rather than an empty set
+// of roles.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeInternalConfiguration] if
+// the file cannot be read or parsed, or [sserr.CodeValidation] if a
+// permission string is malformed.
+func (s *FileRoleSource) LoadRoles(_ context.Context) ([]Role, error) {
+	if _, err := os.Stat(s.path); err != nil {
+		return nil, sserr.Wrapf(err, sserr.CodeInternalConfiguration, "auth: failed to read role file %q", s.path)
+	}
+	var file roleFile
+	if err := config.New().WithFile(s.path).Load(&file); err != nil {
+		return nil, err
+	}
+	return rolesFromDefinitions(file.Roles)
+}
+
+// DefaultRoleTable is the table used by [PostgresRoleSource] when no
+// table name is supplied.
+const DefaultRoleTable = "auth_roles"
+
+// tableNamePattern restricts table names to optionally schema-qualified
+// SQL identifiers. Table names are interpolated into SQL, so anything that
+// does not match is rejected to rule out injection.
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}(\.[A-Za-z_][A-Za-z0-9_]{0,62})?$`)
+
+// PostgresRoleSource loads roles from a PostgreSQL table with one row per
+// role. Call [PostgresRoleSource.EnsureSchema] once at startup (or manage
+// the table with your migration tool) before use.
+//
+// PostgresRoleSource is safe for concurrent use.
+type PostgresRoleSource struct {
+	client *postgres.Client
+	table  string
+}
+
+// Compile-time assertion that PostgresRoleSource implements RoleSource.
+var _ RoleSource = (*PostgresRoleSource)(nil)
+
+// NewPostgresRoleSource returns a RoleSource that reads table using
+// client. If table is empty, [DefaultRoleTable] is used.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if client is
+// nil or table is not a valid, optionally schema-qualified identifier.
+func NewPostgresRoleSource(client *postgres.Client, table string) (*PostgresRoleSource, error) {
+	if client == nil {
+		return nil, sserr.New(sserr.CodeValidation, "auth: postgres role source requires a client")
+	}
+	if table == "" {
+		table = DefaultRoleTable
+	}
+	if !tableNamePattern.MatchString(table) {
+		return nil, sserr.Newf(sserr.CodeValidation, "auth: invalid role table name %q", table)
+	}
+	return &PostgresRoleSource{client: client, table: table}, nil
+}
+
+// EnsureSchema creates the role table if it does not already exist.
+func (s *PostgresRoleSource) EnsureSchema(ctx context.Context) error {
+	stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name        TEXT   PRIMARY KEY,
+	description TEXT   NOT NULL DEFAULT '',
+	inherits    TEXT[] NOT NULL DEFAULT '{}',
+	permissions TEXT[] NOT NULL DEFAULT '{}'
+)`, s.table)
+	if _, err := s.client.Exec(ctx, stmt); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to create role table")
+	}
+	return nil
+}
+
+// LoadRoles implements [RoleSource].
+//
+// Returns a *[sserr.Error] with code [sserr.CodeInternalDatabase] if the
+// table cannot be read, or [sserr.CodeValidation] if a permission string
+// is malformed.
+func (s *PostgresRoleSource) LoadRoles(ctx context.Context) ([]Role, error) {
+	stmt := fmt.Sprintf(`SELECT name, description, inherits, permissions FROM %s ORDER BY name`, s.table)
+	rows, err := s.client.Query(ctx, stmt)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to load roles")
+	}
+	defer rows.Close()
+
+	var defs []RoleDefinition
+	for rows.Next() {
+		var d RoleDefinition
+		if err := rows.Scan(&d.Name, &d.Description, &d.Inherits, &d.Permissions); err != nil {
+			return nil, sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to scan role")
+		}
+		defs = append(defs, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternalDatabase, "auth: failed to load roles")
+	}
+	return rolesFromDefinitions(defs)
+}
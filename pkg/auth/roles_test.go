@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/postgres"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// newRolesTestRoles returns a three-level role hierarchy: admin inherits
+// operator, which inherits viewer.
+func newRolesTestRoles() []Role {
+	return []Role{
+		{Name: "admin", Inherits: []string{"operator"}, Permissions: []Permission{{Resource: "users", Action: "*"}}},
+		{Name: "operator", Inherits: []string{"viewer"}, Permissions: []Permission{{Resource: "deployments", Action: "*"}}},
+		{Name: "viewer", Permissions: []Permission{{Resource: "*", Action: "read"}}},
+	}
+}
+
+// staticRoleSource is a RoleSource that returns roles, or err if set.
+type staticRoleSource struct {
+	roles []Role
+	err   error
+}
+
+func (s *staticRoleSource) LoadRoles(context.Context) ([]Role, error) {
+	return s.roles, s.err
+}
+
+// ---------------------------------------------------------------------------
+// RoleRegistry
+// ---------------------------------------------------------------------------
+
+func TestNewRoleRegistry_Inheritance(t *testing.T) {
+	t.Parallel()
+	registry, err := NewRoleRegistry(newRolesTestRoles())
+	require.NoError(t, err)
+
+	admin, ok := registry.Role("admin")
+	require.True(t, ok)
+	assert.Equal(t, []Permission{
+		{Resource: "users", Action: "*"},
+		{Resource: "deployments", Action: "*"},
+		{Resource: "*", Action: "read"},
+	}, admin.Permissions)
+	assert.Equal(t, []string{"operator"}, admin.Inherits)
+	assert.True(t, admin.HasPermission("logs", "read"), "transitively inherited from viewer")
+
+	viewer, ok := registry.Role("viewer")
+	require.True(t, ok)
+	assert.False(t, viewer.HasPermission("deployments", "write"))
+
+	_, ok = registry.Role("missing")
+	assert.False(t, ok)
+
+	names := make([]string, 0, 3)
+	for _, role := range registry.Roles() {
+		names = append(names, role.Name)
+	}
+	assert.Equal(t, []string{"admin", "operator", "viewer"}, names)
+	assert.Len(t, registry.RolePermissions()["operator"], 2)
+}
+
+func TestNewRoleRegistry_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		roles   []Role
+		message string
+	}{
+		"empty":          {nil, "at least one role"},
+		"unnamed":        {[]Role{{Name: " "}}, "name must not be empty"},
+		"duplicate":      {[]Role{{Name: "a"}, {Name: "a"}}, `duplicate role "a"`},
+		"unknown parent": {[]Role{{Name: "a", Inherits: []string{"b"}}}, `unknown role "b"`},
+		"self cycle":     {[]Role{{Name: "a", Inherits: []string{"a"}}}, "cycle: a -> a"},
+		"cycle": {[]Role{
+			{Name: "a", Inherits: []string{"b"}},
+			{Name: "b", Inherits: []string{"c"}},
+			{Name: "c", Inherits: []string{"a"}},
+		}, "cycle: a -> b -> c -> a"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewRoleRegistry(tt.roles)
+			require.Error(t, err)
+			assert.True(t, sserr.IsValidation(err), "error = %v", err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestRoleRegistry_ReturnsCopies(t *testing.T) {
+	t.Parallel()
+	registry, err := NewRoleRegistry(newRolesTestRoles())
+	require.NoError(t, err)
+
+	role, _ := registry.Role("viewer")
+	role.Permissions[0] = Permission{Resource: "*", Action: "*"}
+	registry.RolePermissions()["viewer"][0] = Permission{Resource: "*", Action: "*"}
+
+	role, _ = registry.Role("viewer")
+	assert.Equal(t, Permission{Resource: "*", Action: "read"}, role.Permissions[0])
+}
+
+func TestRoleRegistry_Reload(t *testing.T) {
+	t.Parallel()
+	registry, err := NewRoleRegistry(newRolesTestRoles())
+	require.NoError(t, err)
+	mapper := registry.PermissionMapper()
+	claims := map[string]any{"roles": []any{"support"}}
+	assert.Empty(t, mapper(claims))
+
+	ctx := context.Background()
+	source := &staticRoleSource{roles: append(newRolesTestRoles(),
+		Role{Name: "support", Inherits: []string{"viewer"}, Permissions: []Permission{{Resource: "tickets", Action: "write"}}})}
+	require.NoError(t, registry.Reload(ctx, source))
+	assert.ElementsMatch(t, []Permission{
+		{Resource: "tickets", Action: "write"},
+		{Resource: "*", Action: "read"},
+	}, mapper(claims), "existing mappers see reloaded roles")
+
+	// Failed loads and invalid roles keep the current roles.
+	source.err = errors.New("connection refused")
+	assert.Error(t, registry.Reload(ctx, source))
+	assert.Error(t, registry.Reload(ctx, &staticRoleSource{roles: []Role{{Name: "a", Inherits: []string{"a"}}}}))
+	_, ok := registry.Role("support")
+	assert.True(t, ok)
+}
+
+// ---------------------------------------------------------------------------
+// Role sources
+// ---------------------------------------------------------------------------
+
+func TestFileRoleSource(t *testing.T) {
+	t.Parallel()
+	files := map[string]string{
+		"roles.yaml": `
+roles:
+  - name: viewer
+    permissions: ["*:read"]
+  - name: operator
+    description: Operates deployments
+    inherits: [viewer]
+    permissions: ["deployments:*", "logs:delete[namespace == claims.tenant]"]
+`,
+		"roles.json": `{"roles": [
+  {"name": "viewer", "permissions": ["*:read"]},
+  {"name": "operator", "description": "Operates deployments", "inherits": ["viewer"],
+   "permissions": ["deployments:*", "logs:delete[namespace == claims.tenant]"]}
+]}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			roles, err := NewFileRoleSource(path).LoadRoles(context.Background())
+			require.NoError(t, err)
+			require.Len(t, roles, 2)
+			assert.Equal(t, Role{
+				Name:        "operator",
+				Description: "Operates deployments",
+				Inherits:    []string{"viewer"},
+				Permissions: []Permission{
+					{Resource: "deployments", Action: "*"},
+					{Resource: "logs", Action: "delete", Condition: "namespace == claims.tenant"},
+				},
+			}, roles[1])
+		})
+	}
+}
+
+func TestFileRoleSource_Errors(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	_, err := NewFileRoleSource(filepath.Join(dir, "missing.yaml")).LoadRoles(ctx)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalConfiguration), "error = %v", err)
+
+	malformed := filepath.Join(dir, "malformed.json")
+	require.NoError(t, os.WriteFile(malformed, []byte(`{"roles": [`), 0o600))
+	_, err = NewFileRoleSource(malformed).LoadRoles(ctx)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalConfiguration), "error = %v", err)
+
+	badPermission := filepath.Join(dir, "bad.yaml")
+	require.NoError(t, os.WriteFile(badPermission, []byte("roles:\n  - name: a\n    permissions: [nocolon]\n"), 0o600))
+	_, err = NewFileRoleSource(badPermission).LoadRoles(ctx)
+	assert.True(t, sserr.IsValidation(err), "error = %v", err)
+}
+
+func TestNewPostgresRoleSource_Invalid(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	client := postgres.NewFromPool(mock, nil)
+
+	_, err = NewPostgresRoleSource(nil, "")
+	assert.True(t, sserr.IsValidation(err))
+	for _, table := range []string{"bad name", "t; DROP TABLE x", "a.b.c", "1abc"} {
+		_, err := NewPostgresRoleSource(client, table)
+		assert.True(t, sserr.IsValidation(err), "table %q", table)
+	}
+}
+
+func TestPostgresRoleSource_LoadRoles(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT name, description, inherits, permissions FROM auth_roles").
+		WillReturnRows(pgxmock.NewRows([]string{"name", "description", "inherits", "permissions"}).
+			AddRow("operator", "", []string{"viewer"}, []string{"deployments:*"}).
+			AddRow("viewer", "Read-only", []string{}, []string{"*:read"}))
+
+	source, err := NewPostgresRoleSource(postgres.NewFromPool(mock, nil), "")
+	require.NoError(t, err)
+	roles, err := source.LoadRoles(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []Role{
+		{Name: "operator", Inherits: []string{"viewer"}, Permissions: []Permission{{Resource: "deployments", Action: "*"}}},
+		{Name: "viewer", Description: "Read-only", Permissions: []Permission{{Resource: "*", Action: "read"}}},
+	}, roles)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRoleSource_LoadRoles_Error(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT name").WillReturnError(errors.New("connection refused"))
+	source, err := NewPostgresRoleSource(postgres.NewFromPool(mock, nil), "")
+	require.NoError(t, err)
+	_, err = source.LoadRoles(context.Background())
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase), "error = %v", err)
+}
@@ -0,0 +1,646 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// HeaderIdentitySignature carries the signature over the propagated
+// identity headers when header signing is enabled. Its value has the form
+//
+//	alg=<algorithm>,t=<unix seconds>,n=<nonce>,s=<base64url signature>
+//
+// See [PropagationSigner] and [PropagationVerifier].
+const HeaderIdentitySignature = "x-identity-signature"
+
+// HeaderIdentityAudience names the service a signed header bundle is
+// addressed to. It is covered by the signature, and a
+// [PropagationVerifier] rejects bundles addressed to another service, so
+// a bundle captured on its way to one service cannot be replayed against
+// another.
+const HeaderIdentityAudience = "x-identity-audience"
+
+// DefaultSignatureMaxAge is the default maximum age of a signed header
+// bundle accepted by a [PropagationVerifier]. It also bounds how far in
+// the future a bundle's timestamp may be, to tolerate clock skew.
+const DefaultSignatureMaxAge = 30 * time.Second
+
+// Signature algorithm identifiers carried in [HeaderIdentitySignature].
+const (
+	// SignatureAlgHS256 is HMAC-SHA256 with a shared key.
+	SignatureAlgHS256 = "HS256"
+
+	// SignatureAlgEdDSA is Ed25519 with an asymmetric key pair.
+	SignatureAlgEdDSA = "EdDSA"
+)
+
+// signatureDomain prefixes every signed payload. It separates header
+// signatures from other uses of the same key (e.g., platform JWTs signed
+// with the platform HMAC key).
+const signatureDomain = "stricklysoft-identity-headers-v1"
+
+// nonceSize is the number of random bytes in a generated nonce.
+const nonceSize = 16
+
+// maxNonceLength bounds the length of an incoming nonce to keep nonce
+// stores from being filled with oversized keys.
+const maxNonceLength = 64
+
+// signedHeaders lists, in canonical order, the headers covered by the
+// signature. Absent headers are signed as empty values, so removing a
+// header invalidates the signature just like modifying it.
+var signedHeaders = []string{
+	HeaderIdentityID,
+	HeaderIdentityType,
+	HeaderIdentityClaims,
+	HeaderCallerService,
+	HeaderCallChain,
+	HeaderIdentityAudience,
+}
+
+// optionalSignedHeaders lists, in canonical order, headers covered by the
+// signature only when present, so that bundles without them sign the same
+// payload as before they were introduced and services of either version
+// verify each other. Removing one leaves the signature valid; this is
+// acceptable for [HeaderIdentity], whose removal only downgrades the
+// identity rebuilt downstream to a [BasicIdentity] without permissions.
+var optionalSignedHeaders = []string{
+	HeaderIdentity,
+}
+
+// HeaderSigner produces signatures over propagated identity headers.
+// Implementations must be safe for concurrent use.
+type HeaderSigner interface {
+	// Algorithm returns the algorithm identifier recorded in
+	// [HeaderIdentitySignature] (e.g., [SignatureAlgHS256]).
+	Algorithm() string
+
+	// Sign returns the signature of payload.
+	Sign(payload []byte) ([]byte, error)
+}
+
+// HeaderVerifier checks signatures over propagated identity headers.
+// Implementations must be safe for concurrent use.
+type HeaderVerifier interface {
+	// Algorithm returns the algorithm identifier this verifier accepts.
+	Algorithm() string
+
+	// Verify reports whether sig is a valid signature of payload.
+	Verify(payload, sig []byte) bool
+}
+
+// ===========================================================================
+// Keys
+// ===========================================================================
+
+// HMACHeaderKey signs and verifies header bundles with HMAC-SHA256 using a
+// key shared by every service in the platform. It implements both
+// [HeaderSigner] and [HeaderVerifier]. The platform signing key
+// ([ValidatorConfig.PlatformSigningKey]) may be reused; signatures are
+// domain-separated from platform tokens.
+type HMACHeaderKey struct {
+	key []byte
+}
+
+// NewHMACHeaderKey creates an [HMACHeaderKey]. Returns a [*sserr.Error]
+// with code [sserr.CodeValidation] if the key is shorter than 32 bytes.
+func NewHMACHeaderKey(key Secret) (*HMACHeaderKey, error) {
+	if len(key.Value()) < 32 {
+		return nil, sserr.New(sserr.CodeValidation,
+			"auth: header signing key must be at least 32 bytes")
+	}
+	return &HMACHeaderKey{key: []byte(key.Value())}, nil
+}
+
+// Algorithm returns [SignatureAlgHS256].
+func (k *HMACHeaderKey) Algorithm() string { return SignatureAlgHS256 }
+
+// Sign returns the HMAC-SHA256 of payload.
+func (k *HMACHeaderKey) Sign(payload []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+// Verify compares sig to the HMAC-SHA256 of payload in constant time.
+func (k *HMACHeaderKey) Verify(payload, sig []byte) bool {
+	expected, _ := k.Sign(payload)
+	return hmac.Equal(expected, sig)
+}
+
+// Ed25519HeaderSigner signs header bundles with an Ed25519 private key.
+// Receiving services verify with the matching [Ed25519HeaderVerifier], so
+// they cannot themselves forge bundles from the signing service.
+type Ed25519HeaderSigner struct {
+	key ed25519.PrivateKey
+}
+
+// NewEd25519HeaderSigner creates an [Ed25519HeaderSigner]. Returns a
+// [*sserr.Error] with code [sserr.CodeValidation] if the key is malformed.
+func NewEd25519HeaderSigner(key ed25519.PrivateKey) (*Ed25519HeaderSigner, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, sserr.Newf(sserr.CodeValidation,
+			"auth: ed25519 private key must be %d bytes", ed25519.PrivateKeySize)
+	}
+	return &Ed25519HeaderSigner{key: key}, nil
+}
+
+// Algorithm returns [SignatureAlgEdDSA].
+func (s *Ed25519HeaderSigner) Algorithm() string { return SignatureAlgEdDSA }
+
+// Sign returns the Ed25519 signature of payload.
+func (s *Ed25519HeaderSigner) Sign(payload []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, payload), nil
+}
+
+// Ed25519HeaderVerifier verifies header bundles with an Ed25519 public key.
+type Ed25519HeaderVerifier struct {
+	key ed25519.PublicKey
+}
+
+// NewEd25519HeaderVerifier creates an [Ed25519HeaderVerifier]. Returns a
+// [*sserr.Error] with code [sserr.CodeValidation] if the key is malformed.
+func NewEd25519HeaderVerifier(key ed25519.PublicKey) (*Ed25519HeaderVerifier, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, sserr.Newf(sserr.CodeValidation,
+			"auth: ed25519 public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	return &Ed25519HeaderVerifier{key: key}, nil
+}
+
+// Algorithm returns [SignatureAlgEdDSA].
+func (v *Ed25519HeaderVerifier) Algorithm() string { return SignatureAlgEdDSA }
+
+// Verify reports whether sig is a valid Ed25519 signature of payload.
+func (v *Ed25519HeaderVerifier) Verify(payload, sig []byte) bool {
+	return ed25519.Verify(v.key, payload, sig)
+}
+
+// ===========================================================================
+// Nonce Store
+// ===========================================================================
+
+// NonceStore records the nonces of accepted header bundles so that a
+// replayed bundle is rejected. Implementations must be safe for
+// concurrent use.
+//
+// A nonce only needs to be remembered until its bundle would be rejected
+// as too old, so the store is told when each nonce expires.
+type NonceStore interface {
+	// Remember records nonce until expiresAt. Returns false if the nonce
+	// is already recorded and has not expired.
+	Remember(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// MemoryNonceStore is an in-process [NonceStore]. It detects replays only
+// against the same process, so it is suitable only for services with a
+// single replica; services with several replicas behind a load balancer
+// must use a shared store such as [RedisNonceStore], or a bundle can be
+// replayed against each replica.
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	nextSweep int
+}
+
+// minNonceSweep is the number of stored nonces below which
+// [MemoryNonceStore] does not bother evicting expired entries.
+const minNonceSweep = 1024
+
+// NewMemoryNonceStore creates an empty [MemoryNonceStore].
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces:    make(map[string]time.Time),
+		nextSweep: minNonceSweep,
+	}
+}
+
+// Remember implements [NonceStore].
+func (s *MemoryNonceStore) Remember(_ context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if exp, ok := s.nonces[nonce]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.nonces[nonce] = expiresAt
+
+	// Evict expired nonces once the map has doubled since the last sweep,
+	// keeping the amortized cost per call constant.
+	if len(s.nonces) >= s.nextSweep {
+		for n, exp := range s.nonces {
+			if !now.Before(exp) {
+				delete(s.nonces, n)
+			}
+		}
+		s.nextSweep = max(minNonceSweep, 2*len(s.nonces))
+	}
+	return true, nil
+}
+
+// DefaultNonceKeyPrefix is the key prefix used by [RedisNonceStore] when
+// none is supplied. Nonces are stored at "<prefix><nonce>".
+const DefaultNonceKeyPrefix = "auth:nonce:"
+
+// redisNonceScript records KEYS[1] for ARGV[1] milliseconds unless it is
+// already recorded, returning 1 if it was recorded and 0 otherwise.
+const redisNonceScript = `
+if redis.call('SET', KEYS[1], '1', 'NX', 'PX', ARGV[1]) then
+  return 1
+end
+return 0
+`
+
+// RedisNonceStore is a [NonceStore] shared by all replicas through Redis.
+// Each nonce is recorded with an atomic SET NX under a key that expires
+// with its bundle, so a bundle accepted by one replica is rejected by
+// every other.
+//
+// RedisNonceStore is safe for concurrent use.
+type RedisNonceStore struct {
+	client *redis.Client
+	prefix string
+	now    func() time.Time
+}
+
+// Compile-time interface compliance check.
+var _ NonceStore = (*RedisNonceStore)(nil)
+
+// NewRedisNonceStore returns a nonce store backed by client, storing
+// nonces under prefix. An empty prefix uses [DefaultNonceKeyPrefix].
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if client is
+// nil.
+func NewRedisNonceStore(client *redis.Client, prefix string) (*RedisNonceStore, error) {
+	if client == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"auth: redis nonce store requires a client")
+	}
+	if prefix == "" {
+		prefix = DefaultNonceKeyPrefix
+	}
+	return &RedisNonceStore{client: client, prefix: prefix, now: time.Now}, nil
+}
+
+// Remember implements [NonceStore]. A nonce whose expiry has passed is
+// reported as fresh without being stored. Returns a *[sserr.Error] with
+// code [sserr.CodeInternalDatabase] if Redis fails.
+func (s *RedisNonceStore) Remember(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	ttl := expiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return true, nil
+	}
+	result, err := s.client.Eval(ctx, redisNonceScript, []string{s.prefix + nonce},
+		max(ttl.Milliseconds(), 1))
+	if err != nil {
+		return false, sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"auth: failed to record nonce")
+	}
+	recorded, _ := result.(int64)
+	return recorded == 1, nil
+}
+
+// ===========================================================================
+// Signing and Verification
+// ===========================================================================
+
+// PropagationSigner adds a [HeaderIdentitySignature] to outgoing identity
+// headers. Pass it to [NewPropagatingRoundTripper], [UnaryClientInterceptor],
+// or [StreamClientInterceptor] with [WithPropagationSigner].
+type PropagationSigner struct {
+	signer HeaderSigner
+	now    func() time.Time
+}
+
+// NewPropagationSigner creates a [PropagationSigner]. Returns a
+// [*sserr.Error] with code [sserr.CodeValidation] if signer is nil.
+func NewPropagationSigner(signer HeaderSigner) (*PropagationSigner, error) {
+	if signer == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"auth: header signer must not be nil")
+	}
+	return &PropagationSigner{signer: signer, now: time.Now}, nil
+}
+
+// Sign addresses headers to audience, the name of the receiving service,
+// by setting [HeaderIdentityAudience], and adds a
+// [HeaderIdentitySignature] entry covering the identity headers, the
+// audience, the current time, and a fresh random nonce. Returns an error
+// if audience is empty.
+func (s *PropagationSigner) Sign(audience string, headers map[string]string) error {
+	if audience == "" {
+		return fmt.Errorf("auth: signed identity headers require an audience")
+	}
+	headers[HeaderIdentityAudience] = audience
+
+	raw := make([]byte, nonceSize)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("auth: failed to generate nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(raw)
+	ts := s.now().Unix()
+	alg := s.signer.Algorithm()
+
+	sig, err := s.signer.Sign(signaturePayload(alg, ts, nonce, func(key string) string {
+		return headers[key]
+	}))
+	if err != nil {
+		return fmt.Errorf("auth: failed to sign identity headers: %w", err)
+	}
+	headers[HeaderIdentitySignature] = fmt.Sprintf("alg=%s,t=%d,n=%s,s=%s",
+		alg, ts, nonce, base64.RawURLEncoding.EncodeToString(sig))
+	return nil
+}
+
+// PropagationVerifier checks the [HeaderIdentitySignature] of incoming
+// identity headers, rejecting bundles that are unsigned, tampered with,
+// addressed to another service, replayed, or too old. Pass it to
+// [HTTPMiddleware], [UnaryServerInterceptor], or [StreamServerInterceptor]
+// with [WithPropagationVerifier].
+type PropagationVerifier struct {
+	verifier HeaderVerifier
+	maxAge   time.Duration
+	nonces   NonceStore
+	now      func() time.Time
+}
+
+// NewPropagationVerifier creates a [PropagationVerifier]. A maxAge of zero
+// or less uses [DefaultSignatureMaxAge]; a nil nonces uses a new
+// [MemoryNonceStore], which is only suitable for a service with a single
+// replica; pass a [RedisNonceStore] otherwise. Returns a [*sserr.Error]
+// with code [sserr.CodeValidation] if verifier is nil.
+func NewPropagationVerifier(verifier HeaderVerifier, maxAge time.Duration, nonces NonceStore) (*PropagationVerifier, error) {
+	if verifier == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"auth: header verifier must not be nil")
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultSignatureMaxAge
+	}
+	if nonces == nil {
+		nonces = NewMemoryNonceStore()
+	}
+	return &PropagationVerifier{
+		verifier: verifier,
+		maxAge:   maxAge,
+		nonces:   nonces,
+		now:      time.Now,
+	}, nil
+}
+
+// Verify checks the signed header bundle read through getValue, which
+// must be addressed to audience, the name of the verifying service. A
+// request that carries none of the propagated identity headers is
+// accepted, since it was not forwarded by another service.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeAuthenticationExpired] if
+// the bundle is too old (or too far in the future),
+// [sserr.CodeAuthenticationInvalid] if the signature is missing,
+// malformed, or does not match, the bundle is addressed to another
+// service, or the nonce was already used, or [sserr.CodeUnavailable] if
+// the nonce store fails.
+func (v *PropagationVerifier) Verify(ctx context.Context, audience string, getValue func(key string) string) error {
+	value := getValue(HeaderIdentitySignature)
+	if value == "" {
+		for _, keys := range [][]string{signedHeaders, optionalSignedHeaders} {
+			for _, key := range keys {
+				if getValue(key) != "" {
+					return sserr.New(sserr.CodeAuthenticationInvalid,
+						"auth: propagated identity headers are not signed")
+				}
+			}
+		}
+		return nil
+	}
+
+	alg, ts, nonce, sig, err := parseSignatureHeader(value)
+	if err != nil {
+		return err
+	}
+	if alg != v.verifier.Algorithm() {
+		return sserr.Newf(sserr.CodeAuthenticationInvalid,
+			"auth: unexpected header signature algorithm %q", alg)
+	}
+
+	age := v.now().Sub(time.Unix(ts, 0))
+	if age > v.maxAge || age < -v.maxAge {
+		return sserr.New(sserr.CodeAuthenticationExpired,
+			"auth: propagated identity headers are too old")
+	}
+
+	if !v.verifier.Verify(signaturePayload(alg, ts, nonce, getValue), sig) {
+		return sserr.New(sserr.CodeAuthenticationInvalid,
+			"auth: propagated identity header signature is invalid")
+	}
+	// Check the audience before recording the nonce, so that a bundle
+	// addressed to another service does not burn its nonce in a shared
+	// store.
+	if got := getValue(HeaderIdentityAudience); got == "" || got != audience {
+		return sserr.Newf(sserr.CodeAuthenticationInvalid,
+			"auth: propagated identity headers are addressed to %q, not %q", got, audience)
+	}
+
+	// Record the nonce only after the signature checks out, so forged
+	// bundles cannot fill the store or burn legitimate nonces.
+	fresh, err := v.nonces.Remember(ctx, nonce, time.Unix(ts, 0).Add(v.maxAge))
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeUnavailable,
+			"auth: failed to record header signature nonce")
+	}
+	if !fresh {
+		return sserr.New(sserr.CodeAuthenticationInvalid,
+			"auth: propagated identity headers were replayed")
+	}
+	return nil
+}
+
+// signaturePayload builds the canonical byte string that is signed. Each
+// header value is length-prefixed so that no two distinct bundles share a
+// payload.
+func signaturePayload(alg string, ts int64, nonce string, getValue func(key string) string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n%d\n%s\n", signatureDomain, alg, ts, nonce)
+	for _, key := range signedHeaders {
+		v := getValue(key)
+		fmt.Fprintf(&b, "%s:%d:%s\n", key, len(v), v)
+	}
+	for _, key := range optionalSignedHeaders {
+		if v := getValue(key); v != "" {
+			fmt.Fprintf(&b, "%s:%d:%s\n", key, len(v), v)
+		}
+	}
+	return []byte(b.String())
+}
+
+// parseSignatureHeader parses a [HeaderIdentitySignature] value.
+func parseSignatureHeader(value string) (alg string, ts int64, nonce string, sig []byte, err error) {
+	invalid := func(reason string) error {
+		return sserr.Newf(sserr.CodeAuthenticationInvalid,
+			"auth: malformed identity header signature: %s", reason)
+	}
+
+	var tsRaw, sigRaw string
+	for _, part := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return "", 0, "", nil, invalid("expected key=value pairs")
+		}
+		switch k {
+		case "alg":
+			alg = v
+		case "t":
+			tsRaw = v
+		case "n":
+			nonce = v
+		case "s":
+			sigRaw = v
+		}
+	}
+	if alg == "" || tsRaw == "" || nonce == "" || sigRaw == "" {
+		return "", 0, "", nil, invalid("alg, t, n, and s are required")
+	}
+	if len(nonce) > maxNonceLength {
+		return "", 0, "", nil, invalid("nonce too long")
+	}
+	if ts, err = strconv.ParseInt(tsRaw, 10, 64); err != nil {
+		return "", 0, "", nil, invalid("invalid timestamp")
+	}
+	if sig, err = base64.RawURLEncoding.DecodeString(sigRaw); err != nil {
+		return "", 0, "", nil, invalid("invalid signature encoding")
+	}
+	return alg, ts, nonce, sig, nil
+}
+
+// ===========================================================================
+// Options
+// ===========================================================================
+
+// PropagationOption configures identity propagation and authentication in
+// [HTTPMiddleware], [NewPropagatingRoundTripper], and the gRPC
+// interceptors.
+type PropagationOption func(*propagationOptions)
+
+// propagationOptions holds the settings applied by [PropagationOption]s.
+type propagationOptions struct {
+	signer        *PropagationSigner
+	verifier      *PropagationVerifier
+	certValidator CertificateValidator
+	chainPolicy   *CallChainPolicy
+	tokenSource   TokenSource
+	audience      string
+}
+
+// WithPropagationSigner signs outgoing identity headers. It applies to
+// [NewPropagatingRoundTripper], [UnaryClientInterceptor], and
+// [StreamClientInterceptor], and is ignored by server-side middleware.
+func WithPropagationSigner(signer *PropagationSigner) PropagationOption {
+	return func(o *propagationOptions) { o.signer = signer }
+}
+
+// WithPropagationVerifier requires incoming identity headers to carry a
+// valid signature. It applies to [HTTPMiddleware],
+// [UnaryServerInterceptor], and [StreamServerInterceptor], and is ignored
+// by client-side propagation.
+func WithPropagationVerifier(verifier *PropagationVerifier) PropagationOption {
+	return func(o *propagationOptions) { o.verifier = verifier }
+}
+
+// WithPropagationAudience sets the audience of signed identity headers
+// (see [HeaderIdentityAudience]). On the client side, it is the audience
+// of every outgoing bundle, overriding the default: the first DNS label
+// of the request host or gRPC dial target, e.g. "agent-manager" for
+// "agent-manager.platform.svc:8443". On the server side, it is the
+// audience accepted by [WithPropagationVerifier], overriding the default:
+// the service's own name.
+func WithPropagationAudience(audience string) PropagationOption {
+	return func(o *propagationOptions) { o.audience = audience }
+}
+
+// WithCertificateValidator authenticates requests without a bearer token
+// by their verified TLS client certificate, so that either a token or a
+// client certificate identifies the caller. A request with a bearer token
+// is always authenticated by the token. It applies to [HTTPMiddleware],
+// [UnaryServerInterceptor], and [StreamServerInterceptor], and is ignored
+// by client-side propagation.
+func WithCertificateValidator(validator CertificateValidator) PropagationOption {
+	return func(o *propagationOptions) { o.certValidator = validator }
+}
+
+// WithCallChainPolicy rejects requests whose propagated call chain
+// violates policy, and requests whose call chain header is malformed. It
+// applies to [HTTPMiddleware], [UnaryServerInterceptor], and
+// [StreamServerInterceptor], and is ignored by client-side propagation.
+func WithCallChainPolicy(policy CallChainPolicy) PropagationOption {
+	return func(o *propagationOptions) { o.chainPolicy = &policy }
+}
+
+// WithTokenSource sends a bearer token from source in the Authorization
+// header of outgoing requests, so that the service authenticates as
+// itself to downstream services. Requests that already carry an
+// Authorization header are left unchanged, and a request fails if source
+// returns an error. It applies to [NewPropagatingRoundTripper],
+// [UnaryClientInterceptor], and [StreamClientInterceptor], and is
+// ignored by server-side middleware.
+func WithTokenSource(source TokenSource) PropagationOption {
+	return func(o *propagationOptions) { o.tokenSource = source }
+}
+
+// newPropagationOptions applies opts to a zero-value configuration.
+func newPropagationOptions(opts []PropagationOption) propagationOptions {
+	var o propagationOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// serverAudience returns the audience accepted by a server named
+// serviceName: the audience set by [WithPropagationAudience], or
+// serviceName.
+func (o propagationOptions) serverAudience(serviceName string) string {
+	if o.audience != "" {
+		return o.audience
+	}
+	return serviceName
+}
+
+// serviceAudience returns the default audience for requests to hostport:
+// the first DNS label of its host, lowercased, or the whole address if
+// the host is an IP address.
+func serviceAudience(hostport string) string {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	label, _, _ := strings.Cut(host, ".")
+	return label
+}
+
+// targetAudience returns the default audience for a gRPC dial target such
+// as "agent-manager:443" or "dns:///agent-manager.platform.svc:443".
+func targetAudience(target string) string {
+	if _, rest, ok := strings.Cut(target, "://"); ok {
+		target = rest[strings.LastIndex(rest, "/")+1:]
+	}
+	return serviceAudience(target)
+}
@@ -0,0 +1,665 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StricklySoft/stricklysoft-core/internal/testutil/fakes"
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// testHeaderKey is a 32-byte HMAC key for header signing tests.
+const testHeaderKey = Secret("header-signing-key-0123456789abcdef")
+
+// testAudience is the audience of signed test headers: the service name
+// of the servers in these tests.
+const testAudience = "downstream"
+
+// newTestHMACPair returns a signer and verifier sharing testHeaderKey.
+func newTestHMACPair(t *testing.T) (*PropagationSigner, *PropagationVerifier) {
+	t.Helper()
+	key, err := NewHMACHeaderKey(testHeaderKey)
+	require.NoError(t, err)
+	signer, err := NewPropagationSigner(key)
+	require.NoError(t, err)
+	verifier, err := NewPropagationVerifier(key, 0, nil)
+	require.NoError(t, err)
+	return signer, verifier
+}
+
+// signedTestHeaders returns identity headers for newTestIdentity signed by
+// signer.
+func signedTestHeaders(t *testing.T, signer *PropagationSigner) map[string]string {
+	t.Helper()
+	identity := newTestIdentity()
+	chain := (&CallChain{OriginalID: identity.ID(), OriginalType: identity.Type()}).
+		AppendCaller(CallerInfo{ServiceName: "gateway", IdentityID: identity.ID(), IdentityType: identity.Type()})
+	headers, err := identityToHeaders(identity, "gateway", chain)
+	require.NoError(t, err)
+	require.NoError(t, signer.Sign(testAudience, headers))
+	return headers
+}
+
+// mapGetter returns a header getter over m.
+func mapGetter(m map[string]string) func(string) string {
+	return func(key string) string { return m[key] }
+}
+
+// failingNonceStore is a NonceStore that always returns err.
+type failingNonceStore struct{ err error }
+
+func (s failingNonceStore) Remember(context.Context, string, time.Time) (bool, error) {
+	return false, s.err
+}
+
+// ---------------------------------------------------------------------------
+// Keys
+// ---------------------------------------------------------------------------
+
+func TestNewHMACHeaderKey_ShortKey(t *testing.T) {
+	t.Parallel()
+	_, err := NewHMACHeaderKey(Secret("too-short"))
+	require.Error(t, err)
+	assert.True(t, sserr.IsValidation(err))
+}
+
+func TestNewEd25519Keys_InvalidSize(t *testing.T) {
+	t.Parallel()
+	_, err := NewEd25519HeaderSigner(ed25519.PrivateKey("short"))
+	assert.True(t, sserr.IsValidation(err))
+	_, err = NewEd25519HeaderVerifier(ed25519.PublicKey("short"))
+	assert.True(t, sserr.IsValidation(err))
+}
+
+func TestNewPropagationSigner_NilSigner(t *testing.T) {
+	t.Parallel()
+	_, err := NewPropagationSigner(nil)
+	assert.True(t, sserr.IsValidation(err))
+	_, err = NewPropagationVerifier(nil, 0, nil)
+	assert.True(t, sserr.IsValidation(err))
+}
+
+// ---------------------------------------------------------------------------
+// PropagationVerifier
+// ---------------------------------------------------------------------------
+
+func TestPropagationVerifier_HMACRoundTrip(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+	headers := signedTestHeaders(t, signer)
+
+	assert.True(t, strings.HasPrefix(headers[HeaderIdentitySignature], "alg=HS256,t="))
+	assert.NoError(t, verifier.Verify(context.Background(), testAudience, mapGetter(headers)))
+}
+
+func TestPropagationVerifier_Ed25519RoundTrip(t *testing.T) {
+	t.Parallel()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := NewEd25519HeaderSigner(priv)
+	require.NoError(t, err)
+	signer, err := NewPropagationSigner(key)
+	require.NoError(t, err)
+	pubKey, err := NewEd25519HeaderVerifier(pub)
+	require.NoError(t, err)
+	verifier, err := NewPropagationVerifier(pubKey, time.Minute, NewMemoryNonceStore())
+	require.NoError(t, err)
+
+	headers := signedTestHeaders(t, signer)
+	assert.NoError(t, verifier.Verify(context.Background(), testAudience, mapGetter(headers)))
+
+	// A verifier for a different key pair rejects the bundle.
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := NewEd25519HeaderVerifier(otherPub)
+	require.NoError(t, err)
+	other, err := NewPropagationVerifier(otherKey, 0, nil)
+	require.NoError(t, err)
+	assert.True(t, sserr.HasCode(other.Verify(context.Background(), testAudience, mapGetter(headers)), sserr.CodeAuthenticationInvalid))
+}
+
+func TestPropagationVerifier_RejectsTampering(t *testing.T) {
+	t.Parallel()
+	for _, key := range append(signedHeaders, optionalSignedHeaders...) {
+		t.Run(key, func(t *testing.T) {
+			t.Parallel()
+			signer, verifier := newTestHMACPair(t)
+			headers := signedTestHeaders(t, signer)
+			headers[key] += "x"
+
+			err := verifier.Verify(context.Background(), testAudience, mapGetter(headers))
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error code = %v", sserr.GetCode(err))
+		})
+	}
+}
+
+func TestPropagationVerifier_RejectsRemovedHeader(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+	headers := signedTestHeaders(t, signer)
+	delete(headers, HeaderCallChain)
+
+	err := verifier.Verify(context.Background(), testAudience, mapGetter(headers))
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid))
+}
+
+func TestPropagationVerifier_OptionalHeaders(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+	ctx := context.Background()
+
+	// Bundles from services that do not send the identity envelope verify.
+	headers := signedTestHeaders(t, signer)
+	envelope := headers[HeaderIdentity]
+	require.NotEmpty(t, envelope)
+	delete(headers, HeaderIdentity)
+	delete(headers, HeaderIdentitySignature)
+	require.NoError(t, signer.Sign(testAudience, headers))
+	require.NoError(t, verifier.Verify(ctx, testAudience, mapGetter(headers)))
+
+	// An envelope cannot be added to such a bundle.
+	headers = signedTestHeaders(t, signer)
+	delete(headers, HeaderIdentity)
+	delete(headers, HeaderIdentitySignature)
+	require.NoError(t, signer.Sign(testAudience, headers))
+	headers[HeaderIdentity] = envelope
+	err := verifier.Verify(ctx, testAudience, mapGetter(headers))
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+
+	// Nor sent unsigned.
+	err = verifier.Verify(ctx, testAudience, mapGetter(map[string]string{HeaderIdentity: envelope}))
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+}
+
+func TestPropagationVerifier_RejectsOtherAudience(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+	headers := signedTestHeaders(t, signer)
+	assert.Equal(t, testAudience, headers[HeaderIdentityAudience])
+
+	err := verifier.Verify(context.Background(), "billing", mapGetter(headers))
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error code = %v", sserr.GetCode(err))
+	assert.Contains(t, err.Error(), "addressed to")
+
+	// The rejected bundle did not consume its nonce.
+	assert.NoError(t, verifier.Verify(context.Background(), testAudience, mapGetter(headers)))
+}
+
+func TestPropagationSigner_EmptyAudience(t *testing.T) {
+	t.Parallel()
+	signer, _ := newTestHMACPair(t)
+	headers := map[string]string{HeaderIdentityID: "usr-1"}
+	assert.Error(t, signer.Sign("", headers))
+	assert.Empty(t, headers[HeaderIdentitySignature])
+}
+
+func TestPropagationVerifier_RejectsReplay(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+	headers := signedTestHeaders(t, signer)
+
+	require.NoError(t, verifier.Verify(context.Background(), testAudience, mapGetter(headers)))
+	err := verifier.Verify(context.Background(), testAudience, mapGetter(headers))
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid))
+	assert.Contains(t, err.Error(), "replayed")
+}
+
+func TestPropagationVerifier_RejectsStaleAndFuture(t *testing.T) {
+	t.Parallel()
+	for _, offset := range []time.Duration{-time.Minute, time.Minute} {
+		signer, verifier := newTestHMACPair(t)
+		signer.now = func() time.Time { return time.Now().Add(offset) }
+		headers := signedTestHeaders(t, signer)
+
+		err := verifier.Verify(context.Background(), testAudience, mapGetter(headers))
+		require.Error(t, err, "offset %s", offset)
+		assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationExpired), "offset %s: error code = %v", offset, sserr.GetCode(err))
+	}
+}
+
+func TestPropagationVerifier_MissingSignature(t *testing.T) {
+	t.Parallel()
+	_, verifier := newTestHMACPair(t)
+
+	// No propagated headers at all: nothing to verify.
+	assert.NoError(t, verifier.Verify(context.Background(), testAudience, mapGetter(nil)))
+
+	// Propagated headers without a signature are rejected.
+	err := verifier.Verify(context.Background(), testAudience, mapGetter(map[string]string{HeaderCallerService: "gateway"}))
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid))
+}
+
+func TestPropagationVerifier_MalformedSignature(t *testing.T) {
+	t.Parallel()
+	_, verifier := newTestHMACPair(t)
+	inputs := []string{
+		"garbage",
+		"alg=HS256,t=1,n=abc",
+		"alg=HS256,t=soon,n=abc,s=AAAA",
+		"alg=HS256,t=1,n=abc,s=!!!",
+		"alg=HS256,t=1,n=" + strings.Repeat("n", maxNonceLength+1) + ",s=AAAA",
+		"alg=EdDSA,t=1,n=abc,s=AAAA",
+	}
+	for _, input := range inputs {
+		err := verifier.Verify(context.Background(), testAudience, mapGetter(map[string]string{HeaderIdentitySignature: input}))
+		assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "input %q: error code = %v", input, sserr.GetCode(err))
+	}
+}
+
+func TestPropagationVerifier_NonceStoreError(t *testing.T) {
+	t.Parallel()
+	signer, _ := newTestHMACPair(t)
+	key, err := NewHMACHeaderKey(testHeaderKey)
+	require.NoError(t, err)
+	verifier, err := NewPropagationVerifier(key, 0, failingNonceStore{err: errors.New("redis down")})
+	require.NoError(t, err)
+
+	err = verifier.Verify(context.Background(), testAudience, mapGetter(signedTestHeaders(t, signer)))
+	assert.True(t, sserr.HasCode(err, sserr.CodeUnavailable))
+}
+
+// ---------------------------------------------------------------------------
+// MemoryNonceStore
+// ---------------------------------------------------------------------------
+
+func TestMemoryNonceStore_ExpiryAndSweep(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryNonceStore()
+	ctx := context.Background()
+
+	fresh, err := store.Remember(ctx, "n1", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+	fresh, _ = store.Remember(ctx, "n1", time.Now().Add(time.Minute))
+	assert.False(t, fresh, "unexpired nonce must be rejected")
+
+	// An expired nonce may be reused.
+	_, _ = store.Remember(ctx, "n2", time.Now().Add(-time.Second))
+	fresh, _ = store.Remember(ctx, "n2", time.Now().Add(time.Minute))
+	assert.True(t, fresh)
+
+	// Expired nonces are evicted once the store grows.
+	for i := 0; i < minNonceSweep; i++ {
+		_, _ = store.Remember(ctx, fmt.Sprintf("expired-%d", i), time.Now().Add(-time.Second))
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.Less(t, len(store.nonces), minNonceSweep)
+}
+
+// ---------------------------------------------------------------------------
+// RedisNonceStore
+// ---------------------------------------------------------------------------
+
+// newTestRedisNonceStore returns a RedisNonceStore over a fake that
+// emulates the nonce script.
+func newTestRedisNonceStore(t *testing.T) (*RedisNonceStore, *fakes.Redis) {
+	t.Helper()
+	fake := fakes.NewRedis()
+	fake.HandleScript(redisNonceScript, func(r *fakes.Redis, keys []string, args []interface{}) (interface{}, error) {
+		ctx := context.Background()
+		if r.Exists(ctx, keys[0]).Val() > 0 {
+			return int64(0), nil
+		}
+		r.Set(ctx, keys[0], "1", time.Duration(args[0].(int64))*time.Millisecond)
+		return int64(1), nil
+	})
+	store, err := NewRedisNonceStore(redis.NewFromClient(fake, nil), "")
+	require.NoError(t, err)
+	return store, fake
+}
+
+func TestNewRedisNonceStore_NilClient(t *testing.T) {
+	t.Parallel()
+	_, err := NewRedisNonceStore(nil, "")
+	assert.True(t, sserr.HasCode(err, sserr.CodeValidation), "error = %v", err)
+}
+
+func TestRedisNonceStore_Remember(t *testing.T) {
+	t.Parallel()
+	store, fake := newTestRedisNonceStore(t)
+	ctx := context.Background()
+
+	fresh, err := store.Remember(ctx, "n1", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+	fresh, err = store.Remember(ctx, "n1", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, fresh, "unexpired nonce must be rejected")
+	assert.Equal(t, 1, fake.Keys())
+	ttl, err := redis.NewFromClient(fake, nil).TTL(ctx, DefaultNonceKeyPrefix+"n1")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second), "the nonce expires with its bundle")
+
+	// An expired nonce is accepted without being stored.
+	fresh, err = store.Remember(ctx, "n2", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+	assert.Equal(t, 1, fake.Keys())
+}
+
+func TestRedisNonceStore_SharedAcrossReplicas(t *testing.T) {
+	t.Parallel()
+	store, _ := newTestRedisNonceStore(t)
+	key, err := NewHMACHeaderKey(testHeaderKey)
+	require.NoError(t, err)
+	signer, err := NewPropagationSigner(key)
+	require.NoError(t, err)
+	replica1, err := NewPropagationVerifier(key, 0, store)
+	require.NoError(t, err)
+	replica2, err := NewPropagationVerifier(key, 0, store)
+	require.NoError(t, err)
+
+	headers := signedTestHeaders(t, signer)
+	require.NoError(t, replica1.Verify(context.Background(), testAudience, mapGetter(headers)))
+	err = replica2.Verify(context.Background(), testAudience, mapGetter(headers))
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "bundle replayed against another replica: %v", err)
+}
+
+func TestRedisNonceStore_Error(t *testing.T) {
+	t.Parallel()
+	store, fake := newTestRedisNonceStore(t)
+	fake.SetError(errors.New("connection refused"))
+
+	_, err := store.Remember(context.Background(), "n1", time.Now().Add(time.Minute))
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase), "error = %v", err)
+}
+
+// ---------------------------------------------------------------------------
+// Audience
+// ---------------------------------------------------------------------------
+
+func TestServiceAudience(t *testing.T) {
+	t.Parallel()
+	tests := []struct{ hostport, want string }{
+		{"downstream", "downstream"},
+		{"Agent-Manager.platform.svc.cluster.local:8443", "agent-manager"},
+		{"10.0.0.7:8080", "10.0.0.7"},
+		{"[::1]:8080", "::1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, serviceAudience(tt.hostport), "host %q", tt.hostport)
+	}
+}
+
+func TestTargetAudience(t *testing.T) {
+	t.Parallel()
+	tests := []struct{ target, want string }{
+		{"agent-manager:443", "agent-manager"},
+		{"dns:///agent-manager.platform.svc:443", "agent-manager"},
+		{"dns://8.8.8.8/agent-manager.platform.svc:443", "agent-manager"},
+		{"passthrough:///10.0.0.7:9000", "10.0.0.7"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, targetAudience(tt.target), "target %q", tt.target)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Middleware and Interceptors
+// ---------------------------------------------------------------------------
+
+func TestHTTPMiddleware_SignedHeaders(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+
+	// Propagate through a signing round tripper into a verifying middleware.
+	var reached bool
+	server := HTTPMiddleware(&mockValidator{identity: newTestIdentity()}, "downstream", WithPropagationVerifier(verifier))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached = true
+			caller, ok := CallerServiceFromContext(r.Context())
+			assert.True(t, ok)
+			assert.Equal(t, "gateway", caller)
+		}))
+
+	mock := &mockRoundTripper{response: &http.Response{StatusCode: http.StatusOK}}
+	rt := NewPropagatingRoundTripper("gateway", mock, WithPropagationSigner(signer))
+	ctx := ContextWithIdentity(context.Background(), newTestIdentity())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://downstream/api", nil)
+	_, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	require.NotEmpty(t, mock.capturedReq.Header.Get(HeaderIdentitySignature))
+
+	forward := func() int {
+		in := httptest.NewRequest(http.MethodGet, "/api", nil)
+		in.Header = mock.capturedReq.Header.Clone()
+		in.Header.Set(HeaderAuthorization, "Bearer valid-token")
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, in)
+		return rr.Code
+	}
+	assert.Equal(t, http.StatusOK, forward())
+	assert.True(t, reached)
+
+	// The same bundle is rejected when replayed.
+	reached = false
+	assert.Equal(t, http.StatusUnauthorized, forward())
+	assert.False(t, reached)
+}
+
+func TestHTTPMiddleware_RejectsOtherAudience(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+	handler := HTTPMiddleware(&mockValidator{identity: newTestIdentity()}, "downstream", WithPropagationVerifier(verifier))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("inner handler should not be called with headers addressed to another service")
+		}))
+
+	// A bundle sent to billing cannot be replayed against downstream.
+	mock := &mockRoundTripper{response: &http.Response{StatusCode: http.StatusOK}}
+	rt := NewPropagatingRoundTripper("gateway", mock, WithPropagationSigner(signer))
+	ctx := ContextWithIdentity(context.Background(), newTestIdentity())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://billing.platform.svc:8080/api", nil)
+	_, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "billing", mock.capturedReq.Header.Get(HeaderIdentityAudience))
+
+	in := httptest.NewRequest(http.MethodGet, "/api", nil)
+	in.Header = mock.capturedReq.Header.Clone()
+	in.Header.Set(HeaderAuthorization, "Bearer valid-token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, in)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestHTTPMiddleware_RejectsForgedHeaders(t *testing.T) {
+	t.Parallel()
+	_, verifier := newTestHMACPair(t)
+	handler := HTTPMiddleware(&mockValidator{identity: newTestIdentity()}, "downstream", WithPropagationVerifier(verifier))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("inner handler should not be called with forged headers")
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set(HeaderAuthorization, "Bearer valid-token")
+	req.Header.Set(HeaderCallerService, "admin-service")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestGRPCInterceptors_SignedMetadata(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+
+	client := UnaryClientInterceptor("gateway", WithPropagationSigner(signer), WithPropagationAudience("downstream"))
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	ctx := ContextWithIdentity(context.Background(), newTestIdentity())
+	require.NoError(t, client(ctx, "/test.Service/Method", "req", "reply", nil, invoker))
+	require.NotEmpty(t, outgoing.Get(HeaderIdentitySignature))
+
+	server := UnaryServerInterceptor(&mockValidator{identity: newTestIdentity()}, "downstream", WithPropagationVerifier(verifier))
+	call := func(md metadata.MD) error {
+		md = metadata.Join(md, metadata.Pairs(HeaderAuthorization, "Bearer valid-token"))
+		inCtx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := server(inCtx, "req", &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+			return "ok", nil
+		})
+		return err
+	}
+
+	// Tampered metadata is rejected without consuming the nonce.
+	tampered := outgoing.Copy()
+	tampered.Set(HeaderCallerService, "admin-service")
+	assert.Equal(t, codes.Unauthenticated, status.Code(call(tampered)))
+
+	require.NoError(t, call(outgoing))
+	assert.Equal(t, codes.Unauthenticated, status.Code(call(outgoing)), "replayed metadata must be rejected")
+}
+
+// envelopeTestServer returns a verifying middleware named "downstream"
+// that authenticates every request as validated, and a client that signs
+// the identity in its request context for it. The handler records the
+// identity it sees in got.
+func envelopeTestServer(t *testing.T, validated Identity, got *Identity) (*httptest.Server, *http.Client) {
+	t.Helper()
+	signer, verifier := newTestHMACPair(t)
+	handler := HTTPMiddleware(&mockValidator{identity: validated}, "downstream", WithPropagationVerifier(verifier))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*got, _ = IdentityFromContext(r.Context())
+		}))
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := &http.Client{Transport: NewPropagatingRoundTripper("gateway", nil,
+		WithPropagationSigner(signer), WithPropagationAudience("downstream"))}
+	return server, client
+}
+
+// sendAs sends a request to server through client on behalf of identity.
+func sendAs(t *testing.T, server *httptest.Server, client *http.Client, identity Identity) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ContextWithIdentity(context.Background(), identity),
+		http.MethodGet, server.URL+"/api", nil)
+	require.NoError(t, err)
+	req.Header.Set(HeaderAuthorization, "Bearer caller-token")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestHTTPMiddleware_TypedIdentityEnvelope(t *testing.T) {
+	t.Parallel()
+	readDocs := []Permission{{Resource: "documents", Action: "read"}}
+	validated := mustNewUserIdentity(t, "usr-1", "ada@corp.example", "", map[string]any{"sub": "usr-1"}, readDocs)
+	var got Identity
+	server, client := envelopeTestServer(t, validated, &got)
+
+	sendAs(t, server, client, mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", map[string]any{"sub": "usr-1"}, readDocs))
+	user, ok := got.(*UserIdentity)
+	require.True(t, ok, "identity = %T", got)
+	assert.Equal(t, "ada@example.com", user.Email(), "descriptive fields come from the envelope")
+	assert.Equal(t, "Ada", user.DisplayName())
+	assert.Equal(t, readDocs, user.Permissions())
+
+	// An envelope for another identity is ignored.
+	sendAs(t, server, client, mustNewUserIdentity(t, "usr-2", "eve@example.com", "Eve", nil, readDocs))
+	assert.Same(t, validated, got)
+}
+
+func TestHTTPMiddleware_EnvelopeCannotAddPermissions(t *testing.T) {
+	t.Parallel()
+	all := []Permission{{Resource: "*", Action: "*"}}
+	readDocs := []Permission{{Resource: "documents", Action: "read"}}
+
+	t.Run("forwarded user token", func(t *testing.T) {
+		t.Parallel()
+		validated := mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", map[string]any{"sub": "usr-1"}, readDocs)
+		var got Identity
+		server, client := envelopeTestServer(t, validated, &got)
+
+		sendAs(t, server, client, mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada",
+			map[string]any{"sub": "usr-1", "roles": []any{"admin"}}, all))
+		require.IsType(t, &UserIdentity{}, got)
+		assert.Equal(t, readDocs, got.(*UserIdentity).Permissions())
+		assert.False(t, got.HasPermission("users", "delete"))
+		assert.Equal(t, validated.Claims(), got.Claims(), "claims come from the credentials")
+	})
+
+	t.Run("own service token", func(t *testing.T) {
+		t.Parallel()
+		validated := NewBasicIdentity("svc-gateway", IdentityTypeService, nil)
+		var got Identity
+		server, client := envelopeTestServer(t, validated, &got)
+
+		forged, err := NewServiceIdentity("svc-gateway", "gateway", "platform", nil, all)
+		require.NoError(t, err)
+		sendAs(t, server, client, forged)
+		service, ok := got.(*ServiceIdentity)
+		require.True(t, ok, "identity = %T", got)
+		assert.Equal(t, "gateway", service.ServiceName())
+		assert.Empty(t, service.Permissions())
+		assert.False(t, service.HasPermission("documents", "read"))
+	})
+
+	t.Run("agent delegation", func(t *testing.T) {
+		t.Parallel()
+		validated := NewBasicIdentity("agent-1", IdentityTypeAgent, nil)
+		var got Identity
+		server, client := envelopeTestServer(t, validated, &got)
+
+		user := mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", nil, all)
+		agent, err := NewAgentIdentity(newTestAgentInfo(), nil, all, user)
+		require.NoError(t, err)
+		sendAs(t, server, client, agent)
+		typed, ok := got.(*AgentIdentity)
+		require.True(t, ok, "identity = %T", got)
+		assert.Equal(t, "research-agent", typed.Name())
+		assert.Nil(t, typed.OnBehalfOf(), "delegation is never taken from a header")
+		assert.Empty(t, typed.Permissions())
+	})
+}
+
+func TestGRPCInterceptors_TypedIdentityEnvelope(t *testing.T) {
+	t.Parallel()
+	signer, verifier := newTestHMACPair(t)
+	forged := mustNewUserIdentity(t, "usr-1", "ada@example.com", "Ada", nil, []Permission{{Resource: "*", Action: "*"}})
+
+	client := UnaryClientInterceptor("gateway", WithPropagationSigner(signer), WithPropagationAudience("downstream"))
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	require.NoError(t, client(ContextWithIdentity(context.Background(), forged), "/test.Service/Method", "req", "reply", nil, invoker))
+
+	validated := NewBasicIdentity("usr-1", IdentityTypeUser, map[string]any{"sub": "usr-1"})
+	server := UnaryServerInterceptor(&mockValidator{identity: validated}, "downstream", WithPropagationVerifier(verifier))
+	md := metadata.Join(outgoing, metadata.Pairs(HeaderAuthorization, "Bearer user-token"))
+	var got Identity
+	_, err := server(metadata.NewIncomingContext(context.Background(), md), "req", &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req any) (any, error) {
+			got, _ = IdentityFromContext(ctx)
+			return "ok", nil
+		})
+	require.NoError(t, err)
+	user, ok := got.(*UserIdentity)
+	require.True(t, ok, "identity = %T", got)
+	assert.Equal(t, "ada@example.com", user.Email())
+	assert.Empty(t, user.Permissions(), "the envelope must not add permissions")
+}
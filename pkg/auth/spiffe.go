@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ---------------------------------------------------------------------------
+// SPIFFEID — workload identity from an X.509-SVID
+// ---------------------------------------------------------------------------
+
+// SPIFFEID is a Kubernetes workload's SPIFFE ID, of the form
+// "spiffe://<trust-domain>/ns/<namespace>/sa/<service-account>".
+type SPIFFEID struct {
+	// TrustDomain is the SPIFFE trust domain, in lowercase.
+	TrustDomain string
+
+	// Namespace is the workload's Kubernetes namespace.
+	Namespace string
+
+	// ServiceAccount is the workload's Kubernetes ServiceAccount name.
+	ServiceAccount string
+}
+
+// String returns the SPIFFE ID as a URI.
+func (id SPIFFEID) String() string {
+	return "spiffe://" + id.TrustDomain + "/ns/" + id.Namespace + "/sa/" + id.ServiceAccount
+}
+
+// ParseSPIFFEID parses a SPIFFE ID of the form
+// "spiffe://<trust-domain>/ns/<namespace>/sa/<service-account>". Returns an
+// error if s is not a valid SPIFFE ID or has a different path.
+func ParseSPIFFEID(s string) (SPIFFEID, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return SPIFFEID{}, fmt.Errorf("auth: invalid SPIFFE ID %q: %w", s, err)
+	}
+	if u.Scheme != "spiffe" {
+		return SPIFFEID{}, fmt.Errorf("auth: SPIFFE ID %q must use the spiffe scheme", s)
+	}
+	if u.Host == "" || u.Port() != "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return SPIFFEID{}, fmt.Errorf("auth: SPIFFE ID %q must have a trust domain and no port, user info, query, or fragment", s)
+	}
+	parts := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+	if len(parts) != 4 || parts[0] != "ns" || parts[2] != "sa" || !validSPIFFESegment(parts[1]) || !validSPIFFESegment(parts[3]) {
+		return SPIFFEID{}, fmt.Errorf("auth: SPIFFE ID %q does not have the form spiffe://<trust-domain>/ns/<namespace>/sa/<service-account>", s)
+	}
+	return SPIFFEID{
+		TrustDomain:    strings.ToLower(u.Host),
+		Namespace:      parts[1],
+		ServiceAccount: parts[3],
+	}, nil
+}
+
+// validSPIFFESegment reports whether s is a non-empty SPIFFE ID path
+// segment, made only of the characters the SPIFFE specification allows.
+func validSPIFFESegment(s string) bool {
+	if s == "" || s == "." || s == ".." {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// ---------------------------------------------------------------------------
+// SPIFFEValidator — CertificateValidator for SPIFFE X.509-SVIDs
+// ---------------------------------------------------------------------------
+
+// SPIFFEConfig holds the configuration for [SPIFFEValidator].
+type SPIFFEConfig struct {
+	// TrustDomains lists the SPIFFE trust domains whose workloads are
+	// accepted (e.g., "cluster.local"). At least one is required.
+	TrustDomains []string `json:"trust_domains"`
+
+	// PermissionMapper extracts permissions from the claims built from
+	// the SPIFFE ID ("sub", "spiffe_id", "trust_domain", "namespace", and
+	// "service_account"). If nil, workloads have no permissions.
+	PermissionMapper func(claims map[string]any) []Permission `json:"-"`
+}
+
+// SPIFFEValidator authenticates workloads by the SPIFFE ID in the URI SAN
+// of their client certificate (X.509-SVID). It implements
+// [CertificateValidator].
+//
+// SPIFFEValidator does not verify certificate chains: the TLS server must
+// be configured to require and verify client certificates against the
+// SPIFFE trust bundle, and only verified certificates are passed to it.
+//
+// SPIFFEValidator is safe for concurrent use by multiple goroutines.
+type SPIFFEValidator struct {
+	trustDomains []string
+	permMapper   func(claims map[string]any) []Permission
+	tracer       trace.Tracer
+}
+
+// Compile-time assertion that SPIFFEValidator implements CertificateValidator.
+var _ CertificateValidator = (*SPIFFEValidator)(nil)
+
+// NewSPIFFEValidator creates a SPIFFEValidator with the given
+// configuration. Returns a *[sserr.Error] with code [sserr.CodeValidation]
+// if no trust domain is configured or a trust domain is invalid.
+func NewSPIFFEValidator(cfg SPIFFEConfig) (*SPIFFEValidator, error) {
+	if len(cfg.TrustDomains) == 0 {
+		return nil, sserr.New(sserr.CodeValidation, "auth: at least one SPIFFE trust domain is required")
+	}
+	trustDomains := make([]string, 0, len(cfg.TrustDomains))
+	for _, td := range cfg.TrustDomains {
+		if td == "" || strings.ContainsAny(td, ":/") {
+			return nil, sserr.Newf(sserr.CodeValidation, "auth: invalid SPIFFE trust domain %q", td)
+		}
+		trustDomains = append(trustDomains, strings.ToLower(td))
+	}
+	return &SPIFFEValidator{
+		trustDomains: trustDomains,
+		permMapper:   cfg.PermissionMapper,
+		tracer:       otel.Tracer(tracerName),
+	}, nil
+}
+
+// ValidateCertificate returns the [ServiceIdentity] of the workload that
+// presented cert. The certificate must be a leaf with exactly one URI SAN,
+// holding a SPIFFE ID in an allowed trust domain.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeAuthenticationInvalid] if
+// the certificate is not an acceptable X.509-SVID.
+func (v *SPIFFEValidator) ValidateCertificate(ctx context.Context, cert *x509.Certificate) (Identity, error) {
+	_, span := startSpan(ctx, v.tracer, "auth.ValidateCertificate")
+	var retErr error
+	defer func() {
+		finishSpan(span, retErr)
+		span.End()
+	}()
+
+	if cert == nil {
+		retErr = sserr.New(sserr.CodeAuthenticationInvalid, "auth: client certificate is missing")
+		return nil, retErr
+	}
+	if cert.IsCA {
+		retErr = sserr.New(sserr.CodeAuthenticationInvalid, "auth: client certificate must not be a CA certificate")
+		return nil, retErr
+	}
+	if len(cert.URIs) != 1 {
+		retErr = sserr.New(sserr.CodeAuthenticationInvalid, "auth: client certificate must have exactly one URI SAN")
+		return nil, retErr
+	}
+
+	id, err := ParseSPIFFEID(cert.URIs[0].String())
+	if err != nil {
+		retErr = sserr.Wrap(err, sserr.CodeAuthenticationInvalid, "auth: client certificate has an invalid SPIFFE ID")
+		return nil, retErr
+	}
+	span.SetAttributes(attribute.String("auth.spiffe_id", id.String()))
+	if !slices.Contains(v.trustDomains, id.TrustDomain) {
+		retErr = sserr.Newf(sserr.CodeAuthenticationInvalid, "auth: SPIFFE trust domain %q is not allowed", id.TrustDomain)
+		return nil, retErr
+	}
+
+	claims := map[string]any{
+		"sub":             id.String(),
+		"spiffe_id":       id.String(),
+		"trust_domain":    id.TrustDomain,
+		"namespace":       id.Namespace,
+		"service_account": id.ServiceAccount,
+	}
+	var permissions []Permission
+	if v.permMapper != nil {
+		permissions = v.permMapper(claims)
+	}
+	identity, err := NewServiceIdentity(id.String(), id.ServiceAccount, id.Namespace, claims, permissions)
+	if err != nil {
+		retErr = sserr.Wrap(err, sserr.CodeAuthenticationInvalid, "auth: failed to create service identity from SPIFFE ID")
+		return nil, retErr
+	}
+	return identity, nil
+}
+
+// ---------------------------------------------------------------------------
+// Peer certificate extraction
+// ---------------------------------------------------------------------------
+
+// verifiedPeerCertificate returns the leaf certificate of the first
+// verified chain in state, or nil if the peer presented no certificate
+// or it was not verified during the handshake.
+func verifiedPeerCertificate(state *tls.ConnectionState) *x509.Certificate {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// grpcPeerCertificate returns the verified client certificate of the gRPC
+// peer in ctx, or nil if the peer did not connect with a verified TLS
+// client certificate.
+func grpcPeerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return verifiedPeerCertificate(&tlsInfo.State)
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// spiffeTestCert returns an unsigned leaf certificate with the given URI
+// SANs, for tests that only inspect certificate fields.
+func spiffeTestCert(t *testing.T, uris ...string) *x509.Certificate {
+	t.Helper()
+	cert := &x509.Certificate{}
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		cert.URIs = append(cert.URIs, u)
+	}
+	return cert
+}
+
+// spiffeTestTLSState returns a connection state whose verified chain ends
+// in cert.
+func spiffeTestTLSState(cert *x509.Certificate) *tls.ConnectionState {
+	return &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+}
+
+// newTestSPIFFEValidator returns a SPIFFEValidator for the "cluster.local"
+// trust domain that grants every workload read access to documents.
+func newTestSPIFFEValidator(t *testing.T) *SPIFFEValidator {
+	t.Helper()
+	v, err := NewSPIFFEValidator(SPIFFEConfig{
+		TrustDomains: []string{"cluster.local"},
+		PermissionMapper: func(map[string]any) []Permission {
+			return []Permission{{Resource: "documents", Action: "read"}}
+		},
+	})
+	require.NoError(t, err)
+	return v
+}
+
+// ---------------------------------------------------------------------------
+// SPIFFEID
+// ---------------------------------------------------------------------------
+
+func TestParseSPIFFEID(t *testing.T) {
+	t.Parallel()
+	id, err := ParseSPIFFEID("spiffe://Cluster.Local/ns/payments/sa/ledger")
+	require.NoError(t, err)
+	assert.Equal(t, SPIFFEID{TrustDomain: "cluster.local", Namespace: "payments", ServiceAccount: "ledger"}, id)
+	assert.Equal(t, "spiffe://cluster.local/ns/payments/sa/ledger", id.String())
+
+	invalid := []string{
+		"https://cluster.local/ns/payments/sa/ledger",
+		"spiffe:///ns/payments/sa/ledger",
+		"spiffe://cluster.local:8443/ns/payments/sa/ledger",
+		"spiffe://user@cluster.local/ns/payments/sa/ledger",
+		"spiffe://cluster.local/ns/payments/sa/ledger?x=1",
+		"spiffe://cluster.local/ns/payments/sa/ledger#frag",
+		"spiffe://cluster.local/ns/payments",
+		"spiffe://cluster.local/ns//sa/ledger",
+		"spiffe://cluster.local/workload/ledger",
+		"spiffe://cluster.local/ns/payments/sa/ledger/extra",
+		"spiffe://cluster.local/ns/pay%2Fments/sa/ledger",
+	}
+	for _, s := range invalid {
+		_, err := ParseSPIFFEID(s)
+		assert.Error(t, err, s)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// SPIFFEValidator
+// ---------------------------------------------------------------------------
+
+func TestNewSPIFFEValidator_Invalid(t *testing.T) {
+	t.Parallel()
+	for name, cfg := range map[string]SPIFFEConfig{
+		"no trust domains":   {},
+		"empty trust domain": {TrustDomains: []string{""}},
+		"URI trust domain":   {TrustDomains: []string{"spiffe://cluster.local"}},
+	} {
+		_, err := NewSPIFFEValidator(cfg)
+		assert.True(t, sserr.IsValidation(err), name)
+	}
+}
+
+func TestSPIFFEValidator_ValidateCertificate(t *testing.T) {
+	t.Parallel()
+	v := newTestSPIFFEValidator(t)
+	ctx := context.Background()
+
+	identity, err := v.ValidateCertificate(ctx, spiffeTestCert(t, "spiffe://cluster.local/ns/payments/sa/ledger"))
+	require.NoError(t, err)
+	svc, ok := identity.(*ServiceIdentity)
+	require.True(t, ok, "identity type = %T", identity)
+	assert.Equal(t, "spiffe://cluster.local/ns/payments/sa/ledger", svc.ID())
+	assert.Equal(t, "ledger", svc.ServiceName())
+	assert.Equal(t, "payments", svc.Namespace())
+	assert.Equal(t, "cluster.local", svc.Claims()["trust_domain"])
+	assert.True(t, svc.HasPermission("documents", "read"))
+
+	caCert := spiffeTestCert(t, "spiffe://cluster.local/ns/payments/sa/ledger")
+	caCert.IsCA = true
+	rejected := map[string]*x509.Certificate{
+		"nil":                     nil,
+		"CA certificate":          caCert,
+		"no URI SAN":              spiffeTestCert(t),
+		"two URI SANs":            spiffeTestCert(t, "spiffe://cluster.local/ns/a/sa/b", "spiffe://cluster.local/ns/c/sa/d"),
+		"not a SPIFFE ID":         spiffeTestCert(t, "https://cluster.local/ns/payments/sa/ledger"),
+		"disallowed trust domain": spiffeTestCert(t, "spiffe://partner.example/ns/payments/sa/ledger"),
+	}
+	for name, cert := range rejected {
+		_, err := v.ValidateCertificate(ctx, cert)
+		assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "%s: error = %v", name, err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Middleware chaining
+// ---------------------------------------------------------------------------
+
+func TestHTTPMiddleware_CertificateValidator(t *testing.T) {
+	t.Parallel()
+	middleware := HTTPMiddleware(&mockValidator{identity: newTestIdentity()}, "test-service",
+		WithCertificateValidator(newTestSPIFFEValidator(t)))
+	cert := spiffeTestCert(t, "spiffe://cluster.local/ns/payments/sa/ledger")
+
+	var got Identity
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = IdentityFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name       string
+		auth       string
+		tls        *tls.ConnectionState
+		wantStatus int
+		wantID     string
+	}{
+		{"certificate", "", spiffeTestTLSState(cert), http.StatusOK, "spiffe://cluster.local/ns/payments/sa/ledger"},
+		{"token takes precedence", "Bearer valid-token", spiffeTestTLSState(cert), http.StatusOK, "user-42"},
+		{"token without certificate", "Bearer valid-token", nil, http.StatusOK, "user-42"},
+		{"non-bearer header with certificate", "Basic abc", spiffeTestTLSState(cert), http.StatusUnauthorized, ""},
+		{"unverified certificate", "", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, http.StatusUnauthorized, ""},
+		{"neither", "", nil, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		got = nil
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		req.TLS = tt.tls
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, tt.wantStatus, rec.Code, tt.name)
+		if tt.wantID != "" && assert.NotNil(t, got, tt.name) {
+			assert.Equal(t, tt.wantID, got.ID(), tt.name)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor_CertificateValidator(t *testing.T) {
+	t.Parallel()
+	interceptor := UnaryServerInterceptor(&mockValidator{identity: newTestIdentity()}, "test-service",
+		WithCertificateValidator(newTestSPIFFEValidator(t)))
+	cert := spiffeTestCert(t, "spiffe://cluster.local/ns/payments/sa/ledger")
+	peerCtx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: *spiffeTestTLSState(cert)},
+	})
+
+	var got Identity
+	handler := func(ctx context.Context, _ any) (any, error) {
+		got, _ = IdentityFromContext(ctx)
+		return nil, nil
+	}
+
+	_, err := interceptor(peerCtx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "spiffe://cluster.local/ns/payments/sa/ledger", got.ID())
+
+	tokenCtx := metadata.NewIncomingContext(peerCtx, metadata.Pairs(HeaderAuthorization, "Bearer valid-token"))
+	_, err = interceptor(tokenCtx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", got.ID())
+
+	// A peer certificate outside the allowed trust domains is rejected.
+	otherCtx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: *spiffeTestTLSState(spiffeTestCert(t, "spiffe://partner.example/ns/a/sa/b"))},
+	})
+	_, err = interceptor(otherCtx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestHTTPMiddleware_MutualTLS(t *testing.T) {
+	t.Parallel()
+	// A CA standing in for the SPIFFE trust bundle issues the client SVID.
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientTemplate := spiffeTestCert(t, "spiffe://cluster.local/ns/payments/sa/ledger")
+	clientTemplate.SerialNumber = big.NewInt(2)
+	clientTemplate.NotBefore = time.Now().Add(-time.Hour)
+	clientTemplate.NotAfter = time.Now().Add(time.Hour)
+	clientTemplate.KeyUsage = x509.KeyUsageDigitalSignature
+	clientTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, caCert, &clientKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	srv := httptest.NewUnstartedServer(HTTPMiddleware(nil, "test-service",
+		WithCertificateValidator(newTestSPIFFEValidator(t)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := IdentityFromContext(r.Context())
+		_, _ = w.Write([]byte(identity.ID()))
+	})))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: roots}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	transport := client.Transport.(*http.Transport)
+	transport.TLSClientConfig.Certificates = []tls.Certificate{{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}}
+
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body := make([]byte, 128)
+	n, _ := resp.Body.Read(body)
+	assert.Equal(t, "spiffe://cluster.local/ns/payments/sa/ledger", string(body[:n]))
+}
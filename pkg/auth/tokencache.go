@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ===========================================================================
+// SharedTokenCache
+// ===========================================================================
+
+// SharedTokenCache is a second-tier cache of validated identities shared
+// by the replicas of a service. Set it as
+// [ValidatorConfig.SharedTokenCache]; on a miss in its in-memory cache,
+// the validator looks the token up in the shared cache before verifying
+// it, and stores every identity it verifies there. Implementations must
+// be safe for concurrent use.
+type SharedTokenCache interface {
+	// Get returns the identity cached for the token with the given hash
+	// and the time its entry expires, or a nil Identity if there is none.
+	Get(ctx context.Context, tokenHash string) (Identity, time.Time, error)
+
+	// Put caches identity for the token with the given hash until
+	// expiresAt.
+	Put(ctx context.Context, tokenHash string, identity Identity, expiresAt time.Time) error
+}
+
+// ===========================================================================
+// RedisTokenCache
+// ===========================================================================
+
+// DefaultTokenCacheKeyPrefix is the key prefix used by [RedisTokenCache]
+// when none is supplied. Identities are stored at "<prefix><token hash>".
+const DefaultTokenCacheKeyPrefix = "auth:token:"
+
+// tokenCacheKeyInfo separates the encryption key [RedisTokenCache]
+// derives from its secret from other uses of the same secret, such as
+// signing platform tokens.
+const tokenCacheKeyInfo = "stricklysoft auth token cache v1"
+
+// RedisTokenCacheOption configures a [RedisTokenCache].
+type RedisTokenCacheOption func(*redisTokenCacheOptions)
+
+// redisTokenCacheOptions holds the settings applied by
+// [RedisTokenCacheOption] functions.
+type redisTokenCacheOptions struct {
+	prefix string
+	meter  metric.Meter
+}
+
+// WithTokenCacheKeyPrefix sets the key prefix. Defaults to
+// [DefaultTokenCacheKeyPrefix].
+func WithTokenCacheKeyPrefix(prefix string) RedisTokenCacheOption {
+	return func(o *redisTokenCacheOptions) {
+		if prefix != "" {
+			o.prefix = prefix
+		}
+	}
+}
+
+// WithTokenCacheMeter sets the meter used for cache metrics. Defaults to
+// the global meter provider.
+func WithTokenCacheMeter(meter metric.Meter) RedisTokenCacheOption {
+	return func(o *redisTokenCacheOptions) { o.meter = meter }
+}
+
+// RedisTokenCache is a [SharedTokenCache] stored in Redis. Entries are
+// encrypted and authenticated with AES-256-GCM under a key derived from a
+// platform secret, so that neither the identities nor their claims can be
+// read from Redis, and an entry written without the secret, or moved to
+// another token's key, is ignored. Each entry expires with the token, or
+// sooner if the validator's cache TTL is shorter.
+//
+// Entries that cannot be decrypted, for example after the secret is
+// rotated, are treated as misses and overwritten once the token is
+// validated again.
+//
+// The cache records the counter "auth.token_cache.shared.lookups" with
+// attribute "auth.result" ("hit", "miss", "invalid", or "error"), from
+// which the hit rate is hit / (hit + miss + invalid + error).
+//
+// RedisTokenCache is safe for concurrent use.
+type RedisTokenCache struct {
+	client  *redis.Client
+	prefix  string
+	aead    cipher.AEAD
+	lookups metric.Int64Counter
+
+	// now returns the current time. Replaced in tests.
+	now func() time.Time
+}
+
+// Compile-time interface compliance check.
+var _ SharedTokenCache = (*RedisTokenCache)(nil)
+
+// NewRedisTokenCache returns a token cache backed by client whose entries
+// are encrypted with a key derived from secret. Every replica sharing the
+// cache must use the same secret; the platform signing key may be reused.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeValidation] if client is
+// nil or secret is shorter than 32 bytes.
+func NewRedisTokenCache(client *redis.Client, secret Secret, opts ...RedisTokenCacheOption) (*RedisTokenCache, error) {
+	if client == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"auth: redis token cache requires a client")
+	}
+	if len(secret.Value()) < 32 {
+		return nil, sserr.New(sserr.CodeValidation,
+			"auth: redis token cache secret must be at least 32 bytes")
+	}
+	o := redisTokenCacheOptions{prefix: DefaultTokenCacheKeyPrefix, meter: otel.Meter(tracerName)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret.Value()))
+	mac.Write([]byte(tokenCacheKeyInfo))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal,
+			"auth: failed to initialize token cache cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal,
+			"auth: failed to initialize token cache cipher")
+	}
+
+	c := &RedisTokenCache{
+		client: client,
+		prefix: o.prefix,
+		aead:   aead,
+		now:    time.Now,
+	}
+	if c.lookups, err = o.meter.Int64Counter("auth.token_cache.shared.lookups",
+		metric.WithDescription("Shared token cache lookups, by result."),
+		metric.WithUnit("{lookup}")); err != nil {
+		c.lookups = noop.Int64Counter{}
+	}
+	return c, nil
+}
+
+// redisTokenCacheEntry is the plaintext of a [RedisTokenCache] entry.
+type redisTokenCacheEntry struct {
+	ExpiresAt time.Time      `json:"expires_at"`
+	Identity  identityRecord `json:"identity"`
+}
+
+// key returns the Redis key of the entry for tokenHash.
+func (c *RedisTokenCache) key(tokenHash string) string {
+	return c.prefix + tokenHash
+}
+
+// Get implements [SharedTokenCache]. Returns a *[sserr.Error] with code
+// [sserr.CodeInternalDatabase] if Redis fails.
+func (c *RedisTokenCache) Get(ctx context.Context, tokenHash string) (Identity, time.Time, error) {
+	key := c.key(tokenHash)
+	value, err := c.client.Get(ctx, key)
+	if errors.Is(err, goredis.Nil) {
+		c.record(ctx, "miss")
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		c.record(ctx, "error")
+		return nil, time.Time{}, sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"auth: failed to read token cache")
+	}
+
+	identity, expiresAt, ok := c.open(key, []byte(value))
+	if !ok {
+		c.record(ctx, "invalid")
+		return nil, time.Time{}, nil
+	}
+	c.record(ctx, "hit")
+	return identity, expiresAt, nil
+}
+
+// open decrypts and decodes the entry stored at key. Reports false if
+// the entry is not authentic, cannot be decoded, or has expired.
+func (c *RedisTokenCache) open(key string, sealed []byte) (Identity, time.Time, bool) {
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, time.Time{}, false
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(key))
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	var entry redisTokenCacheEntry
+	if err := json.Unmarshal(plaintext, &entry); err != nil {
+		return nil, time.Time{}, false
+	}
+	if !c.now().Before(entry.ExpiresAt) {
+		return nil, time.Time{}, false
+	}
+	identity, err := entry.Identity.identity()
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	return identity, entry.ExpiresAt, true
+}
+
+// Put implements [SharedTokenCache]. Nothing is stored if expiresAt has
+// passed. Returns a *[sserr.Error] with code [sserr.CodeValidation] if
+// identity is not one of the identity types of this package, or
+// [sserr.CodeInternalDatabase] if Redis fails.
+func (c *RedisTokenCache) Put(ctx context.Context, tokenHash string, identity Identity, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(c.now())
+	if ttl <= 0 {
+		return nil
+	}
+	record, err := newIdentityRecord(identity)
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeValidation,
+			"auth: identity cannot be cached")
+	}
+	plaintext, err := json.Marshal(redisTokenCacheEntry{ExpiresAt: expiresAt, Identity: record})
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeInternal,
+			"auth: failed to encode token cache entry")
+	}
+
+	key := c.key(tokenHash)
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternal,
+			"auth: failed to generate token cache nonce")
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(key))
+	if err := c.client.Set(ctx, key, sealed, ttl); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"auth: failed to write token cache")
+	}
+	return nil
+}
+
+// record counts a lookup with the given result.
+func (c *RedisTokenCache) record(ctx context.Context, result string) {
+	c.lookups.Add(ctx, 1, metric.WithAttributes(attribute.String("auth.result", result)))
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/StricklySoft/stricklysoft-core/internal/testutil/fakes"
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// newTestRedisTokenCache returns a RedisTokenCache over a new Redis fake,
+// with the fake and the cache's meter.
+func newTestRedisTokenCache(t *testing.T) (*RedisTokenCache, *fakes.Redis, *countingMeter) {
+	t.Helper()
+	fake := fakes.NewRedis()
+	meter := &countingMeter{}
+	cache, err := NewRedisTokenCache(redis.NewFromClient(fake, nil), Secret(testSigningKey),
+		WithTokenCacheKeyPrefix("test:token:"), WithTokenCacheMeter(meter))
+	require.NoError(t, err)
+	return cache, fake, meter
+}
+
+// newSharedCacheValidator returns a platform validator using cache and
+// checker, verifying tokens with a keyring whose verifications are
+// recorded by the returned meter.
+func newSharedCacheValidator(t *testing.T, cache SharedTokenCache, checker RevocationChecker) (*JWTValidator, *countingMeter) {
+	t.Helper()
+	meter := &countingMeter{}
+	keyring, err := NewKeyring([]SigningKey{{ID: "k1", Secret: Secret(testSigningKey)}}, WithKeyringMeter(meter))
+	require.NoError(t, err)
+	cfg := newPlatformConfig()
+	cfg.PlatformSigningKey = ""
+	cfg.PlatformKeyring = keyring
+	cfg.SharedTokenCache = cache
+	cfg.RevocationChecker = checker
+	v, err := NewJWTValidator(cfg)
+	require.NoError(t, err)
+	return v, meter
+}
+
+// foreignIdentity is an Identity implemented outside the package.
+type foreignIdentity struct {
+	*BasicIdentity
+}
+
+// ---------------------------------------------------------------------------
+// RedisTokenCache
+// ---------------------------------------------------------------------------
+
+func TestNewRedisTokenCache_Invalid(t *testing.T) {
+	t.Parallel()
+	client := redis.NewFromClient(fakes.NewRedis(), nil)
+
+	_, err := NewRedisTokenCache(nil, Secret(testSigningKey))
+	assert.True(t, sserr.IsValidation(err), "nil client: %v", err)
+	_, err = NewRedisTokenCache(client, "short")
+	assert.True(t, sserr.IsValidation(err), "short secret: %v", err)
+}
+
+func TestRedisTokenCache_RoundTrip(t *testing.T) {
+	t.Parallel()
+	cache, fake, meter := newTestRedisTokenCache(t)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	perms := []Permission{{Resource: "documents", Action: "read", Scope: "production"}}
+	claims := map[string]any{"sub": "id-1", "roles": []any{"admin"}, "iat": float64(1700000000)}
+
+	service, err := NewServiceIdentity("svc-1", "nexus-gateway", "platform", claims, perms)
+	require.NoError(t, err)
+	user, err := NewUserIdentity("usr-1", "ada@example.com", "Ada", claims, perms)
+	require.NoError(t, err)
+	tests := map[string]Identity{
+		"basic":   NewBasicIdentity("sys-1", IdentityTypeSystem, claims),
+		"service": service,
+		"user":    user,
+	}
+	for name, identity := range tests {
+		require.NoError(t, cache.Put(ctx, name, identity, expiresAt), name)
+		got, gotExpiresAt, err := cache.Get(ctx, name)
+		require.NoError(t, err, name)
+		assert.Equal(t, identity, got, name)
+		assert.True(t, expiresAt.Equal(gotExpiresAt), name)
+
+		ttl, err := redis.NewFromClient(fake, nil).TTL(ctx, "test:token:"+name)
+		require.NoError(t, err)
+		assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 2, name)
+	}
+
+	got, _, err := cache.Get(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Equal(t, int64(3), meter.get("auth.token_cache.shared.lookups", "auth.result=hit"))
+	assert.Equal(t, int64(1), meter.get("auth.token_cache.shared.lookups", "auth.result=miss"))
+
+	// Expired tokens are not stored.
+	require.NoError(t, cache.Put(ctx, "expired", user, time.Now().Add(-time.Second)))
+	assert.Equal(t, 3, fake.Keys())
+}
+
+func TestRedisTokenCache_Encrypted(t *testing.T) {
+	t.Parallel()
+	cache, fake, meter := newTestRedisTokenCache(t)
+	client := redis.NewFromClient(fake, nil)
+	ctx := context.Background()
+	user, err := NewUserIdentity("usr-1", "ada@example.com", "Ada", nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, cache.Put(ctx, "hash-1", user, time.Now().Add(time.Minute)))
+
+	stored, err := client.Get(ctx, "test:token:hash-1")
+	require.NoError(t, err)
+	assert.NotContains(t, stored, "ada@example.com")
+	assert.NotContains(t, stored, "usr-1")
+
+	// An entry moved to another token's key is rejected.
+	require.NoError(t, client.Set(ctx, "test:token:hash-2", stored, time.Minute))
+	// So is an entry written with another secret.
+	other, err := NewRedisTokenCache(client, "another-32-byte-test-signing-key", WithTokenCacheKeyPrefix("test:token:"))
+	require.NoError(t, err)
+	require.NoError(t, other.Put(ctx, "hash-3", user, time.Now().Add(time.Minute)))
+	// And a truncated one.
+	require.NoError(t, client.Set(ctx, "test:token:hash-4", stored[:4], time.Minute))
+
+	for _, hash := range []string{"hash-2", "hash-3", "hash-4"} {
+		got, _, err := cache.Get(ctx, hash)
+		require.NoError(t, err, hash)
+		assert.Nil(t, got, hash)
+	}
+	assert.Equal(t, int64(3), meter.get("auth.token_cache.shared.lookups", "auth.result=invalid"))
+}
+
+func TestRedisTokenCache_RedisDown(t *testing.T) {
+	t.Parallel()
+	cache, fake, meter := newTestRedisTokenCache(t)
+	ctx := context.Background()
+	fake.SetError(errors.New("connection refused"))
+
+	_, _, err := cache.Get(ctx, "hash-1")
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase), "error = %v", err)
+	assert.Equal(t, int64(1), meter.get("auth.token_cache.shared.lookups", "auth.result=error"))
+
+	err = cache.Put(ctx, "hash-1", NewBasicIdentity("sys-1", IdentityTypeSystem, nil), time.Now().Add(time.Minute))
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase), "error = %v", err)
+}
+
+func TestRedisTokenCache_UnsupportedIdentity(t *testing.T) {
+	t.Parallel()
+	cache, _, _ := newTestRedisTokenCache(t)
+	err := cache.Put(context.Background(), "hash-1", foreignIdentity{NewBasicIdentity("sys-1", IdentityTypeSystem, nil)}, time.Now().Add(time.Minute))
+	assert.True(t, sserr.IsValidation(err), "error = %v", err)
+}
+
+// ---------------------------------------------------------------------------
+// JWTValidator with a SharedTokenCache
+// ---------------------------------------------------------------------------
+
+func TestValidate_SharedTokenCache(t *testing.T) {
+	t.Parallel()
+	cache, _, _ := newTestRedisTokenCache(t)
+	revocations := NewMemoryRevocationStore()
+	replicaA, meterA := newSharedCacheValidator(t, cache, revocations)
+	replicaB, meterB := newSharedCacheValidator(t, cache, revocations)
+	ctx := context.Background()
+	token := revocationTestToken(t, "t-1", "usr-1", time.Now())
+
+	want, err := replicaA.Validate(ctx, token)
+	require.NoError(t, err)
+	got, err := replicaB.Validate(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, int64(1), meterA.get("auth.platform_key.verifications", "auth.kid=k1,auth.result=verified"))
+	assert.Zero(t, meterB.get("auth.platform_key.verifications", "auth.kid=k1,auth.result=verified"),
+		"replica B should use the identity validated by replica A")
+	_, cached := replicaB.tokenCache.get(tokenHash(token))
+	assert.True(t, cached, "a shared cache hit should populate the in-memory cache")
+
+	// Identities from the shared cache are still checked for revocation.
+	other := revocationTestToken(t, "t-2", "usr-1", time.Now())
+	_, err = replicaA.Validate(ctx, other)
+	require.NoError(t, err)
+	require.NoError(t, revocations.Revoke(ctx, Revocation{TokenID: "t-2"}))
+	_, err = replicaB.Validate(ctx, other)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+}
+
+func TestValidate_SharedTokenCacheDown(t *testing.T) {
+	t.Parallel()
+	cache, fake, _ := newTestRedisTokenCache(t)
+	fake.SetError(errors.New("connection refused"))
+	v, meter := newSharedCacheValidator(t, cache, nil)
+
+	identity, err := v.Validate(context.Background(), revocationTestToken(t, "t-1", "usr-1", time.Now()))
+	require.NoError(t, err, "a failing shared cache should not fail validation")
+	assert.Equal(t, "usr-1", identity.ID())
+	assert.Equal(t, int64(1), meter.get("auth.platform_key.verifications", "auth.kid=k1,auth.result=verified"))
+}
+
+func TestValidate_SharedTokenCacheTTL(t *testing.T) {
+	t.Parallel()
+	cache, fake, _ := newTestRedisTokenCache(t)
+	v, _ := newSharedCacheValidator(t, cache, nil)
+	ctx := context.Background()
+	token := revocationTestToken(t, "t-1", "usr-1", time.Now())
+
+	_, err := v.Validate(ctx, token)
+	require.NoError(t, err)
+	ttl, err := redis.NewFromClient(fake, nil).TTL(ctx, "test:token:"+tokenHash(token))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ttl, 5*time.Minute, "entries should expire with the token cache TTL")
+	assert.Greater(t, ttl, 4*time.Minute)
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+const (
+	// DefaultKubernetesAPIServer is the in-cluster address of the
+	// Kubernetes API server, used for TokenReview requests.
+	DefaultKubernetesAPIServer = "https://kubernetes.default.svc"
+
+	// DefaultTokenReviewCacheTTL is the default time a TokenReview result
+	// is cached.
+	DefaultTokenReviewCacheTTL = time.Minute
+)
+
+// tokenReviewPath is the Kubernetes API path for creating TokenReviews.
+const tokenReviewPath = "/apis/authentication.k8s.io/v1/tokenreviews"
+
+// ---------------------------------------------------------------------------
+// TokenReview API types
+// ---------------------------------------------------------------------------
+
+// tokenReview is the subset of the authentication.k8s.io/v1 TokenReview
+// resource used to request and read a review.
+type tokenReview struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Spec       tokenReviewSpec    `json:"spec"`
+	Status     *tokenReviewStatus `json:"status,omitempty"`
+}
+
+// tokenReviewSpec holds the token to review and the audiences it must be
+// valid for.
+type tokenReviewSpec struct {
+	Token     string   `json:"token"`
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+// tokenReviewStatus is the API server's verdict on a token.
+type tokenReviewStatus struct {
+	Authenticated bool            `json:"authenticated"`
+	User          tokenReviewUser `json:"user"`
+	Audiences     []string        `json:"audiences,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// tokenReviewUser identifies the authenticated user of a token.
+type tokenReviewUser struct {
+	Username string `json:"username"`
+	UID      string `json:"uid"`
+}
+
+// ---------------------------------------------------------------------------
+// tokenReviewer — Kubernetes TokenReview client
+// ---------------------------------------------------------------------------
+
+// tokenReviewer verifies Kubernetes ServiceAccount tokens by asking the
+// API server, which unlike local JWKS verification also rejects bound
+// tokens whose pod or secret has been deleted. Rejections are cached
+// here; accepted tokens are cached by the validator's token cache, with
+// their entry capped by cacheUntil.
+type tokenReviewer struct {
+	url         string
+	audience    string
+	saTokenPath string
+	client      HTTPClient
+	ttl         time.Duration
+	maxSize     int
+
+	mu       sync.Mutex
+	rejected map[string]time.Time // token hash -> cache expiry
+}
+
+// newTokenReviewer creates a tokenReviewer from cfg, whose defaults must
+// already be applied.
+func newTokenReviewer(cfg *ValidatorConfig, client HTTPClient) *tokenReviewer {
+	return &tokenReviewer{
+		url:         strings.TrimRight(cfg.KubernetesAPIServer, "/") + tokenReviewPath,
+		audience:    cfg.KubernetesAudience,
+		saTokenPath: cfg.SATokenPath,
+		client:      client,
+		ttl:         cfg.TokenReviewCacheTTL,
+		maxSize:     cfg.TokenCacheMaxSize,
+		rejected:    make(map[string]time.Time),
+	}
+}
+
+// cacheUntil returns the time until which a token expiring at tokenExp
+// may be cached after a review, so that deleted pods are noticed within
+// the review cache TTL.
+func (r *tokenReviewer) cacheUntil(tokenExp time.Time) time.Time {
+	if until := time.Now().Add(r.ttl); until.Before(tokenExp) {
+		return until
+	}
+	return tokenExp
+}
+
+// validate reviews tokenStr and returns the identity it represents.
+// Returns a *[sserr.Error] with code [sserr.CodeUnavailable] if the API
+// server is unreachable, so that the caller can fall back to local
+// verification, and [sserr.CodeAuthenticationInvalid] if the API server
+// rejects the token.
+func (r *tokenReviewer) validate(ctx context.Context, tokenStr string, permMapper func(map[string]any) []Permission) (Identity, error) {
+	hash := tokenHash(tokenStr)
+	if r.isRejected(hash) {
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: kubernetes token review rejected token")
+	}
+
+	status, err := r.review(ctx, tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if !status.Authenticated {
+		r.reject(hash)
+		if status.Error != "" {
+			return nil, sserr.Wrap(errors.New(status.Error), sserr.CodeAuthenticationInvalid,
+				"auth: kubernetes token review rejected token")
+		}
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: kubernetes token review rejected token")
+	}
+	if r.audience != "" && !slices.Contains(status.Audiences, r.audience) {
+		r.reject(hash)
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: token audience is invalid")
+	}
+
+	// The API server has verified the token, so its claims can be used
+	// to build the identity.
+	token, _, err := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeAuthenticationInvalid, "auth: token is malformed")
+	}
+	mc, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid, "auth: invalid kubernetes token claims")
+	}
+	claimsMap := mapClaimsToMap(mc)
+	identity, err := parseK8sServiceAccountClaims(claimsMap, permMapper(claimsMap))
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeAuthenticationInvalid, "auth: failed to parse kubernetes service account claims")
+	}
+
+	// Reject tokens the API server authenticated as someone other than
+	// the service account named in their claims, such as tokens of an
+	// OIDC provider configured on the API server.
+	if identity.ID() != status.User.Username {
+		return nil, sserr.New(sserr.CodeAuthenticationInvalid,
+			"auth: kubernetes token review user does not match token claims")
+	}
+	return identity, nil
+}
+
+// review sends a TokenReview for tokenStr to the API server,
+// authenticated with the service's own ServiceAccount token.
+func (r *tokenReviewer) review(ctx context.Context, tokenStr string) (*tokenReviewStatus, error) {
+	saToken, err := ReadServiceAccountToken(r.saTokenPath)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to read service account token for token review")
+	}
+
+	reqBody := tokenReview{
+		APIVersion: "authentication.k8s.io/v1",
+		Kind:       "TokenReview",
+		Spec:       tokenReviewSpec{Token: tokenStr},
+	}
+	if r.audience != "" {
+		reqBody.Spec.Audiences = []string{r.audience}
+	}
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to encode token review")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to create token review request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+saToken)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeUnavailable, "auth: kubernetes API server is unreachable")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated:
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return nil, sserr.Newf(sserr.CodeUnavailable, "auth: kubernetes token review returned status %d", resp.StatusCode)
+	default:
+		return nil, sserr.Newf(sserr.CodeInternal, "auth: kubernetes token review returned status %d", resp.StatusCode)
+	}
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeUnavailable, "auth: failed to read token review response")
+	}
+	var result tokenReview
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to parse token review response")
+	}
+	if result.Status == nil {
+		return nil, sserr.New(sserr.CodeInternal, "auth: token review response missing status")
+	}
+	return result.Status, nil
+}
+
+// isRejected reports whether the token with hash was recently rejected.
+func (r *tokenReviewer) isRejected(hash string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expiresAt, ok := r.rejected[hash]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiresAt) {
+		delete(r.rejected, hash)
+		return false
+	}
+	return true
+}
+
+// reject caches the rejection of the token with hash. If the cache is
+// full after removing expired entries, the rejection is not cached.
+func (r *tokenReviewer) reject(hash string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if len(r.rejected) >= r.maxSize {
+		for k, expiresAt := range r.rejected {
+			if now.After(expiresAt) {
+				delete(r.rejected, k)
+			}
+		}
+	}
+	if len(r.rejected) >= r.maxSize {
+		return
+	}
+	r.rejected[hash] = now.Add(r.ttl)
+}
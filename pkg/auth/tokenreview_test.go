@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// tokenReviewTestAPIServer stands in for the Kubernetes API server. It
+// answers TokenReviews with respond, or with status if respond returns
+// nil, and counts the reviews it receives.
+type tokenReviewTestAPIServer struct {
+	*httptest.Server
+	reviews atomic.Int32
+	status  atomic.Int32
+	respond func(review tokenReview) *tokenReviewStatus
+}
+
+// newTokenReviewTestAPIServer starts a tokenReviewTestAPIServer that
+// authenticates tokens as the test service account.
+func newTokenReviewTestAPIServer(t *testing.T) *tokenReviewTestAPIServer {
+	t.Helper()
+	s := &tokenReviewTestAPIServer{
+		respond: func(tokenReview) *tokenReviewStatus {
+			return &tokenReviewStatus{
+				Authenticated: true,
+				User:          tokenReviewUser{Username: "system:serviceaccount:my-namespace:my-service-account"},
+				Audiences:     []string{"https://kubernetes.default.svc.cluster.local"},
+			}
+		},
+	}
+	s.status.Store(http.StatusCreated)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, tokenReviewPath, r.URL.Path)
+		assert.Equal(t, "Bearer reviewer-token", r.Header.Get("Authorization"))
+		s.reviews.Add(1)
+
+		var review tokenReview
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&review)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, []string{"https://kubernetes.default.svc.cluster.local"}, review.Spec.Audiences)
+		w.WriteHeader(int(s.status.Load()))
+		review.Status = s.respond(review)
+		_ = json.NewEncoder(w).Encode(review)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTokenReviewTestValidator creates a JWTValidator that reviews
+// Kubernetes tokens with apiServerURL and falls back to the JWKS served
+// at jwksURL.
+func newTokenReviewTestValidator(t *testing.T, apiServerURL, jwksURL string) *JWTValidator {
+	t.Helper()
+	saTokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(saTokenPath, []byte("reviewer-token\n"), 0o600))
+	validator, err := NewJWTValidator(ValidatorConfig{
+		EnableKubernetes:      true,
+		KubernetesIssuer:      jwksURL,
+		KubernetesAudience:    "https://kubernetes.default.svc.cluster.local",
+		KubernetesTokenReview: true,
+		KubernetesAPIServer:   apiServerURL,
+		TokenCacheTTL:         5 * time.Minute,
+		TokenCacheMaxSize:     100,
+		JWKSCacheTTL:          time.Hour,
+		ClockSkew:             30 * time.Second,
+		SATokenPath:           saTokenPath,
+	})
+	require.NoError(t, err)
+	return validator
+}
+
+// ---------------------------------------------------------------------------
+// TokenReview
+// ---------------------------------------------------------------------------
+
+func TestValidatorConfig_Validate_NegativeTokenReviewCacheTTL(t *testing.T) {
+	t.Parallel()
+	cfg := DefaultValidatorConfig()
+	cfg.TokenReviewCacheTTL = -time.Second
+	err := cfg.Validate()
+	require.NotNil(t, err)
+	assert.Equal(t, sserr.CodeValidation, err.Code)
+}
+
+func TestValidateKubernetesToken_TokenReview(t *testing.T) {
+	t.Parallel()
+	key := mustGenerateRSAKeyPair(t)
+	jwks := serveJWKS(t, key, "k8s-key")
+	apiServer := newTokenReviewTestAPIServer(t)
+	validator := newTokenReviewTestValidator(t, apiServer.URL, jwks.URL)
+	ctx := context.Background()
+
+	token := mustGenerateRSAToken(t, key, "k8s-key", makeK8sClaims(jwks.URL))
+	identity, err := validator.Validate(ctx, token)
+	require.NoError(t, err)
+	svc, ok := identity.(*ServiceIdentity)
+	require.True(t, ok, "identity type = %T", identity)
+	assert.Equal(t, "my-namespace", svc.Namespace())
+	assert.Equal(t, "my-service-account", svc.ServiceName())
+
+	_, err = validator.Validate(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), apiServer.reviews.Load(), "result should be cached")
+
+	// The cached identity expires with the review cache TTL, not the token.
+	validator.tokenCache.mu.RLock()
+	entry := validator.tokenCache.entries[tokenHash(token)]
+	validator.tokenCache.mu.RUnlock()
+	assert.WithinDuration(t, time.Now().Add(DefaultTokenReviewCacheTTL), entry.expiresAt, 5*time.Second)
+}
+
+func TestValidateKubernetesToken_TokenReviewRejects(t *testing.T) {
+	t.Parallel()
+	key := mustGenerateRSAKeyPair(t)
+	jwks := serveJWKS(t, key, "k8s-key")
+	apiServer := newTokenReviewTestAPIServer(t)
+	// The token's signature is valid, but its pod has been deleted.
+	apiServer.respond = func(tokenReview) *tokenReviewStatus {
+		return &tokenReviewStatus{Error: "pod \"my-pod\" not found"}
+	}
+	validator := newTokenReviewTestValidator(t, apiServer.URL, jwks.URL)
+	ctx := context.Background()
+
+	token := mustGenerateRSAToken(t, key, "k8s-key", makeK8sClaims(jwks.URL))
+	for range 2 {
+		_, err := validator.Validate(ctx, token)
+		assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+	}
+	assert.Equal(t, int32(1), apiServer.reviews.Load(), "rejection should be cached")
+}
+
+func TestValidateKubernetesToken_TokenReviewUserMismatch(t *testing.T) {
+	t.Parallel()
+	key := mustGenerateRSAKeyPair(t)
+	jwks := serveJWKS(t, key, "k8s-key")
+	apiServer := newTokenReviewTestAPIServer(t)
+	apiServer.respond = func(tokenReview) *tokenReviewStatus {
+		return &tokenReviewStatus{
+			Authenticated: true,
+			User:          tokenReviewUser{Username: "oidc:alice"},
+			Audiences:     []string{"https://kubernetes.default.svc.cluster.local"},
+		}
+	}
+	validator := newTokenReviewTestValidator(t, apiServer.URL, jwks.URL)
+
+	token := mustGenerateRSAToken(t, key, "k8s-key", makeK8sClaims(jwks.URL))
+	_, err := validator.Validate(context.Background(), token)
+	assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+}
+
+func TestValidateKubernetesToken_TokenReviewFallsBackToJWKS(t *testing.T) {
+	t.Parallel()
+	key := mustGenerateRSAKeyPair(t)
+	otherKey := mustGenerateRSAKeyPair(t)
+	jwks := serveJWKS(t, key, "k8s-key")
+
+	tests := map[string]func(t *testing.T) string{
+		"server error": func(t *testing.T) string {
+			apiServer := newTokenReviewTestAPIServer(t)
+			apiServer.status.Store(http.StatusServiceUnavailable)
+			return apiServer.URL
+		},
+		"unreachable": func(t *testing.T) string {
+			apiServer := newTokenReviewTestAPIServer(t)
+			apiServer.Close()
+			return apiServer.URL
+		},
+	}
+	for name, apiServerURL := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			validator := newTokenReviewTestValidator(t, apiServerURL(t), jwks.URL)
+			ctx := context.Background()
+
+			token := mustGenerateRSAToken(t, key, "k8s-key", makeK8sClaims(jwks.URL))
+			identity, err := validator.Validate(ctx, token)
+			require.NoError(t, err)
+			assert.Equal(t, "system:serviceaccount:my-namespace:my-service-account", identity.ID())
+
+			forged := mustGenerateRSAToken(t, otherKey, "k8s-key", makeK8sClaims(jwks.URL))
+			_, err = validator.Validate(ctx, forged)
+			assert.True(t, sserr.HasCode(err, sserr.CodeAuthenticationInvalid), "error = %v", err)
+		})
+	}
+}
+
+func TestValidateKubernetesToken_TokenReviewForbidden(t *testing.T) {
+	t.Parallel()
+	key := mustGenerateRSAKeyPair(t)
+	jwks := serveJWKS(t, key, "k8s-key")
+	apiServer := newTokenReviewTestAPIServer(t)
+	// A reviewer without RBAC permission to create tokenreviews is a
+	// misconfiguration, not an outage, so there is no fallback.
+	apiServer.status.Store(http.StatusForbidden)
+	validator := newTokenReviewTestValidator(t, apiServer.URL, jwks.URL)
+
+	token := mustGenerateRSAToken(t, key, "k8s-key", makeK8sClaims(jwks.URL))
+	_, err := validator.Validate(context.Background(), token)
+	assert.True(t, sserr.IsInternal(err), "error = %v", err)
+}
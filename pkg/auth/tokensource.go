@@ -0,0 +1,436 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+const (
+	// DefaultTokenRefreshBefore is the default time before a token's
+	// expiry at which a [TokenSource] obtains a new one, so that tokens
+	// do not expire in flight.
+	DefaultTokenRefreshBefore = time.Minute
+
+	// unknownTokenLifetime is how long a token whose expiry is unknown is
+	// reused before it is obtained again.
+	unknownTokenLifetime = time.Minute
+
+	// serviceAccountTokenReloadInterval is how often the projected
+	// ServiceAccount token file is re-read. The kubelet rotates the file
+	// well before the token expires.
+	serviceAccountTokenReloadInterval = time.Minute
+)
+
+// TokenSource supplies the bearer token a service presents on its own
+// outbound calls. Use [WithTokenSource] to attach the token to requests
+// made through [PropagatingRoundTripper], [UnaryClientInterceptor], and
+// [StreamClientInterceptor].
+//
+// Implementations must be safe for concurrent use.
+type TokenSource interface {
+	// Token returns a bearer token that is valid for at least a short
+	// time after Token returns.
+	Token(ctx context.Context) (string, error)
+}
+
+// ---------------------------------------------------------------------------
+// cachedTokenSource — shared caching and refresh
+// ---------------------------------------------------------------------------
+
+// cachedTokenSource caches the token returned by fetch until shortly
+// before it expires. A token due for refresh but not yet expired is
+// still returned while a new one is fetched in the background; callers
+// wait only when there is no usable token. Concurrent fetches are merged
+// into one.
+type cachedTokenSource struct {
+	// fetch obtains a new token and its expiry. A zero expiry means the
+	// expiry is unknown.
+	fetch func(ctx context.Context) (string, time.Time, error)
+
+	// refreshBefore is how long before expiry a new token is fetched.
+	refreshBefore time.Duration
+
+	// maxAge, if positive, bounds how long a token is reused regardless
+	// of its expiry.
+	maxAge time.Duration
+
+	now func() time.Time
+
+	fetches singleflight.Group
+
+	mu        sync.Mutex
+	token     string
+	expiry    time.Time
+	refreshAt time.Time
+}
+
+// Token returns the cached token. If it is due for refresh but has not
+// yet expired, it is returned and a new one is fetched in the
+// background; a failed refresh is logged. If there is no token or it has
+// expired, Token waits for a new one, returning early with ctx's error if
+// ctx is done first.
+func (c *cachedTokenSource) Token(ctx context.Context) (string, error) {
+	now := c.now()
+	c.mu.Lock()
+	token, expiry, refreshAt := c.token, c.expiry, c.refreshAt
+	c.mu.Unlock()
+
+	if token != "" && now.Before(expiry) {
+		if !now.Before(refreshAt) {
+			c.refreshInBackground(ctx)
+		}
+		return token, nil
+	}
+
+	ch := c.fetches.DoChan("", func() (any, error) {
+		return c.fetchAndStore(context.WithoutCancel(ctx))
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return res.Val.(string), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// refreshInBackground fetches a new token without waiting for the
+// result. The fetch outlives ctx, keeping only its values.
+func (c *cachedTokenSource) refreshInBackground(ctx context.Context) {
+	c.fetches.DoChan("", func() (any, error) {
+		return c.fetchAndStore(context.WithoutCancel(ctx))
+	})
+}
+
+// fetchAndStore fetches a new token and caches it. A failed fetch keeps
+// the cached token, which is logged if it has not yet expired.
+func (c *cachedTokenSource) fetchAndStore(ctx context.Context) (any, error) {
+	token, expiry, err := c.fetch(ctx)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if c.token != "" && now.Before(c.expiry) {
+			slog.WarnContext(ctx, "auth: failed to refresh token, using cached token",
+				"error", err,
+				"expires_in", c.expiry.Sub(now).String(),
+			)
+		}
+		return nil, err
+	}
+
+	if expiry.IsZero() {
+		expiry = now.Add(unknownTokenLifetime)
+	}
+	refreshAt := expiry.Add(-c.refreshBefore)
+	if !refreshAt.After(now) {
+		// The token lives no longer than the refresh margin; use it for
+		// half its lifetime rather than fetching on every call.
+		refreshAt = now.Add(expiry.Sub(now) / 2)
+	}
+	if c.maxAge > 0 && refreshAt.After(now.Add(c.maxAge)) {
+		refreshAt = now.Add(c.maxAge)
+	}
+	c.token, c.expiry, c.refreshAt = token, expiry, refreshAt
+	return token, nil
+}
+
+// jwtExpiry returns the "exp" claim of token without verifying it, or
+// the zero time if token is not a JWT or has no expiry. It is used only
+// to schedule refreshes of tokens the service holds itself.
+func jwtExpiry(token string) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}
+	}
+	return exp.Time
+}
+
+// ---------------------------------------------------------------------------
+// ClientCredentialsConfig — configuration for client-credentials tokens
+// ---------------------------------------------------------------------------
+
+// ClientCredentialsConfig configures a [ClientCredentialsTokenSource].
+type ClientCredentialsConfig struct {
+	// TokenURL is the authorization server's OAuth 2.0 token endpoint
+	// (e.g., "https://idp.example.com/oauth2/token"). Required.
+	TokenURL string `json:"token_url" env:"AUTH_CLIENT_TOKEN_URL"`
+
+	// ClientID and ClientSecret are the service's client credentials,
+	// sent using HTTP Basic authentication (client_secret_basic).
+	// ClientID is required.
+	ClientID     string `json:"client_id" env:"AUTH_CLIENT_ID"`
+	ClientSecret Secret `json:"client_secret" env:"AUTH_CLIENT_SECRET"`
+
+	// Scopes are the scopes requested for the token. If empty, the
+	// authorization server's default scopes are granted. This field is
+	// optional.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Audience, if set, is sent as the "audience" parameter, which many
+	// authorization servers use to choose the token's "aud" claim. This
+	// field is optional.
+	Audience string `json:"audience,omitempty" env:"AUTH_CLIENT_AUDIENCE"`
+
+	// RefreshBefore is how long before a token expires a new one is
+	// requested. Must be non-negative. Zero means
+	// [DefaultTokenRefreshBefore].
+	RefreshBefore time.Duration `json:"refresh_before" env:"AUTH_CLIENT_REFRESH_BEFORE"`
+
+	// HTTPClient is the HTTP client used to call the token endpoint. If
+	// nil, a default [http.Client] with a 10-second timeout is used. It
+	// must not be a client whose transport uses this token source.
+	HTTPClient HTTPClient `json:"-"`
+}
+
+// Validate checks the configuration for logical correctness and returns
+// a *[sserr.Error] with code [sserr.CodeValidation] if any field is
+// invalid.
+func (c *ClientCredentialsConfig) Validate() *sserr.Error {
+	u, err := url.Parse(c.TokenURL)
+	if c.TokenURL == "" || err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return sserr.New(sserr.CodeValidation, "auth: token URL must be an absolute http(s) URL")
+	}
+	if c.ClientID == "" {
+		return sserr.New(sserr.CodeValidation, "auth: client ID must not be empty")
+	}
+	if c.RefreshBefore < 0 {
+		return sserr.New(sserr.CodeValidation, "auth: token refresh margin must be non-negative")
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// ClientCredentialsTokenSource — OAuth 2.0 client credentials grant
+// ---------------------------------------------------------------------------
+
+// ClientCredentialsTokenSource obtains access tokens for the service
+// itself with the OAuth 2.0 client credentials grant (RFC 6749 section
+// 4.4). It implements the [TokenSource] interface.
+//
+// Tokens are cached and a new one is requested [DefaultTokenRefreshBefore]
+// (or RefreshBefore) before the cached one expires; until it does, the
+// cached token is still returned while the new one is requested in the
+// background. Concurrent callers share a single request to the token
+// endpoint, and only wait for it when there is no unexpired token. If
+// the endpoint fails while the cached token is still valid, the cached
+// token is used.
+//
+// ClientCredentialsTokenSource is safe for concurrent use by multiple
+// goroutines.
+type ClientCredentialsTokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret Secret
+	scopes       []string
+	audience     string
+	httpClient   HTTPClient
+	tracer       trace.Tracer
+	cache        cachedTokenSource
+}
+
+// Compile-time assertion that ClientCredentialsTokenSource implements
+// TokenSource.
+var _ TokenSource = (*ClientCredentialsTokenSource)(nil)
+
+// NewClientCredentialsTokenSource creates a ClientCredentialsTokenSource
+// with the given configuration. The configuration is validated before
+// use; an error is returned if the configuration is invalid. No token is
+// requested until the first call to Token.
+func NewClientCredentialsTokenSource(cfg ClientCredentialsConfig) (*ClientCredentialsTokenSource, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	refreshBefore := cfg.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = DefaultTokenRefreshBefore
+	}
+
+	s := &ClientCredentialsTokenSource{
+		tokenURL:     cfg.TokenURL,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		scopes:       append([]string(nil), cfg.Scopes...),
+		audience:     cfg.Audience,
+		httpClient:   httpClient,
+		tracer:       otel.Tracer(tracerName),
+	}
+	s.cache = cachedTokenSource{
+		fetch:         s.fetch,
+		refreshBefore: refreshBefore,
+		now:           time.Now,
+	}
+	return s, nil
+}
+
+// Token returns a cached access token, requesting a new one from the
+// token endpoint if needed. Token waits for the request only if there is
+// no unexpired token, and returns ctx's error if ctx is done first; the
+// request itself continues and its token is cached.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeUnavailable] if the
+// endpoint is unreachable or overloaded, or [sserr.CodeInternal] if it
+// rejects the request, such as for wrong client credentials.
+func (s *ClientCredentialsTokenSource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(ctx)
+}
+
+// fetch requests a new access token from the token endpoint and returns
+// it with its expiry, which is zero if the response has none.
+func (s *ClientCredentialsTokenSource) fetch(ctx context.Context) (string, time.Time, error) {
+	ctx, span := startSpan(ctx, s.tracer, "auth.FetchClientCredentialsToken")
+	defer span.End()
+
+	token, expiry, err := s.request(ctx)
+	finishSpan(span, err)
+	return token, expiry, err
+}
+
+// request performs the token request for fetch.
+func (s *ClientCredentialsTokenSource) request(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.scopes) > 0 {
+		form.Set("scope", strings.Join(s.scopes, " "))
+	}
+	if s.audience != "" {
+		form.Set("audience", s.audience)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to create token request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// RFC 6749 section 2.3.1: client credentials are form-encoded before
+	// being used as the Basic authentication user and password.
+	req.SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret.Value()))
+
+	requested := time.Now()
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, sserr.Wrap(err, sserr.CodeUnavailable, "auth: token endpoint is unreachable")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return "", time.Time{}, sserr.Newf(sserr.CodeUnavailable, "auth: token endpoint returned status %d", resp.StatusCode)
+	default:
+		return "", time.Time{}, sserr.Newf(sserr.CodeInternal, "auth: token endpoint returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", time.Time{}, sserr.Wrap(err, sserr.CodeUnavailable, "auth: failed to read token response")
+	}
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", time.Time{}, sserr.Wrap(err, sserr.CodeInternal, "auth: failed to parse token response")
+	}
+	if tokenResp.AccessToken == "" {
+		return "", time.Time{}, sserr.New(sserr.CodeInternal, "auth: token response has no access token")
+	}
+	if !strings.EqualFold(tokenResp.TokenType, "bearer") {
+		return "", time.Time{}, sserr.Newf(sserr.CodeInternal, "auth: token endpoint returned unsupported token type %q", tokenResp.TokenType)
+	}
+
+	// expires_in is measured from the response, so measuring it from the
+	// request errs on the early side.
+	var expiry time.Time
+	if tokenResp.ExpiresIn > 0 {
+		expiry = requested.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	} else {
+		expiry = jwtExpiry(tokenResp.AccessToken)
+	}
+	return tokenResp.AccessToken, expiry, nil
+}
+
+// ---------------------------------------------------------------------------
+// ServiceAccountTokenSource — the pod's projected ServiceAccount token
+// ---------------------------------------------------------------------------
+
+// ServiceAccountTokenSource supplies the pod's projected Kubernetes
+// ServiceAccount token, for calls to services that validate ServiceAccount
+// tokens with [JWTValidator]. It implements the [TokenSource] interface.
+//
+// The token file is re-read every minute, and sooner if the cached token
+// is about to expire, so that tokens rotated by the kubelet are picked
+// up. If the file cannot be read while the cached token is still valid,
+// the cached token is used.
+//
+// ServiceAccountTokenSource is safe for concurrent use by multiple
+// goroutines.
+type ServiceAccountTokenSource struct {
+	path  string
+	cache cachedTokenSource
+}
+
+// Compile-time assertion that ServiceAccountTokenSource implements
+// TokenSource.
+var _ TokenSource = (*ServiceAccountTokenSource)(nil)
+
+// NewServiceAccountTokenSource creates a ServiceAccountTokenSource that
+// reads the token file at path. If path is empty, [DefaultSATokenPath] is
+// used. The file is not read until the first call to Token.
+func NewServiceAccountTokenSource(path string) *ServiceAccountTokenSource {
+	if path == "" {
+		path = DefaultSATokenPath
+	}
+	s := &ServiceAccountTokenSource{path: path}
+	s.cache = cachedTokenSource{
+		fetch:         s.fetch,
+		refreshBefore: DefaultTokenRefreshBefore,
+		maxAge:        serviceAccountTokenReloadInterval,
+		now:           time.Now,
+	}
+	return s
+}
+
+// Token returns the ServiceAccount token, re-reading the token file if
+// needed.
+//
+// Returns a *[sserr.Error] with code [sserr.CodeUnavailable] if the token
+// file cannot be read or is empty.
+func (s *ServiceAccountTokenSource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(ctx)
+}
+
+// fetch reads the token file and returns the token with its expiry.
+func (s *ServiceAccountTokenSource) fetch(_ context.Context) (string, time.Time, error) {
+	token, err := ReadServiceAccountToken(s.path)
+	if err != nil {
+		return "", time.Time{}, sserr.Wrap(err, sserr.CodeUnavailable, "auth: service account token is unavailable")
+	}
+	return token, jwtExpiry(token), nil
+}
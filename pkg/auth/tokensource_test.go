@@ -0,0 +1,461 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// tokenEndpointTestServer stands in for an OAuth 2.0 token endpoint. It
+// issues numbered tokens that expire after expiresIn seconds, and counts
+// the requests it receives.
+type tokenEndpointTestServer struct {
+	*httptest.Server
+	requests  atomic.Int32
+	status    atomic.Int32
+	expiresIn int64
+}
+
+// newTokenEndpointTestServer starts a tokenEndpointTestServer that
+// requires the client credentials "client-a" and "secret-a".
+func newTokenEndpointTestServer(t *testing.T, expiresIn int64) *tokenEndpointTestServer {
+	t.Helper()
+	s := &tokenEndpointTestServer{expiresIn: expiresIn}
+	s.status.Store(http.StatusOK)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := s.requests.Add(1)
+		assert.Equal(t, http.MethodPost, r.Method)
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "client-a", id)
+		assert.Equal(t, "secret-a", secret)
+		assert.Equal(t, "client_credentials", r.PostFormValue("grant_type"))
+		assert.Equal(t, "agents:read agents:write", r.PostFormValue("scope"))
+
+		w.WriteHeader(int(s.status.Load()))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   s.expiresIn,
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// tokenTestClock is a clock that tests advance while background token
+// refreshes read it.
+type tokenTestClock struct{ nanos atomic.Int64 }
+
+// newTokenTestClock returns a tokenTestClock set to the current time.
+func newTokenTestClock() *tokenTestClock {
+	c := &tokenTestClock{}
+	c.nanos.Store(time.Now().UnixNano())
+	return c
+}
+
+func (c *tokenTestClock) Now() time.Time { return time.Unix(0, c.nanos.Load()) }
+
+func (c *tokenTestClock) Advance(d time.Duration) { c.nanos.Add(int64(d)) }
+
+// newClientCredentialsTestSource creates a ClientCredentialsTokenSource
+// for the endpoint of s whose clock is read from clock.
+func newClientCredentialsTestSource(t *testing.T, s *tokenEndpointTestServer, clock *tokenTestClock) *ClientCredentialsTokenSource {
+	t.Helper()
+	source, err := NewClientCredentialsTokenSource(ClientCredentialsConfig{
+		TokenURL:     s.URL + "/oauth2/token",
+		ClientID:     "client-a",
+		ClientSecret: Secret("secret-a"),
+		Scopes:       []string{"agents:read", "agents:write"},
+	})
+	require.NoError(t, err)
+	if clock != nil {
+		source.cache.now = clock.Now
+	}
+	return source
+}
+
+// staticTokenSource is a TokenSource that returns a fixed token or error.
+type staticTokenSource struct {
+	token string
+	err   error
+}
+
+func (s staticTokenSource) Token(context.Context) (string, error) {
+	return s.token, s.err
+}
+
+// ---------------------------------------------------------------------------
+// ClientCredentialsConfig
+// ---------------------------------------------------------------------------
+
+func TestClientCredentialsConfig_Validate(t *testing.T) {
+	t.Parallel()
+	valid := ClientCredentialsConfig{TokenURL: "https://idp.example.com/oauth2/token", ClientID: "client-a"}
+	assert.Nil(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(*ClientCredentialsConfig)
+	}{
+		{"empty token URL", func(c *ClientCredentialsConfig) { c.TokenURL = "" }},
+		{"relative token URL", func(c *ClientCredentialsConfig) { c.TokenURL = "/oauth2/token" }},
+		{"empty client ID", func(c *ClientCredentialsConfig) { c.ClientID = "" }},
+		{"negative refresh margin", func(c *ClientCredentialsConfig) { c.RefreshBefore = -time.Second }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := valid
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			require.NotNil(t, err)
+			assert.True(t, sserr.IsValidation(err))
+		})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// ClientCredentialsTokenSource
+// ---------------------------------------------------------------------------
+
+func TestClientCredentialsTokenSource_CachesAndRefreshes(t *testing.T) {
+	t.Parallel()
+	s := newTokenEndpointTestServer(t, 600)
+	clock := newTokenTestClock()
+	source := newClientCredentialsTestSource(t, s, clock)
+	ctx := context.Background()
+
+	token, err := source.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	clock.Advance(8 * time.Minute)
+	token, err = source.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token, "token should be cached until the refresh margin")
+
+	// Within the refresh margin, the cached token is returned while a new
+	// one is requested in the background.
+	clock.Advance(90 * time.Second)
+	token, err = source.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+	assert.Eventually(t, func() bool {
+		token, err := source.Token(ctx)
+		return err == nil && token == "token-2"
+	}, time.Second, 5*time.Millisecond, "token should be refreshed before it expires")
+	assert.Equal(t, int32(2), s.requests.Load())
+}
+
+func TestClientCredentialsTokenSource_ShortLivedToken(t *testing.T) {
+	t.Parallel()
+	s := newTokenEndpointTestServer(t, 30)
+	clock := newTokenTestClock()
+	source := newClientCredentialsTestSource(t, s, clock)
+
+	_, err := source.Token(context.Background())
+	require.NoError(t, err)
+	clock.Advance(10 * time.Second)
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token, "a token shorter-lived than the margin should be reused for half its lifetime")
+	assert.Equal(t, int32(1), s.requests.Load())
+}
+
+func TestClientCredentialsTokenSource_RefreshFailureUsesCachedToken(t *testing.T) {
+	t.Parallel()
+	s := newTokenEndpointTestServer(t, 600)
+	clock := newTokenTestClock()
+	source := newClientCredentialsTestSource(t, s, clock)
+	_, err := source.Token(context.Background())
+	require.NoError(t, err)
+
+	s.status.Store(http.StatusServiceUnavailable)
+	clock.Advance(9*time.Minute + 30*time.Second)
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+	assert.Eventually(t, func() bool { return s.requests.Load() == 2 }, time.Second, 5*time.Millisecond)
+
+	clock.Advance(time.Minute)
+	_, err = source.Token(context.Background())
+	assert.True(t, sserr.IsUnavailable(err), "expired token should not be used, got %v", err)
+}
+
+func TestClientCredentialsTokenSource_SingleFlight(t *testing.T) {
+	t.Parallel()
+	s := newTokenEndpointTestServer(t, 600)
+	source := newClientCredentialsTestSource(t, s, nil)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := source.Token(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "token-1", token)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), s.requests.Load())
+}
+
+func TestCachedTokenSource_RefreshDoesNotBlock(t *testing.T) {
+	t.Parallel()
+	clock := newTokenTestClock()
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	source := &cachedTokenSource{
+		fetch: func(ctx context.Context) (string, time.Time, error) {
+			n := fetches.Add(1)
+			if n > 1 {
+				<-release
+			}
+			return fmt.Sprintf("token-%d", n), clock.Now().Add(10 * time.Minute), nil
+		},
+		refreshBefore: time.Minute,
+		now:           clock.Now,
+	}
+	ctx := context.Background()
+	token, err := source.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// While the refresh is blocked, callers keep getting the cached token.
+	clock.Advance(9*time.Minute + 30*time.Second)
+	for range 3 {
+		token, err = source.Token(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", token)
+	}
+
+	// Once the token has expired, callers wait, but only as long as
+	// their own context allows.
+	clock.Advance(time.Minute)
+	shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = source.Token(shortCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The abandoned fetch still completes and its token is cached.
+	close(release)
+	assert.Eventually(t, func() bool {
+		token, err := source.Token(ctx)
+		return err == nil && token == "token-2"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), fetches.Load(), "concurrent refreshes should share one fetch")
+}
+
+func TestClientCredentialsTokenSource_EndpointErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		status int
+		code   sserr.Code
+	}{
+		{http.StatusServiceUnavailable, sserr.CodeUnavailable},
+		{http.StatusTooManyRequests, sserr.CodeUnavailable},
+		{http.StatusUnauthorized, sserr.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			t.Parallel()
+			s := newTokenEndpointTestServer(t, 600)
+			s.status.Store(int32(tt.status))
+			source := newClientCredentialsTestSource(t, s, nil)
+
+			_, err := source.Token(context.Background())
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, tt.code), "got %v", err)
+		})
+	}
+}
+
+func TestClientCredentialsTokenSource_InvalidResponses(t *testing.T) {
+	t.Parallel()
+	for name, body := range map[string]string{
+		"no token":       `{"token_type": "Bearer", "expires_in": 60}`,
+		"wrong type":     `{"access_token": "t", "token_type": "mac", "expires_in": 60}`,
+		"not json":       `token=t`,
+		"no token type":  `{"access_token": "t", "expires_in": 60}`,
+		"token not text": `{"access_token": 42, "token_type": "Bearer"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(body))
+			}))
+			defer server.Close()
+			source, err := NewClientCredentialsTokenSource(ClientCredentialsConfig{TokenURL: server.URL, ClientID: "client-a"})
+			require.NoError(t, err)
+
+			_, err = source.Token(context.Background())
+			assert.True(t, sserr.HasCode(err, sserr.CodeInternal), "got %v", err)
+		})
+	}
+}
+
+func TestClientCredentialsTokenSource_ExpiryFromJWT(t *testing.T) {
+	t.Parallel()
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	jwtToken := jwtTestGenerateHMACToken(t, []byte(testSigningKey), jwt.MapClaims{"sub": "svc", "exp": exp.Unix()})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": jwtToken, "token_type": "bearer"})
+	}))
+	defer server.Close()
+	source, err := NewClientCredentialsTokenSource(ClientCredentialsConfig{TokenURL: server.URL, ClientID: "client-a"})
+	require.NoError(t, err)
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, jwtToken, token)
+	assert.True(t, source.cache.expiry.Equal(exp), "expiry %v, want %v", source.cache.expiry, exp)
+}
+
+// ---------------------------------------------------------------------------
+// ServiceAccountTokenSource
+// ---------------------------------------------------------------------------
+
+func TestServiceAccountTokenSource_RereadsFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("sa-token-1\n"), 0o600))
+	source := NewServiceAccountTokenSource(path)
+	clock := newTokenTestClock()
+	source.cache.now = clock.Now
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sa-token-1", token)
+
+	require.NoError(t, os.WriteFile(path, []byte("sa-token-2"), 0o600))
+	token, _ = source.Token(context.Background())
+	assert.Equal(t, "sa-token-1", token, "token file should not be re-read on every call")
+
+	clock.Advance(serviceAccountTokenReloadInterval)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sa-token-2", token)
+}
+
+func TestServiceAccountTokenSource_Missing(t *testing.T) {
+	t.Parallel()
+	source := NewServiceAccountTokenSource(filepath.Join(t.TempDir(), "missing"))
+	_, err := source.Token(context.Background())
+	require.Error(t, err)
+	assert.True(t, sserr.IsUnavailable(err))
+}
+
+// ---------------------------------------------------------------------------
+// WithTokenSource
+// ---------------------------------------------------------------------------
+
+func TestPropagatingRoundTripper_TokenSource(t *testing.T) {
+	t.Parallel()
+	mock := &mockRoundTripper{response: &http.Response{StatusCode: http.StatusOK}}
+	rt := NewPropagatingRoundTripper("my-service", mock, WithTokenSource(staticTokenSource{token: "svc-token"}))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://downstream/api/data", nil)
+	_, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer svc-token", mock.capturedReq.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("Authorization"), "original request should not be mutated")
+
+	ctx := ContextWithIdentity(context.Background(), newTestIdentity())
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "http://downstream/api/data", nil)
+	req.Header.Set("Authorization", "Bearer caller-token")
+	_, err = rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer caller-token", mock.capturedReq.Header.Get("Authorization"))
+	assert.Equal(t, "user-42", mock.capturedReq.Header.Get(HeaderIdentityID))
+}
+
+func TestPropagatingRoundTripper_TokenSourceError(t *testing.T) {
+	t.Parallel()
+	mock := &mockRoundTripper{response: &http.Response{StatusCode: http.StatusOK}}
+	sourceErr := sserr.New(sserr.CodeUnavailable, "auth: token endpoint is unreachable")
+	rt := NewPropagatingRoundTripper("my-service", mock, WithTokenSource(staticTokenSource{err: sourceErr}))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://downstream/api/data", nil)
+	_, err := rt.RoundTrip(req)
+	assert.True(t, errors.Is(err, sourceErr))
+	assert.Nil(t, mock.capturedReq, "request should not be sent")
+}
+
+func TestClientInterceptors_TokenSource(t *testing.T) {
+	t.Parallel()
+	source := WithTokenSource(staticTokenSource{token: "svc-token"})
+
+	var captured metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		captured, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	require.NoError(t, UnaryClientInterceptor("client-service", source)(context.Background(), "/test.Service/Method", nil, nil, nil, invoker))
+	assert.Equal(t, []string{"Bearer svc-token"}, captured.Get(HeaderAuthorization))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), HeaderAuthorization, "Bearer caller-token")
+	require.NoError(t, UnaryClientInterceptor("client-service", source)(ctx, "/test.Service/Method", nil, nil, nil, invoker))
+	assert.Equal(t, []string{"Bearer caller-token"}, captured.Get(HeaderAuthorization))
+
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		captured, _ = metadata.FromOutgoingContext(ctx)
+		return nil, nil
+	}
+	_, err := StreamClientInterceptor("client-service", source)(context.Background(), &grpc.StreamDesc{}, nil, "/test.Service/Stream", streamer)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer svc-token"}, captured.Get(HeaderAuthorization))
+
+	failing := WithTokenSource(staticTokenSource{err: sserr.New(sserr.CodeUnavailable, "auth: down")})
+	err = UnaryClientInterceptor("client-service", failing)(context.Background(), "/test.Service/Method", nil, nil, nil, invoker)
+	assert.True(t, sserr.IsUnavailable(err))
+}
+
+// ---------------------------------------------------------------------------
+// End to end
+// ---------------------------------------------------------------------------
+
+func TestTokenSource_AuthenticatesToHTTPMiddleware(t *testing.T) {
+	t.Parallel()
+	issuer := newTestIssuer(t)
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := issuer.Issue(r.Context(), NewBasicIdentity("billing", IdentityTypeService, nil))
+		assert.NoError(t, err)
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": token, "token_type": "Bearer", "expires_in": 300})
+	}))
+	defer tokenServer.Close()
+
+	validator, err := NewJWTValidator(newPlatformConfig())
+	require.NoError(t, err)
+	downstream := httptest.NewServer(HTTPMiddleware(validator, "ledger")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(MustIdentityFromContext(r.Context()).ID()))
+	})))
+	defer downstream.Close()
+
+	source, err := NewClientCredentialsTokenSource(ClientCredentialsConfig{TokenURL: tokenServer.URL, ClientID: "billing"})
+	require.NoError(t, err)
+	client := &http.Client{Transport: NewPropagatingRoundTripper("billing", nil, WithTokenSource(source))}
+
+	resp, err := client.Get(downstream.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -76,6 +76,9 @@ type Cmdable interface {
 	// LLen returns the length of a list.
 	LLen(ctx context.Context, key string) *redis.IntCmd
 
+	// LTrim trims a list to the specified range of elements.
+	LTrim(ctx context.Context, key string, start, stop int64) *redis.StatusCmd
+
 	// SAdd adds one or more members to a set.
 	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
 
@@ -88,6 +91,12 @@ type Cmdable interface {
 	// SRem removes one or more members from a set.
 	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
 
+	// Eval executes a Lua script atomically on the server.
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+
+	// Publish posts a message to a pub/sub channel.
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+
 	// Ping pings the Redis server.
 	Ping(ctx context.Context) *redis.StatusCmd
 
@@ -99,6 +108,18 @@ type Cmdable interface {
 // satisfies the Cmdable interface at compile time rather than at runtime.
 var _ Cmdable = (*redis.Client)(nil)
 
+// Subscriber is implemented by a [Cmdable] that can open pub/sub
+// subscriptions, such as [*redis.Client]. It is separate from [Cmdable]
+// because a [*redis.PubSub] holds a dedicated connection and cannot be
+// constructed by test doubles.
+type Subscriber interface {
+	// Subscribe subscribes to the given channels.
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// Compile-time interface compliance check.
+var _ Subscriber = (*redis.Client)(nil)
+
 // Client is a Redis client with OpenTelemetry tracing and structured error
 // handling. It wraps a [Cmdable] (typically [*redis.Client]) and adds
 // cross-cutting concerns (tracing, error classification) transparently to
@@ -493,6 +514,25 @@ func (c *Client) LLen(ctx context.Context, key string) (int64, error) {
 	return val, nil
 }
 
+// LTrim trims a list stored at key so that it contains only the elements
+// in the inclusive range [start, stop], with OpenTelemetry tracing. The
+// offsets are zero-based and may be negative to count from the end of the
+// list. Use this together with [Client.RPush] to maintain capped lists.
+//
+// Example:
+//
+//	// Keep only the 100 most recent entries.
+//	err := client.LTrim(ctx, "events", -100, -1)
+func (c *Client) LTrim(ctx context.Context, key string, start, stop int64) error {
+	ctx, span := c.startSpan(ctx, "LTrim", fmt.Sprintf("LTRIM %s %d %d", key, start, stop))
+	err := c.cmdable.LTrim(ctx, key, start, stop).Err()
+	finishSpan(span, err)
+	if err != nil {
+		return wrapError(err, "redis: ltrim failed")
+	}
+	return nil
+}
+
 // SAdd adds one or more members to a set stored at key and returns the
 // number of members added (not including members already present), with
 // OpenTelemetry tracing.
@@ -558,6 +598,80 @@ func (c *Client) SRem(ctx context.Context, key string, members ...interface{}) (
 	return val, nil
 }
 
+// Eval executes a Lua script atomically on the server with the given keys
+// and arguments, with OpenTelemetry tracing. Scripts are the building block
+// for compare-and-set operations (such as lease renewal) that must not
+// interleave with other clients' commands.
+//
+// The result is the script's return value as decoded by go-redis: int64
+// for integers, string for bulk strings, []interface{} for arrays. A nil
+// script result is reported as an error wrapping [redis.Nil].
+//
+// Example:
+//
+//	res, err := client.Eval(ctx,
+//	    `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`,
+//	    []string{"lock:jobs"}, owner)
+func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	ctx, span := c.startSpan(ctx, "Eval", fmt.Sprintf("EVAL <script> %d %v", len(keys), keys))
+	val, err := c.cmdable.Eval(ctx, script, keys, args...).Result()
+	finishSpan(span, err)
+	if err != nil {
+		return nil, wrapError(err, "redis: eval failed")
+	}
+	return val, nil
+}
+
+// Publish posts message to a pub/sub channel and returns the number of
+// subscribers that received it, with OpenTelemetry tracing.
+//
+// Example:
+//
+//	receivers, err := client.Publish(ctx, "events", payload)
+func (c *Client) Publish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	ctx, span := c.startSpan(ctx, "Publish", fmt.Sprintf("PUBLISH %s", channel))
+	val, err := c.cmdable.Publish(ctx, channel, message).Result()
+	finishSpan(span, err)
+	if err != nil {
+		return 0, wrapError(err, "redis: publish failed")
+	}
+	return val, nil
+}
+
+// Subscribe subscribes to channels and waits for the server to confirm
+// the subscription, so that messages published after Subscribe returns
+// are delivered. The caller must close the returned [*redis.PubSub].
+//
+// Returns a [*sserr.Error] with code [sserr.CodeInternal] if the
+// underlying [Cmdable] does not implement [Subscriber].
+//
+// Example:
+//
+//	sub, err := client.Subscribe(ctx, "events")
+//	if err != nil {
+//	    return err
+//	}
+//	defer sub.Close()
+//	for msg := range sub.Channel() {
+//	    handle(msg.Payload)
+//	}
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (*redis.PubSub, error) {
+	subscriber, ok := c.cmdable.(Subscriber)
+	if !ok {
+		return nil, sserr.New(sserr.CodeInternal,
+			"redis: client does not support subscriptions")
+	}
+	ctx, span := c.startSpan(ctx, "Subscribe", fmt.Sprintf("SUBSCRIBE %v", channels))
+	sub := subscriber.Subscribe(ctx, channels...)
+	_, err := sub.Receive(ctx)
+	finishSpan(span, err)
+	if err != nil {
+		_ = sub.Close()
+		return nil, wrapError(err, "redis: subscribe failed")
+	}
+	return sub, nil
+}
+
 // Health verifies that the Redis connection is alive by executing a ping.
 // It applies [DefaultHealthTimeout] if the provided context has no deadline.
 //
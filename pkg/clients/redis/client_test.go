@@ -105,6 +105,11 @@ func (m *mockCmdable) LLen(ctx context.Context, key string) *redis.IntCmd {
 	return args.Get(0).(*redis.IntCmd)
 }
 
+func (m *mockCmdable) LTrim(ctx context.Context, key string, start, stop int64) *redis.StatusCmd {
+	args := m.Called(ctx, key, start, stop)
+	return args.Get(0).(*redis.StatusCmd)
+}
+
 func (m *mockCmdable) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
 	args := m.Called(ctx, key, members)
 	return args.Get(0).(*redis.IntCmd)
@@ -130,6 +135,16 @@ func (m *mockCmdable) Ping(ctx context.Context) *redis.StatusCmd {
 	return args.Get(0).(*redis.StatusCmd)
 }
 
+func (m *mockCmdable) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	callArgs := m.Called(ctx, script, keys, args)
+	return callArgs.Get(0).(*redis.Cmd)
+}
+
+func (m *mockCmdable) Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd {
+	args := m.Called(ctx, channel, message)
+	return args.Get(0).(*redis.IntCmd)
+}
+
 func (m *mockCmdable) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -438,6 +453,41 @@ func TestClient_LRange_Success(t *testing.T) {
 	m.AssertExpectations(t)
 }
 
+// ===========================================================================
+// LTrim Tests
+// ===========================================================================
+
+// TestClient_LTrim_Success verifies that LTrim forwards the range to the
+// underlying client and returns nil on success.
+func TestClient_LTrim_Success(t *testing.T) {
+	t.Parallel()
+	m := new(mockCmdable)
+	m.On("LTrim", mock.Anything, "list1", int64(-100), int64(-1)).
+		Return(newStatusCmd("OK", nil))
+
+	client := NewFromClient(m, &Config{DB: 0})
+	err := client.LTrim(context.Background(), "list1", -100, -1)
+	require.NoError(t, err)
+
+	m.AssertExpectations(t)
+}
+
+// TestClient_LTrim_Error verifies that LTrim wraps errors as
+// CodeInternalDatabase.
+func TestClient_LTrim_Error(t *testing.T) {
+	t.Parallel()
+	m := new(mockCmdable)
+	m.On("LTrim", mock.Anything, "list1", int64(0), int64(9)).
+		Return(newStatusCmd("", errors.New("connection reset")))
+
+	client := NewFromClient(m, &Config{DB: 0})
+	err := client.LTrim(context.Background(), "list1", 0, 9)
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase))
+
+	m.AssertExpectations(t)
+}
+
 // ===========================================================================
 // SAdd Tests
 // ===========================================================================
@@ -515,6 +565,92 @@ func TestClient_Health_Failure(t *testing.T) {
 	m.AssertExpectations(t)
 }
 
+// ===========================================================================
+// Eval Tests
+// ===========================================================================
+
+// TestClient_Eval_Success verifies that Eval forwards the script, keys, and
+// arguments and returns the script result.
+func TestClient_Eval_Success(t *testing.T) {
+	t.Parallel()
+	m := new(mockCmdable)
+	cmd := redis.NewCmd(context.Background())
+	cmd.SetVal(int64(1))
+	m.On("Eval", mock.Anything, "return 1", []string{"key1"}, []interface{}{"arg1"}).
+		Return(cmd)
+
+	client := NewFromClient(m, &Config{DB: 0})
+	val, err := client.Eval(context.Background(), "return 1", []string{"key1"}, "arg1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), val)
+
+	m.AssertExpectations(t)
+}
+
+// TestClient_Eval_Error verifies that Eval wraps script errors as
+// CodeInternalDatabase.
+func TestClient_Eval_Error(t *testing.T) {
+	t.Parallel()
+	m := new(mockCmdable)
+	cmd := redis.NewCmd(context.Background())
+	cmd.SetErr(errors.New("ERR Error compiling script"))
+	m.On("Eval", mock.Anything, "bad", []string(nil), []interface{}(nil)).
+		Return(cmd)
+
+	client := NewFromClient(m, &Config{DB: 0})
+	_, err := client.Eval(context.Background(), "bad", nil)
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase))
+
+	m.AssertExpectations(t)
+}
+
+// ===========================================================================
+// Pub/Sub Tests
+// ===========================================================================
+
+// TestClient_Publish_Success verifies that Publish returns the number of
+// receivers.
+func TestClient_Publish_Success(t *testing.T) {
+	t.Parallel()
+	m := new(mockCmdable)
+	m.On("Publish", mock.Anything, "events", "hello").Return(newIntCmd(2))
+
+	client := NewFromClient(m, &Config{DB: 0})
+	n, err := client.Publish(context.Background(), "events", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	m.AssertExpectations(t)
+}
+
+// TestClient_Publish_Error verifies that Publish wraps errors as
+// CodeInternalDatabase.
+func TestClient_Publish_Error(t *testing.T) {
+	t.Parallel()
+	m := new(mockCmdable)
+	cmd := redis.NewIntCmd(context.Background())
+	cmd.SetErr(errors.New("connection refused"))
+	m.On("Publish", mock.Anything, "events", "hello").Return(cmd)
+
+	client := NewFromClient(m, &Config{DB: 0})
+	_, err := client.Publish(context.Background(), "events", "hello")
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase))
+
+	m.AssertExpectations(t)
+}
+
+// TestClient_Subscribe_Unsupported verifies that Subscribe fails when the
+// underlying cmdable cannot open subscriptions.
+func TestClient_Subscribe_Unsupported(t *testing.T) {
+	t.Parallel()
+	client := NewFromClient(new(mockCmdable), nil)
+	_, err := client.Subscribe(context.Background(), "events")
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternal))
+}
+
 // ===========================================================================
 // Close Tests
 // ===========================================================================
@@ -414,6 +414,31 @@ func (s *RedisIntegrationSuite) TestClose_ReleasesResources() {
 		"Health() should fail after Close()")
 }
 
+// ===========================================================================
+// Pub/Sub Tests
+// ===========================================================================
+
+// TestPublishSubscribe verifies that a message published after Subscribe
+// returns is delivered to the subscriber.
+func (s *RedisIntegrationSuite) TestPublishSubscribe() {
+	channel := "test:pubsub:events"
+	sub, err := s.client.Subscribe(s.ctx, channel)
+	require.NoError(s.T(), err)
+	defer sub.Close()
+
+	receivers, err := s.client.Publish(s.ctx, channel, "hello")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(1), receivers)
+
+	select {
+	case msg := <-sub.Channel():
+		assert.Equal(s.T(), channel, msg.Channel)
+		assert.Equal(s.T(), "hello", msg.Payload)
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("message was not delivered")
+	}
+}
+
 // ===========================================================================
 // Concurrency Tests
 // ===========================================================================
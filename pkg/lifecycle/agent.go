@@ -30,6 +30,20 @@ const tracerName = "github.com/StricklySoft/stricklysoft-core/pkg/lifecycle"
 // and triggering alerts on failure transitions.
 type StateChangeHandler func(old, new State)
 
+// TransitionHandler is a callback invoked on every lifecycle state change
+// with the full [StateTransition], including the reason and any error that
+// caused it. Register one with [BaseAgentBuilder.OnTransition].
+//
+// TransitionHandlers run under the same conditions as
+// [StateChangeHandler]: synchronously, under the agent's state mutex, and
+// with panic recovery.
+type TransitionHandler func(t StateTransition)
+
+// journalTimeout bounds how long a single flush of pending transitions to
+// the [StateStore] may take. Journal writes never fail a transition, so
+// a slow store only delays the caller by at most this long.
+const journalTimeout = 5 * time.Second
+
 // Hook is a function called during a lifecycle transition (start, stop,
 // pause, resume). It receives the caller's context, which may carry
 // deadlines, cancellation signals, and identity information.
@@ -192,10 +206,24 @@ type AgentInfo struct {
 	// Capabilities is the list of capabilities the agent supports.
 	Capabilities []Capability `json:"capabilities"`
 
+	// Requirements is the list of capabilities the agent depends on from
+	// its peers. Omitted from JSON when empty.
+	Requirements []CapabilityRequirement `json:"requirements,omitempty"`
+
 	// StartedAt is the time the agent entered StateRunning. Nil if the
 	// agent has not started or has been stopped.
 	StartedAt *time.Time `json:"started_at,omitempty"`
 
+	// Jobs is the status of each scheduled job. Omitted from JSON when the
+	// agent has no jobs.
+	Jobs []JobStatus `json:"jobs,omitempty"`
+
+	// Previous is the last transition journaled by an earlier run of the
+	// agent, loaded from the [StateStore] on first use. Nil if no store
+	// is configured or the agent has no recorded history. Use it to see
+	// why a previous instance stopped or failed.
+	Previous *StateTransition `json:"previous,omitempty"`
+
 	// Uptime is the elapsed time since the agent entered StateRunning.
 	// Zero if the agent is not currently running.
 	Uptime time.Duration `json:"uptime,omitempty"`
@@ -232,18 +260,47 @@ type BaseAgent struct {
 	capabilities []Capability
 	startedAt    *time.Time
 
+	// Capability requirements — set at construction via builder, never
+	// modified.
+	requirements []CapabilityRequirement
+
 	// Observability — set at construction, never modified.
 	tracer trace.Tracer
 	logger *slog.Logger
 
-	// Lifecycle hooks — set at construction via builder, never modified.
-	onStart  Hook
-	onStop   Hook
-	onPause  Hook
-	onResume Hook
+	// Lifecycle hook chains — set at construction via builder, never
+	// modified. Each chain holds the WithOn* hook (if any) followed by the
+	// named hooks in registration order.
+	startHooks  []NamedHook
+	stopHooks   []NamedHook
+	pauseHooks  []NamedHook
+	resumeHooks []NamedHook
 
 	// State change observers — set at construction via builder, never modified.
-	stateHandlers []StateChangeHandler
+	stateHandlers      []StateChangeHandler
+	transitionHandlers []TransitionHandler
+
+	// Transition journal. stateStore is set at construction and never
+	// modified; pending and previous are protected by mu. journalMu
+	// serializes flushes so transitions reach the store in order, and
+	// protects previousLoaded.
+	stateStore     StateStore
+	journalMu      sync.Mutex
+	pending        []StateTransition
+	previous       *StateTransition
+	previousLoaded bool
+
+	// Leader election. elector is set at construction and never modified;
+	// the remaining fields are protected by mu. electionDone is non-nil
+	// while the election goroutine runs.
+	elector        *LeaderElector
+	electionCancel context.CancelFunc
+	electionDone   chan struct{}
+	leaderPaused   bool
+
+	// Scheduled jobs — set at construction via builder, never modified.
+	// Nil if the agent has no jobs.
+	scheduler *scheduler
 }
 
 // Compile-time interface compliance check. This ensures that *BaseAgent
@@ -286,11 +343,40 @@ func (a *BaseAgent) Capabilities() []Capability {
 	return cloneCapabilities(a.capabilities)
 }
 
+// Requirements returns a copy of the capability requirements registered via
+// [BaseAgentBuilder.WithRequirement]. Returns nil if the agent has no
+// requirements. This method is safe for concurrent use.
+func (a *BaseAgent) Requirements() []CapabilityRequirement {
+	if len(a.requirements) == 0 {
+		return nil
+	}
+	copied := make([]CapabilityRequirement, len(a.requirements))
+	copy(copied, a.requirements)
+	return copied
+}
+
+// CheckCompatibility verifies that peer provides every capability this
+// agent requires, at a version satisfying each requirement's constraint.
+// Returns nil if the agents are compatible, or a [*sserr.Error] with code
+// [sserr.CodeConflictVersionMismatch] describing each unmet requirement.
+// See [CheckCompatibility] for details.
+//
+// Example:
+//
+//	if err := orchestrator.CheckCompatibility(worker); err != nil {
+//	    return fmt.Errorf("cannot dispatch to %s: %w", worker.ID(), err)
+//	}
+func (a *BaseAgent) CheckCompatibility(peer Agent) error {
+	return CheckCompatibility(peer.Capabilities(), a.requirements...)
+}
+
 // Info returns a point-in-time snapshot of the agent's identity, state,
 // capabilities, and uptime. The returned [AgentInfo] contains deep copies
 // of all mutable fields and is safe to serialize to JSON. This method is
 // safe for concurrent use.
 func (a *BaseAgent) Info() AgentInfo {
+	a.loadPrevious()
+
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
@@ -300,6 +386,16 @@ func (a *BaseAgent) Info() AgentInfo {
 		Version:      a.version,
 		State:        a.state,
 		Capabilities: cloneCapabilities(a.capabilities),
+		Requirements: a.Requirements(),
+	}
+
+	if a.previous != nil {
+		p := *a.previous
+		info.Previous = &p
+	}
+
+	if a.scheduler != nil {
+		info.Jobs = a.scheduler.statuses()
 	}
 
 	if a.startedAt != nil && a.state == StateRunning {
@@ -348,7 +444,8 @@ func (a *BaseAgent) Health(ctx context.Context) error {
 //
 // SetState is exported for use by concrete agent implementations that
 // need to set state programmatically (e.g., transitioning to
-// [StateFailed] when an internal error is detected).
+// [StateFailed] when an internal error is detected). Use
+// [BaseAgent.SetStateWithReason] to record why the transition happened.
 //
 // Example:
 //
@@ -357,15 +454,40 @@ func (a *BaseAgent) Health(ctx context.Context) error {
 //	    _ = agent.SetState(lifecycle.StateFailed)
 //	}
 func (a *BaseAgent) SetState(new State) error {
+	return a.SetStateWithReason(new, "", nil)
+}
+
+// SetStateWithReason is like [BaseAgent.SetState] but also records a
+// human-readable reason and the error (if any) that caused the transition.
+// Both are passed to [TransitionHandler] functions and journaled to the
+// [StateStore], so operators can later see why the agent changed state.
+//
+// Journal failures are logged and never cause the transition to fail.
+//
+// Example:
+//
+//	if err := consumer.Run(ctx); err != nil {
+//	    _ = agent.SetStateWithReason(lifecycle.StateFailed, "queue consumer exited", err)
+//	}
+func (a *BaseAgent) SetStateWithReason(new State, reason string, cause error) error {
+	return a.transition(context.Background(), new, reason, cause)
+}
+
+// transition performs a validated state change and journals it using ctx
+// for tracing. The caller must NOT hold a.mu.
+func (a *BaseAgent) transition(ctx context.Context, new State, reason string, cause error) error {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-	return a.setStateLocked(new)
+	err := a.setStateLocked(new, reason, cause)
+	a.mu.Unlock()
+	a.flushJournal(ctx)
+	return err
 }
 
 // setStateLocked transitions state while the mutex is already held. This
 // allows lifecycle methods to combine state transitions and startedAt
-// updates atomically. Callers must hold a.mu.Lock().
-func (a *BaseAgent) setStateLocked(new State) error {
+// updates atomically. Callers must hold a.mu.Lock() and should call
+// [BaseAgent.flushJournal] after releasing it.
+func (a *BaseAgent) setStateLocked(new State, reason string, cause error) error {
 	old := a.state
 	if !ValidTransition(old, new) {
 		return sserr.Newf(sserr.CodeConflict,
@@ -374,28 +496,178 @@ func (a *BaseAgent) setStateLocked(new State) error {
 
 	a.state = new
 
+	t := StateTransition{
+		AgentID:   a.id,
+		From:      old,
+		To:        new,
+		Reason:    reason,
+		Timestamp: time.Now().UTC(),
+	}
+	if cause != nil {
+		t.Error = cause.Error()
+	}
+
+	// Queue the transition for the journal. Store writes may involve
+	// network I/O, so they happen in flushJournal after the lock is
+	// released.
+	if a.stateStore != nil {
+		a.pending = append(a.pending, t)
+	}
+
 	// Notify state change handlers under the lock to guarantee ordering.
 	// Each handler is called in a deferred-recover wrapper to prevent a
 	// panicking handler from crashing the agent or corrupting state.
 	for _, h := range a.stateHandlers {
 		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					a.logger.Error("lifecycle: state change handler panicked",
-						"panic", r,
-						"agent_id", a.id,
-						"old_state", string(old),
-						"new_state", string(new),
-					)
-				}
-			}()
+			defer a.recoverHandler(old, new)
 			h(old, new)
 		}()
 	}
+	for _, h := range a.transitionHandlers {
+		func() {
+			defer a.recoverHandler(old, new)
+			h(t)
+		}()
+	}
 
 	return nil
 }
 
+// recoverHandler recovers and logs a panic from a state change or
+// transition handler. It must be called directly via defer.
+func (a *BaseAgent) recoverHandler(old, new State) {
+	if r := recover(); r != nil {
+		a.logger.Error("lifecycle: state change handler panicked",
+			"panic", r,
+			"agent_id", a.id,
+			"old_state", string(old),
+			"new_state", string(new),
+		)
+	}
+}
+
+// PreviousTransition returns the last transition journaled by an earlier
+// run of this agent, as loaded from the [StateStore]. The boolean is false
+// if no store is configured, the agent has no recorded history, or the
+// history could not be loaded. History is loaded once, on the first call
+// to this method, [BaseAgent.Info], [BaseAgent.Start], or the first
+// journaled transition, so it is available before the agent starts.
+//
+// Example:
+//
+//	if prev, ok := agent.PreviousTransition(); ok && prev.To == lifecycle.StateFailed {
+//	    slog.Warn("previous run failed", "reason", prev.Reason, "error", prev.Error)
+//	}
+func (a *BaseAgent) PreviousTransition() (StateTransition, bool) {
+	a.loadPrevious()
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.previous == nil {
+		return StateTransition{}, false
+	}
+	return *a.previous, true
+}
+
+// History returns up to limit of the agent's most recent journaled
+// transitions, oldest first, including those from earlier runs. A limit
+// of zero or less returns the full retained history. Returns nil if no
+// [StateStore] is configured.
+func (a *BaseAgent) History(ctx context.Context, limit int) ([]StateTransition, error) {
+	if a.stateStore == nil {
+		return nil, nil
+	}
+	a.flushJournal(ctx)
+	return a.stateStore.History(ctx, a.id, limit)
+}
+
+// loadPrevious loads the agent's last journaled transition from the store
+// if it has not been loaded yet. The caller must NOT hold a.mu.
+func (a *BaseAgent) loadPrevious() {
+	if a.stateStore == nil {
+		return
+	}
+
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	if a.previousLoaded {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), journalTimeout)
+	defer cancel()
+	a.loadPreviousLocked(ctx)
+}
+
+// loadPreviousLocked loads the agent's last journaled transition from the
+// store the first time it is called. Callers must hold a.journalMu.
+func (a *BaseAgent) loadPreviousLocked(ctx context.Context) {
+	if a.previousLoaded {
+		return
+	}
+	a.previousLoaded = true
+
+	prev, ok, err := a.stateStore.Last(ctx, a.id)
+	if err != nil {
+		a.logger.WarnContext(ctx, "lifecycle: failed to load previous state",
+			"agent_id", a.id,
+			"error", err,
+		)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	a.previous = &prev
+	a.mu.Unlock()
+
+	a.logger.InfoContext(ctx, "lifecycle: loaded previous state",
+		"agent_id", a.id,
+		"previous_state", string(prev.To),
+		"previous_reason", prev.Reason,
+		"previous_error", prev.Error,
+		"previous_at", prev.Timestamp,
+	)
+}
+
+// flushJournal writes queued transitions to the state store in order.
+// Failures are logged and do not affect the agent's state. The caller's
+// cancellation is ignored so that a transition caused by a canceled
+// context is still recorded. The caller must NOT hold a.mu.
+func (a *BaseAgent) flushJournal(ctx context.Context) {
+	if a.stateStore == nil {
+		return
+	}
+
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), journalTimeout)
+	defer cancel()
+
+	// Load the previous run's last transition before this run writes
+	// anything, so it is never mistaken for one of our own.
+	a.loadPreviousLocked(ctx)
+
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+
+	for _, t := range pending {
+		if err := a.stateStore.Append(ctx, t); err != nil {
+			a.logger.WarnContext(ctx, "lifecycle: failed to journal state transition",
+				"agent_id", a.id,
+				"from", string(t.From),
+				"to", string(t.To),
+				"error", err,
+			)
+		}
+	}
+}
+
 // requireState checks that the agent is currently in the expected state.
 // Returns a [*sserr.Error] with code [sserr.CodeConflict] if the current
 // state does not match. The caller must NOT hold a.mu.
@@ -418,15 +690,17 @@ func failSpan(span trace.Span, err error) {
 }
 
 // Start begins the agent's operation. It transitions the agent through
-// [StateStarting] to [StateRunning], executing any registered OnStart
-// hook between the two transitions.
+// [StateStarting] to [StateRunning], executing the registered start hooks
+// in order between the two transitions.
 //
 // The context controls the deadline for startup. If the context is
 // already canceled, Start returns immediately without modifying state.
 //
-// If the OnStart hook returns an error, the agent transitions to
-// [StateFailed] and the error is returned wrapped with
-// [sserr.CodeInternal].
+// If a start hook returns an error, the remaining hooks are skipped, the
+// [NamedHook.Rollback] hooks of those that already succeeded run in
+// reverse order, and the agent transitions to [StateFailed]. The error is
+// returned wrapped with [sserr.CodeInternal], or [sserr.CodeTimeout] if
+// the hook exceeded its [NamedHook.Timeout].
 func (a *BaseAgent) Start(ctx context.Context) error {
 	ctx, span := a.tracer.Start(ctx, "lifecycle.Start",
 		trace.WithSpanKind(trace.SpanKindInternal),
@@ -444,8 +718,12 @@ func (a *BaseAgent) Start(ctx context.Context) error {
 			"lifecycle: start canceled before execution")
 	}
 
+	// Load the previous run's last transition before recording any of
+	// this run's transitions.
+	a.flushJournal(ctx)
+
 	// Transition to Starting.
-	if err := a.SetState(StateStarting); err != nil {
+	if err := a.transition(ctx, StateStarting, "start requested", nil); err != nil {
 		failSpan(span, err)
 		return err
 	}
@@ -456,25 +734,16 @@ func (a *BaseAgent) Start(ctx context.Context) error {
 		"agent_version", a.version,
 	)
 
-	// Execute the OnStart hook outside the lock.
-	if a.onStart != nil {
-		if err := a.onStart(ctx); err != nil {
-			a.logger.ErrorContext(ctx, "lifecycle: start hook failed",
-				"agent_id", a.id,
-				"error", err,
-			)
-			_ = a.SetState(StateFailed)
-			failSpan(span, err)
-			return sserr.Wrap(err, sserr.CodeInternal,
-				"lifecycle: start hook failed")
-		}
+	// Execute the start hooks outside the lock.
+	if err := a.runHooks(ctx, span, hookPhaseStart, a.startHooks); err != nil {
+		return err
 	}
 
 	// Transition to Running and record the start timestamp atomically
 	// under the same lock to prevent a window where Info() could see
 	// StateRunning with nil startedAt.
 	a.mu.Lock()
-	if err := a.setStateLocked(StateRunning); err != nil {
+	if err := a.setStateLocked(StateRunning, "started", nil); err != nil {
 		a.mu.Unlock()
 		failSpan(span, err)
 		return err
@@ -482,27 +751,36 @@ func (a *BaseAgent) Start(ctx context.Context) error {
 	now := time.Now().UTC()
 	a.startedAt = &now
 	a.mu.Unlock()
+	a.flushJournal(ctx)
 
 	a.logger.InfoContext(ctx, "lifecycle: agent started",
 		"agent_id", a.id,
 		"agent_name", a.name,
 	)
+
+	// Run the first election round before returning so that a replica
+	// that is not the leader is already paused when Start returns.
+	a.startElection(ctx)
+	if a.scheduler != nil {
+		a.scheduler.start(ctx)
+	}
 	span.SetStatus(codes.Ok, "")
 
 	return nil
 }
 
 // Stop gracefully shuts down the agent. It transitions the agent through
-// [StateStopping] to [StateStopped], executing any registered OnStop hook
-// between the two transitions.
+// [StateStopping] to [StateStopped], executing the registered stop hooks
+// in order between the two transitions.
 //
 // If the agent is already in a terminal state ([StateStopped] or
 // [StateFailed]), Stop is a no-op and returns nil. This makes it safe
 // to call Stop multiple times or in a deferred cleanup.
 //
-// If the OnStop hook returns an error, the agent transitions to
-// [StateFailed] and the error is returned wrapped with
-// [sserr.CodeInternal].
+// Every stop hook runs even if an earlier one fails. If any returns an
+// error, the agent transitions to [StateFailed] and the first error is
+// returned wrapped with [sserr.CodeInternal], or [sserr.CodeTimeout] if
+// the hook exceeded its [NamedHook.Timeout].
 func (a *BaseAgent) Stop(ctx context.Context) error {
 	ctx, span := a.tracer.Start(ctx, "lifecycle.Stop",
 		trace.WithSpanKind(trace.SpanKindInternal),
@@ -513,6 +791,16 @@ func (a *BaseAgent) Stop(ctx context.Context) error {
 	)
 	defer span.End()
 
+	// Leave the election first so that leadership changes cannot race
+	// with shutdown.
+	a.stopElection()
+
+	// Cancel scheduled jobs and wait for in-flight runs before the OnStop
+	// hook releases the resources they use.
+	if a.scheduler != nil {
+		a.scheduler.stop()
+	}
+
 	// Terminal states: Stop is a no-op.
 	if a.State().IsTerminal() {
 		span.SetStatus(codes.Ok, "")
@@ -527,7 +815,7 @@ func (a *BaseAgent) Stop(ctx context.Context) error {
 	}
 
 	// Transition to Stopping.
-	if err := a.SetState(StateStopping); err != nil {
+	if err := a.transition(ctx, StateStopping, "stop requested", nil); err != nil {
 		failSpan(span, err)
 		return err
 	}
@@ -537,29 +825,21 @@ func (a *BaseAgent) Stop(ctx context.Context) error {
 		"agent_name", a.name,
 	)
 
-	// Execute the OnStop hook outside the lock.
-	if a.onStop != nil {
-		if err := a.onStop(ctx); err != nil {
-			a.logger.ErrorContext(ctx, "lifecycle: stop hook failed",
-				"agent_id", a.id,
-				"error", err,
-			)
-			_ = a.SetState(StateFailed)
-			failSpan(span, err)
-			return sserr.Wrap(err, sserr.CodeInternal,
-				"lifecycle: stop hook failed")
-		}
+	// Execute the stop hooks outside the lock.
+	if err := a.runHooks(ctx, span, hookPhaseStop, a.stopHooks); err != nil {
+		return err
 	}
 
 	// Transition to Stopped and clear the start timestamp atomically.
 	a.mu.Lock()
-	if err := a.setStateLocked(StateStopped); err != nil {
+	if err := a.setStateLocked(StateStopped, "stopped", nil); err != nil {
 		a.mu.Unlock()
 		failSpan(span, err)
 		return err
 	}
 	a.startedAt = nil
 	a.mu.Unlock()
+	a.flushJournal(ctx)
 
 	a.logger.InfoContext(ctx, "lifecycle: agent stopped",
 		"agent_id", a.id,
@@ -571,14 +851,15 @@ func (a *BaseAgent) Stop(ctx context.Context) error {
 }
 
 // Pause temporarily suspends the agent's operation. It validates that the
-// agent is in [StateRunning], executes any registered OnPause hook, and
+// agent is in [StateRunning], executes the registered pause hooks, and
 // then transitions to [StatePaused]. The hook runs while the agent is
 // still in [StateRunning], ensuring external observers do not see
 // [StatePaused] until the pause operation is complete.
 //
-// If the OnPause hook returns an error, the agent transitions to
-// [StateFailed] and the error is returned wrapped with
-// [sserr.CodeInternal].
+// If a pause hook returns an error, the remaining hooks are skipped, the
+// agent transitions to [StateFailed], and the error is returned wrapped
+// with [sserr.CodeInternal], or [sserr.CodeTimeout] if the hook exceeded
+// its [NamedHook.Timeout].
 func (a *BaseAgent) Pause(ctx context.Context) error {
 	ctx, span := a.tracer.Start(ctx, "lifecycle.Pause",
 		trace.WithSpanKind(trace.SpanKindInternal),
@@ -609,22 +890,13 @@ func (a *BaseAgent) Pause(ctx context.Context) error {
 		"agent_name", a.name,
 	)
 
-	// Execute the OnPause hook while still in StateRunning.
-	if a.onPause != nil {
-		if err := a.onPause(ctx); err != nil {
-			a.logger.ErrorContext(ctx, "lifecycle: pause hook failed",
-				"agent_id", a.id,
-				"error", err,
-			)
-			_ = a.SetState(StateFailed)
-			failSpan(span, err)
-			return sserr.Wrap(err, sserr.CodeInternal,
-				"lifecycle: pause hook failed")
-		}
+	// Execute the pause hooks while still in StateRunning.
+	if err := a.runHooks(ctx, span, hookPhasePause, a.pauseHooks); err != nil {
+		return err
 	}
 
 	// Transition to Paused after the hook succeeds.
-	if err := a.SetState(StatePaused); err != nil {
+	if err := a.transition(ctx, StatePaused, "paused", nil); err != nil {
 		failSpan(span, err)
 		return err
 	}
@@ -639,14 +911,15 @@ func (a *BaseAgent) Pause(ctx context.Context) error {
 }
 
 // Resume restores a paused agent to [StateRunning]. It validates that the
-// agent is in [StatePaused], executes any registered OnResume hook, and
+// agent is in [StatePaused], executes the registered resume hooks, and
 // then transitions to [StateRunning]. The hook runs while the agent is
 // still in [StatePaused], ensuring external observers do not see
 // [StateRunning] until the resume operation is complete.
 //
-// If the OnResume hook returns an error, the agent transitions to
-// [StateFailed] and the error is returned wrapped with
-// [sserr.CodeInternal].
+// If a resume hook returns an error, the remaining hooks are skipped, the
+// agent transitions to [StateFailed], and the error is returned wrapped
+// with [sserr.CodeInternal], or [sserr.CodeTimeout] if the hook exceeded
+// its [NamedHook.Timeout].
 func (a *BaseAgent) Resume(ctx context.Context) error {
 	ctx, span := a.tracer.Start(ctx, "lifecycle.Resume",
 		trace.WithSpanKind(trace.SpanKindInternal),
@@ -670,27 +943,26 @@ func (a *BaseAgent) Resume(ctx context.Context) error {
 		return err
 	}
 
+	// A replica that is not the leader must stay paused.
+	if a.elector != nil && !a.elector.IsLeader() {
+		err := sserr.New(sserr.CodeConflict,
+			"lifecycle: cannot resume, agent does not hold leadership")
+		failSpan(span, err)
+		return err
+	}
+
 	a.logger.InfoContext(ctx, "lifecycle: resuming agent",
 		"agent_id", a.id,
 		"agent_name", a.name,
 	)
 
-	// Execute the OnResume hook while still in StatePaused.
-	if a.onResume != nil {
-		if err := a.onResume(ctx); err != nil {
-			a.logger.ErrorContext(ctx, "lifecycle: resume hook failed",
-				"agent_id", a.id,
-				"error", err,
-			)
-			_ = a.SetState(StateFailed)
-			failSpan(span, err)
-			return sserr.Wrap(err, sserr.CodeInternal,
-				"lifecycle: resume hook failed")
-		}
+	// Execute the resume hooks while still in StatePaused.
+	if err := a.runHooks(ctx, span, hookPhaseResume, a.resumeHooks); err != nil {
+		return err
 	}
 
 	// Transition to Running after the hook succeeds.
-	if err := a.SetState(StateRunning); err != nil {
+	if err := a.transition(ctx, StateRunning, "resumed", nil); err != nil {
 		failSpan(span, err)
 		return err
 	}
@@ -704,6 +976,132 @@ func (a *BaseAgent) Resume(ctx context.Context) error {
 	return nil
 }
 
+// Jobs returns a snapshot of the status of each job registered via
+// [BaseAgentBuilder.WithScheduledJob] and related methods, in
+// registration order. Returns nil if the agent has no jobs.
+func (a *BaseAgent) Jobs() []JobStatus {
+	if a.scheduler == nil {
+		return nil
+	}
+	return a.scheduler.statuses()
+}
+
+// IsLeader reports whether the agent currently holds leadership. Agents
+// without leader election (see [BaseAgentBuilder.WithLeaderElection])
+// always report true.
+func (a *BaseAgent) IsLeader() bool {
+	if a.elector == nil {
+		return true
+	}
+	return a.elector.IsLeader()
+}
+
+// FencingToken returns the fencing token of the agent's current leadership
+// term. The boolean is false if the agent has no leader election or does
+// not currently hold leadership. Include the token in writes to shared
+// systems so they can reject writes from a superseded leader.
+func (a *BaseAgent) FencingToken() (int64, bool) {
+	if a.elector == nil {
+		return 0, false
+	}
+	return a.elector.FencingToken()
+}
+
+// startElection joins the leader election, if configured. The first round
+// runs synchronously; later rounds run on a background goroutine until
+// [BaseAgent.stopElection] is called. The election outlives ctx's
+// cancellation but keeps its values (e.g., trace context).
+func (a *BaseAgent) startElection(ctx context.Context) {
+	if a.elector == nil {
+		return
+	}
+	if err := a.elector.begin(); err != nil {
+		a.logger.ErrorContext(ctx, "lifecycle: failed to join leader election",
+			"agent_id", a.id,
+			"error", err,
+		)
+		return
+	}
+
+	ectx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	a.mu.Lock()
+	a.electionCancel = cancel
+	a.electionDone = done
+	a.leaderPaused = false
+	a.mu.Unlock()
+
+	a.elector.step(ectx)
+	go func() {
+		defer close(done)
+		defer a.elector.end()
+		a.elector.loop(ectx)
+	}()
+}
+
+// stopElection leaves the leader election, releasing leadership if held,
+// and waits for the election goroutine to exit. It is a no-op if no
+// election is running. The caller must NOT hold a.mu.
+func (a *BaseAgent) stopElection() {
+	a.mu.Lock()
+	cancel, done := a.electionCancel, a.electionDone
+	a.electionCancel, a.electionDone = nil, nil
+	a.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// onLeadershipChange pauses the agent when it loses leadership and resumes
+// it when leadership is regained. Only pauses caused by the election are
+// undone; an agent paused by an operator stays paused.
+func (a *BaseAgent) onLeadershipChange(ctx context.Context, leader bool, token int64) {
+	a.mu.Lock()
+	active := a.electionDone != nil
+	state := a.state
+	leaderPaused := a.leaderPaused
+	if active && !leader && state == StateRunning {
+		a.leaderPaused = true
+	}
+	a.mu.Unlock()
+
+	// Ignore the final notification sent while leaving the election.
+	if !active {
+		return
+	}
+
+	switch {
+	case !leader && state == StateRunning:
+		a.logger.InfoContext(ctx, "lifecycle: leadership lost, pausing agent",
+			"agent_id", a.id,
+		)
+		if err := a.Pause(ctx); err != nil {
+			a.logger.ErrorContext(ctx, "lifecycle: failed to pause agent after losing leadership",
+				"agent_id", a.id,
+				"error", err,
+			)
+		}
+	case leader && state == StatePaused && leaderPaused:
+		a.logger.InfoContext(ctx, "lifecycle: leadership acquired, resuming agent",
+			"agent_id", a.id,
+			"fencing_token", token,
+		)
+		if err := a.Resume(ctx); err != nil {
+			a.logger.ErrorContext(ctx, "lifecycle: failed to resume agent after acquiring leadership",
+				"agent_id", a.id,
+				"error", err,
+			)
+			return
+		}
+		a.mu.Lock()
+		a.leaderPaused = false
+		a.mu.Unlock()
+	}
+}
+
 // cloneCapabilities returns a deep copy of a capability slice, including
 // independent copies of each capability's metadata map.
 func cloneCapabilities(caps []Capability) []Capability {
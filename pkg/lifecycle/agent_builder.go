@@ -2,6 +2,7 @@ package lifecycle
 
 import (
 	"log/slog"
+	"time"
 
 	"go.opentelemetry.io/otel"
 
@@ -35,12 +36,21 @@ type BaseAgentBuilder struct {
 	name          string
 	version       string
 	capabilities  []Capability
+	requirements  []CapabilityRequirement
 	logger        *slog.Logger
 	onStart       Hook
 	onStop        Hook
 	onPause       Hook
 	onResume      Hook
+	startHooks    []NamedHook
+	stopHooks     []NamedHook
+	pauseHooks    []NamedHook
+	resumeHooks   []NamedHook
 	stateHandlers []StateChangeHandler
+	transitions   []TransitionHandler
+	stateStore    StateStore
+	elector       *LeaderElector
+	jobs          []jobSpec
 }
 
 // NewBaseAgentBuilder creates a new builder with the required identity fields.
@@ -74,6 +84,15 @@ func (b *BaseAgentBuilder) WithCapabilities(caps []Capability) *BaseAgentBuilder
 	return b
 }
 
+// WithRequirement declares that the agent depends on a capability provided
+// by its peers, at a version matching the requirement's constraint. The
+// constraint is validated during [BaseAgentBuilder.Build]. Requirements are
+// reported via [AgentInfo] and evaluated by [BaseAgent.CheckCompatibility].
+func (b *BaseAgentBuilder) WithRequirement(req CapabilityRequirement) *BaseAgentBuilder {
+	b.requirements = append(b.requirements, req)
+	return b
+}
+
 // WithLogger sets a custom [*slog.Logger] for the agent. If not called,
 // [slog.Default] is used. The logger is used for lifecycle event logging
 // and panic recovery messages.
@@ -120,6 +139,40 @@ func (b *BaseAgentBuilder) WithOnResume(hook Hook) *BaseAgentBuilder {
 	return b
 }
 
+// WithStartHook appends a [NamedHook] to the start chain. Start hooks run
+// in registration order, after any hook set with
+// [BaseAgentBuilder.WithOnStart]. If one fails, the Rollback hooks of the
+// start hooks that already succeeded run in reverse order before the
+// agent transitions to [StateFailed].
+func (b *BaseAgentBuilder) WithStartHook(hook NamedHook) *BaseAgentBuilder {
+	b.startHooks = append(b.startHooks, hook)
+	return b
+}
+
+// WithStopHook appends a [NamedHook] to the stop chain. Stop hooks run in
+// registration order, after any hook set with [BaseAgentBuilder.WithOnStop].
+// Every stop hook runs even if an earlier one fails.
+func (b *BaseAgentBuilder) WithStopHook(hook NamedHook) *BaseAgentBuilder {
+	b.stopHooks = append(b.stopHooks, hook)
+	return b
+}
+
+// WithPauseHook appends a [NamedHook] to the pause chain. Pause hooks run
+// in registration order, after any hook set with
+// [BaseAgentBuilder.WithOnPause].
+func (b *BaseAgentBuilder) WithPauseHook(hook NamedHook) *BaseAgentBuilder {
+	b.pauseHooks = append(b.pauseHooks, hook)
+	return b
+}
+
+// WithResumeHook appends a [NamedHook] to the resume chain. Resume hooks
+// run in registration order, after any hook set with
+// [BaseAgentBuilder.WithOnResume].
+func (b *BaseAgentBuilder) WithResumeHook(hook NamedHook) *BaseAgentBuilder {
+	b.resumeHooks = append(b.resumeHooks, hook)
+	return b
+}
+
 // OnStateChange registers a [StateChangeHandler] that is called on every
 // state transition. Multiple handlers may be registered and are called in
 // registration order. Handlers execute synchronously under the state mutex
@@ -132,13 +185,111 @@ func (b *BaseAgentBuilder) OnStateChange(handler StateChangeHandler) *BaseAgentB
 	return b
 }
 
+// OnTransition registers a [TransitionHandler] that is called on every
+// state transition with the full [StateTransition], including its reason
+// and cause. Multiple handlers may be registered and are called in
+// registration order, after all [StateChangeHandler] functions.
+func (b *BaseAgentBuilder) OnTransition(handler TransitionHandler) *BaseAgentBuilder {
+	b.transitions = append(b.transitions, handler)
+	return b
+}
+
+// WithStateStore sets the [StateStore] used to journal every state
+// transition. When set, the agent loads its last journaled transition from
+// a previous run (see [BaseAgent.PreviousTransition]) and records each
+// new transition with its timestamp, reason, and error. Journal failures
+// are logged and never fail a transition.
+//
+// Example:
+//
+//	store, _ := lifecycle.NewPostgresStateStore(db, "")
+//	agent, err := lifecycle.NewBaseAgentBuilder("agent-001", "research-agent", "1.0.0").
+//	    WithStateStore(store).
+//	    Build()
+func (b *BaseAgentBuilder) WithStateStore(store StateStore) *BaseAgentBuilder {
+	b.stateStore = store
+	return b
+}
+
+// WithLeaderElection makes the agent participate in elector's election,
+// so that only one replica runs at a time. The agent joins the election
+// when it reaches [StateRunning] and leaves it, releasing leadership, on
+// [BaseAgent.Stop]. A replica that is not the leader is moved to
+// [StatePaused] (running its OnPause hook) and is resumed when it gains
+// leadership; [BaseAgent.Resume] is refused while it is not the leader.
+//
+// Each elector can be attached to only one agent: Build returns an error
+// if elector already belongs to an agent, including one built earlier by
+// the same builder.
+//
+// Example:
+//
+//	lock, _ := lifecycle.NewPostgresLeaderLock(db, "compactor")
+//	elector, _ := lifecycle.NewLeaderElector(lock, 5*time.Second)
+//	agent, err := lifecycle.NewBaseAgentBuilder(podName, "compactor", "1.0.0").
+//	    WithLeaderElection(elector).
+//	    Build()
+func (b *BaseAgentBuilder) WithLeaderElection(elector *LeaderElector) *BaseAgentBuilder {
+	b.elector = elector
+	return b
+}
+
+// WithScheduledJob registers a job that runs on schedule while the agent is
+// in [StateRunning]. The scheduler starts when the agent starts and is
+// stopped, canceling the job's context and waiting for in-flight runs, at
+// the beginning of [BaseAgent.Stop]. Activations that fall due while the
+// agent is paused or while the previous run is still in progress are
+// skipped. Each run is traced with a "lifecycle.Job" span and recorded in
+// [BaseAgent.Jobs].
+//
+// Job names must be unique and non-empty; Build returns an error
+// otherwise.
+func (b *BaseAgentBuilder) WithScheduledJob(name string, schedule Schedule, job Job) *BaseAgentBuilder {
+	b.jobs = append(b.jobs, jobSpec{name: name, schedule: schedule, job: job})
+	return b
+}
+
+// WithCronJob registers a job that runs on a cron expression. See
+// [ParseCron] for the syntax and [BaseAgentBuilder.WithScheduledJob] for
+// the execution rules. An invalid expression is reported by Build.
+//
+// Example:
+//
+//	builder.WithCronJob("compact", "*/15 * * * *", func(ctx context.Context) error {
+//	    return store.Compact(ctx)
+//	})
+func (b *BaseAgentBuilder) WithCronJob(name, expr string, job Job) *BaseAgentBuilder {
+	schedule, err := ParseCron(expr)
+	b.jobs = append(b.jobs, jobSpec{name: name, schedule: schedule, job: job, err: err})
+	return b
+}
+
+// WithIntervalJob registers a job that runs every interval, each run
+// delayed by a random duration in [0, jitter). See
+// [BaseAgentBuilder.WithScheduledJob] for the execution rules.
+func (b *BaseAgentBuilder) WithIntervalJob(name string, every, jitter time.Duration, job Job) *BaseAgentBuilder {
+	var err error
+	if every <= 0 {
+		err = sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: job interval must be positive, got %s", every)
+	}
+	b.jobs = append(b.jobs, jobSpec{name: name, schedule: WithJitter(Every(every), jitter), job: job, err: err})
+	return b
+}
+
 // Build validates the configuration and constructs a [*BaseAgent]. Returns
 // a [*sserr.Error] with code [sserr.CodeValidation] if any required field
-// is empty or any capability has an empty Name or Version.
+// is empty, any capability has an empty Name or Version, a named hook or
+// scheduled job is unnamed, incomplete, or duplicated, or a non-start
+// hook has a rollback; or [sserr.CodeValidationFormat] if a
+// capability version is not a valid semantic version, a requirement
+// constraint cannot be parsed, or a job schedule is invalid; or
+// [sserr.CodeConflict] if the leader elector is already attached to an
+// agent.
 //
 // Build performs defensive copies of all mutable inputs (capabilities,
-// state handlers) to prevent external mutation after construction. The
-// initial state is [StateUnknown].
+// requirements, hooks, state and transition handlers) to prevent external
+// mutation after construction. The initial state is [StateUnknown].
 func (b *BaseAgentBuilder) Build() (*BaseAgent, error) {
 	if b.id == "" {
 		return nil, sserr.New(sserr.CodeValidation,
@@ -162,6 +313,41 @@ func (b *BaseAgentBuilder) Build() (*BaseAgent, error) {
 		caps[i] = c.Clone()
 	}
 
+	// Validate and defensively copy requirements.
+	var reqs []CapabilityRequirement
+	if len(b.requirements) > 0 {
+		reqs = make([]CapabilityRequirement, len(b.requirements))
+		for i, r := range b.requirements {
+			if _, err := validateRequirement(r); err != nil {
+				return nil, err
+			}
+			reqs[i] = r
+		}
+	}
+
+	// Validate hooks and build one chain per phase.
+	startHooks, err := buildHookChain(hookPhaseStart, b.onStart, b.startHooks)
+	if err != nil {
+		return nil, err
+	}
+	stopHooks, err := buildHookChain(hookPhaseStop, b.onStop, b.stopHooks)
+	if err != nil {
+		return nil, err
+	}
+	pauseHooks, err := buildHookChain(hookPhasePause, b.onPause, b.pauseHooks)
+	if err != nil {
+		return nil, err
+	}
+	resumeHooks, err := buildHookChain(hookPhaseResume, b.onResume, b.resumeHooks)
+	if err != nil {
+		return nil, err
+	}
+
+	sched, err := buildScheduler(b.jobs)
+	if err != nil {
+		return nil, err
+	}
+
 	logger := b.logger
 	if logger == nil {
 		logger = slog.Default()
@@ -170,19 +356,37 @@ func (b *BaseAgentBuilder) Build() (*BaseAgent, error) {
 	// Defensive copy of state handlers.
 	handlers := make([]StateChangeHandler, len(b.stateHandlers))
 	copy(handlers, b.stateHandlers)
+	transitions := make([]TransitionHandler, len(b.transitions))
+	copy(transitions, b.transitions)
 
-	return &BaseAgent{
-		id:            b.id,
-		name:          b.name,
-		version:       b.version,
-		state:         StateUnknown,
-		capabilities:  caps,
-		tracer:        otel.Tracer(tracerName),
-		logger:        logger,
-		onStart:       b.onStart,
-		onStop:        b.onStop,
-		onPause:       b.onPause,
-		onResume:      b.onResume,
-		stateHandlers: handlers,
-	}, nil
+	agent := &BaseAgent{
+		id:                 b.id,
+		name:               b.name,
+		version:            b.version,
+		state:              StateUnknown,
+		capabilities:       caps,
+		requirements:       reqs,
+		tracer:             otel.Tracer(tracerName),
+		logger:             logger,
+		startHooks:         startHooks,
+		stopHooks:          stopHooks,
+		pauseHooks:         pauseHooks,
+		resumeHooks:        resumeHooks,
+		stateHandlers:      handlers,
+		transitionHandlers: transitions,
+		stateStore:         b.stateStore,
+		elector:            b.elector,
+		scheduler:          sched,
+	}
+	if b.elector != nil && !b.elector.attach(agent.onLeadershipChange) {
+		return nil, sserr.New(sserr.CodeConflict,
+			"lifecycle: leader elector is already attached to an agent")
+	}
+	if sched != nil {
+		sched.agentID = agent.id
+		sched.tracer = agent.tracer
+		sched.logger = logger
+		sched.active = func() bool { return agent.State() == StateRunning }
+	}
+	return agent, nil
 }
@@ -266,3 +266,66 @@ func TestBaseAgentBuilder_WithLogger(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, logger, agent.logger)
 }
+
+// ===========================================================================
+// Capability Requirement Tests
+// ===========================================================================
+
+// TestBaseAgentBuilder_Build_InvalidCapabilityVersion verifies that Build
+// rejects a capability whose version is not a valid semantic version.
+func TestBaseAgentBuilder_Build_InvalidCapabilityVersion(t *testing.T) {
+	t.Parallel()
+	_, err := NewBaseAgentBuilder("agent-001", "test-agent", "1.0.0").
+		WithCapability(Capability{Name: "search", Version: "v1"}).
+		Build()
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeValidationFormat))
+}
+
+// TestBaseAgentBuilder_Build_InvalidRequirement verifies that Build rejects
+// a requirement with a malformed constraint or empty name.
+func TestBaseAgentBuilder_Build_InvalidRequirement(t *testing.T) {
+	t.Parallel()
+	_, err := NewBaseAgentBuilder("agent-001", "test-agent", "1.0.0").
+		WithRequirement(CapabilityRequirement{Name: "search", Constraint: "^"}).
+		Build()
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeValidationFormat))
+
+	_, err = NewBaseAgentBuilder("agent-001", "test-agent", "1.0.0").
+		WithRequirement(CapabilityRequirement{Constraint: "*"}).
+		Build()
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeValidation))
+}
+
+// TestBaseAgent_CheckCompatibility verifies that an agent's registered
+// requirements are evaluated against a peer's capabilities and reported
+// via Info.
+func TestBaseAgent_CheckCompatibility(t *testing.T) {
+	t.Parallel()
+	consumer, err := NewBaseAgentBuilder("agent-001", "orchestrator", "1.0.0").
+		WithRequirement(CapabilityRequirement{Name: "web-search", Constraint: "^1.2"}).
+		Build()
+	require.NoError(t, err)
+
+	compatible, err := NewBaseAgentBuilder("agent-002", "researcher", "1.0.0").
+		WithCapability(Capability{Name: "web-search", Version: "1.3.0"}).
+		Build()
+	require.NoError(t, err)
+
+	outdated, err := NewBaseAgentBuilder("agent-003", "researcher", "0.9.0").
+		WithCapability(Capability{Name: "web-search", Version: "1.1.0"}).
+		Build()
+	require.NoError(t, err)
+
+	assert.NoError(t, consumer.CheckCompatibility(compatible))
+	err = consumer.CheckCompatibility(outdated)
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeConflictVersionMismatch))
+
+	info := consumer.Info()
+	require.Len(t, info.Requirements, 1)
+	assert.Equal(t, "web-search", info.Requirements[0].Name)
+	assert.Nil(t, compatible.Requirements())
+}
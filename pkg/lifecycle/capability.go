@@ -34,7 +34,8 @@ type Capability struct {
 	Name string `json:"name"`
 
 	// Version is the semantic version of the capability implementation
-	// (e.g., "1.0.0"). Must not be empty.
+	// (e.g., "1.0.0"). Must be a valid semantic version as accepted by
+	// [ParseSemVer].
 	Version string `json:"version"`
 
 	// Description is a human-readable summary of what this capability
@@ -50,7 +51,8 @@ type Capability struct {
 // NewCapability creates a new [Capability] with validated fields. The metadata
 // map is defensively copied to prevent external mutation. Returns a
 // [*sserr.Error] with code [sserr.CodeValidation] if name or version is
-// empty.
+// empty, or [sserr.CodeValidationFormat] if version is not a valid
+// semantic version.
 //
 // Example:
 //
@@ -64,6 +66,10 @@ func NewCapability(name, version, description string, metadata map[string]string
 		return Capability{}, sserr.Newf(sserr.CodeValidation,
 			"lifecycle: capability %q version must not be empty", name)
 	}
+	if _, err := ParseSemVer(version); err != nil {
+		return Capability{}, sserr.Wrapf(err, sserr.CodeValidationFormat,
+			"lifecycle: capability %q version is invalid", name)
+	}
 
 	// Defensive copy of metadata to prevent external mutation.
 	var copied map[string]string
@@ -82,8 +88,9 @@ func NewCapability(name, version, description string, metadata map[string]string
 	}, nil
 }
 
-// validateCapability checks that a Capability has non-empty Name and Version
-// fields. Returns a [*sserr.Error] with code [sserr.CodeValidation] if
+// validateCapability checks that a Capability has a non-empty Name and a
+// Version that parses as a semantic version. Returns a [*sserr.Error] with
+// code [sserr.CodeValidation] or [sserr.CodeValidationFormat] if
 // validation fails. This is used by [BaseAgentBuilder.Build] to reject
 // invalid capabilities registered via [BaseAgentBuilder.WithCapability].
 func validateCapability(c Capability) error {
@@ -95,9 +102,49 @@ func validateCapability(c Capability) error {
 		return sserr.Newf(sserr.CodeValidation,
 			"lifecycle: capability %q version must not be empty", c.Name)
 	}
+	if _, err := ParseSemVer(c.Version); err != nil {
+		return sserr.Wrapf(err, sserr.CodeValidationFormat,
+			"lifecycle: capability %q version is invalid", c.Name)
+	}
 	return nil
 }
 
+// SemVer parses and returns the capability's [Version] as a [SemVer].
+// Returns a [*sserr.Error] with code [sserr.CodeValidationFormat] if the
+// version is not a valid semantic version (e.g., a Capability constructed
+// as a struct literal without going through [NewCapability]).
+func (c Capability) SemVer() (SemVer, error) {
+	return ParseSemVer(c.Version)
+}
+
+// Satisfies reports whether the capability's version satisfies the given
+// constraint expression (e.g., "^1.2", ">=2.0 <3", "~1.4.1"). See
+// [Constraint] for the full syntax.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidationFormat] if the
+// constraint cannot be parsed or the capability version is invalid.
+//
+// Example:
+//
+//	ok, err := cap.Satisfies("^1.2")
+//	if err != nil {
+//	    return err
+//	}
+//	if !ok {
+//	    return fmt.Errorf("model-execution %s is too old", cap.Version)
+//	}
+func (c Capability) Satisfies(constraint string) (bool, error) {
+	cons, err := ParseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	v, err := c.SemVer()
+	if err != nil {
+		return false, err
+	}
+	return cons.Check(v), nil
+}
+
 // Clone returns a deep copy of the Capability, including a copy of the
 // [Metadata] map. This is used internally by [BaseAgent] to return
 // defensive copies from [BaseAgent.Capabilities] and [BaseAgent.Info].
@@ -169,3 +169,118 @@ func TestCapability_JSONOmitsEmptyMetadata(t *testing.T) {
 	jsonStr := string(data)
 	assert.NotContains(t, jsonStr, "metadata")
 }
+
+// ===========================================================================
+// Semantic Version Validation Tests
+// ===========================================================================
+
+// TestNewCapability_InvalidVersion verifies that NewCapability rejects a
+// version that is not a valid semantic version.
+func TestNewCapability_InvalidVersion(t *testing.T) {
+	t.Parallel()
+	_, err := NewCapability("search", "1.0", "desc", nil)
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeValidationFormat),
+		"error code = %v, want CodeValidationFormat", sserr.GetCode(err))
+	assert.True(t, sserr.IsValidation(err), "IsValidation() should be true for malformed version")
+}
+
+// TestCapability_Satisfies verifies that Satisfies evaluates constraint
+// expressions against the capability version.
+func TestCapability_Satisfies(t *testing.T) {
+	t.Parallel()
+	cap, err := NewCapability("model-execution", "1.4.2", "", nil)
+	require.NoError(t, err)
+
+	ok, err := cap.Satisfies("^1.2")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = cap.Satisfies(">=2.0 <3")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = cap.Satisfies("~1.4.1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+// TestCapability_Satisfies_InvalidConstraint verifies that Satisfies
+// returns a CodeValidationFormat error for a malformed constraint.
+func TestCapability_Satisfies_InvalidConstraint(t *testing.T) {
+	t.Parallel()
+	cap := Capability{Name: "search", Version: "1.0.0"}
+	_, err := cap.Satisfies(">=")
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeValidationFormat))
+}
+
+// TestCapability_Satisfies_InvalidVersion verifies that Satisfies returns
+// an error when the capability was built as a literal with a malformed
+// version.
+func TestCapability_Satisfies_InvalidVersion(t *testing.T) {
+	t.Parallel()
+	cap := Capability{Name: "search", Version: "latest"}
+	_, err := cap.Satisfies("*")
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeValidationFormat))
+}
+
+// ===========================================================================
+// CheckCompatibility Tests
+// ===========================================================================
+
+// TestCheckCompatibility_Satisfied verifies that CheckCompatibility returns
+// nil when every requirement is met.
+func TestCheckCompatibility_Satisfied(t *testing.T) {
+	t.Parallel()
+	caps := []Capability{
+		{Name: "model-execution", Version: "1.4.2"},
+		{Name: "policy-evaluation", Version: "2.1.0"},
+	}
+	err := CheckCompatibility(caps,
+		CapabilityRequirement{Name: "model-execution", Constraint: "^1.2"},
+		CapabilityRequirement{Name: "policy-evaluation", Constraint: ">=2.0 <3"},
+	)
+	assert.NoError(t, err)
+}
+
+// TestCheckCompatibility_AnyMatchingVersion verifies that a requirement is
+// met if any capability with the required name matches.
+func TestCheckCompatibility_AnyMatchingVersion(t *testing.T) {
+	t.Parallel()
+	caps := []Capability{
+		{Name: "search", Version: "1.0.0"},
+		{Name: "search", Version: "2.3.0"},
+	}
+	err := CheckCompatibility(caps, CapabilityRequirement{Name: "search", Constraint: "^2"})
+	assert.NoError(t, err)
+}
+
+// TestCheckCompatibility_Unmet verifies that CheckCompatibility reports
+// missing and incompatible capabilities with CodeConflictVersionMismatch
+// and structured details.
+func TestCheckCompatibility_Unmet(t *testing.T) {
+	t.Parallel()
+	caps := []Capability{{Name: "model-execution", Version: "1.4.2"}}
+	err := CheckCompatibility(caps,
+		CapabilityRequirement{Name: "model-execution", Constraint: ">=2.0 <3"},
+		CapabilityRequirement{Name: "code-generation", Constraint: "*"},
+	)
+	require.Error(t, err)
+	ssErr, ok := sserr.AsError(err)
+	require.True(t, ok)
+	assert.Equal(t, sserr.CodeConflictVersionMismatch, ssErr.Code)
+	assert.Contains(t, ssErr.Message, "model-execution: have 1.4.2, want >=2.0 <3")
+	assert.Equal(t, []string{"code-generation"}, ssErr.Details["missing"])
+	assert.Len(t, ssErr.Details["incompatible"], 1)
+}
+
+// TestCheckCompatibility_InvalidRequirement verifies that a malformed
+// requirement constraint is reported as a validation error.
+func TestCheckCompatibility_InvalidRequirement(t *testing.T) {
+	t.Parallel()
+	err := CheckCompatibility(nil, CapabilityRequirement{Name: "search", Constraint: ""})
+	require.Error(t, err)
+	assert.True(t, sserr.IsValidation(err))
+}
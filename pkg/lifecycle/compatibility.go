@@ -0,0 +1,128 @@
+package lifecycle
+
+import (
+	"fmt"
+	"strings"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// CapabilityRequirement declares that an agent depends on a named
+// capability provided by another agent, at a version matching Constraint.
+// Requirements are registered via [BaseAgentBuilder.WithRequirement] and
+// evaluated with [BaseAgent.CheckCompatibility] or [CheckCompatibility]
+// before work is routed between agents.
+//
+// Example:
+//
+//	req := lifecycle.CapabilityRequirement{
+//	    Name:       "model-execution",
+//	    Constraint: "^1.2",
+//	}
+type CapabilityRequirement struct {
+	// Name is the name of the required capability (e.g., "model-execution").
+	// Must not be empty.
+	Name string `json:"name"`
+
+	// Constraint is the version constraint expression the provider's
+	// capability version must satisfy (e.g., "^1.2", ">=2.0 <3"). See
+	// [Constraint] for the syntax. Must not be empty; use "*" to accept
+	// any version.
+	Constraint string `json:"constraint"`
+}
+
+// String returns the requirement in "name constraint" form for logging.
+func (r CapabilityRequirement) String() string {
+	return r.Name + " " + r.Constraint
+}
+
+// validateRequirement checks that a CapabilityRequirement has a non-empty
+// Name and a Constraint that parses, and returns the parsed Constraint.
+// Returns a [*sserr.Error] with code [sserr.CodeValidation] or
+// [sserr.CodeValidationFormat] on failure.
+func validateRequirement(r CapabilityRequirement) (Constraint, error) {
+	if r.Name == "" {
+		return Constraint{}, sserr.New(sserr.CodeValidation,
+			"lifecycle: capability requirement name must not be empty")
+	}
+	cons, err := ParseConstraint(r.Constraint)
+	if err != nil {
+		return Constraint{}, sserr.Wrapf(err, sserr.CodeValidationFormat,
+			"lifecycle: capability requirement %q has an invalid constraint", r.Name)
+	}
+	return cons, nil
+}
+
+// CheckCompatibility verifies that caps satisfies every requirement. A
+// requirement is met if at least one capability with the required name has
+// a version matching the requirement's constraint.
+//
+// Returns nil if all requirements are met. Otherwise returns a
+// [*sserr.Error] with code [sserr.CodeConflictVersionMismatch] listing
+// every unmet requirement. The error details carry two string slices:
+// "missing" (no capability with the required name) and "incompatible"
+// (the capability exists but no version matches). Malformed requirement
+// constraints return [sserr.CodeValidationFormat].
+//
+// Example:
+//
+//	err := lifecycle.CheckCompatibility(peer.Capabilities(),
+//	    lifecycle.CapabilityRequirement{Name: "policy-evaluation", Constraint: ">=2.0 <3"},
+//	)
+//	if sserr.IsConflict(err) {
+//	    // route to a different peer
+//	}
+func CheckCompatibility(caps []Capability, reqs ...CapabilityRequirement) error {
+	var missing, incompatible []string
+
+	for _, req := range reqs {
+		cons, err := validateRequirement(req)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		satisfied := false
+		var versions []string
+		for _, c := range caps {
+			if c.Name != req.Name {
+				continue
+			}
+			found = true
+			versions = append(versions, c.Version)
+			if v, err := c.SemVer(); err == nil && cons.Check(v) {
+				satisfied = true
+				break
+			}
+		}
+
+		switch {
+		case !found:
+			missing = append(missing, req.Name)
+		case !satisfied:
+			incompatible = append(incompatible, fmt.Sprintf("%s: have %s, want %s",
+				req.Name, strings.Join(versions, ", "), cons))
+		}
+	}
+
+	if len(missing) == 0 && len(incompatible) == 0 {
+		return nil
+	}
+
+	problems := make([]string, 0, len(missing)+len(incompatible))
+	for _, name := range missing {
+		problems = append(problems, name+": not provided")
+	}
+	problems = append(problems, incompatible...)
+
+	err := sserr.Newf(sserr.CodeConflictVersionMismatch,
+		"lifecycle: incompatible capabilities: %s", strings.Join(problems, "; "))
+	details := map[string]any{}
+	if len(missing) > 0 {
+		details["missing"] = missing
+	}
+	if len(incompatible) > 0 {
+		details["incompatible"] = incompatible
+	}
+	return err.WithDetails(details)
+}
@@ -0,0 +1,306 @@
+package lifecycle
+
+import (
+	"strings"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// Constraint is a parsed version constraint expression used to check
+// whether a [SemVer] is acceptable. Constraints are used by
+// [Capability.Satisfies] and [CheckCompatibility] to detect version
+// mismatches between agents before work is routed to them.
+//
+// The syntax follows the widely used npm/Cargo conventions:
+//
+//	1.2.3          exactly 1.2.3 (also "=1.2.3")
+//	1.2, 1.2.x     any 1.2 patch release (>=1.2.0 <1.3.0)
+//	*, x           any version
+//	!=1.2.3        anything except 1.2.3
+//	>1.2 >=2.0 <3 <=2.1.0
+//	               comparisons; missing components are zero-filled for
+//	               ">=" and "<", and rounded up for ">" and "<="
+//	~1.4.1         patch-level changes (>=1.4.1 <1.5.0)
+//	~1             minor-level changes (>=1.0.0 <2.0.0)
+//	^1.2           compatible changes (>=1.2.0 <2.0.0); for 0.x versions
+//	               the left-most non-zero component is held fixed
+//	               (^0.2.3 is >=0.2.3 <0.3.0)
+//
+// Comparators separated by whitespace or commas must all match (AND).
+// Groups separated by "||" are alternatives (OR):
+//
+//	">=2.0 <3 || ^4.1"
+//
+// A pre-release version (e.g., "2.0.0-rc.1") only satisfies a group if at
+// least one comparator in that group names a pre-release with the same
+// MAJOR.MINOR.PATCH. This prevents "^1.2" from unexpectedly matching
+// "1.9.0-alpha".
+//
+// Constraint is immutable and safe for concurrent use.
+type Constraint struct {
+	raw    string
+	groups [][]comparator
+}
+
+// comparator is a single primitive version comparison produced by
+// expanding a constraint term.
+type comparator struct {
+	op      string // one of "=", "!=", ">", ">=", "<", "<="
+	version SemVer
+}
+
+// ParseConstraint parses a version constraint expression. See [Constraint]
+// for the supported syntax.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidationFormat] if the
+// expression is empty or malformed.
+//
+// Example:
+//
+//	c, err := lifecycle.ParseConstraint(">=2.0 <3")
+//	if err != nil {
+//	    return err
+//	}
+//	v, _ := lifecycle.ParseSemVer("2.4.1")
+//	c.Check(v) // true
+func ParseConstraint(s string) (Constraint, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return Constraint{}, sserr.New(sserr.CodeValidationFormat,
+			"lifecycle: version constraint must not be empty")
+	}
+
+	var groups [][]comparator
+	for _, group := range strings.Split(raw, "||") {
+		terms := tokenizeConstraintGroup(group)
+		if len(terms) == 0 {
+			return Constraint{}, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: invalid version constraint %q: empty alternative", raw)
+		}
+		var comps []comparator
+		for _, term := range terms {
+			expanded, err := expandTerm(term)
+			if err != nil {
+				return Constraint{}, sserr.Wrapf(err, sserr.CodeValidationFormat,
+					"lifecycle: invalid version constraint %q", raw)
+			}
+			comps = append(comps, expanded...)
+		}
+		groups = append(groups, comps)
+	}
+
+	return Constraint{raw: raw, groups: groups}, nil
+}
+
+// Check reports whether v satisfies the constraint.
+func (c Constraint) Check(v SemVer) bool {
+	for _, group := range c.groups {
+		if groupMatches(group, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the constraint expression as originally supplied (with
+// surrounding whitespace removed).
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// groupMatches reports whether v satisfies every comparator in the group,
+// applying the pre-release opt-in rule described on [Constraint].
+func groupMatches(group []comparator, v SemVer) bool {
+	for _, cmp := range group {
+		if !cmp.matches(v) {
+			return false
+		}
+	}
+	if v.Prerelease == "" {
+		return true
+	}
+	for _, cmp := range group {
+		if cmp.version.Prerelease != "" && cmp.version.sameCore(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether v satisfies this single comparison.
+func (c comparator) matches(v SemVer) bool {
+	r := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return r == 0
+	case "!=":
+		return r != 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	default:
+		return false
+	}
+}
+
+// tokenizeConstraintGroup splits an AND group into terms. Commas are
+// treated as whitespace, and a bare operator token (e.g., ">=" in
+// ">= 2.0") is joined with the version that follows it.
+func tokenizeConstraintGroup(group string) []string {
+	fields := strings.Fields(strings.ReplaceAll(group, ",", " "))
+	var terms []string
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if isConstraintOperator(f) && i+1 < len(fields) {
+			f += fields[i+1]
+			i++
+		}
+		terms = append(terms, f)
+	}
+	return terms
+}
+
+// constraintOperators lists the supported term prefixes, longest first so
+// that prefix matching picks ">=" before ">".
+var constraintOperators = []string{"~>", ">=", "<=", "!=", "==", "^", "~", ">", "<", "="}
+
+// isConstraintOperator reports whether s consists solely of an operator.
+func isConstraintOperator(s string) bool {
+	for _, op := range constraintOperators {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOperator separates the leading operator (if any) from a term.
+func splitOperator(term string) (op, rest string) {
+	for _, candidate := range constraintOperators {
+		if strings.HasPrefix(term, candidate) {
+			return candidate, term[len(candidate):]
+		}
+	}
+	return "", term
+}
+
+// parsePartial parses a possibly partial or wildcard version used inside a
+// constraint term. It returns the version (with missing components set to
+// zero) and the number of concrete numeric components (0-3).
+func parsePartial(s string) (SemVer, int, error) {
+	parts := strings.SplitN(s, ".", 3)
+	concrete := len(parts)
+	for i, p := range parts {
+		if p == "*" || p == "x" || p == "X" {
+			concrete = i
+			// Everything after a wildcard must also be a wildcard.
+			for _, rest := range parts[i+1:] {
+				if rest != "*" && rest != "x" && rest != "X" {
+					return SemVer{}, 0, sserr.Newf(sserr.CodeValidationFormat,
+						"lifecycle: invalid wildcard version %q", s)
+				}
+			}
+			break
+		}
+	}
+	if concrete == 0 {
+		return SemVer{}, 0, nil
+	}
+	v, n, err := parseVersionParts(strings.Join(parts[:concrete], "."))
+	if err != nil {
+		return SemVer{}, 0, err
+	}
+	return v, n, nil
+}
+
+// bump returns the smallest version above every version matching the
+// partial version v with n concrete components. For n == 3 it increments
+// the patch component.
+func bump(v SemVer, n int) SemVer {
+	switch n {
+	case 1:
+		return SemVer{Major: v.Major + 1}
+	case 2:
+		return SemVer{Major: v.Major, Minor: v.Minor + 1}
+	default:
+		return SemVer{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+}
+
+// expandTerm converts a single constraint term into primitive comparators.
+func expandTerm(term string) ([]comparator, error) {
+	op, rest := splitOperator(term)
+	if rest == "" {
+		return nil, sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: constraint term %q is missing a version", term)
+	}
+	v, n, err := parsePartial(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	// A wildcard-only term ("*", ">=*", "~x") matches everything; terms
+	// that would match nothing are rejected as malformed.
+	if n == 0 {
+		switch op {
+		case "", "=", "==", ">=", "<=", "~", "~>", "^":
+			return []comparator{{op: ">=", version: SemVer{}}}, nil
+		default:
+			return nil, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: constraint term %q cannot be used with a wildcard", term)
+		}
+	}
+
+	switch op {
+	case "", "=", "==":
+		if n == 3 {
+			return []comparator{{op: "=", version: v}}, nil
+		}
+		return []comparator{{op: ">=", version: v}, {op: "<", version: bump(v, n)}}, nil
+	case "!=":
+		if n != 3 {
+			return nil, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: constraint term %q requires a full MAJOR.MINOR.PATCH version", term)
+		}
+		return []comparator{{op: "!=", version: v}}, nil
+	case ">":
+		if n == 3 {
+			return []comparator{{op: ">", version: v}}, nil
+		}
+		return []comparator{{op: ">=", version: bump(v, n)}}, nil
+	case ">=":
+		return []comparator{{op: ">=", version: v}}, nil
+	case "<":
+		return []comparator{{op: "<", version: v}}, nil
+	case "<=":
+		if n == 3 {
+			return []comparator{{op: "<=", version: v}}, nil
+		}
+		return []comparator{{op: "<", version: bump(v, n)}}, nil
+	case "~", "~>":
+		upper := bump(v, 2)
+		if n == 1 {
+			upper = bump(v, 1)
+		}
+		return []comparator{{op: ">=", version: v}, {op: "<", version: upper}}, nil
+	case "^":
+		var upper SemVer
+		switch {
+		case v.Major > 0 || n == 1:
+			upper = bump(v, 1)
+		case v.Minor > 0 || n == 2:
+			upper = bump(v, 2)
+		default:
+			upper = bump(v, 3)
+		}
+		return []comparator{{op: ">=", version: v}, {op: "<", version: upper}}, nil
+	default:
+		return nil, sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: unsupported constraint operator in %q", term)
+	}
+}
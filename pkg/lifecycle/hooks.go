@@ -0,0 +1,271 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// NamedHook is a lifecycle [Hook] with a name, an optional timeout, and,
+// for start hooks, an optional compensating hook. Named hooks are
+// registered with [BaseAgentBuilder.WithStartHook] and its siblings and
+// run in registration order, after any hook set with the corresponding
+// WithOn* method.
+//
+// Example:
+//
+//	agent, err := lifecycle.NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+//	    WithStartHook(lifecycle.NamedHook{
+//	        Name:     "database",
+//	        Run:      db.Connect,
+//	        Timeout:  10 * time.Second,
+//	        Rollback: db.Close,
+//	    }).
+//	    WithStartHook(lifecycle.NamedHook{Name: "queue", Run: queue.Subscribe}).
+//	    Build()
+type NamedHook struct {
+	// Name identifies the hook in errors, logs, span events, and
+	// transition reasons. Required and unique within a phase.
+	Name string
+
+	// Run is the hook itself. Required.
+	Run Hook
+
+	// Timeout bounds the hook's execution. Zero means the hook is bounded
+	// only by the caller's context. A hook that ignores its context is
+	// abandoned when the timeout elapses and keeps running in the
+	// background, so hooks should honor cancellation.
+	Timeout time.Duration
+
+	// Rollback undoes the effects of a successful start hook. If a later
+	// start hook fails, the Rollback hooks of every start hook that
+	// already succeeded run in reverse order before the agent transitions
+	// to [StateFailed]. Rollback hooks share the hook's Timeout and are
+	// not canceled by the caller's context. Only valid for start hooks.
+	Rollback Hook
+}
+
+// hookPhase names the lifecycle operation a hook belongs to.
+type hookPhase string
+
+const (
+	hookPhaseStart  hookPhase = "start"
+	hookPhaseStop   hookPhase = "stop"
+	hookPhasePause  hookPhase = "pause"
+	hookPhaseResume hookPhase = "resume"
+)
+
+// Hook outcomes recorded on span events.
+const (
+	hookOutcomeOK      = "ok"
+	hookOutcomeFailed  = "failed"
+	hookOutcomeTimeout = "timeout"
+)
+
+// errHookTimeout is returned by callHook when a hook exceeds its own
+// timeout (as opposed to the caller's context being done).
+var errHookTimeout = errors.New("hook timed out")
+
+// label describes a hook for errors and transition reasons. The unnamed
+// hook set via a WithOn* method keeps the historical "start hook" form.
+func (p hookPhase) label(name string) string {
+	if name == "" {
+		return string(p) + " hook"
+	}
+	return fmt.Sprintf("%s hook %q", p, name)
+}
+
+// eventName returns the hook name recorded on span events and logs. The
+// unnamed hook is reported as, e.g., "OnStart".
+func (p hookPhase) eventName(name string) string {
+	if name == "" {
+		return "On" + strings.ToUpper(string(p[:1])) + string(p[1:])
+	}
+	return name
+}
+
+// buildHookChain combines the hook set via a WithOn* method with the
+// named hooks for one phase, validating the named hooks. Returns nil if
+// the phase has no hooks.
+func buildHookChain(phase hookPhase, legacy Hook, named []NamedHook) ([]NamedHook, error) {
+	var chain []NamedHook
+	if legacy != nil {
+		chain = append(chain, NamedHook{Run: legacy})
+	}
+	seen := make(map[string]bool, len(named))
+	for _, h := range named {
+		if h.Name == "" {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"lifecycle: %s hook name must not be empty", phase)
+		}
+		if h.Run == nil {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"lifecycle: %s requires a function", phase.label(h.Name))
+		}
+		if h.Timeout < 0 {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"lifecycle: %s timeout must not be negative", phase.label(h.Name))
+		}
+		if h.Rollback != nil && phase != hookPhaseStart {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"lifecycle: %s cannot have a rollback, only start hooks can", phase.label(h.Name))
+		}
+		if seen[h.Name] {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"lifecycle: duplicate %s hook name %q", phase, h.Name)
+		}
+		seen[h.Name] = true
+		chain = append(chain, h)
+	}
+	return chain, nil
+}
+
+// runHooks executes a phase's hooks in order, recording a span event for
+// each. Start, pause, and resume hooks stop at the first failure; stop
+// hooks all run so that one failed cleanup does not prevent the others.
+//
+// On failure, runHooks rolls back the start hooks that already succeeded,
+// transitions the agent to [StateFailed], and returns the error to hand
+// back to the caller: wrapped with [sserr.CodeTimeout] if the first
+// failing hook timed out, or [sserr.CodeInternal] otherwise.
+func (a *BaseAgent) runHooks(ctx context.Context, span trace.Span, phase hookPhase, hooks []NamedHook) error {
+	var (
+		failed    *NamedHook
+		first     error
+		causes    []error
+		succeeded int
+	)
+	for i := range hooks {
+		h := &hooks[i]
+		err := a.callHookTraced(ctx, span, "lifecycle.hook", phase, h.Name, h.Run, h.Timeout)
+		if err == nil {
+			succeeded++
+			continue
+		}
+		a.logger.ErrorContext(ctx, "lifecycle: "+string(phase)+" hook failed",
+			"agent_id", a.id,
+			"hook", phase.eventName(h.Name),
+			"error", err,
+		)
+		causes = append(causes, err)
+		if failed == nil {
+			failed, first = h, err
+		}
+		if phase != hookPhaseStop {
+			break
+		}
+	}
+	if failed == nil {
+		return nil
+	}
+
+	if phase == hookPhaseStart {
+		a.rollbackHooks(ctx, span, hooks[:succeeded])
+	}
+
+	label := phase.label(failed.Name)
+	var (
+		reason  = label + " failed"
+		wrapped error
+	)
+	if errors.Is(first, errHookTimeout) {
+		reason = label + " timed out"
+		wrapped = sserr.Wrapf(first, sserr.CodeTimeout,
+			"lifecycle: %s timed out after %s", label, failed.Timeout)
+	} else {
+		wrapped = sserr.Wrap(first, sserr.CodeInternal, "lifecycle: "+reason)
+	}
+	_ = a.transition(ctx, StateFailed, reason, errors.Join(causes...))
+	failSpan(span, wrapped)
+	return wrapped
+}
+
+// rollbackHooks runs the Rollback hooks of the given start hooks in
+// reverse order. Rollbacks are not canceled by ctx; their failures are
+// logged and recorded on the span but do not stop the remaining
+// rollbacks.
+func (a *BaseAgent) rollbackHooks(ctx context.Context, span trace.Span, succeeded []NamedHook) {
+	rctx := context.WithoutCancel(ctx)
+	for i := len(succeeded) - 1; i >= 0; i-- {
+		h := succeeded[i]
+		if h.Rollback == nil {
+			continue
+		}
+		if err := a.callHookTraced(rctx, span, "lifecycle.hook.rollback", hookPhaseStart, h.Name, h.Rollback, h.Timeout); err != nil {
+			a.logger.ErrorContext(ctx, "lifecycle: start hook rollback failed",
+				"agent_id", a.id,
+				"hook", hookPhaseStart.eventName(h.Name),
+				"error", err,
+			)
+		}
+	}
+}
+
+// callHookTraced runs fn via callHook and records the outcome as a span
+// event.
+func (a *BaseAgent) callHookTraced(ctx context.Context, span trace.Span, event string, phase hookPhase, name string, fn Hook, timeout time.Duration) error {
+	started := time.Now()
+	err := callHook(ctx, fn, timeout)
+	outcome := hookOutcomeOK
+	switch {
+	case errors.Is(err, errHookTimeout):
+		outcome = hookOutcomeTimeout
+	case err != nil:
+		outcome = hookOutcomeFailed
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("hook.phase", string(phase)),
+		attribute.String("hook.name", phase.eventName(name)),
+		attribute.String("hook.outcome", outcome),
+		attribute.Int64("hook.duration_ms", time.Since(started).Milliseconds()),
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("hook.error", err.Error()))
+	}
+	span.AddEvent(event, trace.WithAttributes(attrs...))
+	return err
+}
+
+// callHook runs fn, bounded by timeout if it is positive. If the timeout
+// elapses, callHook returns an error wrapping errHookTimeout without
+// waiting for fn to return; if ctx is done first, it returns ctx's error.
+// A panic in fn is returned as an error.
+func callHook(ctx context.Context, fn Hook, timeout time.Duration) error {
+	if timeout <= 0 {
+		return runHook(ctx, fn)
+	}
+	hctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- runHook(hctx, fn) }()
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() == nil && errors.Is(hctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %w", errHookTimeout, err)
+		}
+		return err
+	case <-hctx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %w", errHookTimeout, hctx.Err())
+	}
+}
+
+// runHook calls fn, converting a panic into a [sserr.CodeInternal] error.
+func runHook(ctx context.Context, fn Hook) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = sserr.Newf(sserr.CodeInternal, "lifecycle: hook panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
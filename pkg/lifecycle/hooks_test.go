@@ -0,0 +1,304 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// callLog records hook invocations in order. It is safe for concurrent
+// use because timed hooks run on their own goroutine.
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+// hook returns a hook that records name and returns err.
+func (l *callLog) hook(name string, err error) Hook {
+	return func(context.Context) error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.calls = append(l.calls, name)
+		return err
+	}
+}
+
+// get returns a copy of the recorded calls.
+func (l *callLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.calls...)
+}
+
+// ===========================================================================
+// Hook Chain Tests
+// ===========================================================================
+
+// TestHooks_RunInOrder verifies that the WithOn* hook runs first, followed
+// by the named hooks in registration order, for every phase.
+func TestHooks_RunInOrder(t *testing.T) {
+	t.Parallel()
+	var log callLog
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithStartHook(NamedHook{Name: "db", Run: log.hook("start:db", nil)}).
+		WithOnStart(log.hook("start:legacy", nil)).
+		WithStartHook(NamedHook{Name: "queue", Run: log.hook("start:queue", nil)}).
+		WithPauseHook(NamedHook{Name: "workers", Run: log.hook("pause:workers", nil)}).
+		WithResumeHook(NamedHook{Name: "workers", Run: log.hook("resume:workers", nil)}).
+		WithStopHook(NamedHook{Name: "queue", Run: log.hook("stop:queue", nil)}).
+		WithStopHook(NamedHook{Name: "db", Run: log.hook("stop:db", nil)}).
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, agent.Start(ctx))
+	require.NoError(t, agent.Pause(ctx))
+	require.NoError(t, agent.Resume(ctx))
+	require.NoError(t, agent.Stop(ctx))
+
+	assert.Equal(t, []string{
+		"start:legacy", "start:db", "start:queue",
+		"pause:workers", "resume:workers",
+		"stop:queue", "stop:db",
+	}, log.get())
+}
+
+// TestHooks_StartFailureRollsBack verifies that a failed start hook skips
+// the remaining hooks, runs the rollbacks of the hooks that succeeded in
+// reverse order, and fails the agent with a reason naming the hook.
+func TestHooks_StartFailureRollsBack(t *testing.T) {
+	t.Parallel()
+	var log callLog
+	store := NewMemoryStateStore()
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithStateStore(store).
+		WithStartHook(NamedHook{Name: "a", Run: log.hook("start:a", nil), Rollback: log.hook("rollback:a", nil)}).
+		WithStartHook(NamedHook{Name: "b", Run: log.hook("start:b", nil)}).
+		WithStartHook(NamedHook{Name: "c", Run: log.hook("start:c", nil), Rollback: log.hook("rollback:c", errors.New("ignored"))}).
+		WithStartHook(NamedHook{Name: "d", Run: log.hook("start:d", errors.New("boom")), Rollback: log.hook("rollback:d", nil)}).
+		WithStartHook(NamedHook{Name: "e", Run: log.hook("start:e", nil)}).
+		Build()
+	require.NoError(t, err)
+
+	err = agent.Start(context.Background())
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternal))
+	assert.Contains(t, err.Error(), `start hook "d" failed`)
+	assert.Equal(t, StateFailed, agent.State())
+
+	assert.Equal(t, []string{
+		"start:a", "start:b", "start:c", "start:d",
+		"rollback:c", "rollback:a",
+	}, log.get(), "only succeeded hooks are rolled back, in reverse order, despite rollback errors")
+
+	last, ok, err := store.Last(context.Background(), "agent-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, `start hook "d" failed`, last.Reason)
+	assert.Equal(t, "boom", last.Error)
+}
+
+// TestHooks_Timeout verifies that a hook exceeding its timeout fails the
+// phase with CodeTimeout even if it ignores its context.
+func TestHooks_Timeout(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	defer close(release)
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithStartHook(NamedHook{
+			Name:    "stuck",
+			Timeout: 10 * time.Millisecond,
+			Run: func(context.Context) error {
+				<-release
+				return nil
+			},
+		}).
+		Build()
+	require.NoError(t, err)
+
+	err = agent.Start(context.Background())
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeTimeout), "error code = %v, want CodeTimeout", sserr.GetCode(err))
+	assert.Contains(t, err.Error(), `start hook "stuck" timed out after 10ms`)
+	assert.Equal(t, StateFailed, agent.State())
+}
+
+// TestHooks_TimeoutCancelsContext verifies that a hook honoring its
+// context observes the deadline.
+func TestHooks_TimeoutCancelsContext(t *testing.T) {
+	t.Parallel()
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithPauseHook(NamedHook{
+			Name:    "drain",
+			Timeout: 10 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}).
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, agent.Start(ctx))
+	err = agent.Pause(ctx)
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeTimeout))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestHooks_Panic verifies that a panicking hook, with or without a
+// timeout, fails its phase instead of crashing the process.
+func TestHooks_Panic(t *testing.T) {
+	t.Parallel()
+	var log callLog
+	panics := func(context.Context) error { panic("kaboom") }
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithStartHook(NamedHook{Name: "a", Run: log.hook("start:a", nil), Rollback: log.hook("rollback:a", nil)}).
+		WithStartHook(NamedHook{Name: "b", Run: panics, Timeout: time.Second}).
+		Build()
+	require.NoError(t, err)
+
+	err = agent.Start(context.Background())
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternal), "error code = %v, want CodeInternal", sserr.GetCode(err))
+	assert.Contains(t, err.Error(), "lifecycle: hook panicked: kaboom")
+	assert.Equal(t, []string{"start:a", "rollback:a"}, log.get())
+	assert.Equal(t, StateFailed, agent.State())
+
+	agent, err = NewBaseAgentBuilder("agent-2", "worker", "1.0.0").
+		WithStopHook(NamedHook{Name: "c", Run: panics}).
+		Build()
+	require.NoError(t, err)
+	require.NoError(t, agent.Start(context.Background()))
+	err = agent.Stop(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "lifecycle: hook panicked: kaboom")
+	assert.Equal(t, StateFailed, agent.State())
+}
+
+// TestHooks_StopRunsAll verifies that every stop hook runs even if an
+// earlier one fails, and that the first failure is reported.
+func TestHooks_StopRunsAll(t *testing.T) {
+	t.Parallel()
+	var log callLog
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithStopHook(NamedHook{Name: "a", Run: log.hook("stop:a", errors.New("first"))}).
+		WithStopHook(NamedHook{Name: "b", Run: log.hook("stop:b", errors.New("second"))}).
+		WithStopHook(NamedHook{Name: "c", Run: log.hook("stop:c", nil)}).
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, agent.Start(ctx))
+	err = agent.Stop(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `stop hook "a" failed`)
+	assert.Equal(t, []string{"stop:a", "stop:b", "stop:c"}, log.get())
+	assert.Equal(t, StateFailed, agent.State())
+}
+
+// TestHooks_LegacyErrorUnchanged verifies that a failing WithOn* hook
+// keeps its original error message and transition reason.
+func TestHooks_LegacyErrorUnchanged(t *testing.T) {
+	t.Parallel()
+	var reasons []string
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithOnStart(func(context.Context) error { return errors.New("boom") }).
+		OnTransition(func(tr StateTransition) { reasons = append(reasons, tr.Reason) }).
+		Build()
+	require.NoError(t, err)
+
+	err = agent.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "lifecycle: start hook failed")
+	assert.Equal(t, []string{"start requested", "start hook failed"}, reasons)
+}
+
+// TestHooks_SpanEvents verifies that each hook and rollback is recorded as
+// an event on the operation's span.
+func TestHooks_SpanEvents(t *testing.T) {
+	t.Parallel()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithOnStart(func(context.Context) error { return nil }).
+		WithStartHook(NamedHook{
+			Name:     "db",
+			Run:      func(context.Context) error { return nil },
+			Rollback: func(context.Context) error { return nil },
+		}).
+		WithStartHook(NamedHook{Name: "queue", Run: func(context.Context) error { return errors.New("boom") }}).
+		Build()
+	require.NoError(t, err)
+	agent.tracer = tp.Tracer(tracerName)
+
+	require.Error(t, agent.Start(context.Background()))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	type event struct{ name, hook, outcome string }
+	var got []event
+	for _, ev := range spans[0].Events {
+		if ev.Name == "exception" {
+			continue
+		}
+		attrs := attribute.NewSet(ev.Attributes...)
+		hook, _ := attrs.Value("hook.name")
+		outcome, _ := attrs.Value("hook.outcome")
+		phase, _ := attrs.Value("hook.phase")
+		assert.Equal(t, "start", phase.AsString())
+		got = append(got, event{ev.Name, hook.AsString(), outcome.AsString()})
+	}
+	assert.Equal(t, []event{
+		{"lifecycle.hook", "OnStart", "ok"},
+		{"lifecycle.hook", "db", "ok"},
+		{"lifecycle.hook", "queue", "failed"},
+		{"lifecycle.hook.rollback", "db", "ok"},
+	}, got)
+}
+
+// TestBuild_InvalidHooks verifies that Build rejects invalid named hooks.
+func TestBuild_InvalidHooks(t *testing.T) {
+	t.Parallel()
+	noop := func(context.Context) error { return nil }
+	tests := []struct {
+		name    string
+		builder *BaseAgentBuilder
+	}{
+		{"empty name", NewBaseAgentBuilder("a", "w", "1.0.0").WithStartHook(NamedHook{Run: noop})},
+		{"nil run", NewBaseAgentBuilder("a", "w", "1.0.0").WithStopHook(NamedHook{Name: "h"})},
+		{"negative timeout", NewBaseAgentBuilder("a", "w", "1.0.0").WithPauseHook(NamedHook{Name: "h", Run: noop, Timeout: -time.Second})},
+		{"rollback outside start", NewBaseAgentBuilder("a", "w", "1.0.0").WithStopHook(NamedHook{Name: "h", Run: noop, Rollback: noop})},
+		{"duplicate", NewBaseAgentBuilder("a", "w", "1.0.0").
+			WithResumeHook(NamedHook{Name: "h", Run: noop}).
+			WithResumeHook(NamedHook{Name: "h", Run: noop})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := tt.builder.Build()
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, sserr.CodeValidation), "error code = %v, want CodeValidation", sserr.GetCode(err))
+		})
+	}
+
+	// The same name may be reused across phases.
+	_, err := NewBaseAgentBuilder("a", "w", "1.0.0").
+		WithStartHook(NamedHook{Name: "h", Run: noop}).
+		WithStopHook(NamedHook{Name: "h", Run: noop}).
+		Build()
+	assert.NoError(t, err)
+}
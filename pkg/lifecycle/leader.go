@@ -0,0 +1,266 @@
+package lifecycle
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// DefaultLeaderRenewInterval is the interval at which a [LeaderElector]
+// acquires or renews its lock when no interval is supplied.
+const DefaultLeaderRenewInterval = 5 * time.Second
+
+// LeaderLock is a distributed lock used by [LeaderElector] to ensure that
+// at most one replica of an agent holds leadership at a time.
+//
+// The SDK provides two implementations:
+//   - [PostgresLeaderLock], backed by a transaction-scoped advisory lock
+//   - [RedisLeaderLock], backed by a lease key with a fencing counter
+//
+// Implementations are used by a single elector goroutine and need not be
+// safe for concurrent use.
+type LeaderLock interface {
+	// Acquire attempts to acquire the lock, or to renew it if this
+	// instance already holds it. It returns true and the fencing token of
+	// the current term if the lock is held.
+	//
+	// Fencing tokens increase strictly each time the lock changes hands.
+	// Pass the token to downstream systems with every write so they can
+	// reject writes from a leader that has been superseded but has not
+	// yet noticed.
+	Acquire(ctx context.Context) (token int64, held bool, err error)
+
+	// Release gives up the lock if held. It is safe to call when the lock
+	// is not held.
+	Release(ctx context.Context) error
+}
+
+// LeadershipHandler is called by a [LeaderElector] when this instance
+// gains or loses leadership. token is the fencing token of the new term
+// when leader is true, and zero otherwise.
+//
+// Handlers run on the elector's goroutine; the next renewal is delayed
+// until they return.
+type LeadershipHandler func(ctx context.Context, leader bool, token int64)
+
+// LeaderElector periodically acquires or renews a [LeaderLock] and
+// notifies registered handlers when leadership changes. Attach one to an
+// agent with [BaseAgentBuilder.WithLeaderElection] to pause the agent
+// while another replica leads, or drive it directly with
+// [LeaderElector.Run].
+//
+// A failed acquire or renew is treated as loss of leadership: when in
+// doubt, the elector assumes another replica may be leading.
+//
+// A LeaderElector is safe for concurrent use, but only one Run may be
+// active at a time.
+type LeaderElector struct {
+	lock     LeaderLock
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	running  bool
+	known    bool
+	leader   bool
+	token    int64
+	handlers []LeadershipHandler
+	attached bool
+}
+
+// NewLeaderElector returns an elector that acquires or renews lock every
+// interval. An interval of zero or less defaults to
+// [DefaultLeaderRenewInterval]. For lease-based locks the interval must be
+// well below the lease TTL; one third of the TTL is a good choice.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidation] if lock is
+// nil.
+//
+// Example:
+//
+//	lock, _ := lifecycle.NewRedisLeaderLock(rdb, "compactor", podName, 15*time.Second)
+//	elector, err := lifecycle.NewLeaderElector(lock, 5*time.Second)
+func NewLeaderElector(lock LeaderLock, interval time.Duration) (*LeaderElector, error) {
+	if lock == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"lifecycle: leader elector requires a lock")
+	}
+	if interval <= 0 {
+		interval = DefaultLeaderRenewInterval
+	}
+	return &LeaderElector{
+		lock:     lock,
+		interval: interval,
+		logger:   slog.Default(),
+	}, nil
+}
+
+// WithLogger sets the logger used for election events. If not called,
+// [slog.Default] is used. Must be called before [LeaderElector.Run].
+func (e *LeaderElector) WithLogger(logger *slog.Logger) *LeaderElector {
+	if logger != nil {
+		e.logger = logger
+	}
+	return e
+}
+
+// OnChange registers a handler called when leadership is gained or lost.
+// Handlers are called in registration order. The first election round
+// always notifies handlers, even if this instance does not become leader,
+// so that they can act on the initial outcome.
+func (e *LeaderElector) OnChange(handler LeadershipHandler) *LeaderElector {
+	e.mu.Lock()
+	e.handlers = append(e.handlers, handler)
+	e.mu.Unlock()
+	return e
+}
+
+// attach registers handler on behalf of the agent the elector is attached
+// to. Reports false, registering nothing, if the elector is already
+// attached to an agent.
+func (e *LeaderElector) attach(handler LeadershipHandler) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.attached {
+		return false
+	}
+	e.attached = true
+	e.handlers = append(e.handlers, handler)
+	return true
+}
+
+// IsLeader reports whether this instance currently holds leadership.
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// FencingToken returns the fencing token of the current term. The boolean
+// is false if this instance is not the leader.
+func (e *LeaderElector) FencingToken() (int64, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.token, e.leader
+}
+
+// Run participates in the election until ctx is canceled, then releases
+// the lock and notifies handlers of the loss of leadership. It returns nil
+// when ctx is canceled, or a [*sserr.Error] with code
+// [sserr.CodeConflict] if Run is already active.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	defer e.end()
+	e.step(ctx)
+	e.loop(ctx)
+	return nil
+}
+
+// begin marks the elector as running.
+func (e *LeaderElector) begin() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running {
+		return sserr.New(sserr.CodeConflict,
+			"lifecycle: leader election is already running")
+	}
+	e.running = true
+	e.known = false
+	return nil
+}
+
+// end marks the elector as stopped.
+func (e *LeaderElector) end() {
+	e.mu.Lock()
+	e.running = false
+	e.mu.Unlock()
+}
+
+// loop renews leadership every interval until ctx is canceled, then
+// resigns.
+func (e *LeaderElector) loop(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.resign(ctx)
+			return
+		case <-ticker.C:
+			e.step(ctx)
+		}
+	}
+}
+
+// step performs a single acquire-or-renew round and notifies handlers if
+// the outcome changed.
+func (e *LeaderElector) step(ctx context.Context) {
+	actx, cancel := context.WithTimeout(ctx, e.interval)
+	token, held, err := e.lock.Acquire(actx)
+	cancel()
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		e.logger.WarnContext(ctx, "lifecycle: leader lock acquire failed",
+			"error", err,
+		)
+		held, token = false, 0
+	}
+	e.update(ctx, held, token)
+}
+
+// resign releases the lock and notifies handlers if leadership is lost.
+// It ignores the cancellation of ctx so the release can complete.
+func (e *LeaderElector) resign(ctx context.Context) {
+	rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.interval)
+	defer cancel()
+	if err := e.lock.Release(rctx); err != nil {
+		e.logger.WarnContext(rctx, "lifecycle: leader lock release failed",
+			"error", err,
+		)
+	}
+	e.update(rctx, false, 0)
+}
+
+// update records the outcome of an election round and calls handlers if
+// it differs from the previous outcome. A change of fencing token while
+// leading is reported as a new term.
+func (e *LeaderElector) update(ctx context.Context, leader bool, token int64) {
+	e.mu.Lock()
+	if !leader {
+		token = 0
+	}
+	if e.known && e.leader == leader && e.token == token {
+		e.mu.Unlock()
+		return
+	}
+	e.known = true
+	e.leader = leader
+	e.token = token
+	handlers := make([]LeadershipHandler, len(e.handlers))
+	copy(handlers, e.handlers)
+	e.mu.Unlock()
+
+	if leader {
+		e.logger.InfoContext(ctx, "lifecycle: acquired leadership", "fencing_token", token)
+	} else {
+		e.logger.InfoContext(ctx, "lifecycle: not the leader")
+	}
+
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					e.logger.Error("lifecycle: leadership handler panicked", "panic", r)
+				}
+			}()
+			h(ctx, leader, token)
+		}()
+	}
+}
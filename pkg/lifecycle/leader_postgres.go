@@ -0,0 +1,123 @@
+package lifecycle
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/postgres"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// PostgresLeaderLock is a [LeaderLock] backed by a PostgreSQL advisory
+// lock. The lock is taken with pg_try_advisory_xact_lock inside a
+// transaction that stays open for as long as leadership is held, which
+// pins one pooled connection. If that connection dies, PostgreSQL ends the
+// transaction and releases the lock automatically, so a crashed leader
+// never blocks its successors.
+//
+// Because the holding transaction is idle between renewals, a server-side
+// idle_in_transaction_session_timeout would end it and silently drop the
+// lock. The lock therefore disables that timeout for its own transaction
+// with SET LOCAL; a connection pooler in transaction mode, or any proxy
+// that closes idle transactions, must not sit between the lock and the
+// server.
+//
+// The fencing token is the ID of the holding transaction
+// (txid_current()), which PostgreSQL assigns in strictly increasing order.
+type PostgresLeaderLock struct {
+	client *postgres.Client
+	name   string
+	key    int64
+	tx     pgx.Tx
+	token  int64
+}
+
+// Compile-time interface compliance check.
+var _ LeaderLock = (*PostgresLeaderLock)(nil)
+
+// NewPostgresLeaderLock returns a lock named name. All replicas that
+// compete for the same role must use the same name; the advisory lock key
+// is derived from it by hashing.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidation] if client is
+// nil or name is empty.
+func NewPostgresLeaderLock(client *postgres.Client, name string) (*PostgresLeaderLock, error) {
+	if client == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"lifecycle: postgres leader lock requires a client")
+	}
+	if name == "" {
+		return nil, sserr.New(sserr.CodeValidation,
+			"lifecycle: leader lock name must not be empty")
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return &PostgresLeaderLock{client: client, name: name, key: int64(h.Sum64())}, nil
+}
+
+// Acquire implements [LeaderLock]. When the lock is already held it
+// verifies that the holding connection is still alive; if it is not, the
+// lock is reported as lost.
+func (l *PostgresLeaderLock) Acquire(ctx context.Context) (int64, bool, error) {
+	if l.tx != nil {
+		if _, err := l.tx.Exec(ctx, "SELECT 1"); err != nil {
+			l.drop(ctx)
+			return 0, false, sserr.Wrapf(err, sserr.CodeInternalDatabase,
+				"lifecycle: lost leader lock %q", l.name)
+		}
+		return l.token, true, nil
+	}
+
+	tx, err := l.client.Begin(ctx)
+	if err != nil {
+		return 0, false, sserr.Wrapf(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to acquire leader lock %q", l.name)
+	}
+
+	var locked bool
+	var token int64
+	err = tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1), txid_current()", l.key).
+		Scan(&locked, &token)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return 0, false, sserr.Wrapf(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to acquire leader lock %q", l.name)
+	}
+	if !locked {
+		_ = tx.Rollback(ctx)
+		return 0, false, nil
+	}
+	// Keep the server from ending the transaction, and with it the lock,
+	// while it idles between renewals.
+	if _, err := tx.Exec(ctx, "SET LOCAL idle_in_transaction_session_timeout = 0"); err != nil {
+		_ = tx.Rollback(ctx)
+		return 0, false, sserr.Wrapf(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to acquire leader lock %q", l.name)
+	}
+
+	l.tx = tx
+	l.token = token
+	return token, true, nil
+}
+
+// Release implements [LeaderLock] by ending the holding transaction.
+func (l *PostgresLeaderLock) Release(ctx context.Context) error {
+	if l.tx == nil {
+		return nil
+	}
+	tx := l.tx
+	l.tx, l.token = nil, 0
+	if err := tx.Rollback(ctx); err != nil {
+		return sserr.Wrapf(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to release leader lock %q", l.name)
+	}
+	return nil
+}
+
+// drop discards the holding transaction after a failure.
+func (l *PostgresLeaderLock) drop(ctx context.Context) {
+	_ = l.tx.Rollback(ctx)
+	l.tx, l.token = nil, 0
+}
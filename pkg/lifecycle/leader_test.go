@@ -0,0 +1,499 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/StricklySoft/stricklysoft-core/internal/testutil/fakes"
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/postgres"
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// leaseTable is an in-process stand-in for a shared lock server, used to
+// simulate several replicas competing for leadership.
+type leaseTable struct {
+	mu     sync.Mutex
+	holder string
+	fence  int64
+	fail   bool
+}
+
+// memoryLeaderLock is a LeaderLock over a shared leaseTable.
+type memoryLeaderLock struct {
+	table *leaseTable
+	owner string
+}
+
+func (l *memoryLeaderLock) Acquire(context.Context) (int64, bool, error) {
+	l.table.mu.Lock()
+	defer l.table.mu.Unlock()
+	if l.table.fail {
+		return 0, false, errors.New("lock server unavailable")
+	}
+	switch l.table.holder {
+	case l.owner:
+		return l.table.fence, true, nil
+	case "":
+		l.table.holder = l.owner
+		l.table.fence++
+		return l.table.fence, true, nil
+	default:
+		return 0, false, nil
+	}
+}
+
+func (l *memoryLeaderLock) Release(context.Context) error {
+	l.table.mu.Lock()
+	defer l.table.mu.Unlock()
+	if l.table.holder == l.owner {
+		l.table.holder = ""
+	}
+	return nil
+}
+
+// leadershipEvent records a single LeadershipHandler call.
+type leadershipEvent struct {
+	leader bool
+	token  int64
+}
+
+// eventRecorder collects leadership events safely across goroutines.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []leadershipEvent
+}
+
+func (r *eventRecorder) handle(_ context.Context, leader bool, token int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, leadershipEvent{leader, token})
+}
+
+func (r *eventRecorder) snapshot() []leadershipEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]leadershipEvent(nil), r.events...)
+}
+
+// registerLeaseScripts installs Go equivalents of the Redis lease scripts
+// on the fake.
+func registerLeaseScripts(fake *fakes.Redis) {
+	ctx := context.Background()
+	fake.HandleScript(redisAcquireScript, func(r *fakes.Redis, keys []string, args []interface{}) (interface{}, error) {
+		owner := args[0].(string)
+		ttl := time.Duration(args[1].(int64)) * time.Millisecond
+		cur, err := r.HGet(ctx, keys[0], "owner").Result()
+		if err == nil && cur == owner {
+			r.Expire(ctx, keys[0], ttl)
+			tok, _ := r.HGet(ctx, keys[0], "token").Result()
+			return strconv.ParseInt(tok, 10, 64)
+		}
+		if err == nil {
+			return int64(0), nil
+		}
+		token := r.Incr(ctx, keys[1]).Val()
+		r.HSet(ctx, keys[0], "owner", owner, "token", token)
+		r.Expire(ctx, keys[0], ttl)
+		return token, nil
+	})
+	fake.HandleScript(redisReleaseScript, func(r *fakes.Redis, keys []string, args []interface{}) (interface{}, error) {
+		if cur, err := r.HGet(ctx, keys[0], "owner").Result(); err == nil && cur == args[0].(string) {
+			return r.Del(ctx, keys[0]).Val(), nil
+		}
+		return int64(0), nil
+	})
+}
+
+// ===========================================================================
+// LeaderElector Tests
+// ===========================================================================
+
+// TestNewLeaderElector_Validation verifies that a nil lock is rejected and
+// that a non-positive interval falls back to the default.
+func TestNewLeaderElector_Validation(t *testing.T) {
+	t.Parallel()
+	_, err := NewLeaderElector(nil, time.Second)
+	assert.True(t, sserr.IsValidation(err))
+
+	e, err := NewLeaderElector(&memoryLeaderLock{table: &leaseTable{}, owner: "a"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLeaderRenewInterval, e.interval)
+}
+
+// TestLeaderElector_Run verifies that Run acquires leadership, reports the
+// fencing token, and releases leadership when its context is canceled.
+func TestLeaderElector_Run(t *testing.T) {
+	t.Parallel()
+	table := &leaseTable{}
+	e, err := NewLeaderElector(&memoryLeaderLock{table: table, owner: "a"}, 10*time.Millisecond)
+	require.NoError(t, err)
+	rec := &eventRecorder{}
+	e.OnChange(rec.handle)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- e.Run(ctx) }()
+
+	require.Eventually(t, e.IsLeader, time.Second, time.Millisecond)
+	token, ok := e.FencingToken()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), token)
+
+	// A second concurrent Run is refused.
+	assert.True(t, sserr.IsConflict(e.Run(ctx)))
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.False(t, e.IsLeader())
+	assert.Empty(t, table.holder, "lock must be released on exit")
+	assert.Equal(t, []leadershipEvent{{true, 1}, {false, 0}}, rec.snapshot())
+}
+
+// TestLeaderElector_AcquireErrorDemotes verifies that a failed renewal is
+// treated as a loss of leadership.
+func TestLeaderElector_AcquireErrorDemotes(t *testing.T) {
+	t.Parallel()
+	table := &leaseTable{}
+	e, err := NewLeaderElector(&memoryLeaderLock{table: table, owner: "a"}, 5*time.Millisecond)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+
+	require.Eventually(t, e.IsLeader, time.Second, time.Millisecond)
+	table.mu.Lock()
+	table.fail = true
+	table.mu.Unlock()
+	require.Eventually(t, func() bool { return !e.IsLeader() }, time.Second, time.Millisecond)
+}
+
+// ===========================================================================
+// BaseAgent Leader Election Tests
+// ===========================================================================
+
+// TestBaseAgent_LeaderElection verifies that only the leader replica runs,
+// that a follower is paused and cannot be resumed manually, and that the
+// follower takes over when the leader stops.
+func TestBaseAgent_LeaderElection(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	table := &leaseTable{}
+
+	newReplica := func(id string) *BaseAgent {
+		e, err := NewLeaderElector(&memoryLeaderLock{table: table, owner: id}, 5*time.Millisecond)
+		require.NoError(t, err)
+		agent, err := NewBaseAgentBuilder(id, "compactor", "1.0.0").
+			WithLeaderElection(e).
+			Build()
+		require.NoError(t, err)
+		return agent
+	}
+	leader := newReplica("replica-1")
+	follower := newReplica("replica-2")
+
+	require.NoError(t, leader.Start(ctx))
+	require.NoError(t, follower.Start(ctx))
+
+	assert.Equal(t, StateRunning, leader.State())
+	assert.True(t, leader.IsLeader())
+	token, ok := leader.FencingToken()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), token)
+
+	assert.Equal(t, StatePaused, follower.State(), "follower must be paused when Start returns")
+	assert.False(t, follower.IsLeader())
+	assert.True(t, sserr.IsConflict(follower.Resume(ctx)))
+
+	require.NoError(t, leader.Stop(ctx))
+	require.Eventually(t, func() bool { return follower.State() == StateRunning },
+		time.Second, time.Millisecond)
+	token, ok = follower.FencingToken()
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), token, "fencing token must increase on takeover")
+
+	require.NoError(t, follower.Stop(ctx))
+	assert.Equal(t, StateStopped, follower.State())
+	assert.Empty(t, table.holder)
+}
+
+// TestBaseAgent_LeaderElection_OperatorPause verifies that regaining
+// leadership does not resume an agent that an operator paused.
+func TestBaseAgent_LeaderElection_OperatorPause(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	table := &leaseTable{}
+	e, err := NewLeaderElector(&memoryLeaderLock{table: table, owner: "a"}, time.Hour)
+	require.NoError(t, err)
+	agent, err := NewBaseAgentBuilder("a", "compactor", "1.0.0").
+		WithLeaderElection(e).
+		Build()
+	require.NoError(t, err)
+
+	require.NoError(t, agent.Start(ctx))
+	require.NoError(t, agent.Pause(ctx))
+
+	agent.onLeadershipChange(ctx, true, 7)
+	assert.Equal(t, StatePaused, agent.State())
+	require.NoError(t, agent.Stop(ctx))
+}
+
+// TestBaseAgentBuilder_LeaderElectionSingleUse verifies that an elector
+// cannot be attached to a second agent, even by the same builder.
+func TestBaseAgentBuilder_LeaderElectionSingleUse(t *testing.T) {
+	t.Parallel()
+	e, err := NewLeaderElector(&memoryLeaderLock{table: &leaseTable{}, owner: "a"}, time.Hour)
+	require.NoError(t, err)
+	builder := NewBaseAgentBuilder("a", "compactor", "1.0.0").WithLeaderElection(e)
+
+	_, err = builder.Build()
+	require.NoError(t, err)
+	_, err = builder.Build()
+	assert.True(t, sserr.IsConflict(err), "error = %v", err)
+	_, err = NewBaseAgentBuilder("b", "compactor", "1.0.0").WithLeaderElection(e).Build()
+	assert.True(t, sserr.IsConflict(err), "error = %v", err)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	assert.Len(t, e.handlers, 1)
+}
+
+// TestBaseAgent_IsLeader_NoElection verifies that agents without leader
+// election always report leadership and no fencing token.
+func TestBaseAgent_IsLeader_NoElection(t *testing.T) {
+	t.Parallel()
+	agent, err := NewBaseAgentBuilder("a", "worker", "1.0.0").Build()
+	require.NoError(t, err)
+	assert.True(t, agent.IsLeader())
+	_, ok := agent.FencingToken()
+	assert.False(t, ok)
+}
+
+// ===========================================================================
+// PostgresLeaderLock Tests
+// ===========================================================================
+
+// TestPostgresLeaderLock_AcquireRenewRelease verifies that the lock holds
+// a transaction while leading, renews by probing it, and releases it by
+// rolling back.
+func TestPostgresLeaderLock_AcquireRenewRelease(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	lock, err := NewPostgresLeaderLock(postgres.NewFromPool(mock, nil), "compactor")
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_xact_lock($1), txid_current()")).
+		WithArgs(lock.key).
+		WillReturnRows(pgxmock.NewRows([]string{"locked", "txid"}).AddRow(true, int64(4711)))
+	mock.ExpectExec(regexp.QuoteMeta("SET LOCAL idle_in_transaction_session_timeout = 0")).
+		WillReturnResult(pgxmock.NewResult("SET", 0))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT 1")).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectRollback()
+
+	ctx := context.Background()
+	token, held, err := lock.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, int64(4711), token)
+
+	token, held, err = lock.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, int64(4711), token)
+
+	require.NoError(t, lock.Release(ctx))
+	require.NoError(t, lock.Release(ctx), "releasing an unheld lock is a no-op")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPostgresLeaderLock_Contended verifies that a lock held by another
+// session is reported as not held and the transaction is rolled back.
+func TestPostgresLeaderLock_Contended(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	lock, err := NewPostgresLeaderLock(postgres.NewFromPool(mock, nil), "compactor")
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").
+		WithArgs(lock.key).
+		WillReturnRows(pgxmock.NewRows([]string{"locked", "txid"}).AddRow(false, int64(4712)))
+	mock.ExpectRollback()
+
+	_, held, err := lock.Acquire(context.Background())
+	require.NoError(t, err)
+	assert.False(t, held)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPostgresLeaderLock_RenewFailure verifies that a dead holding
+// connection is reported as a loss of the lock.
+func TestPostgresLeaderLock_RenewFailure(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	lock, err := NewPostgresLeaderLock(postgres.NewFromPool(mock, nil), "compactor")
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("pg_try_advisory_xact_lock").
+		WithArgs(lock.key).
+		WillReturnRows(pgxmock.NewRows([]string{"locked", "txid"}).AddRow(true, int64(1)))
+	mock.ExpectExec("SET LOCAL").WillReturnResult(pgxmock.NewResult("SET", 0))
+	mock.ExpectExec("SELECT 1").WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	ctx := context.Background()
+	_, held, err := lock.Acquire(ctx)
+	require.NoError(t, err)
+	require.True(t, held)
+
+	_, held, err = lock.Acquire(ctx)
+	require.Error(t, err)
+	assert.False(t, held)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPostgresLeaderLock_BeginFailure verifies that a failure to open the
+// holding transaction is wrapped as a database error.
+func TestPostgresLeaderLock_BeginFailure(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	lock, err := NewPostgresLeaderLock(postgres.NewFromPool(mock, nil), "compactor")
+	require.NoError(t, err)
+
+	mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+
+	_, held, err := lock.Acquire(context.Background())
+	assert.False(t, held)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase), "error = %v", err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestNewPostgresLeaderLock_Validation verifies constructor validation.
+func TestNewPostgresLeaderLock_Validation(t *testing.T) {
+	t.Parallel()
+	_, err := NewPostgresLeaderLock(nil, "compactor")
+	assert.True(t, sserr.IsValidation(err))
+
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	_, err = NewPostgresLeaderLock(postgres.NewFromPool(mock, nil), "")
+	assert.True(t, sserr.IsValidation(err))
+}
+
+// ===========================================================================
+// RedisLeaderLock Tests
+// ===========================================================================
+
+// TestRedisLeaderLock_Lease verifies mutual exclusion between owners,
+// renewal by the holder, owner-checked release, and increasing fencing
+// tokens across terms.
+func TestRedisLeaderLock_Lease(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fake := fakes.NewRedis()
+	registerLeaseScripts(fake)
+	client := redis.NewFromClient(fake, nil)
+
+	a, err := NewRedisLeaderLock(client, "compactor", "pod-a", time.Minute)
+	require.NoError(t, err)
+	b, err := NewRedisLeaderLock(client, "compactor", "pod-b", time.Minute)
+	require.NoError(t, err)
+
+	token, held, err := a.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, int64(1), token)
+
+	_, held, err = b.Acquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, held, "lease must be exclusive")
+
+	token, held, err = a.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, int64(1), token, "renewal keeps the term")
+
+	require.NoError(t, b.Release(ctx))
+	_, held, _ = b.Acquire(ctx)
+	assert.False(t, held, "non-owner release must not free the lease")
+
+	require.NoError(t, a.Release(ctx))
+	token, held, err = b.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, int64(2), token)
+}
+
+// TestRedisLeaderLock_Expiry verifies that an unrenewed lease expires and
+// can be taken over.
+func TestRedisLeaderLock_Expiry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fake := fakes.NewRedis()
+	registerLeaseScripts(fake)
+	client := redis.NewFromClient(fake, nil)
+
+	a, err := NewRedisLeaderLock(client, "compactor", "pod-a", 5*time.Millisecond)
+	require.NoError(t, err)
+	b, err := NewRedisLeaderLock(client, "compactor", "pod-b", time.Minute)
+	require.NoError(t, err)
+
+	_, held, err := a.Acquire(ctx)
+	require.NoError(t, err)
+	require.True(t, held)
+
+	time.Sleep(10 * time.Millisecond)
+	token, held, err := b.Acquire(ctx)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, int64(2), token)
+}
+
+// TestRedisLeaderLock_Error verifies that Redis failures are wrapped and
+// that constructor arguments are validated.
+func TestRedisLeaderLock_Error(t *testing.T) {
+	t.Parallel()
+	fake := fakes.NewRedis()
+	fake.SetError(errors.New("connection refused"))
+	client := redis.NewFromClient(fake, nil)
+
+	lock, err := NewRedisLeaderLock(client, "compactor", "pod-a", time.Second)
+	require.NoError(t, err)
+	_, held, err := lock.Acquire(context.Background())
+	assert.False(t, held)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase))
+
+	_, err = NewRedisLeaderLock(nil, "compactor", "pod-a", time.Second)
+	assert.True(t, sserr.IsValidation(err))
+	_, err = NewRedisLeaderLock(client, "", "pod-a", time.Second)
+	assert.True(t, sserr.IsValidation(err))
+	_, err = NewRedisLeaderLock(client, "compactor", "", time.Second)
+	assert.True(t, sserr.IsValidation(err))
+	_, err = NewRedisLeaderLock(client, "compactor", "pod-a", 0)
+	assert.True(t, sserr.IsValidation(err))
+}
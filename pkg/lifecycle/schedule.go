@@ -0,0 +1,347 @@
+package lifecycle
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// Schedule determines when a scheduled job runs. Next returns the first
+// activation time strictly after t, or the zero time if the schedule has
+// no further activations.
+//
+// The SDK provides cron schedules ([ParseCron]), fixed intervals
+// ([Every]), and random jitter for either ([WithJitter]). Custom
+// implementations may also implement [fmt.Stringer] to be described in
+// [JobStatus].
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// ===========================================================================
+// Interval Schedules
+// ===========================================================================
+
+// intervalSchedule activates at a fixed interval after the previous
+// activation.
+type intervalSchedule struct {
+	every time.Duration
+}
+
+// Every returns a schedule that activates every d, measured from the
+// previous activation (or from when the job was scheduled). Durations
+// shorter than one millisecond are raised to one millisecond.
+func Every(d time.Duration) Schedule {
+	if d < time.Millisecond {
+		d = time.Millisecond
+	}
+	return intervalSchedule{every: d}
+}
+
+// Next implements [Schedule].
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.every)
+}
+
+// String returns the schedule in "@every <duration>" form.
+func (s intervalSchedule) String() string {
+	return "@every " + s.every.String()
+}
+
+// jitterSchedule delays each activation of an underlying schedule by a
+// random amount.
+type jitterSchedule struct {
+	base Schedule
+	max  time.Duration
+}
+
+// WithJitter returns a schedule that delays each activation of s by a
+// uniformly random duration in [0, max). Jitter spreads the load of
+// replicas that share a schedule, so they do not hit a dependency at the
+// same instant. A max of zero or less returns s unchanged.
+func WithJitter(s Schedule, max time.Duration) Schedule {
+	if max <= 0 {
+		return s
+	}
+	return jitterSchedule{base: s, max: max}
+}
+
+// Next implements [Schedule].
+func (s jitterSchedule) Next(t time.Time) time.Time {
+	next := s.base.Next(t)
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(rand.N(s.max))
+}
+
+// String describes the underlying schedule and the jitter.
+func (s jitterSchedule) String() string {
+	return describeSchedule(s.base) + " ~" + s.max.String()
+}
+
+// describeSchedule returns a human-readable description of s.
+func describeSchedule(s Schedule) string {
+	if str, ok := s.(interface{ String() string }); ok {
+		return str.String()
+	}
+	return "custom"
+}
+
+// ===========================================================================
+// Cron Schedules
+// ===========================================================================
+
+// cronSchedule is a parsed five-field cron expression. Each field is a
+// bitmask of permitted values.
+type cronSchedule struct {
+	expr                     string
+	minute, hour, dom, month uint64
+	dow                      uint64
+	domStar, dowStar         bool
+}
+
+// cronField describes the permitted range and names of one cron field.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day of week accepts 0-7, where both 0 and 7 are Sunday.
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronDescriptors maps the predefined schedules to their expressions.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard five-field cron expression:
+//
+//	┌───────────── minute (0-59)
+//	│ ┌─────────── hour (0-23)
+//	│ │ ┌───────── day of month (1-31)
+//	│ │ │ ┌─────── month (1-12 or JAN-DEC)
+//	│ │ │ │ ┌───── day of week (0-7 or SUN-SAT; 0 and 7 are Sunday)
+//	│ │ │ │ │
+//	* * * * *
+//
+// Each field accepts "*", single values, ranges ("1-5"), lists ("1,15"),
+// and steps ("*/15", "0-30/10"). As in classic cron, if both day of month
+// and day of week are restricted, a time matches if either does.
+//
+// The descriptors @yearly, @annually, @monthly, @weekly, @daily,
+// @midnight, and @hourly are accepted, as is "@every <duration>" (e.g.,
+// "@every 90s"), which is equivalent to [Every].
+//
+// Schedules are evaluated in the location of the time passed to Next;
+// the scheduler uses the local time zone of the process.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidationFormat] if the
+// expression is malformed.
+func ParseCron(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d <= 0 {
+			return nil, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: invalid cron interval %q", expr)
+		}
+		return Every(d), nil
+	}
+	if strings.HasPrefix(spec, "@") {
+		mapped, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: unknown cron descriptor %q", expr)
+		}
+		spec = mapped
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	s := cronSchedule{expr: strings.TrimSpace(expr)}
+	var err error
+	if s.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, wrapCronError(err, expr)
+	}
+	if s.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, wrapCronError(err, expr)
+	}
+	if s.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, wrapCronError(err, expr)
+	}
+	if s.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, wrapCronError(err, expr)
+	}
+	if s.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, wrapCronError(err, expr)
+	}
+	// Fold Sunday-as-7 onto 0.
+	if s.dow&(1<<7) != 0 {
+		s.dow = (s.dow | 1) &^ (1 << 7)
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+// wrapCronError adds the full expression to a field parse error.
+func wrapCronError(err error, expr string) error {
+	return sserr.Wrapf(err, sserr.CodeValidationFormat,
+		"lifecycle: invalid cron expression %q", expr)
+}
+
+// parseCronField parses one comma-separated cron field into a bitmask.
+func parseCronField(field string, f cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		bitsForPart, err := parseCronPart(part, f)
+		if err != nil {
+			return 0, err
+		}
+		mask |= bitsForPart
+	}
+	return mask, nil
+}
+
+// parseCronPart parses a single list element: "*", "N", "N-M", with an
+// optional "/step".
+func parseCronPart(part string, f cronField) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepPart)
+		if err != nil || n <= 0 {
+			return 0, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: invalid step %q in %s field", stepPart, f.name)
+		}
+		step = n
+	}
+
+	lo, hi := f.min, f.max
+	switch {
+	case rangePart == "*" || rangePart == "?":
+	case strings.Contains(rangePart, "-"):
+		a, b, _ := strings.Cut(rangePart, "-")
+		var err error
+		if lo, err = parseCronValue(a, f); err != nil {
+			return 0, err
+		}
+		if hi, err = parseCronValue(b, f); err != nil {
+			return 0, err
+		}
+		if lo > hi {
+			return 0, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: invalid range %q in %s field", rangePart, f.name)
+		}
+	default:
+		v, err := parseCronValue(rangePart, f)
+		if err != nil {
+			return 0, err
+		}
+		lo = v
+		// "N/step" means from N to the end of the range.
+		if !hasStep {
+			hi = v
+		}
+	}
+
+	var mask uint64
+	for v := lo; v <= hi; v += step {
+		mask |= 1 << uint(v)
+	}
+	return mask, nil
+}
+
+// parseCronValue parses a single numeric or named value within a field.
+func parseCronValue(s string, f cronField) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: invalid value %q in %s field (allowed %d-%d)", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// cronSearchLimit bounds the search for the next activation so that
+// expressions that can never match (e.g., "0 0 30 2 *") terminate.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// Next implements [Schedule]. Returns the zero time if the expression has
+// no activation within the next five years.
+func (s cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(cronSearchLimit)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			// Jump straight to the next permitted minute in this hour.
+			rest := s.minute >> uint(t.Minute()+1)
+			if rest == 0 {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			} else {
+				t = t.Add(time.Duration(bits.TrailingZeros64(rest)+1) * time.Minute)
+			}
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the classic cron day-of-month / day-of-week rule.
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// String returns the expression as originally supplied.
+func (s cronSchedule) String() string {
+	return s.expr
+}
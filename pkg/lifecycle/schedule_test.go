@@ -0,0 +1,107 @@
+package lifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ===========================================================================
+// ParseCron Tests
+// ===========================================================================
+
+// TestParseCron_Next verifies activation times for common expressions,
+// including ranges, steps, names, descriptors, and the day-of-month /
+// day-of-week OR rule.
+func TestParseCron_Next(t *testing.T) {
+	t.Parallel()
+	// Thursday, 2026-01-01 10:07:30 UTC.
+	from := time.Date(2026, 1, 1, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2026, 1, 2, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * MON-FRI", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * sun", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 9 1,15 * *", time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 FEB *", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"5-10/5 10 * * *", time.Date(2026, 1, 1, 10, 10, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}, // Friday OR the 13th
+		{"@hourly", time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			s, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(from))
+		})
+	}
+}
+
+// TestParseCron_Impossible verifies that an expression that can never
+// match yields the zero time instead of looping forever.
+func TestParseCron_Impossible(t *testing.T) {
+	t.Parallel()
+	s, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+// TestParseCron_Invalid verifies that malformed expressions are rejected
+// with CodeValidationFormat.
+func TestParseCron_Invalid(t *testing.T) {
+	t.Parallel()
+	inputs := []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *",
+		"@fortnightly", "@every", "@every -1s", "@every soon",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseCron(input)
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, sserr.CodeValidationFormat),
+				"error code = %v, want CodeValidationFormat", sserr.GetCode(err))
+		})
+	}
+}
+
+// ===========================================================================
+// Interval and Jitter Tests
+// ===========================================================================
+
+// TestEvery_WithJitter verifies interval activations and that jitter stays
+// within its bound.
+func TestEvery_WithJitter(t *testing.T) {
+	t.Parallel()
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, from.Add(time.Minute), Every(time.Minute).Next(from))
+	assert.Equal(t, "@every 1m0s", describeSchedule(Every(time.Minute)))
+
+	s := WithJitter(Every(time.Minute), 10*time.Second)
+	for i := 0; i < 100; i++ {
+		next := s.Next(from)
+		assert.False(t, next.Before(from.Add(time.Minute)))
+		assert.True(t, next.Before(from.Add(70*time.Second)))
+	}
+	assert.Equal(t, "@every 1m0s ~10s", describeSchedule(s))
+	assert.Equal(t, Every(time.Minute), WithJitter(Every(time.Minute), 0))
+}
@@ -0,0 +1,299 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// Job is a unit of scheduled work. The context is canceled when the agent
+// stops; long-running jobs must honor it so that [BaseAgent.Stop] does not
+// block.
+type Job func(ctx context.Context) error
+
+// JobStatus is a point-in-time snapshot of a scheduled job's execution
+// history. It is returned by [BaseAgent.Jobs] and included in
+// [AgentInfo].
+type JobStatus struct {
+	// Name is the job's unique name within the agent.
+	Name string `json:"name"`
+
+	// Schedule describes when the job runs (e.g., "*/5 * * * *").
+	Schedule string `json:"schedule"`
+
+	// Running is true while a run is in progress.
+	Running bool `json:"running"`
+
+	// Runs is the number of completed runs, successful or not.
+	Runs uint64 `json:"runs"`
+
+	// Failures is the number of runs that returned an error or panicked.
+	Failures uint64 `json:"failures"`
+
+	// Skipped is the number of activations that did not run, either
+	// because the agent was not in [StateRunning] or because the previous
+	// run was still in progress.
+	Skipped uint64 `json:"skipped"`
+
+	// LastRun is the start time of the most recent run. Nil if the job
+	// has never run.
+	LastRun *time.Time `json:"last_run,omitempty"`
+
+	// LastDuration is how long the most recent run took.
+	LastDuration time.Duration `json:"last_duration,omitempty"`
+
+	// LastError is the error message of the most recent run, or empty if
+	// it succeeded.
+	LastError string `json:"last_error,omitempty"`
+
+	// NextRun is the next planned activation. Nil if the scheduler is not
+	// running or the schedule has no further activations.
+	NextRun *time.Time `json:"next_run,omitempty"`
+}
+
+// jobSpec is a job registered via the builder.
+type jobSpec struct {
+	name     string
+	schedule Schedule
+	job      Job
+	err      error // deferred parse error, reported by Build
+}
+
+// scheduledJob is a job together with its mutable execution status.
+type scheduledJob struct {
+	name     string
+	schedule Schedule
+	fn       Job
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// snapshot returns a copy of the job's status.
+func (j *scheduledJob) snapshot() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := j.status
+	if st.LastRun != nil {
+		t := *st.LastRun
+		st.LastRun = &t
+	}
+	if st.NextRun != nil {
+		t := *st.NextRun
+		st.NextRun = &t
+	}
+	return st
+}
+
+// setNext records the next planned activation.
+func (j *scheduledJob) setNext(next time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if next.IsZero() {
+		j.status.NextRun = nil
+		return
+	}
+	j.status.NextRun = &next
+}
+
+// skip records an activation that did not run.
+func (j *scheduledJob) skip() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Skipped++
+}
+
+// scheduler runs an agent's jobs on their schedules while the agent is in
+// [StateRunning]. Each job has its own goroutine, so a job never overlaps
+// with itself; activations that fall due while a run is still in progress
+// are skipped.
+type scheduler struct {
+	jobs    []*scheduledJob
+	agentID string
+	tracer  trace.Tracer
+	logger  *slog.Logger
+	active  func() bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// start launches one goroutine per job. Jobs receive a context derived
+// from ctx that keeps its values but is canceled only by stop.
+func (s *scheduler) start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	sctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(sctx, j)
+	}
+}
+
+// stop cancels all jobs and waits for in-flight runs to return.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	s.wg.Wait()
+}
+
+// loop waits for each activation of j and runs it if the agent is
+// running.
+func (s *scheduler) loop(ctx context.Context, j *scheduledJob) {
+	defer s.wg.Done()
+	defer j.setNext(time.Time{})
+
+	next := j.schedule.Next(time.Now())
+	for !next.IsZero() {
+		j.setNext(next)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !s.active() {
+			j.skip()
+			next = j.schedule.Next(time.Now())
+			continue
+		}
+
+		s.execute(ctx, j)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Activations that fell due during the run are skipped rather
+		// than run back to back.
+		now := time.Now()
+		if due := j.schedule.Next(next); !due.IsZero() && !due.After(now) {
+			j.skip()
+			s.logger.WarnContext(ctx, "lifecycle: job overran its schedule, skipping missed runs",
+				"agent_id", s.agentID,
+				"job", j.name,
+			)
+		}
+		next = j.schedule.Next(now)
+	}
+}
+
+// execute performs a single traced run of j, recovering panics.
+func (s *scheduler) execute(ctx context.Context, j *scheduledJob) {
+	ctx, span := s.tracer.Start(ctx, "lifecycle.Job",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("agent.id", s.agentID),
+			attribute.String("job.name", j.name),
+		),
+	)
+	defer span.End()
+
+	started := time.Now().UTC()
+	j.mu.Lock()
+	j.status.Running = true
+	j.status.LastRun = &started
+	j.mu.Unlock()
+
+	err := runJob(ctx, j.fn)
+	elapsed := time.Since(started)
+
+	j.mu.Lock()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastDuration = elapsed
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+	}
+	j.mu.Unlock()
+
+	if err != nil {
+		s.logger.ErrorContext(ctx, "lifecycle: job failed",
+			"agent_id", s.agentID,
+			"job", j.name,
+			"duration", elapsed,
+			"error", err,
+		)
+		failSpan(span, err)
+		return
+	}
+	span.SetStatus(codes.Ok, "")
+}
+
+// runJob calls fn, converting a panic into a [sserr.CodeInternal] error.
+func runJob(ctx context.Context, fn Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = sserr.New(sserr.CodeInternal, fmt.Sprintf("lifecycle: job panicked: %v", r))
+		}
+	}()
+	return fn(ctx)
+}
+
+// statuses returns a snapshot of every job's status in registration
+// order.
+func (s *scheduler) statuses() []JobStatus {
+	out := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		out[i] = j.snapshot()
+	}
+	return out
+}
+
+// buildScheduler validates job specs and constructs a scheduler. Returns
+// nil if there are no jobs.
+func buildScheduler(specs []jobSpec) (*scheduler, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(specs))
+	jobs := make([]*scheduledJob, 0, len(specs))
+	for _, spec := range specs {
+		if spec.name == "" {
+			return nil, sserr.New(sserr.CodeValidation,
+				"lifecycle: job name must not be empty")
+		}
+		if spec.err != nil {
+			return nil, sserr.Wrapf(spec.err, sserr.CodeValidationFormat,
+				"lifecycle: job %q has an invalid schedule", spec.name)
+		}
+		if spec.schedule == nil || spec.job == nil {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"lifecycle: job %q requires a schedule and a function", spec.name)
+		}
+		if seen[spec.name] {
+			return nil, sserr.Newf(sserr.CodeValidation,
+				"lifecycle: duplicate job name %q", spec.name)
+		}
+		seen[spec.name] = true
+		jobs = append(jobs, &scheduledJob{
+			name:     spec.name,
+			schedule: spec.schedule,
+			fn:       spec.job,
+			status:   JobStatus{Name: spec.name, Schedule: describeSchedule(spec.schedule)},
+		})
+	}
+	return &scheduler{jobs: jobs}, nil
+}
@@ -0,0 +1,199 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// jobStatus returns the status of the named job, failing the test if it
+// does not exist.
+func jobStatus(t *testing.T, agent *BaseAgent, name string) JobStatus {
+	t.Helper()
+	for _, st := range agent.Jobs() {
+		if st.Name == name {
+			return st
+		}
+	}
+	t.Fatalf("job %q not found", name)
+	return JobStatus{}
+}
+
+// ===========================================================================
+// Scheduler Tests
+// ===========================================================================
+
+// TestScheduler_RunsWhileRunning verifies that an interval job runs
+// repeatedly while the agent is running, records its runs, and appears in
+// Info.
+func TestScheduler_RunsWhileRunning(t *testing.T) {
+	t.Parallel()
+	var runs atomic.Int64
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithIntervalJob("tick", 2*time.Millisecond, 0, func(context.Context) error {
+			runs.Add(1)
+			return nil
+		}).
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, agent.Start(ctx))
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+	require.NoError(t, agent.Stop(ctx))
+
+	st := jobStatus(t, agent, "tick")
+	assert.Equal(t, "@every 2ms", st.Schedule)
+	assert.GreaterOrEqual(t, st.Runs, uint64(3))
+	assert.Zero(t, st.Failures)
+	assert.NotNil(t, st.LastRun)
+	assert.Nil(t, st.NextRun, "a stopped scheduler has no next run")
+	assert.Len(t, agent.Info().Jobs, 1)
+}
+
+// TestScheduler_SkipsWhilePaused verifies that activations are skipped
+// while the agent is paused and resume afterwards.
+func TestScheduler_SkipsWhilePaused(t *testing.T) {
+	t.Parallel()
+	var runs atomic.Int64
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithIntervalJob("tick", 2*time.Millisecond, 0, func(context.Context) error {
+			runs.Add(1)
+			return nil
+		}).
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, agent.Start(ctx))
+	require.NoError(t, agent.Pause(ctx))
+	// Allow any run in flight at the moment of pausing to finish.
+	time.Sleep(5 * time.Millisecond)
+	paused := runs.Load()
+
+	require.Eventually(t, func() bool { return jobStatus(t, agent, "tick").Skipped >= 2 },
+		time.Second, time.Millisecond)
+	assert.Equal(t, paused, runs.Load(), "job must not run while paused")
+
+	require.NoError(t, agent.Resume(ctx))
+	require.Eventually(t, func() bool { return runs.Load() > paused }, time.Second, time.Millisecond)
+	require.NoError(t, agent.Stop(ctx))
+}
+
+// TestScheduler_CanceledOnStop verifies that Stop cancels an in-flight
+// run's context and waits for it to return before stopping.
+func TestScheduler_CanceledOnStop(t *testing.T) {
+	t.Parallel()
+	started := make(chan struct{})
+	var canceled atomic.Bool
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithIntervalJob("long", time.Millisecond, 0, func(ctx context.Context) error {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-ctx.Done()
+			canceled.Store(true)
+			return ctx.Err()
+		}).
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, agent.Start(ctx))
+	<-started
+	require.NoError(t, agent.Stop(ctx))
+	assert.True(t, canceled.Load(), "Stop must wait for the canceled run to return")
+	assert.Equal(t, StateStopped, agent.State())
+}
+
+// TestScheduler_RecordsFailuresAndPanics verifies that job errors and
+// panics are recorded as failures without affecting the agent.
+func TestScheduler_RecordsFailuresAndPanics(t *testing.T) {
+	t.Parallel()
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithIntervalJob("fails", 2*time.Millisecond, 0, func(context.Context) error {
+			return errors.New("upstream unavailable")
+		}).
+		WithIntervalJob("panics", 2*time.Millisecond, 0, func(context.Context) error {
+			panic("nil map")
+		}).
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, agent.Start(ctx))
+	require.Eventually(t, func() bool {
+		return jobStatus(t, agent, "fails").Failures > 0 && jobStatus(t, agent, "panics").Failures > 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, StateRunning, agent.State())
+	require.NoError(t, agent.Stop(ctx))
+
+	assert.Equal(t, "upstream unavailable", jobStatus(t, agent, "fails").LastError)
+	assert.Contains(t, jobStatus(t, agent, "panics").LastError, "job panicked: nil map")
+}
+
+// TestScheduler_SkipsOverlappingRuns verifies that a job that outlasts its
+// interval never runs concurrently with itself and that the missed
+// activations are counted as skipped.
+func TestScheduler_SkipsOverlappingRuns(t *testing.T) {
+	t.Parallel()
+	var inFlight, maxInFlight atomic.Int64
+	agent, err := NewBaseAgentBuilder("agent-1", "worker", "1.0.0").
+		WithIntervalJob("slow", time.Millisecond, 0, func(context.Context) error {
+			n := inFlight.Add(1)
+			if n > maxInFlight.Load() {
+				maxInFlight.Store(n)
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+			return nil
+		}).
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, agent.Start(ctx))
+	require.Eventually(t, func() bool { return jobStatus(t, agent, "slow").Runs >= 3 },
+		time.Second, time.Millisecond)
+	require.NoError(t, agent.Stop(ctx))
+
+	assert.Equal(t, int64(1), maxInFlight.Load())
+	assert.Positive(t, jobStatus(t, agent, "slow").Skipped)
+}
+
+// TestBuild_InvalidJobs verifies that Build rejects invalid job
+// registrations.
+func TestBuild_InvalidJobs(t *testing.T) {
+	t.Parallel()
+	noop := func(context.Context) error { return nil }
+	tests := []struct {
+		name    string
+		builder *BaseAgentBuilder
+		code    sserr.Code
+	}{
+		{"bad cron", NewBaseAgentBuilder("a", "w", "1.0.0").WithCronJob("j", "61 * * * *", noop), sserr.CodeValidationFormat},
+		{"bad interval", NewBaseAgentBuilder("a", "w", "1.0.0").WithIntervalJob("j", 0, 0, noop), sserr.CodeValidationFormat},
+		{"empty name", NewBaseAgentBuilder("a", "w", "1.0.0").WithCronJob("", "@hourly", noop), sserr.CodeValidation},
+		{"nil job", NewBaseAgentBuilder("a", "w", "1.0.0").WithScheduledJob("j", Every(time.Second), nil), sserr.CodeValidation},
+		{"nil schedule", NewBaseAgentBuilder("a", "w", "1.0.0").WithScheduledJob("j", nil, noop), sserr.CodeValidation},
+		{"duplicate", NewBaseAgentBuilder("a", "w", "1.0.0").
+			WithCronJob("j", "@hourly", noop).
+			WithCronJob("j", "@daily", noop), sserr.CodeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := tt.builder.Build()
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, tt.code), "error code = %v, want %v", sserr.GetCode(err), tt.code)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package lifecycle
+
+import (
+	"strconv"
+	"strings"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// SemVer is a parsed semantic version following the Semantic Versioning
+// 2.0.0 specification (https://semver.org). It is used to validate
+// [Capability.Version] values and to evaluate [Constraint] expressions.
+//
+// SemVer is a value type and is safe for concurrent use. The zero value
+// represents "0.0.0".
+type SemVer struct {
+	// Major is the major version. Incremented for incompatible API changes.
+	Major uint64
+
+	// Minor is the minor version. Incremented for backward-compatible
+	// functionality additions.
+	Minor uint64
+
+	// Patch is the patch version. Incremented for backward-compatible
+	// bug fixes.
+	Patch uint64
+
+	// Prerelease is the optional dot-separated pre-release identifier
+	// (e.g., "rc.1" in "1.2.0-rc.1"). Empty for release versions.
+	Prerelease string
+
+	// Build is the optional dot-separated build metadata (e.g.,
+	// "sha.5114f85" in "1.2.0+sha.5114f85"). Build metadata is ignored
+	// when comparing versions.
+	Build string
+}
+
+// ParseSemVer parses a semantic version string such as "1.2.3",
+// "1.2.3-rc.1", or "1.2.3+build.7". A single leading "v" is accepted
+// for compatibility with Go module and git tag conventions ("v1.2.3").
+//
+// All three numeric components are required; partial versions such as
+// "1.2" are only accepted inside [Constraint] expressions. Numeric
+// components must not contain leading zeros, per the specification.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidationFormat] if the
+// string is not a valid semantic version.
+func ParseSemVer(s string) (SemVer, error) {
+	v, n, err := parseVersionParts(s)
+	if err != nil {
+		return SemVer{}, err
+	}
+	if n != 3 {
+		return SemVer{}, sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: invalid semantic version %q: expected MAJOR.MINOR.PATCH", s)
+	}
+	return v, nil
+}
+
+// String returns the canonical string form of the version without a
+// leading "v" (e.g., "1.2.3-rc.1+build.7").
+func (v SemVer) String() string {
+	var b strings.Builder
+	b.WriteString(strconv.FormatUint(v.Major, 10))
+	b.WriteByte('.')
+	b.WriteString(strconv.FormatUint(v.Minor, 10))
+	b.WriteByte('.')
+	b.WriteString(strconv.FormatUint(v.Patch, 10))
+	if v.Prerelease != "" {
+		b.WriteByte('-')
+		b.WriteString(v.Prerelease)
+	}
+	if v.Build != "" {
+		b.WriteByte('+')
+		b.WriteString(v.Build)
+	}
+	return b.String()
+}
+
+// Compare returns -1, 0, or +1 depending on whether v is lower than,
+// equal to, or higher than other. Precedence follows section 11 of the
+// specification: numeric components are compared first, a pre-release
+// version has lower precedence than the associated release, and build
+// metadata is ignored.
+func (v SemVer) Compare(other SemVer) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// sameCore reports whether v and other share the same major, minor, and
+// patch components.
+func (v SemVer) sameCore(other SemVer) bool {
+	return v.Major == other.Major && v.Minor == other.Minor && v.Patch == other.Patch
+}
+
+// parseVersionParts parses a possibly partial version ("1", "1.2",
+// "1.2.3-rc.1") and returns the version together with the number of
+// numeric components present. Pre-release and build suffixes are only
+// permitted when all three components are present.
+func parseVersionParts(s string) (SemVer, int, error) {
+	raw := s
+	s = strings.TrimPrefix(s, "v")
+	if s == "" {
+		return SemVer{}, 0, sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: invalid semantic version %q: empty version", raw)
+	}
+
+	var v SemVer
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(v.Build, false) {
+			return SemVer{}, 0, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: invalid semantic version %q: malformed build metadata", raw)
+		}
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(v.Prerelease, true) {
+			return SemVer{}, 0, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: invalid semantic version %q: malformed pre-release", raw)
+		}
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return SemVer{}, 0, sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: invalid semantic version %q: too many components", raw)
+	}
+	if len(parts) < 3 && (v.Prerelease != "" || v.Build != "") {
+		return SemVer{}, 0, sserr.Newf(sserr.CodeValidationFormat,
+			"lifecycle: invalid semantic version %q: pre-release and build require MAJOR.MINOR.PATCH", raw)
+	}
+
+	nums := [3]*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, ok := parseNumericIdentifier(p)
+		if !ok {
+			return SemVer{}, 0, sserr.Newf(sserr.CodeValidationFormat,
+				"lifecycle: invalid semantic version %q: component %q is not a valid number", raw, p)
+		}
+		*nums[i] = n
+	}
+	return v, len(parts), nil
+}
+
+// parseNumericIdentifier parses a non-negative decimal integer without
+// leading zeros.
+func parseNumericIdentifier(s string) (uint64, bool) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// validIdentifiers reports whether s is a non-empty, dot-separated list
+// of identifiers made of ASCII alphanumerics and hyphens. When
+// rejectLeadingZero is true (pre-release), purely numeric identifiers
+// must not have leading zeros.
+func validIdentifiers(s string, rejectLeadingZero bool) bool {
+	if s == "" {
+		return false
+	}
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		numeric := true
+		for i := 0; i < len(id); i++ {
+			c := id[i]
+			switch {
+			case c >= '0' && c <= '9':
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-':
+				numeric = false
+			default:
+				return false
+			}
+		}
+		if rejectLeadingZero && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// compareUint returns -1, 0, or +1 comparing a and b.
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease compares two pre-release strings per section 11.4 of
+// the specification. An empty pre-release (a release version) has higher
+// precedence than any non-empty pre-release.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aNum := parseNumericIdentifier(as[i])
+		bn, bNum := parseNumericIdentifier(bs[i])
+		switch {
+		case aNum && bNum:
+			if c := compareUint(an, bn); c != 0 {
+				return c
+			}
+		case aNum:
+			return -1 // Numeric identifiers sort before alphanumeric ones.
+		case bNum:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareUint(uint64(len(as)), uint64(len(bs)))
+}
@@ -0,0 +1,211 @@
+package lifecycle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// ===========================================================================
+// ParseSemVer Tests
+// ===========================================================================
+
+// TestParseSemVer_Valid verifies that ParseSemVer accepts well-formed
+// semantic versions, including pre-release, build metadata, and a leading
+// "v", and that String returns the canonical form.
+func TestParseSemVer_Valid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input string
+		want  SemVer
+		str   string
+	}{
+		{"1.2.3", SemVer{Major: 1, Minor: 2, Patch: 3}, "1.2.3"},
+		{"0.0.0", SemVer{}, "0.0.0"},
+		{"v2.10.0", SemVer{Major: 2, Minor: 10}, "2.10.0"},
+		{"1.0.0-rc.1", SemVer{Major: 1, Prerelease: "rc.1"}, "1.0.0-rc.1"},
+		{"1.0.0+build.7", SemVer{Major: 1, Build: "build.7"}, "1.0.0+build.7"},
+		{"1.0.0-alpha-1+sha.5114f85", SemVer{Major: 1, Prerelease: "alpha-1", Build: "sha.5114f85"}, "1.0.0-alpha-1+sha.5114f85"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			v, err := ParseSemVer(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, v)
+			assert.Equal(t, tt.str, v.String())
+		})
+	}
+}
+
+// TestParseSemVer_Invalid verifies that ParseSemVer rejects malformed
+// versions with a CodeValidationFormat error.
+func TestParseSemVer_Invalid(t *testing.T) {
+	t.Parallel()
+	inputs := []string{
+		"", "v", "1", "1.2", "1.2.3.4", "01.2.3", "1.02.3", "1.2.03",
+		"a.b.c", "1.2.3-", "1.2.3+", "1.2.3-01", "1.2.3-rc..1", "1.2.3-rc_1",
+		"-1.2.3", "1.2.x", "latest",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseSemVer(input)
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, sserr.CodeValidationFormat),
+				"error code = %v, want CodeValidationFormat", sserr.GetCode(err))
+		})
+	}
+}
+
+// ===========================================================================
+// SemVer.Compare Tests
+// ===========================================================================
+
+// TestSemVer_Compare verifies version precedence, including the
+// pre-release ordering rules from section 11 of the specification and
+// that build metadata is ignored.
+func TestSemVer_Compare(t *testing.T) {
+	t.Parallel()
+	// Each version has strictly lower precedence than the next.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+		"10.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		lo, err := ParseSemVer(ordered[i])
+		require.NoError(t, err)
+		hi, err := ParseSemVer(ordered[i+1])
+		require.NoError(t, err)
+		assert.Equal(t, -1, lo.Compare(hi), "%s < %s", ordered[i], ordered[i+1])
+		assert.Equal(t, 1, hi.Compare(lo), "%s > %s", ordered[i+1], ordered[i])
+	}
+
+	a, _ := ParseSemVer("1.2.3+build.1")
+	b, _ := ParseSemVer("1.2.3+build.2")
+	assert.Equal(t, 0, a.Compare(b), "build metadata must be ignored")
+}
+
+// ===========================================================================
+// Constraint Tests
+// ===========================================================================
+
+// TestConstraint_Check verifies constraint evaluation for each supported
+// operator, partial versions, wildcards, AND groups, and OR alternatives.
+func TestConstraint_Check(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		// Exact and partial equality.
+		{"1.2.3", "1.2.3", true},
+		{"=1.2.3", "1.2.4", false},
+		{"1.2", "1.2.9", true},
+		{"1.2.x", "1.3.0", false},
+		{"1", "1.9.9", true},
+		{"*", "42.0.0", true},
+		{"!=1.2.3", "1.2.4", true},
+		{"!=1.2.3", "1.2.3", false},
+
+		// Comparisons.
+		{">=2.0 <3", "2.0.0", true},
+		{">=2.0 <3", "2.9.9", true},
+		{">=2.0 <3", "3.0.0", false},
+		{">=2.0 <3", "1.9.9", false},
+		{">=2.0, <3", "2.5.0", true},
+		{">= 2.0 < 3", "2.5.0", true},
+		{">1.2", "1.2.9", false},
+		{">1.2", "1.3.0", true},
+		{"<=1.2", "1.2.9", true},
+		{"<=1.2", "1.3.0", false},
+		{">1.2.3", "1.2.4", true},
+		{"<=1.2.3", "1.2.3", true},
+
+		// Tilde ranges.
+		{"~1.4.1", "1.4.1", true},
+		{"~1.4.1", "1.4.9", true},
+		{"~1.4.1", "1.5.0", false},
+		{"~1.4.1", "1.4.0", false},
+		{"~1.4", "1.4.0", true},
+		{"~1", "1.9.0", true},
+		{"~1", "2.0.0", false},
+		{"~>1.4", "1.4.7", true},
+
+		// Caret ranges.
+		{"^1.2", "1.2.0", true},
+		{"^1.2", "1.9.9", true},
+		{"^1.2", "2.0.0", false},
+		{"^1.2", "1.1.9", false},
+		{"^1.2.3", "1.2.2", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.3", true},
+		{"^0.0.3", "0.0.4", false},
+		{"^0.0", "0.0.9", true},
+		{"^0.0", "0.1.0", false},
+		{"^1", "1.5.0", true},
+
+		// OR alternatives.
+		{"^1.2 || ^3.0", "3.4.0", true},
+		{"^1.2 || ^3.0", "2.0.0", false},
+
+		// Pre-release opt-in.
+		{"^1.2", "1.9.0-alpha", false},
+		{">=2.0.0-rc.1", "2.0.0-rc.2", true},
+		{">=2.0.0-rc.1", "2.1.0-rc.1", false},
+		{">=2.0.0-rc.1", "2.1.0", true},
+		{"<3", "3.0.0-rc.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint+"/"+tt.version, func(t *testing.T) {
+			t.Parallel()
+			c, err := ParseConstraint(tt.constraint)
+			require.NoError(t, err)
+			v, err := ParseSemVer(tt.version)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c.Check(v))
+		})
+	}
+}
+
+// TestParseConstraint_Invalid verifies that ParseConstraint rejects
+// malformed expressions with a CodeValidationFormat error.
+func TestParseConstraint_Invalid(t *testing.T) {
+	t.Parallel()
+	inputs := []string{
+		"", "   ", ">=", "^", "1.2 ||", "|| 1.2", ">=a.b", "1.x.3",
+		"!=1.2", ">*", "<*", "1.2-rc.1", "1.2.3.4", "=>1.0",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseConstraint(input)
+			require.Error(t, err)
+			assert.True(t, sserr.HasCode(err, sserr.CodeValidationFormat),
+				"error code = %v, want CodeValidationFormat", sserr.GetCode(err))
+		})
+	}
+}
+
+// TestConstraint_String verifies that String returns the trimmed input.
+func TestConstraint_String(t *testing.T) {
+	t.Parallel()
+	c, err := ParseConstraint("  >=2.0 <3  ")
+	require.NoError(t, err)
+	assert.Equal(t, ">=2.0 <3", c.String())
+}
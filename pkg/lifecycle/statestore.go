@@ -0,0 +1,114 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// StateTransition is a single journaled lifecycle state change. It records
+// what changed, when, and why, so that operators can reconstruct an
+// agent's history — in particular, why it ended up in [StateFailed] —
+// after the process that owned it is gone.
+type StateTransition struct {
+	// AgentID is the ID of the agent that changed state.
+	AgentID string `json:"agent_id"`
+
+	// From is the state before the transition.
+	From State `json:"from"`
+
+	// To is the state after the transition.
+	To State `json:"to"`
+
+	// Reason is a short, human-readable explanation of the transition
+	// (e.g., "start hook failed"). May be empty.
+	Reason string `json:"reason,omitempty"`
+
+	// Error is the message of the error that caused the transition, if
+	// any. Typically set only for transitions to [StateFailed].
+	Error string `json:"error,omitempty"`
+
+	// Timestamp is the time the transition occurred, in UTC.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StateStore persists lifecycle state transitions. Register one with
+// [BaseAgentBuilder.WithStateStore] to journal every transition of a
+// [BaseAgent] and to recover the previous transition on restart via
+// [BaseAgent.PreviousTransition].
+//
+// The SDK provides three implementations:
+//   - [MemoryStateStore] for tests and single-process deployments
+//   - [PostgresStateStore] for durable, queryable history
+//   - [RedisStateStore] for a bounded, low-latency journal
+//
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Append records a transition at the end of the agent's journal.
+	Append(ctx context.Context, t StateTransition) error
+
+	// Last returns the most recent transition recorded for agentID. The
+	// boolean is false if the agent has no recorded transitions.
+	Last(ctx context.Context, agentID string) (StateTransition, bool, error)
+
+	// History returns up to limit of the most recent transitions for
+	// agentID, oldest first. A limit of zero or less returns the full
+	// retained history.
+	History(ctx context.Context, agentID string, limit int) ([]StateTransition, error)
+}
+
+// MemoryStateStore is an in-memory [StateStore]. Its history does not
+// survive a process restart, so it is mainly useful for tests and for
+// exposing recent transitions on a debug endpoint.
+//
+// MemoryStateStore is safe for concurrent use.
+type MemoryStateStore struct {
+	mu      sync.RWMutex
+	entries map[string][]StateTransition
+}
+
+// Compile-time interface compliance check.
+var _ StateStore = (*MemoryStateStore)(nil)
+
+// NewMemoryStateStore returns an empty in-memory state store.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{entries: make(map[string][]StateTransition)}
+}
+
+// Append implements [StateStore].
+func (s *MemoryStateStore) Append(_ context.Context, t StateTransition) error {
+	if t.AgentID == "" {
+		return sserr.New(sserr.CodeValidation,
+			"lifecycle: state transition agent ID must not be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[t.AgentID] = append(s.entries[t.AgentID], t)
+	return nil
+}
+
+// Last implements [StateStore].
+func (s *MemoryStateStore) Last(_ context.Context, agentID string) (StateTransition, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := s.entries[agentID]
+	if len(entries) == 0 {
+		return StateTransition{}, false, nil
+	}
+	return entries[len(entries)-1], true, nil
+}
+
+// History implements [StateStore].
+func (s *MemoryStateStore) History(_ context.Context, agentID string, limit int) ([]StateTransition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := s.entries[agentID]
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	out := make([]StateTransition, len(entries))
+	copy(out, entries)
+	return out, nil
+}
@@ -0,0 +1,178 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/postgres"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// DefaultStateTable is the table used by [PostgresStateStore] when no
+// table name is supplied.
+const DefaultStateTable = "agent_state_transitions"
+
+// tableNamePattern restricts table names to optionally schema-qualified
+// SQL identifiers. Table names are interpolated into SQL, so anything that
+// does not match is rejected to rule out injection.
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}(\.[A-Za-z_][A-Za-z0-9_]{0,62})?$`)
+
+// PostgresStateStore is a [StateStore] backed by a PostgreSQL table. Every
+// transition is kept, giving operators a durable, queryable history of
+// each agent. Call [PostgresStateStore.EnsureSchema] once at startup (or
+// manage the table with your migration tool) before use.
+//
+// PostgresStateStore is safe for concurrent use.
+type PostgresStateStore struct {
+	client *postgres.Client
+	table  string
+}
+
+// Compile-time interface compliance check.
+var _ StateStore = (*PostgresStateStore)(nil)
+
+// NewPostgresStateStore returns a state store that journals transitions to
+// table using client. If table is empty, [DefaultStateTable] is used.
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidation] if client is
+// nil or table is not a valid, optionally schema-qualified identifier.
+//
+// Example:
+//
+//	store, err := lifecycle.NewPostgresStateStore(db, "")
+//	if err != nil {
+//	    return err
+//	}
+//	if err := store.EnsureSchema(ctx); err != nil {
+//	    return err
+//	}
+func NewPostgresStateStore(client *postgres.Client, table string) (*PostgresStateStore, error) {
+	if client == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"lifecycle: postgres state store requires a client")
+	}
+	if table == "" {
+		table = DefaultStateTable
+	}
+	if !tableNamePattern.MatchString(table) {
+		return nil, sserr.Newf(sserr.CodeValidation,
+			"lifecycle: invalid state table name %q", table)
+	}
+	return &PostgresStateStore{client: client, table: table}, nil
+}
+
+// EnsureSchema creates the journal table and its lookup index if they do
+// not already exist.
+func (s *PostgresStateStore) EnsureSchema(ctx context.Context) error {
+	stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id          BIGSERIAL PRIMARY KEY,
+	agent_id    TEXT        NOT NULL,
+	from_state  TEXT        NOT NULL,
+	to_state    TEXT        NOT NULL,
+	reason      TEXT        NOT NULL DEFAULT '',
+	error       TEXT        NOT NULL DEFAULT '',
+	occurred_at TIMESTAMPTZ NOT NULL
+)`, s.table)
+	if _, err := s.client.Exec(ctx, stmt); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to create state table")
+	}
+
+	index := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_agent_idx ON %s (agent_id, id DESC)`,
+		indexPrefix(s.table), s.table)
+	if _, err := s.client.Exec(ctx, index); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to create state table index")
+	}
+	return nil
+}
+
+// Append implements [StateStore].
+func (s *PostgresStateStore) Append(ctx context.Context, t StateTransition) error {
+	if t.AgentID == "" {
+		return sserr.New(sserr.CodeValidation,
+			"lifecycle: state transition agent ID must not be empty")
+	}
+	stmt := fmt.Sprintf(`INSERT INTO %s (agent_id, from_state, to_state, reason, error, occurred_at)
+VALUES ($1, $2, $3, $4, $5, $6)`, s.table)
+	_, err := s.client.Exec(ctx, stmt,
+		t.AgentID, string(t.From), string(t.To), t.Reason, t.Error, t.Timestamp)
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to append state transition")
+	}
+	return nil
+}
+
+// Last implements [StateStore].
+func (s *PostgresStateStore) Last(ctx context.Context, agentID string) (StateTransition, bool, error) {
+	stmt := fmt.Sprintf(`SELECT from_state, to_state, reason, error, occurred_at
+FROM %s WHERE agent_id = $1 ORDER BY id DESC LIMIT 1`, s.table)
+
+	t := StateTransition{AgentID: agentID}
+	var from, to string
+	err := s.client.QueryRow(ctx, stmt, agentID).Scan(&from, &to, &t.Reason, &t.Error, &t.Timestamp)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return StateTransition{}, false, nil
+	}
+	if err != nil {
+		return StateTransition{}, false, sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to load last state transition")
+	}
+	t.From, t.To = State(from), State(to)
+	t.Timestamp = t.Timestamp.UTC()
+	return t, true, nil
+}
+
+// History implements [StateStore].
+func (s *PostgresStateStore) History(ctx context.Context, agentID string, limit int) ([]StateTransition, error) {
+	// Select newest first so LIMIT keeps the most recent rows, then
+	// reverse into chronological order.
+	stmt := fmt.Sprintf(`SELECT from_state, to_state, reason, error, occurred_at
+FROM %s WHERE agent_id = $1 ORDER BY id DESC`, s.table)
+	args := []any{agentID}
+	if limit > 0 {
+		stmt += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := s.client.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to load state history")
+	}
+	defer rows.Close()
+
+	var history []StateTransition
+	for rows.Next() {
+		t := StateTransition{AgentID: agentID}
+		var from, to string
+		if err := rows.Scan(&from, &to, &t.Reason, &t.Error, &t.Timestamp); err != nil {
+			return nil, sserr.Wrap(err, sserr.CodeInternalDatabase,
+				"lifecycle: failed to scan state transition")
+		}
+		t.From, t.To = State(from), State(to)
+		t.Timestamp = t.Timestamp.UTC()
+		history = append(history, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to load state history")
+	}
+
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// indexPrefix derives an index name from a possibly schema-qualified table
+// name by replacing the schema separator.
+func indexPrefix(table string) string {
+	return strings.ReplaceAll(table, ".", "_")
+}
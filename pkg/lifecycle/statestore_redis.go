@@ -0,0 +1,117 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+const (
+	// DefaultStateKeyPrefix is the key prefix used by [RedisStateStore]
+	// when none is supplied. Each agent's journal is stored in a list at
+	// "<prefix><agentID>".
+	DefaultStateKeyPrefix = "lifecycle:journal:"
+
+	// DefaultStateMaxEntries is the number of transitions retained per
+	// agent by [RedisStateStore] when no limit is supplied.
+	DefaultStateMaxEntries = 1000
+)
+
+// RedisStateStore is a [StateStore] backed by one Redis list per agent.
+// The journal is capped at a fixed number of entries; older transitions
+// are discarded as new ones are appended.
+//
+// RedisStateStore is safe for concurrent use.
+type RedisStateStore struct {
+	client     *redis.Client
+	prefix     string
+	maxEntries int64
+}
+
+// Compile-time interface compliance check.
+var _ StateStore = (*RedisStateStore)(nil)
+
+// NewRedisStateStore returns a state store that journals transitions to
+// Redis lists. An empty prefix defaults to [DefaultStateKeyPrefix] and a
+// maxEntries of zero or less defaults to [DefaultStateMaxEntries].
+//
+// Returns a [*sserr.Error] with code [sserr.CodeValidation] if client is
+// nil.
+func NewRedisStateStore(client *redis.Client, prefix string, maxEntries int) (*RedisStateStore, error) {
+	if client == nil {
+		return nil, sserr.New(sserr.CodeValidation,
+			"lifecycle: redis state store requires a client")
+	}
+	if prefix == "" {
+		prefix = DefaultStateKeyPrefix
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultStateMaxEntries
+	}
+	return &RedisStateStore{client: client, prefix: prefix, maxEntries: int64(maxEntries)}, nil
+}
+
+// key returns the Redis list key for agentID.
+func (s *RedisStateStore) key(agentID string) string {
+	return s.prefix + agentID
+}
+
+// Append implements [StateStore].
+func (s *RedisStateStore) Append(ctx context.Context, t StateTransition) error {
+	if t.AgentID == "" {
+		return sserr.New(sserr.CodeValidation,
+			"lifecycle: state transition agent ID must not be empty")
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return sserr.Wrap(err, sserr.CodeInternal,
+			"lifecycle: failed to encode state transition")
+	}
+	key := s.key(t.AgentID)
+	if _, err := s.client.RPush(ctx, key, data); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to append state transition")
+	}
+	if err := s.client.LTrim(ctx, key, -s.maxEntries, -1); err != nil {
+		return sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to trim state journal")
+	}
+	return nil
+}
+
+// Last implements [StateStore].
+func (s *RedisStateStore) Last(ctx context.Context, agentID string) (StateTransition, bool, error) {
+	history, err := s.History(ctx, agentID, 1)
+	if err != nil {
+		return StateTransition{}, false, err
+	}
+	if len(history) == 0 {
+		return StateTransition{}, false, nil
+	}
+	return history[0], true, nil
+}
+
+// History implements [StateStore].
+func (s *RedisStateStore) History(ctx context.Context, agentID string, limit int) ([]StateTransition, error) {
+	start := int64(0)
+	if limit > 0 {
+		start = -int64(limit)
+	}
+	raw, err := s.client.LRange(ctx, s.key(agentID), start, -1)
+	if err != nil {
+		return nil, sserr.Wrap(err, sserr.CodeInternalDatabase,
+			"lifecycle: failed to load state history")
+	}
+	history := make([]StateTransition, 0, len(raw))
+	for _, entry := range raw {
+		var t StateTransition
+		if err := json.Unmarshal([]byte(entry), &t); err != nil {
+			return nil, sserr.Wrap(err, sserr.CodeInternal,
+				"lifecycle: failed to decode state transition")
+		}
+		history = append(history, t)
+	}
+	return history, nil
+}
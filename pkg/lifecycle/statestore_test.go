@@ -0,0 +1,466 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/StricklySoft/stricklysoft-core/internal/testutil/fakes"
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/postgres"
+	"github.com/StricklySoft/stricklysoft-core/pkg/clients/redis"
+	sserr "github.com/StricklySoft/stricklysoft-core/pkg/errors"
+)
+
+// failingStateStore is a StateStore whose every operation fails.
+type failingStateStore struct{}
+
+func (failingStateStore) Append(context.Context, StateTransition) error {
+	return errors.New("store unavailable")
+}
+
+func (failingStateStore) Last(context.Context, string) (StateTransition, bool, error) {
+	return StateTransition{}, false, errors.New("store unavailable")
+}
+
+func (failingStateStore) History(context.Context, string, int) ([]StateTransition, error) {
+	return nil, errors.New("store unavailable")
+}
+
+// transitionPairs reduces a history to its (from, to) pairs for compact
+// assertions.
+func transitionPairs(history []StateTransition) [][2]State {
+	pairs := make([][2]State, len(history))
+	for i, t := range history {
+		pairs[i] = [2]State{t.From, t.To}
+	}
+	return pairs
+}
+
+// ===========================================================================
+// MemoryStateStore Tests
+// ===========================================================================
+
+// TestMemoryStateStore_AppendLastHistory verifies that the in-memory store
+// returns the latest transition from Last and the most recent entries,
+// oldest first, from History.
+func TestMemoryStateStore_AppendLastHistory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+
+	_, ok, err := store.Last(ctx, "agent-1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for _, to := range []State{StateStarting, StateRunning, StateStopping} {
+		require.NoError(t, store.Append(ctx, StateTransition{AgentID: "agent-1", To: to}))
+	}
+	require.NoError(t, store.Append(ctx, StateTransition{AgentID: "agent-2", To: StateFailed}))
+
+	last, ok, err := store.Last(ctx, "agent-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, StateStopping, last.To)
+
+	all, err := store.History(ctx, "agent-1", 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	recent, err := store.History(ctx, "agent-1", 2)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, StateRunning, recent[0].To)
+	assert.Equal(t, StateStopping, recent[1].To)
+}
+
+// TestMemoryStateStore_Append_EmptyAgentID verifies that transitions
+// without an agent ID are rejected.
+func TestMemoryStateStore_Append_EmptyAgentID(t *testing.T) {
+	t.Parallel()
+	err := NewMemoryStateStore().Append(context.Background(), StateTransition{To: StateRunning})
+	require.Error(t, err)
+	assert.True(t, sserr.IsValidation(err))
+}
+
+// ===========================================================================
+// PostgresStateStore Tests
+// ===========================================================================
+
+// TestNewPostgresStateStore_Validation verifies that a nil client and
+// table names that are not plain identifiers are rejected.
+func TestNewPostgresStateStore_Validation(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+	client := postgres.NewFromPool(mock, nil)
+
+	_, err = NewPostgresStateStore(nil, "")
+	assert.True(t, sserr.IsValidation(err))
+
+	for _, table := range []string{"bad name", "t; DROP TABLE x", "a.b.c", "1abc"} {
+		_, err := NewPostgresStateStore(client, table)
+		assert.True(t, sserr.IsValidation(err), "table %q", table)
+	}
+
+	store, err := NewPostgresStateStore(client, "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultStateTable, store.table)
+
+	_, err = NewPostgresStateStore(client, "ops.agent_journal")
+	assert.NoError(t, err)
+}
+
+// TestPostgresStateStore_Append verifies that Append inserts one row with
+// the transition's fields.
+func TestPostgresStateStore_Append(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectExec("INSERT INTO agent_state_transitions").
+		WithArgs("agent-1", "starting", "failed", "start hook failed", "boom", ts).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	store, err := NewPostgresStateStore(postgres.NewFromPool(mock, nil), "")
+	require.NoError(t, err)
+	err = store.Append(context.Background(), StateTransition{
+		AgentID: "agent-1", From: StateStarting, To: StateFailed,
+		Reason: "start hook failed", Error: "boom", Timestamp: ts,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPostgresStateStore_Append_Error verifies that database errors are
+// wrapped with CodeInternalDatabase.
+func TestPostgresStateStore_Append_Error(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec("INSERT INTO agent_state_transitions").
+		WillReturnError(errors.New("connection reset"))
+
+	store, err := NewPostgresStateStore(postgres.NewFromPool(mock, nil), "")
+	require.NoError(t, err)
+	err = store.Append(context.Background(), StateTransition{AgentID: "agent-1", To: StateRunning})
+	require.Error(t, err)
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase))
+}
+
+// TestPostgresStateStore_Last verifies that Last scans the newest row and
+// reports false when the agent has no rows.
+func TestPostgresStateStore_Last(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY id DESC LIMIT 1")).
+		WithArgs("agent-1").
+		WillReturnRows(pgxmock.NewRows([]string{"from_state", "to_state", "reason", "error", "occurred_at"}).
+			AddRow("running", "failed", "worker crashed", "oom", ts))
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY id DESC LIMIT 1")).
+		WithArgs("agent-2").
+		WillReturnError(pgx.ErrNoRows)
+
+	store, err := NewPostgresStateStore(postgres.NewFromPool(mock, nil), "")
+	require.NoError(t, err)
+
+	last, ok, err := store.Last(context.Background(), "agent-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, StateTransition{
+		AgentID: "agent-1", From: StateRunning, To: StateFailed,
+		Reason: "worker crashed", Error: "oom", Timestamp: ts,
+	}, last)
+
+	_, ok, err = store.Last(context.Background(), "agent-2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPostgresStateStore_History verifies that History applies the limit
+// and returns rows in chronological order.
+func TestPostgresStateStore_History(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY id DESC LIMIT $2")).
+		WithArgs("agent-1", 2).
+		WillReturnRows(pgxmock.NewRows([]string{"from_state", "to_state", "reason", "error", "occurred_at"}).
+			AddRow("stopping", "stopped", "stopped", "", ts.Add(time.Second)).
+			AddRow("running", "stopping", "stop requested", "", ts))
+
+	store, err := NewPostgresStateStore(postgres.NewFromPool(mock, nil), "")
+	require.NoError(t, err)
+	history, err := store.History(context.Background(), "agent-1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]State{
+		{StateRunning, StateStopping},
+		{StateStopping, StateStopped},
+	}, transitionPairs(history))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPostgresStateStore_EnsureSchema verifies that EnsureSchema creates
+// the table and index.
+func TestPostgresStateStore_EnsureSchema(t *testing.T) {
+	t.Parallel()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS ops.journal")).
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX IF NOT EXISTS ops_journal_agent_idx ON ops.journal")).
+		WillReturnResult(pgxmock.NewResult("CREATE INDEX", 0))
+
+	store, err := NewPostgresStateStore(postgres.NewFromPool(mock, nil), "ops.journal")
+	require.NoError(t, err)
+	require.NoError(t, store.EnsureSchema(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// ===========================================================================
+// RedisStateStore Tests
+// ===========================================================================
+
+// TestRedisStateStore_RoundTrip verifies that transitions survive a JSON
+// round trip through Redis and that Last and History read them back.
+func TestRedisStateStore_RoundTrip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store, err := NewRedisStateStore(redis.NewFromClient(fakes.NewRedis(), nil), "", 0)
+	require.NoError(t, err)
+
+	_, ok, err := store.Last(ctx, "agent-1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := StateTransition{
+		AgentID: "agent-1", From: StateRunning, To: StateFailed,
+		Reason: "worker crashed", Error: "oom", Timestamp: ts,
+	}
+	require.NoError(t, store.Append(ctx, StateTransition{AgentID: "agent-1", To: StateRunning, Timestamp: ts}))
+	require.NoError(t, store.Append(ctx, want))
+
+	last, ok, err := store.Last(ctx, "agent-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, want, last)
+
+	history, err := store.History(ctx, "agent-1", 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+// TestRedisStateStore_Trim verifies that the journal is capped at
+// maxEntries, keeping the most recent transitions.
+func TestRedisStateStore_Trim(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fake := fakes.NewRedis()
+	store, err := NewRedisStateStore(redis.NewFromClient(fake, nil), "journal:", 2)
+	require.NoError(t, err)
+
+	for _, to := range []State{StateStarting, StateRunning, StateStopping} {
+		require.NoError(t, store.Append(ctx, StateTransition{AgentID: "agent-1", To: to}))
+	}
+
+	history, err := store.History(ctx, "agent-1", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, StateRunning, history[0].To)
+	assert.Equal(t, StateStopping, history[1].To)
+	assert.Equal(t, 1, fake.Keys())
+}
+
+// TestRedisStateStore_Error verifies that Redis failures are wrapped with
+// CodeInternalDatabase.
+func TestRedisStateStore_Error(t *testing.T) {
+	t.Parallel()
+	fake := fakes.NewRedis()
+	fake.SetError(errors.New("connection refused"))
+	store, err := NewRedisStateStore(redis.NewFromClient(fake, nil), "", 0)
+	require.NoError(t, err)
+
+	err = store.Append(context.Background(), StateTransition{AgentID: "agent-1", To: StateRunning})
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase))
+	_, _, err = store.Last(context.Background(), "agent-1")
+	assert.True(t, sserr.HasCode(err, sserr.CodeInternalDatabase))
+}
+
+// ===========================================================================
+// BaseAgent Journal Tests
+// ===========================================================================
+
+// TestBaseAgent_Journal_RecordsTransitions verifies that every lifecycle
+// transition is journaled in order with its reason and timestamp.
+func TestBaseAgent_Journal_RecordsTransitions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+	agent, err := NewBaseAgentBuilder("agent-1", "test-agent", "1.0.0").
+		WithStateStore(store).
+		Build()
+	require.NoError(t, err)
+
+	require.NoError(t, agent.Start(ctx))
+	require.NoError(t, agent.Pause(ctx))
+	require.NoError(t, agent.Resume(ctx))
+	require.NoError(t, agent.Stop(ctx))
+
+	history, err := agent.History(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]State{
+		{StateUnknown, StateStarting},
+		{StateStarting, StateRunning},
+		{StateRunning, StatePaused},
+		{StatePaused, StateRunning},
+		{StateRunning, StateStopping},
+		{StateStopping, StateStopped},
+	}, transitionPairs(history))
+	assert.Equal(t, "start requested", history[0].Reason)
+	assert.Equal(t, "stopped", history[5].Reason)
+	for _, tr := range history {
+		assert.Equal(t, "agent-1", tr.AgentID)
+		assert.False(t, tr.Timestamp.IsZero())
+	}
+}
+
+// TestBaseAgent_Journal_RecordsHookFailure verifies that a failed hook is
+// journaled as a transition to StateFailed carrying the hook's error.
+func TestBaseAgent_Journal_RecordsHookFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+	agent, err := NewBaseAgentBuilder("agent-1", "test-agent", "1.0.0").
+		WithStateStore(store).
+		WithOnStart(func(context.Context) error { return errors.New("database unreachable") }).
+		Build()
+	require.NoError(t, err)
+
+	require.Error(t, agent.Start(ctx))
+
+	last, ok, err := store.Last(ctx, "agent-1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, StateFailed, last.To)
+	assert.Equal(t, "start hook failed", last.Reason)
+	assert.Equal(t, "database unreachable", last.Error)
+}
+
+// TestBaseAgent_PreviousTransition verifies that a new agent instance
+// loads the last transition recorded by a previous instance with the same
+// ID and reports it via PreviousTransition and Info.
+func TestBaseAgent_PreviousTransition(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+
+	first, err := NewBaseAgentBuilder("agent-1", "test-agent", "1.0.0").
+		WithStateStore(store).
+		Build()
+	require.NoError(t, err)
+	require.NoError(t, first.Start(ctx))
+	require.NoError(t, first.SetStateWithReason(StateFailed, "worker crashed", errors.New("oom")))
+
+	second, err := NewBaseAgentBuilder("agent-1", "test-agent", "1.0.0").
+		WithStateStore(store).
+		Build()
+	require.NoError(t, err)
+	prev, ok := second.PreviousTransition()
+	require.True(t, ok, "previous transition should be available before Start")
+	assert.Equal(t, StateFailed, prev.To)
+	assert.Equal(t, "worker crashed", prev.Reason)
+	assert.Equal(t, "oom", prev.Error)
+
+	require.NoError(t, second.Start(ctx))
+
+	prev, ok = second.PreviousTransition()
+	require.True(t, ok)
+	assert.Equal(t, StateFailed, prev.To, "this run's transitions must not replace the previous run's")
+
+	info := second.Info()
+	require.NotNil(t, info.Previous)
+	assert.Equal(t, StateFailed, info.Previous.To)
+}
+
+// TestBaseAgent_InfoPreviousBeforeStart verifies that Info reports the
+// previous run's last transition before the agent is started.
+func TestBaseAgent_InfoPreviousBeforeStart(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+	require.NoError(t, store.Append(ctx, StateTransition{
+		AgentID: "agent-1", From: StateRunning, To: StateStopped,
+		Reason: "shutdown", Timestamp: time.Now(),
+	}))
+
+	agent, err := NewBaseAgentBuilder("agent-1", "test-agent", "1.0.0").
+		WithStateStore(store).
+		Build()
+	require.NoError(t, err)
+	info := agent.Info()
+	require.NotNil(t, info.Previous)
+	assert.Equal(t, StateStopped, info.Previous.To)
+	assert.Equal(t, "shutdown", info.Previous.Reason)
+}
+
+// TestBaseAgent_Journal_StoreFailure verifies that store errors are logged
+// without failing lifecycle transitions.
+func TestBaseAgent_Journal_StoreFailure(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	agent, err := NewBaseAgentBuilder("agent-1", "test-agent", "1.0.0").
+		WithStateStore(failingStateStore{}).
+		Build()
+	require.NoError(t, err)
+
+	require.NoError(t, agent.Start(ctx))
+	assert.Equal(t, StateRunning, agent.State())
+	_, ok := agent.PreviousTransition()
+	assert.False(t, ok)
+	require.NoError(t, agent.Stop(ctx))
+}
+
+// TestBaseAgent_OnTransition verifies that transition handlers receive the
+// reason and cause of each transition.
+func TestBaseAgent_OnTransition(t *testing.T) {
+	t.Parallel()
+	var got []StateTransition
+	agent, err := NewBaseAgentBuilder("agent-1", "test-agent", "1.0.0").
+		OnTransition(func(t StateTransition) { got = append(got, t) }).
+		OnTransition(func(StateTransition) { panic("handler bug") }).
+		Build()
+	require.NoError(t, err)
+
+	require.NoError(t, agent.SetStateWithReason(StateFailed, "watchdog", errors.New("stalled")))
+	require.Len(t, got, 1)
+	assert.Equal(t, StateUnknown, got[0].From)
+	assert.Equal(t, StateFailed, got[0].To)
+	assert.Equal(t, "watchdog", got[0].Reason)
+	assert.Equal(t, "stalled", got[0].Error)
+
+	history, err := agent.History(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Nil(t, history, "History without a store must return nil")
+}